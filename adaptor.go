@@ -7,6 +7,7 @@ type Adaptor struct {
 	port        string
 	connected   bool
 	adaptorType string
+	commands    map[string]func(map[string]interface{}) interface{}
 }
 
 type AdaptorInterface interface {
@@ -19,6 +20,9 @@ type AdaptorInterface interface {
 	SetConnected(bool)
 	SetName(string)
 	SetPort(string)
+	Command(string) func(map[string]interface{}) interface{}
+	Commands() map[string]func(map[string]interface{}) interface{}
+	AddCommand(string, func(map[string]interface{}) interface{})
 	ToJSON() *JSONConnection
 }
 
@@ -31,6 +35,7 @@ func NewAdaptor(name string, adaptorType string, v ...interface{}) *Adaptor {
 		adaptorType: adaptorType,
 		name:        name,
 		port:        "",
+		commands:    make(map[string]func(map[string]interface{}) interface{}),
 	}
 
 	for i := range v {
@@ -71,9 +76,33 @@ func (a *Adaptor) SetConnected(b bool) {
 	a.connected = b
 }
 
+func (a *Adaptor) Command(name string) func(map[string]interface{}) interface{} {
+	return a.commands[name]
+}
+
+func (a *Adaptor) Commands() map[string]func(map[string]interface{}) interface{} {
+	return a.commands
+}
+
+// AddCommand adds a command to the connection, such as a bus scan, which
+// the API runs like the commands of devices.
+func (a *Adaptor) AddCommand(name string, f func(map[string]interface{}) interface{}) {
+	if a.commands == nil {
+		a.commands = make(map[string]func(map[string]interface{}) interface{})
+	}
+	a.commands[name] = f
+}
+
 func (a *Adaptor) ToJSON() *JSONConnection {
-	return &JSONConnection{
-		Name:    a.Name(),
-		Adaptor: a.Type(),
+	jsonConnection := &JSONConnection{
+		Name:     a.Name(),
+		Adaptor:  a.Type(),
+		Commands: []string{},
 	}
+
+	for command := range a.Commands() {
+		jsonConnection.Commands = append(jsonConnection.Commands, command)
+	}
+
+	return jsonConnection
 }
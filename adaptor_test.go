@@ -12,3 +12,18 @@ func TestAdaptor(t *testing.T) {
 	a.SetConnected(true)
 	Assert(t, a.Connected(), true)
 }
+
+func TestAdaptorCommands(t *testing.T) {
+	a := NewAdaptor("myAdaptor", "testBot")
+	Assert(t, len(a.Commands()), 0)
+	Assert(t, a.ToJSON().Commands, []string{})
+	a.AddCommand("scan", func(params map[string]interface{}) interface{} {
+		return params["bus"]
+	})
+	Assert(t, a.Command("scan")(map[string]interface{}{"bus": 1}), 1)
+	Assert(t, a.ToJSON().Commands, []string{"scan"})
+
+	var b Adaptor
+	b.AddCommand("scan", func(params map[string]interface{}) interface{} { return nil })
+	Assert(t, len(b.Commands()), 1)
+}
@@ -105,6 +105,7 @@ func (a *api) Start() {
 	mcpCommandRoute := "/api/commands/:command"
 	deviceCommandRoute := "/api/robots/:robot/devices/:device/commands/:command"
 	robotCommandRoute := "/api/robots/:robot/commands/:command"
+	connectionCommandRoute := "/api/robots/:robot/connections/:connection/commands/:command"
 
 	a.Get("/api/commands", a.mcpCommands)
 	a.Get(mcpCommandRoute, a.executeMcpCommand)
@@ -122,6 +123,9 @@ func (a *api) Start() {
 	a.Post(deviceCommandRoute, a.executeDeviceCommand)
 	a.Get("/api/robots/:robot/connections", a.robotConnections)
 	a.Get("/api/robots/:robot/connections/:connection", a.robotConnection)
+	a.Get("/api/robots/:robot/connections/:connection/commands", a.robotConnectionCommands)
+	a.Get(connectionCommandRoute, a.executeConnectionCommand)
+	a.Post(connectionCommandRoute, a.executeConnectionCommand)
 	a.Get("/api/", a.mcp)
 
 	// robeaux
@@ -249,6 +253,13 @@ func (a *api) robotConnection(res http.ResponseWriter, req *http.Request) {
 	)
 }
 
+func (a *api) robotConnectionCommands(res http.ResponseWriter, req *http.Request) {
+	a.writeJSON(
+		map[string]interface{}{"commands": a.gobot.Robot(req.URL.Query().Get(":robot")).
+			Connection(req.URL.Query().Get(":connection")).ToJSON().Commands}, res,
+	)
+}
+
 func (a *api) executeMcpCommand(res http.ResponseWriter, req *http.Request) {
 	a.executeCommand(a.gobot.Command(req.URL.Query().Get(":command")),
 		res,
@@ -266,6 +277,16 @@ func (a *api) executeDeviceCommand(res http.ResponseWriter, req *http.Request) {
 	)
 }
 
+func (a *api) executeConnectionCommand(res http.ResponseWriter, req *http.Request) {
+	a.executeCommand(
+		a.gobot.Robot(req.URL.Query().Get(":robot")).
+			Connection(req.URL.Query().Get(":connection")).
+			Command(req.URL.Query().Get(":command")),
+		res,
+		req,
+	)
+}
+
 func (a *api) executeRobotCommand(res http.ResponseWriter, req *http.Request) {
 	a.executeCommand(
 		a.gobot.Robot(req.URL.Query().Get(":robot")).
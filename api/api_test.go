@@ -278,6 +278,49 @@ func TestRobotConnection(t *testing.T) {
 	gobot.Assert(t, body["connection"].(map[string]interface{})["name"].(string), "Connection1")
 }
 
+func TestRobotConnectionCommands(t *testing.T) {
+	a := initTestAPI()
+	request, _ := http.NewRequest("GET",
+		"/api/robots/Robot1/connections/Connection1/commands",
+		nil,
+	)
+	response := httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+
+	var body map[string]interface{}
+	json.NewDecoder(response.Body).Decode(&body)
+	gobot.Assert(t, body["commands"].([]interface{}), []interface{}{"TestAdaptorCommand"})
+}
+
+func TestExecuteRobotConnectionCommand(t *testing.T) {
+	var body interface{}
+	a := initTestAPI()
+
+	// known command
+	request, _ := http.NewRequest("POST",
+		"/api/robots/Robot1/connections/Connection1/commands/TestAdaptorCommand",
+		bytes.NewBufferString(`{"name":"human"}`),
+	)
+	request.Header.Add("Content-Type", "application/json")
+	response := httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+
+	json.NewDecoder(response.Body).Decode(&body)
+	gobot.Assert(t, body.(map[string]interface{})["result"].(string), "hi human")
+
+	// unknown command
+	request, _ = http.NewRequest("POST",
+		"/api/robots/Robot1/connections/Connection1/commands/AdaptorCommand1",
+		bytes.NewBufferString(`{"name":"human"}`),
+	)
+	request.Header.Add("Content-Type", "application/json")
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+
+	json.NewDecoder(response.Body).Decode(&body)
+	gobot.Assert(t, body, "Unknown Command")
+}
+
 func TestAPIRouter(t *testing.T) {
 	a := initTestAPI()
 
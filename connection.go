@@ -5,9 +5,13 @@ import (
 	"log"
 )
 
+// JSONConnection holds a JSON representation of a connection. Adaptors
+// may describe the connected hardware in Details.
 type JSONConnection struct {
-	Name    string `json:"name"`
-	Adaptor string `json:"adaptor"`
+	Name     string                 `json:"name"`
+	Adaptor  string                 `json:"adaptor"`
+	Commands []string               `json:"commands"`
+	Details  map[string]interface{} `json:"details,omitempty"`
 }
 
 type Connection AdaptorInterface
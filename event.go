@@ -1,13 +1,18 @@
 package gobot
 
+import "sync"
+
 type callback struct {
 	f    func(interface{})
 	once bool
 }
 
+// Event delivers published values to its callbacks. Callbacks may be
+// added and values published from any goroutine.
 type Event struct {
 	Chan      chan interface{}
 	Callbacks []callback
+	mutex     sync.Mutex
 }
 
 func NewEvent() *Event {
@@ -32,6 +37,7 @@ func (e *Event) Write(data interface{}) {
 
 func (e *Event) Read() {
 	for s := range e.Chan {
+		e.mutex.Lock()
 		tmp := []callback{}
 		for i := range e.Callbacks {
 			go e.Callbacks[i].f(s)
@@ -40,5 +46,12 @@ func (e *Event) Read() {
 			}
 		}
 		e.Callbacks = tmp
+		e.mutex.Unlock()
 	}
 }
+
+func (e *Event) addCallback(c callback) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.Callbacks = append(e.Callbacks, c)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/beaglebone"
+	"github.com/edmontongo/gobot/platforms/gpio"
+	"github.com/edmontongo/gobot/platforms/i2c"
+)
+
+func main() {
+	gbot := gobot.NewGobot()
+	beagleboneAdaptor := beaglebone.NewBeagleboneAdaptor("beaglebone")
+
+	expander := i2c.NewMCP23017Driver(beagleboneAdaptor, "expander")
+	expander.InterruptPin = "P8_7"
+	expander.SetPullUp("A0", true)
+	button := gpio.NewMakeyButtonDriver(expander, "button", "A0")
+	led := gpio.NewLedDriver(expander, "led", "B0")
+
+	adc := i2c.NewADS1115Driver(beagleboneAdaptor, "adc")
+	adc.AnalogMillivolts = true
+	sensor := gpio.NewAnalogSensorDriver(adc, "sensor", "0")
+
+	work := func() {
+		gobot.On(button.Event("push"), func(data interface{}) {
+			led.Toggle()
+		})
+		gobot.On(sensor.Event("data"), func(data interface{}) {
+			fmt.Println("potentiometer", data, "mV")
+		})
+	}
+
+	robot := gobot.NewRobot("panelBot",
+		[]gobot.Connection{beagleboneAdaptor, expander, adc},
+		[]gobot.Device{button, led, sensor},
+		work,
+	)
+
+	gbot.AddRobot(robot)
+
+	gbot.Start()
+}
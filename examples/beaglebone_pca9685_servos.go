@@ -0,0 +1,35 @@
+package main
+
+import (
+	"time"
+
+	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/beaglebone"
+	"github.com/edmontongo/gobot/platforms/gpio"
+	"github.com/edmontongo/gobot/platforms/i2c"
+)
+
+func main() {
+	gbot := gobot.NewGobot()
+	beagleboneAdaptor := beaglebone.NewBeagleboneAdaptor("beaglebone")
+	pca9685 := i2c.NewPCA9685Driver(beagleboneAdaptor, "pca9685")
+	hip := gpio.NewServoDriver(pca9685, "hip", "0")
+	knee := gpio.NewServoDriver(pca9685, "knee", "1")
+	led := gpio.NewLedDriver(pca9685, "led", "15")
+
+	work := func() {
+		led.Breathe(2 * time.Second)
+		hip.Sweep(45, 135, 2*time.Second)
+		knee.Sweep(60, 120, time.Second)
+	}
+
+	robot := gobot.NewRobot("hexapodBot",
+		[]gobot.Connection{beagleboneAdaptor, pca9685},
+		[]gobot.Device{hip, knee, led},
+		work,
+	)
+
+	gbot.AddRobot(robot)
+
+	gbot.Start()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/beaglebone"
+	"github.com/edmontongo/gobot/platforms/i2c"
+)
+
+func main() {
+	gbot := gobot.NewGobot()
+	beagleboneAdaptor := beaglebone.NewBeagleboneAdaptor("beaglebone")
+	imu := i2c.NewMPU6050Driver(beagleboneAdaptor, "imu")
+	compass := i2c.NewHMC5883LDriver(beagleboneAdaptor, "compass")
+
+	work := func() {
+		fmt.Println("Keep still...")
+		bias, err := imu.CalibrateGyro(200)
+		fmt.Println("gyro bias", bias, err)
+
+		fmt.Println("Turn the robot in every direction...")
+		offset, err := compass.CalibrateHardIron(20 * time.Second)
+		fmt.Println("hard iron offset", offset, err)
+
+		gobot.Every(500*time.Millisecond, func() {
+			fmt.Println("heading", compass.TiltCompensatedHeading(imu.Acceleration()))
+		})
+	}
+
+	robot := gobot.NewRobot("compassBot",
+		[]gobot.Connection{beagleboneAdaptor},
+		[]gobot.Device{imu, compass},
+		work,
+	)
+
+	gbot.AddRobot(robot)
+
+	gbot.Start()
+}
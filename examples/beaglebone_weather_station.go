@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/beaglebone"
+	"github.com/edmontongo/gobot/platforms/i2c"
+)
+
+func main() {
+	gbot := gobot.NewGobot()
+	beagleboneAdaptor := beaglebone.NewBeagleboneAdaptor("beaglebone")
+	barometer := i2c.NewBMP280Driver(beagleboneAdaptor, "barometer")
+	hygrometer := i2c.NewHTU21DDriver(beagleboneAdaptor, "hygrometer")
+	light := i2c.NewTSL2561Driver(beagleboneAdaptor, "light")
+
+	work := func() {
+		gobot.On(barometer.Event("pressure"), func(data interface{}) {
+			fmt.Printf("pressure %.0f Pa\n", data)
+		})
+		gobot.On(hygrometer.Event("humidity"), func(data interface{}) {
+			fmt.Printf("humidity %.1f %%\n", data)
+		})
+		gobot.On(hygrometer.Event("temperature"), func(data interface{}) {
+			fmt.Printf("temperature %.1f C\n", data)
+		})
+		gobot.On(light.Event("illuminance"), func(data interface{}) {
+			fmt.Printf("illuminance %.0f lux\n", data)
+		})
+	}
+
+	robot := gobot.NewRobot("weatherBot",
+		[]gobot.Connection{beagleboneAdaptor},
+		[]gobot.Device{barometer, hygrometer, light},
+		work,
+	)
+
+	gbot.AddRobot(robot)
+
+	gbot.Start()
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"github.com/edmontongo/gobot/platforms/beaglebone"
+	"github.com/edmontongo/gobot/platforms/firmata"
+	"github.com/edmontongo/gobot/platforms/i2c"
+	"github.com/edmontongo/gobot/platforms/linux"
+)
+
+// linuxBoards are the boards the linux adaptor can scan, by lower case
+// name.
+var linuxBoards = map[string]linux.Board{
+	"beaglebone":  linux.Beaglebone,
+	"raspberrypi": linux.RaspberryPi,
+	"edison":      linux.Edison,
+	"chip":        linux.Chip,
+}
+
+func Scan() cli.Command {
+	return cli.Command{
+		Name:  "scan",
+		Usage: "Scan an I2C bus for devices, and suggest their drivers",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "adaptor",
+				Value: "beaglebone",
+				Usage: "adaptor of the bus: beaglebone, firmata or linux",
+			},
+			cli.StringFlag{
+				Name:  "port",
+				Value: "/dev/ttyACM0",
+				Usage: "serial port of the Firmata board",
+			},
+			cli.StringFlag{
+				Name:  "board",
+				Value: "raspberrypi",
+				Usage: "board of the linux adaptor: beaglebone, raspberrypi, edison or chip",
+			},
+		},
+		Action: func(c *cli.Context) {
+			bus, err := scanAdaptor(c)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			defer bus.Finalize()
+			if !bus.Connect() {
+				fmt.Println("Could not connect to", c.String("adaptor"))
+				return
+			}
+
+			results, err := i2c.Scan(bus)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			if len(results) == 0 {
+				fmt.Println("No devices found.")
+				return
+			}
+			for _, result := range results {
+				drivers := strings.Join(result.Drivers, ", ")
+				if drivers == "" {
+					drivers = "unknown"
+				}
+				fmt.Printf("%#02x  %v\n", result.Address, drivers)
+			}
+		},
+	}
+}
+
+// scanAdaptor returns the adaptor the flags of c name.
+func scanAdaptor(c *cli.Context) (interface {
+	i2c.I2cBus
+	Connect() bool
+	Finalize() bool
+}, error) {
+	switch c.String("adaptor") {
+	case "beaglebone":
+		return beaglebone.NewBeagleboneAdaptor("beaglebone"), nil
+	case "firmata":
+		return firmata.NewFirmataAdaptor("firmata", c.String("port")), nil
+	case "linux":
+		board, ok := linuxBoards[strings.ToLower(c.String("board"))]
+		if !ok {
+			return nil, fmt.Errorf("Unknown board %v.", c.String("board"))
+		}
+		return linux.NewLinuxAdaptor("linux", board), nil
+	}
+	return nil, fmt.Errorf("Unknown adaptor %v.", c.String("adaptor"))
+}
@@ -1,28 +1,45 @@
 package beaglebone
 
 import (
-	"github.com/edmontongo/gobot"
 	"testing"
+
+	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/gpio"
+	"github.com/edmontongo/gobot/platforms/i2c"
+	"github.com/edmontongo/gobot/platforms/linux"
 )
 
+var _ gobot.AdaptorInterface = (*BeagleboneAdaptor)(nil)
+var _ gpio.DirectPin = (*BeagleboneAdaptor)(nil)
+var _ gpio.PwmController = (*BeagleboneAdaptor)(nil)
+var _ gpio.ServoPulseWriter = (*BeagleboneAdaptor)(nil)
+var _ gpio.DigitalPulseReader = (*BeagleboneAdaptor)(nil)
+var _ gpio.ToneWriter = (*BeagleboneAdaptor)(nil)
+var _ gpio.DigitalWatcher = (*BeagleboneAdaptor)(nil)
+var _ i2c.I2cBus = (*BeagleboneAdaptor)(nil)
+
 func initTestBeagleboneAdaptor() *BeagleboneAdaptor {
-	b := NewBeagleboneAdaptor("bot")
-	b.connect = func() {}
-	return b
+	return NewBeagleboneAdaptor("bot")
 }
 
-func TestBeagleboneAdaptorFinalize(t *testing.T) {
+func TestBeagleboneAdaptor(t *testing.T) {
 	a := initTestBeagleboneAdaptor()
-	gobot.Assert(t, a.Finalize(), true)
+	gobot.Assert(t, a.Name(), "bot")
+	gobot.Assert(t, a.Type(), "BeagleboneAdaptor")
+	gobot.Assert(t, a.Board.Name, linux.Beaglebone.Name)
+	gobot.Refute(t, a.Command("I2cScan"), nil)
 }
-func TestBeagleboneAdaptorConnect(t *testing.T) {
+
+func TestBeagleboneAdaptorFinalize(t *testing.T) {
 	a := initTestBeagleboneAdaptor()
-	gobot.Assert(t, a.Connect(), true)
+	gobot.Assert(t, a.Finalize(), true)
 }
+
 func TestBeagleboneAdaptorDisconnect(t *testing.T) {
 	a := initTestBeagleboneAdaptor()
 	gobot.Assert(t, a.Disconnect(), true)
 }
+
 func TestBeagleboneAdaptorReconnect(t *testing.T) {
 	a := initTestBeagleboneAdaptor()
 	gobot.Assert(t, a.Reconnect(), true)
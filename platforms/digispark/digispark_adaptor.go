@@ -1,16 +1,38 @@
 package digispark
 
 import (
-	"github.com/edmontongo/gobot"
+	"fmt"
+	"math"
 	"strconv"
+	"time"
+
+	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/i2c"
 )
 
+// pwmClock is the frequency of the timer LittleWire uses for PWM, which
+// counts to 256 each period.
+const pwmClock = 16500000
+
+// pwmPrescalers are the clock dividers LittleWire can set for PWM.
+var pwmPrescalers = []uint{1, 8, 64, 256, 1024}
+
 type DigisparkAdaptor struct {
 	gobot.Adaptor
 	littleWire *LittleWire
 	servo      bool
 	pwm        bool
-	connect    func(*DigisparkAdaptor)
+	// servos and pwms hold the last value written to channels A and B,
+	// since LittleWire always updates both
+	servos [2]uint8
+	pwms   [2]uint8
+	// duties, inverted and disabled hold the PwmController settings of
+	// channels A and B
+	duties    [2]float64
+	inverted  [2]bool
+	disabled  [2]bool
+	prescaler uint
+	connect   func(*DigisparkAdaptor)
 }
 
 func NewDigisparkAdaptor(name string) *DigisparkAdaptor {
@@ -45,25 +67,138 @@ func (d *DigisparkAdaptor) DigitalWrite(pin string, level byte) {
 	d.littleWire.DigitalWrite(uint8(p), level)
 }
 func (d *DigisparkAdaptor) DigitalRead(pin string, level byte) {}
+
+// PwmWrite sets the duty cycle of pin "0" (channel A) or "1" (channel B),
+// leaving the other channel as it was. Other pins are ignored.
 func (d *DigisparkAdaptor) PwmWrite(pin string, value byte) {
+	d.PwmSetDutyCycle(pin, float64(value)/255)
+}
+
+// PwmSetPeriod sets the period of both PWM channels, which share a timer.
+// The period must be close to one of about 15.5µs, 124µs, 993µs, 3.97ms
+// or 15.9ms, the periods LittleWire supports.
+func (d *DigisparkAdaptor) PwmSetPeriod(pin string, period time.Duration) error {
+	if _, ok := pwmChannel(pin); !ok {
+		return fmt.Errorf("digispark: pin %v is not a PWM pin", pin)
+	}
+	prescaler, err := pwmPrescaler(period)
+	if err != nil {
+		return err
+	}
+	d.initPwm()
+	if prescaler != d.prescaler {
+		d.littleWire.PwmUpdatePrescaler(prescaler)
+		d.prescaler = prescaler
+	}
+	return nil
+}
+
+// PwmSetDutyCycle sets the fraction of each period, from 0 to 1, that pin
+// is high, to the nearest of 256 levels.
+func (d *DigisparkAdaptor) PwmSetDutyCycle(pin string, duty float64) error {
+	channel, ok := pwmChannel(pin)
+	if !ok {
+		return fmt.Errorf("digispark: pin %v is not a PWM pin", pin)
+	}
+	d.duties[channel] = math.Max(0, math.Min(1, duty))
+	d.updatePwm(channel)
+	return nil
+}
+
+// PwmSetEnabled turns PWM on pin on or off, leaving it low when off.
+func (d *DigisparkAdaptor) PwmSetEnabled(pin string, enabled bool) error {
+	channel, ok := pwmChannel(pin)
+	if !ok {
+		return fmt.Errorf("digispark: pin %v is not a PWM pin", pin)
+	}
+	d.disabled[channel] = !enabled
+	d.updatePwm(channel)
+	return nil
+}
+
+// PwmSetPolarity inverts the duty cycle of pin when inverted is true.
+func (d *DigisparkAdaptor) PwmSetPolarity(pin string, inverted bool) error {
+	channel, ok := pwmChannel(pin)
+	if !ok {
+		return fmt.Errorf("digispark: pin %v is not a PWM pin", pin)
+	}
+	d.inverted[channel] = inverted
+	d.updatePwm(channel)
+	return nil
+}
+
+func (d *DigisparkAdaptor) initPwm() {
 	if d.pwm == false {
 		d.littleWire.PwmInit()
 		d.littleWire.PwmUpdatePrescaler(1)
+		d.prescaler = 1
 		d.pwm = true
 	}
-	d.littleWire.PwmUpdateCompare(value, value)
 }
+
+// updatePwm writes the compare value of channel, leaving the other channel
+// as it was.
+func (d *DigisparkAdaptor) updatePwm(channel int) {
+	d.initPwm()
+	d.pwms[channel] = pwmCompare(d.duties[channel], d.inverted[channel], d.disabled[channel])
+	d.littleWire.PwmUpdateCompare(d.pwms[0], d.pwms[1])
+}
+
 func (d *DigisparkAdaptor) AnalogRead(string) int { return -1 }
 
 func (d *DigisparkAdaptor) InitServo() {}
+
+// ServoWrite moves the servo on pin "0" (channel A) or "1" (channel B),
+// leaving the other channel as it was. Other pins are ignored.
 func (d *DigisparkAdaptor) ServoWrite(pin string, angle uint8) {
+	channel, ok := pwmChannel(pin)
+	if !ok {
+		return
+	}
 	if d.servo == false {
 		d.littleWire.ServoInit()
 		d.servo = true
 	}
-	d.littleWire.ServoUpdateLocation(angle, angle)
+	d.servos[channel] = angle
+	d.littleWire.ServoUpdateLocation(d.servos[0], d.servos[1])
 }
 
-func (d *DigisparkAdaptor) I2cStart(byte)           {}
-func (d *DigisparkAdaptor) I2cRead(uint16) []uint16 { return make([]uint16, 0) }
-func (d *DigisparkAdaptor) I2cWrite([]uint16)       {}
+// pwmCompare returns the 8-bit compare value of a channel.
+func pwmCompare(duty float64, inverted bool, disabled bool) uint8 {
+	if disabled {
+		return 0
+	}
+	if inverted {
+		duty = 1 - duty
+	}
+	return uint8(duty*255 + 0.5)
+}
+
+// pwmPrescaler returns the prescaler giving period, within 5%.
+func pwmPrescaler(period time.Duration) (uint, error) {
+	for _, prescaler := range pwmPrescalers {
+		p := time.Duration(prescaler) * 256 * time.Second / pwmClock
+		if math.Abs(float64(period-p)) <= float64(p)/20 {
+			return prescaler, nil
+		}
+	}
+	return 0, fmt.Errorf("digispark: unsupported PWM period %v", period)
+}
+
+// pwmChannel returns the LittleWire channel, 0 for A or 1 for B, of the
+// pwm pin.
+func pwmChannel(pin string) (int, bool) {
+	switch pin {
+	case "0":
+		return 0, true
+	case "1":
+		return 1, true
+	}
+	return 0, false
+}
+
+// I2cOpen returns an error, as the I2C reads and writes of LittleWire are
+// not wrapped yet.
+func (d *DigisparkAdaptor) I2cOpen(address byte) (i2c.I2cDevice, error) {
+	return nil, fmt.Errorf("digispark: I2C is not supported")
+}
@@ -1,8 +1,11 @@
 package digispark
 
 import (
-	"github.com/edmontongo/gobot"
 	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/gpio"
 )
 
 func initTestDigisparkAdaptor() *DigisparkAdaptor {
@@ -30,3 +33,30 @@ func TestDigisparkAdaptorReconnect(t *testing.T) {
 	a := initTestDigisparkAdaptor()
 	gobot.Assert(t, a.Reconnect(), true)
 }
+
+func TestDigisparkAdaptorPwmChannel(t *testing.T) {
+	channel, ok := pwmChannel("0")
+	gobot.Assert(t, channel, 0)
+	gobot.Assert(t, ok, true)
+	channel, ok = pwmChannel("1")
+	gobot.Assert(t, channel, 1)
+	gobot.Assert(t, ok, true)
+	_, ok = pwmChannel("2")
+	gobot.Assert(t, ok, false)
+}
+
+func TestDigisparkAdaptorPwmPrescaler(t *testing.T) {
+	prescaler, err := pwmPrescaler(993 * time.Microsecond)
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, prescaler, uint(64))
+	prescaler, _ = pwmPrescaler(gpio.PwmFrequency(63))
+	gobot.Assert(t, prescaler, uint(1024))
+	_, err = pwmPrescaler(20 * time.Millisecond)
+	gobot.Refute(t, err, nil)
+}
+
+func TestDigisparkAdaptorPwmCompare(t *testing.T) {
+	gobot.Assert(t, pwmCompare(0.5, false, false), uint8(128))
+	gobot.Assert(t, pwmCompare(0.25, true, false), uint8(191))
+	gobot.Assert(t, pwmCompare(1, false, true), uint8(0))
+}
@@ -0,0 +1,264 @@
+package firmata
+
+import (
+	"math"
+
+	"github.com/edmontongo/gobot"
+)
+
+const (
+	accelStepperData            byte = 0x62
+	accelStepperConfig          byte = 0x00
+	accelStepperZero            byte = 0x01
+	accelStepperStep            byte = 0x02
+	accelStepperTo              byte = 0x03
+	accelStepperEnable          byte = 0x04
+	accelStepperStop            byte = 0x05
+	accelStepperReportPosition  byte = 0x06
+	accelStepperSetAcceleration byte = 0x08
+	accelStepperSetSpeed        byte = 0x09
+	accelStepperMoveComplete    byte = 0x0A
+)
+
+// Stepper interfaces supported by the AccelStepperFirmata extension.
+const (
+	AccelStepperStepDirection byte = 1
+	AccelStepperTwoWire       byte = 2
+	AccelStepperThreeWire     byte = 3
+	AccelStepperFourWire      byte = 4
+)
+
+// Step sizes supported by the AccelStepperFirmata extension.
+const (
+	AccelStepperWholeStep   byte = 0
+	AccelStepperHalfStep    byte = 1
+	AccelStepperQuarterStep byte = 2
+)
+
+// AccelStepperDriver drives a stepper motor attached to a board running
+// the AccelStepperFirmata extension of ConfigurableFirmata.
+type AccelStepperDriver struct {
+	gobot.Driver
+	Device    byte
+	Wires     byte
+	StepSize  byte
+	Pins      []string
+	EnablePin string
+}
+
+// NewAccelStepperDriver creates a driver for stepper number device, wired
+// with the given interface and pins. For AccelStepperStepDirection the pins
+// are the step pin followed by the direction pin.
+func NewAccelStepperDriver(a *FirmataAdaptor, name string, device byte, wires byte, pins ...string) *AccelStepperDriver {
+	s := &AccelStepperDriver{
+		Driver: *gobot.NewDriver(
+			name,
+			"AccelStepperDriver",
+			a,
+		),
+		Device:   device,
+		Wires:    wires,
+		StepSize: AccelStepperWholeStep,
+		Pins:     pins,
+	}
+
+	s.AddEvent("position")
+	s.AddEvent("move_complete")
+
+	s.AddCommand("Step", func(params map[string]interface{}) interface{} {
+		s.Step(int32(params["steps"].(float64)))
+		return nil
+	})
+	s.AddCommand("To", func(params map[string]interface{}) interface{} {
+		s.To(int32(params["position"].(float64)))
+		return nil
+	})
+	s.AddCommand("Zero", func(params map[string]interface{}) interface{} {
+		s.Zero()
+		return nil
+	})
+	s.AddCommand("Stop", func(params map[string]interface{}) interface{} {
+		s.Stop()
+		return nil
+	})
+	s.AddCommand("Enable", func(params map[string]interface{}) interface{} {
+		s.Enable(params["enabled"].(bool))
+		return nil
+	})
+	s.AddCommand("SetSpeed", func(params map[string]interface{}) interface{} {
+		s.SetSpeed(params["speed"].(float64))
+		return nil
+	})
+	s.AddCommand("SetAcceleration", func(params map[string]interface{}) interface{} {
+		s.SetAcceleration(params["acceleration"].(float64))
+		return nil
+	})
+	s.AddCommand("ReportPosition", func(params map[string]interface{}) interface{} {
+		s.ReportPosition()
+		return nil
+	})
+
+	return s
+}
+
+func (s *AccelStepperDriver) adaptor() *FirmataAdaptor {
+	return s.Adaptor().(*FirmataAdaptor)
+}
+
+// Start registers for stepper reports and configures the stepper on the board.
+func (s *AccelStepperDriver) Start() bool {
+	s.adaptor().AddSysexHandler(accelStepperData, s.handleSysex)
+
+	iface := s.Wires<<4 | s.StepSize<<1
+	if s.EnablePin != "" {
+		iface |= 0x01
+	}
+	data := []byte{accelStepperConfig, s.Device, iface}
+	for _, pin := range s.Pins {
+		data = append(data, pinNumber(pin))
+	}
+	if s.EnablePin != "" {
+		data = append(data, pinNumber(s.EnablePin))
+	}
+	s.adaptor().SysexWrite(accelStepperData, data)
+	return true
+}
+func (s *AccelStepperDriver) Init() bool { return true }
+
+// Halt stops the stepper.
+func (s *AccelStepperDriver) Halt() bool {
+	s.Stop()
+	return true
+}
+
+// Zero sets the current position of the stepper as position 0.
+func (s *AccelStepperDriver) Zero() {
+	s.command(accelStepperZero)
+}
+
+// Step moves the stepper by steps relative to its current position. A
+// "move_complete" event is published when the move is done.
+func (s *AccelStepperDriver) Step(steps int32) {
+	s.command(accelStepperStep, encodeAccelStepperInt(steps)...)
+}
+
+// To moves the stepper to the absolute position. A "move_complete" event
+// is published when the move is done.
+func (s *AccelStepperDriver) To(position int32) {
+	s.command(accelStepperTo, encodeAccelStepperInt(position)...)
+}
+
+// Enable switches the stepper's enable pin on or off.
+func (s *AccelStepperDriver) Enable(enabled bool) {
+	state := byte(0)
+	if enabled {
+		state = 1
+	}
+	s.command(accelStepperEnable, state)
+}
+
+// Stop stops the stepper immediately. The board answers with a
+// "position" event.
+func (s *AccelStepperDriver) Stop() {
+	s.command(accelStepperStop)
+}
+
+// ReportPosition asks the board for the current position, which is
+// published as a "position" event.
+func (s *AccelStepperDriver) ReportPosition() {
+	s.command(accelStepperReportPosition)
+}
+
+// SetSpeed sets the maximum speed in steps per second.
+func (s *AccelStepperDriver) SetSpeed(speed float64) {
+	s.command(accelStepperSetSpeed, encodeAccelStepperFloat(speed)...)
+}
+
+// SetAcceleration sets the acceleration in steps per second per second.
+// An acceleration of 0 disables acceleration.
+func (s *AccelStepperDriver) SetAcceleration(acceleration float64) {
+	s.command(accelStepperSetAcceleration, encodeAccelStepperFloat(acceleration)...)
+}
+
+func (s *AccelStepperDriver) command(command byte, data ...byte) {
+	s.adaptor().SysexWrite(accelStepperData,
+		append([]byte{command, s.Device}, data...))
+}
+
+func (s *AccelStepperDriver) handleSysex(data []byte) {
+	if len(data) < 7 || data[1] != s.Device {
+		return
+	}
+	position := decodeAccelStepperInt(data[2:7])
+	switch data[0] {
+	case accelStepperReportPosition:
+		gobot.Publish(s.Event("position"), position)
+	case accelStepperMoveComplete:
+		gobot.Publish(s.Event("move_complete"), position)
+	}
+}
+
+// encodeAccelStepperInt encodes a signed 32-bit value into five 7-bit bytes,
+// with the sign in bit 3 of the last byte.
+func encodeAccelStepperInt(value int32) []byte {
+	negative := value < 0
+	v := uint32(value)
+	if negative {
+		v = uint32(-int64(value))
+	}
+	ret := []byte{
+		byte(v & 0x7F),
+		byte((v >> 7) & 0x7F),
+		byte((v >> 14) & 0x7F),
+		byte((v >> 21) & 0x7F),
+		byte((v >> 28) & 0x07),
+	}
+	if negative {
+		ret[4] |= 0x08
+	}
+	return ret
+}
+
+func decodeAccelStepperInt(data []byte) int32 {
+	value := int32(data[0]) | int32(data[1])<<7 | int32(data[2])<<14 |
+		int32(data[3])<<21 | int32(data[4]&0x07)<<28
+	if data[4]&0x08 != 0 {
+		value = -value
+	}
+	return value
+}
+
+// encodeAccelStepperFloat encodes value into the four byte floating point
+// format used by AccelStepperFirmata: a 23-bit significand, a 4-bit base 10
+// exponent biased by 11 and a sign bit.
+func encodeAccelStepperFloat(value float64) []byte {
+	const maxSignificand = 1 << 23
+
+	sign := byte(0)
+	if value < 0 {
+		sign = 1
+		value = -value
+	}
+	exponent := 0
+	if value != 0 {
+		exponent = int(math.Floor(math.Log10(value)))
+		value /= math.Pow10(exponent)
+	}
+	for value != math.Trunc(value) && value < maxSignificand {
+		exponent--
+		value *= 10
+	}
+	for value > maxSignificand {
+		exponent++
+		value /= 10
+	}
+	significand := uint32(value)
+	exponent += 11
+
+	return []byte{
+		byte(significand & 0x7F),
+		byte((significand >> 7) & 0x7F),
+		byte((significand >> 14) & 0x7F),
+		byte((significand>>21)&0x03) | byte(exponent&0x0F)<<2 | sign<<6,
+	}
+}
@@ -0,0 +1,91 @@
+package firmata
+
+import (
+	"testing"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestAccelStepperDriver() (*AccelStepperDriver, *testReadWriteCloser) {
+	a := initTestFirmataAdaptor()
+	d := NewAccelStepperDriver(a, "stepper", 1, AccelStepperFourWire,
+		"2", "3", "4", "5")
+	serial := a.board.serial.(*testReadWriteCloser)
+	serial.Written()
+	return d, serial
+}
+
+func TestAccelStepperDriverStart(t *testing.T) {
+	d, serial := initTestAccelStepperDriver()
+	d.StepSize = AccelStepperHalfStep
+	d.EnablePin = "6"
+	gobot.Assert(t, d.Start(), true)
+	gobot.Assert(t, serial.Written(),
+		[]byte{0xF0, 0x62, 0x00, 0x01, 0x43, 2, 3, 4, 5, 6, 0xF7})
+}
+
+func TestAccelStepperDriverHalt(t *testing.T) {
+	d, serial := initTestAccelStepperDriver()
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, serial.Written(), []byte{0xF0, 0x62, 0x05, 0x01, 0xF7})
+}
+
+func TestAccelStepperDriverInit(t *testing.T) {
+	d, _ := initTestAccelStepperDriver()
+	gobot.Assert(t, d.Init(), true)
+}
+
+func TestAccelStepperDriverStep(t *testing.T) {
+	d, serial := initTestAccelStepperDriver()
+	d.Step(-200)
+	gobot.Assert(t, serial.Written(),
+		[]byte{0xF0, 0x62, 0x02, 0x01, 0x48, 0x01, 0x00, 0x00, 0x08, 0xF7})
+	d.To(1000)
+	gobot.Assert(t, serial.Written(),
+		[]byte{0xF0, 0x62, 0x03, 0x01, 0x68, 0x07, 0x00, 0x00, 0x00, 0xF7})
+}
+
+func TestAccelStepperDriverCommands(t *testing.T) {
+	d, serial := initTestAccelStepperDriver()
+	d.Zero()
+	gobot.Assert(t, serial.Written(), []byte{0xF0, 0x62, 0x01, 0x01, 0xF7})
+	d.Enable(true)
+	gobot.Assert(t, serial.Written(), []byte{0xF0, 0x62, 0x04, 0x01, 0x01, 0xF7})
+	d.ReportPosition()
+	gobot.Assert(t, serial.Written(), []byte{0xF0, 0x62, 0x06, 0x01, 0xF7})
+	d.SetSpeed(1.5)
+	gobot.Assert(t, serial.Written(),
+		[]byte{0xF0, 0x62, 0x09, 0x01, 15, 0, 0, 10 << 2, 0xF7})
+	d.SetAcceleration(-300)
+	gobot.Assert(t, serial.Written(),
+		[]byte{0xF0, 0x62, 0x08, 0x01, 3, 0, 0, 13<<2 | 1<<6, 0xF7})
+}
+
+func TestAccelStepperDriverEvents(t *testing.T) {
+	d, _ := initTestAccelStepperDriver()
+	d.Start()
+	sem := make(chan int32)
+	gobot.Once(d.Event("move_complete"), func(data interface{}) {
+		sem <- data.(int32)
+	})
+	d.adaptor().board.process([]byte{0xF0, 0x62, 0x0A, 0x01,
+		0x48, 0x01, 0x00, 0x00, 0x08, 0xF7})
+	gobot.Assert(t, <-sem, int32(-200))
+
+	gobot.Once(d.Event("position"), func(data interface{}) {
+		sem <- data.(int32)
+	})
+	// reports for other steppers are ignored
+	d.adaptor().board.process([]byte{0xF0, 0x62, 0x06, 0x02,
+		0x01, 0x00, 0x00, 0x00, 0x00, 0xF7})
+	d.adaptor().board.process([]byte{0xF0, 0x62, 0x06, 0x01,
+		0x68, 0x07, 0x00, 0x00, 0x00, 0xF7})
+	gobot.Assert(t, <-sem, int32(1000))
+}
+
+func TestAccelStepperEncoding(t *testing.T) {
+	for _, v := range []int32{0, 1, -1, 1000, -123456789, 2147483647} {
+		gobot.Assert(t, decodeAccelStepperInt(encodeAccelStepperInt(v)), v)
+	}
+	gobot.Assert(t, encodeAccelStepperFloat(0), []byte{0, 0, 0, 11 << 2})
+}
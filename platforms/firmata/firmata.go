@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"log"
 	"math"
+	"sync"
 	"time"
 
 	"github.com/edmontongo/gobot"
@@ -18,6 +20,13 @@ const (
 	analog                   byte = 0x02
 	pwm                      byte = 0x03
 	servo                    byte = 0x04
+	shift                    byte = 0x05
+	i2cMode                  byte = 0x06
+	oneWire                  byte = 0x07
+	stepper                  byte = 0x08
+	encoder                  byte = 0x09
+	serialMode               byte = 0x0A
+	pullup                   byte = 0x0B
 	low                      byte = 0
 	high                     byte = 1
 	reportVersion            byte = 0xF9
@@ -37,6 +46,7 @@ const (
 	capabilityResponse       byte = 0x6C
 	pinStateQuery            byte = 0x6D
 	pinStateResponse         byte = 0x6E
+	extendedAnalog           byte = 0x6F
 	analogMappingQuery       byte = 0x69
 	analogMappingResponse    byte = 0x6A
 	stringData               byte = 0x71
@@ -50,6 +60,25 @@ const (
 	i2CModeStopReading       byte = 0x03
 )
 
+// modeNames maps pin modes to the names used when reporting capabilities.
+var modeNames = map[byte]string{
+	input:      "input",
+	output:     "output",
+	analog:     "analog",
+	pwm:        "pwm",
+	servo:      "servo",
+	shift:      "shift",
+	i2cMode:    "i2c",
+	oneWire:    "onewire",
+	stepper:    "stepper",
+	encoder:    "encoder",
+	serialMode: "serial",
+	pullup:     "pullup",
+}
+
+// board talks Firmata over serial. Its methods may be called from any
+// goroutine: mutex guards the board state learned from and sent to the
+// board, and writeMutex serializes writes to the serial connection.
 type board struct {
 	serial           io.ReadWriteCloser
 	pins             []pin
@@ -59,7 +88,18 @@ type board struct {
 	minorVersion     byte
 	connected        bool
 	events           map[string]*gobot.Event
+	sysexHandlers    map[byte][]func([]byte)
 	initTimeInterval time.Duration
+	writeInterval    time.Duration
+	pending          [][]byte
+	closed           bool
+	// i2cEnabled sends the I2C config once per connection.
+	i2cEnabled sync.Once
+	// unparsed holds the start of a message split across serial reads.
+	unparsed    []byte
+	mutex       sync.Mutex
+	eventsMutex sync.Mutex
+	writeMutex  sync.Mutex
 }
 
 type pin struct {
@@ -67,6 +107,9 @@ type pin struct {
 	mode           byte
 	value          int
 	analogChannel  byte
+	// pwmResolution is the number of bits of a PWM value, 0 if the pin has
+	// no PWM.
+	pwmResolution byte
 }
 
 func newBoard(sp io.ReadWriteCloser) *board {
@@ -79,77 +122,167 @@ func newBoard(sp io.ReadWriteCloser) *board {
 		analogPins:       []byte{},
 		connected:        false,
 		events:           make(map[string]*gobot.Event),
+		sysexHandlers:    make(map[byte][]func([]byte)),
 		initTimeInterval: 1 * time.Second,
 	}
 
-	for _, s := range []string{
-		"firmware_query",
-		"capability_query",
-		"analog_mapping_query",
-		"report_version",
-		"i2c_reply",
-		"analog_mapping_query",
-		"string_data",
-		"firmware_query",
-	} {
-		board.events[s] = gobot.NewEvent()
-	}
-
 	return board
 }
 
+// event returns the event called name, creating it on first use.
+func (b *board) event(name string) *gobot.Event {
+	b.eventsMutex.Lock()
+	defer b.eventsMutex.Unlock()
+	e, ok := b.events[name]
+	if !ok {
+		e = gobot.NewEvent()
+		b.events[name] = e
+	}
+	return e
+}
+
 func (b *board) connect() {
-	if b.connected == false {
+	if b.isConnected() == false {
+		if b.writeInterval > 0 {
+			b.flushEvery(b.writeInterval)
+		}
 		b.reset()
 		b.initBoard()
+		b.listen()
 
 		for {
 			b.queryReportVersion()
 			<-time.After(b.initTimeInterval)
-			b.readAndProcess()
-			if b.connected == true {
+			if b.isConnected() == true {
 				break
 			}
 		}
 	}
 }
 
+func (b *board) isConnected() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.connected
+}
+
+// disconnect sends any pending writes and closes the serial connection.
+func (b *board) disconnect() error {
+	b.writeMutex.Lock()
+	b.flushPending()
+	b.closed = true
+	b.writeMutex.Unlock()
+	return b.serial.Close()
+}
+
+// listen continuously reads and processes incoming messages until the
+// serial connection is closed, so that replies and unsolicited messages
+// such as sysex extension reports are never left unread.
+func (b *board) listen() {
+	go func() {
+		for {
+			if err := b.readAndProcess(); err != nil {
+				return
+			}
+		}
+	}()
+}
+
 func (b *board) initBoard() {
-	gobot.Once(b.events["firmware_query"], func(data interface{}) {
+	gobot.Once(b.event("firmware_query"), func(data interface{}) {
 		b.queryCapabilities()
 	})
 
-	gobot.Once(b.events["capability_query"], func(data interface{}) {
+	gobot.Once(b.event("capability_query"), func(data interface{}) {
 		b.queryAnalogMapping()
 	})
 
-	gobot.Once(b.events["analog_mapping_query"], func(data interface{}) {
+	gobot.Once(b.event("analog_mapping_query"), func(data interface{}) {
 		b.togglePinReporting(0, high, reportDigital)
 		b.togglePinReporting(1, high, reportDigital)
+		b.mutex.Lock()
 		b.connected = true
+		b.mutex.Unlock()
 	})
 }
 
-func (b *board) readAndProcess() {
-	b.process(b.read())
+// readAndProcess reads from the serial connection and processes the
+// complete messages read so far. Serial ports deliver whatever has arrived,
+// so a message may be split across reads; its start is kept until the rest
+// arrives.
+func (b *board) readAndProcess() error {
+	data, err := b.read()
+	b.unparsed = append(b.unparsed, data...)
+	n := b.process(b.unparsed)
+	b.unparsed = append([]byte{}, b.unparsed[n:]...)
+	return err
 }
 
 func (b *board) reset() {
 	b.write([]byte{systemReset})
 }
 
-func (b *board) setPinMode(pin byte, mode byte) {
+// setPinMode sets the mode of pin, after checking against the capabilities
+// reported by the board that the pin supports it.
+func (b *board) setPinMode(pin byte, mode byte) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if err := b.checkPinMode(pin, mode); err != nil {
+		return err
+	}
 	b.pins[pin].mode = mode
 	b.write([]byte{pinMode, pin, mode})
+	return nil
+}
+
+// analogPin returns the pin the board reported for analog channel.
+func (b *board) analogPin(channel byte) (byte, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for i, p := range b.pins {
+		if p.analogChannel == channel && channel != 127 {
+			return byte(i), nil
+		}
+	}
+	return 0, fmt.Errorf("firmata: analog channel %v does not exist on %v", channel, b.firmwareName)
+}
+
+// supportsPinMode returns nil if pin supports mode, or an error explaining
+// why not.
+func (b *board) supportsPinMode(pin byte, mode byte) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.checkPinMode(pin, mode)
+}
+
+func (b *board) checkPinMode(pin byte, mode byte) error {
+	if int(pin) >= len(b.pins) {
+		return fmt.Errorf("firmata: pin %v does not exist on %v", pin, b.firmwareName)
+	}
+	for _, m := range b.pins[pin].supportedModes {
+		if m == mode {
+			return nil
+		}
+	}
+	return fmt.Errorf("firmata: pin %v does not support %v mode", pin, modeName(mode))
+}
+
+func modeName(mode byte) string {
+	if name, ok := modeNames[mode]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", mode)
 }
 
 func (b *board) digitalWrite(pin byte, value byte) {
 	port := byte(math.Floor(float64(pin) / 8))
 	portValue := byte(0)
 
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
 	b.pins[pin].value = int(value)
 
-	for i := byte(0); i < 8; i++ {
+	for i := byte(0); i < 8 && int(8*port+i) < len(b.pins); i++ {
 		if b.pins[8*port+i].value != 0 {
 			portValue = portValue | (1 << i)
 		}
@@ -157,12 +290,35 @@ func (b *board) digitalWrite(pin byte, value byte) {
 	b.write([]byte{digitalMessage | port, portValue & 0x7F, (portValue >> 7) & 0x7F})
 }
 
-func (b *board) analogWrite(pin byte, value byte) {
-	b.pins[pin].value = int(value)
-	b.write([]byte{analogMessage | pin, value & 0x7F, (value >> 7) & 0x7F})
+// analogWrite sends value, up to 14 bits, to pin. Pins above 15, which an
+// analog message cannot address, are written with an extended analog
+// message.
+func (b *board) analogWrite(pin byte, value int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.pins[pin].value = value
+	if pin > 0x0F {
+		b.write([]byte{startSysex, extendedAnalog, pin,
+			byte(value & 0x7F), byte((value >> 7) & 0x7F), endSysex})
+		return
+	}
+	b.write([]byte{analogMessage | pin, byte(value & 0x7F), byte((value >> 7) & 0x7F)})
+}
+
+// pwmResolution returns the number of bits of a PWM value on pin, or 8 if
+// the board did not report it.
+func (b *board) pwmResolution(pin byte) byte {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if int(pin) < len(b.pins) && b.pins[pin].pwmResolution > 0 {
+		return b.pins[pin].pwmResolution
+	}
+	return 8
 }
 
 func (b *board) version() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
 	return fmt.Sprintf("%v.%v", b.majorVersion, b.minorVersion)
 }
 
@@ -200,188 +356,326 @@ func (b *board) i2cReadRequest(slaveAddress byte, numBytes uint) {
 }
 
 func (b *board) i2cWriteRequest(slaveAddress byte, data []byte) {
-	ret := []byte{startSysex, i2CRequest, slaveAddress, (i2CModeWrite << 3)}
-	for _, val := range data {
-		ret = append(ret, byte(val&0x7F))
-		ret = append(ret, byte((val>>7)&0x7F))
+	b.sysex(i2CRequest, append([]byte{slaveAddress, (i2CModeWrite << 3)},
+		Encode7Bit(data)...))
+}
+
+func (b *board) i2cConfig(delay uint) {
+	b.sysex(i2CConfig, []byte{byte(delay & 0x7F), byte((delay >> 7) & 0x7F)})
+}
+
+// enableI2c enables I2C on the board the first time it is called.
+func (b *board) enableI2c() {
+	b.i2cEnabled.Do(func() { b.i2cConfig(0) })
+}
+
+// sysex wraps data in a sysex message for command and sends it to the board.
+func (b *board) sysex(command byte, data []byte) {
+	ret := append([]byte{startSysex, command}, data...)
+	b.write(append(ret, endSysex))
+}
+
+// write sends commands to the board. Without a write interval they are
+// written straight away; otherwise they are queued and sent by flushEvery.
+func (b *board) write(commands []byte) {
+	b.writeMutex.Lock()
+	defer b.writeMutex.Unlock()
+	if b.closed {
+		return
 	}
-	ret = append(ret, endSysex)
-	b.write(ret)
+	if b.writeInterval == 0 {
+		b.serial.Write(commands[:])
+		return
+	}
+	b.queue(commands)
 }
 
-func (b *board) i2cConfig(data []byte) {
-	ret := []byte{startSysex, i2CConfig}
-	for _, val := range data {
-		ret = append(ret, byte(val&0xFF))
-		ret = append(ret, byte((val>>8)&0xFF))
+// queue adds commands to the pending writes. A digital or analog message
+// replaces a pending one for the same port or pin, as long as only other
+// such messages were queued after it, so that the board only receives the
+// latest value while commands stay in order.
+func (b *board) queue(commands []byte) {
+	if coalescable(commands) {
+		for i := len(b.pending) - 1; i >= 0 && coalescable(b.pending[i]); i-- {
+			if b.pending[i][0] == commands[0] {
+				b.pending[i] = commands
+				return
+			}
+		}
 	}
-	ret = append(ret, endSysex)
-	b.write(ret)
+	b.pending = append(b.pending, commands)
 }
 
-func (b *board) write(commands []byte) {
-	b.serial.Write(commands[:])
+func coalescable(commands []byte) bool {
+	if len(commands) != 3 {
+		return false
+	}
+	return (analogMessageRangeStart <= commands[0] && analogMessageRangeEnd >= commands[0]) ||
+		(digitalMessageRangeStart <= commands[0] && digitalMessageRangeEnd >= commands[0])
+}
+
+// flushEvery sends the pending writes every interval until disconnected.
+func (b *board) flushEvery(interval time.Duration) {
+	go func() {
+		for {
+			<-time.After(interval)
+			b.writeMutex.Lock()
+			if b.closed {
+				b.writeMutex.Unlock()
+				return
+			}
+			b.flushPending()
+			b.writeMutex.Unlock()
+		}
+	}()
+}
+
+// flush sends the pending writes now, for commands whose reply is awaited.
+func (b *board) flush() {
+	b.writeMutex.Lock()
+	defer b.writeMutex.Unlock()
+	if !b.closed {
+		b.flushPending()
+	}
+}
+
+// flushPending writes the pending commands in one go. The caller must hold
+// writeMutex.
+func (b *board) flushPending() {
+	if len(b.pending) == 0 {
+		return
+	}
+	data := []byte{}
+	for _, commands := range b.pending {
+		data = append(data, commands...)
+	}
+	b.pending = nil
+	b.serial.Write(data)
 }
 
-func (b *board) read() []byte {
+func (b *board) read() ([]byte, error) {
 	buf := make([]byte, 1024)
-	b.serial.Read(buf)
-	return buf
+	n, err := b.serial.Read(buf)
+	return buf[:n], err
 }
 
-func (b *board) process(data []byte) {
-	buf := bytes.NewBuffer(data)
-	for {
-		messageType, err := buf.ReadByte()
-		if err != nil {
-			break
+// messageLength returns the length of the message at the start of data, or
+// 0 if it is incomplete. Stray data bytes are skipped one at a time.
+func messageLength(data []byte) int {
+	switch messageType := data[0]; {
+	case reportVersion == messageType,
+		analogMessageRangeStart <= messageType && analogMessageRangeEnd >= messageType,
+		digitalMessageRangeStart <= messageType && digitalMessageRangeEnd >= messageType:
+		if len(data) < 3 {
+			return 0
 		}
-		switch {
-		case reportVersion == messageType:
-			b.majorVersion, _ = buf.ReadByte()
-			b.minorVersion, _ = buf.ReadByte()
-			gobot.Publish(b.events["report_version"], b.version())
-		case analogMessageRangeStart <= messageType &&
-			analogMessageRangeEnd >= messageType:
-
-			leastSignificantByte, _ := buf.ReadByte()
-			mostSignificantByte, _ := buf.ReadByte()
+		return 3
+	case startSysex == messageType:
+		return bytes.IndexByte(data, endSysex) + 1
+	}
+	return 1
+}
 
-			value := uint(leastSignificantByte) | uint(mostSignificantByte)<<7
-			pin := (messageType & 0x0F)
+// process processes the complete messages in data, and returns the number
+// of bytes they take.
+func (b *board) process(data []byte) int {
+	n := 0
+	for n < len(data) {
+		length := messageLength(data[n:])
+		if length == 0 {
+			break
+		}
+		b.processMessage(data[n : n+length])
+		n += length
+	}
+	return n
+}
 
+// processMessage processes one complete message.
+func (b *board) processMessage(message []byte) {
+	buf := bytes.NewBuffer(message)
+	messageType, _ := buf.ReadByte()
+	switch {
+	case reportVersion == messageType:
+		majorVersion, _ := buf.ReadByte()
+		minorVersion, _ := buf.ReadByte()
+		b.mutex.Lock()
+		b.majorVersion, b.minorVersion = majorVersion, minorVersion
+		b.mutex.Unlock()
+		gobot.Publish(b.event("report_version"), b.version())
+	case analogMessageRangeStart <= messageType &&
+		analogMessageRangeEnd >= messageType:
+
+		leastSignificantByte, _ := buf.ReadByte()
+		mostSignificantByte, _ := buf.ReadByte()
+
+		value := uint(leastSignificantByte) | uint(mostSignificantByte)<<7
+		pin := (messageType & 0x0F)
+
+		b.mutex.Lock()
+		if int(pin) < len(b.analogPins) {
 			b.pins[b.analogPins[pin]].value = int(value)
-			gobot.Publish(b.events[fmt.Sprintf("analog_read_%v", pin)],
-				[]byte{
-					byte(value >> 24),
-					byte(value >> 16),
-					byte(value >> 8),
-					byte(value & 0xff),
-				},
-			)
-		case digitalMessageRangeStart <= messageType &&
-			digitalMessageRangeEnd >= messageType:
-
-			port := messageType & 0x0F
-			firstBitmask, _ := buf.ReadByte()
-			secondBitmask, _ := buf.ReadByte()
-			portValue := firstBitmask | (secondBitmask << 7)
-
-			for i := 0; i < 8; i++ {
-				pinNumber := (8*byte(port) + byte(i))
-				pin := b.pins[pinNumber]
-				if byte(pin.mode) == input {
-					pin.value = int((portValue >> (byte(i) & 0x07)) & 0x01)
-					gobot.Publish(b.events[fmt.Sprintf("digital_read_%v", pinNumber)],
-						[]byte{byte(pin.value & 0xff)})
-				}
+		}
+		b.mutex.Unlock()
+		gobot.Publish(b.event(fmt.Sprintf("analog_read_%v", pin)),
+			[]byte{
+				byte(value >> 24),
+				byte(value >> 16),
+				byte(value >> 8),
+				byte(value & 0xff),
+			},
+		)
+	case digitalMessageRangeStart <= messageType &&
+		digitalMessageRangeEnd >= messageType:
+
+		port := messageType & 0x0F
+		firstBitmask, _ := buf.ReadByte()
+		secondBitmask, _ := buf.ReadByte()
+		portValue := firstBitmask | (secondBitmask << 7)
+
+		b.mutex.Lock()
+		for i := 0; i < 8; i++ {
+			pinNumber := (8*byte(port) + byte(i))
+			if int(pinNumber) >= len(b.pins) {
+				break
 			}
-		case startSysex == messageType:
-			currentBuffer := []byte{messageType}
-			for {
-				b, err := buf.ReadByte()
-				if err != nil {
-					break
-				}
-				currentBuffer = append(currentBuffer, b)
-				if currentBuffer[len(currentBuffer)-1] == endSysex {
-					break
-				}
+			pin := &b.pins[pinNumber]
+			if byte(pin.mode) == input {
+				pin.value = int((portValue >> (byte(i) & 0x07)) & 0x01)
+				gobot.Publish(b.event(fmt.Sprintf("digital_read_%v", pinNumber)),
+					[]byte{byte(pin.value & 0xff)})
+			}
+		}
+		b.mutex.Unlock()
+	case startSysex == messageType:
+		currentBuffer := []byte{messageType}
+		for {
+			b, err := buf.ReadByte()
+			if err != nil {
+				break
+			}
+			currentBuffer = append(currentBuffer, b)
+			if currentBuffer[len(currentBuffer)-1] == endSysex {
+				break
 			}
-			command := currentBuffer[1]
-			switch command {
-			case capabilityResponse:
-				supportedModes := 0
-				n := 0
-
-				for _, val := range currentBuffer[2:(len(currentBuffer) - 5)] {
-					if val == 127 {
-						modes := []byte{}
-						for _, mode := range []byte{input, output, analog, pwm, servo} {
-							if (supportedModes & (1 << mode)) != 0 {
-								modes = append(modes, mode)
-							}
+		}
+		if len(currentBuffer) < 2 {
+			break
+		}
+		command := currentBuffer[1]
+		switch command {
+		case capabilityResponse:
+			supportedModes := 0
+			pwmResolution := byte(0)
+			mode := byte(0)
+			n := 0
+			b.mutex.Lock()
+			b.pins = []pin{}
+
+			for _, val := range sysexData(currentBuffer) {
+				if val == 127 {
+					modes := []byte{}
+					for mode := byte(0); mode < 16; mode++ {
+						if (supportedModes & (1 << mode)) != 0 {
+							modes = append(modes, mode)
 						}
-						b.pins = append(b.pins, pin{modes, output, 0, 0})
-						b.events[fmt.Sprintf("digital_read_%v", len(b.pins)-1)] = gobot.NewEvent()
-						b.events[fmt.Sprintf("pin_%v_state", len(b.pins)-1)] = gobot.NewEvent()
-						supportedModes = 0
-						n = 0
-						continue
 					}
-
-					if n == 0 {
-						supportedModes = supportedModes | (1 << val)
-					}
-					n ^= 1
+					b.pins = append(b.pins, pin{modes, output, 0, 127, pwmResolution})
+					supportedModes = 0
+					pwmResolution = 0
+					n = 0
+					continue
 				}
-				gobot.Publish(b.events["capability_query"], nil)
-			case analogMappingResponse:
-				pinIndex := byte(0)
 
-				for _, val := range currentBuffer[2 : len(b.pins)-1] {
+				// each mode is followed by its resolution
+				if n == 0 {
+					mode = val
+					supportedModes = supportedModes | (1 << val)
+				} else if mode == pwm {
+					pwmResolution = val
+				}
+				n ^= 1
+			}
+			b.mutex.Unlock()
+			gobot.Publish(b.event("capability_query"), nil)
+		case analogMappingResponse:
+			pinIndex := byte(0)
+			b.mutex.Lock()
+			b.analogPins = []byte{}
+
+			for _, val := range sysexData(currentBuffer) {
+				if int(pinIndex) >= len(b.pins) {
+					break
+				}
 
-					b.pins[pinIndex].analogChannel = val
+				b.pins[pinIndex].analogChannel = val
 
-					if val != 127 {
-						b.analogPins = append(b.analogPins, pinIndex)
-					}
-					b.events[fmt.Sprintf("analog_read_%v", pinIndex)] = gobot.NewEvent()
-					pinIndex++
+				if val != 127 {
+					b.analogPins = append(b.analogPins, pinIndex)
 				}
+				pinIndex++
+			}
+			b.mutex.Unlock()
 
-				gobot.Publish(b.events["analog_mapping_query"], nil)
-			case pinStateResponse:
-				pin := b.pins[currentBuffer[2]]
-				pin.mode = currentBuffer[3]
-				pin.value = int(currentBuffer[4])
+			gobot.Publish(b.event("analog_mapping_query"), nil)
+		case pinStateResponse:
+			b.mutex.Lock()
+			if len(currentBuffer) < 5 || int(currentBuffer[2]) >= len(b.pins) {
+				b.mutex.Unlock()
+				break
+			}
+			pin := &b.pins[currentBuffer[2]]
+			pin.mode = currentBuffer[3]
+			pin.value = int(currentBuffer[4])
 
-				if len(currentBuffer) > 6 {
-					pin.value = int(uint(pin.value) | uint(currentBuffer[5])<<7)
-				}
-				if len(currentBuffer) > 7 {
-					pin.value = int(uint(pin.value) | uint(currentBuffer[6])<<14)
-				}
+			if len(currentBuffer) > 6 {
+				pin.value = int(uint(pin.value) | uint(currentBuffer[5])<<7)
+			}
+			if len(currentBuffer) > 7 {
+				pin.value = int(uint(pin.value) | uint(currentBuffer[6])<<14)
+			}
 
-				gobot.Publish(b.events[fmt.Sprintf("pin_%v_state", currentBuffer[2])],
-					map[string]int{
-						"pin":   int(currentBuffer[2]),
-						"mode":  int(pin.mode),
-						"value": int(pin.value),
-					},
-				)
-			case i2CReply:
-				i2cReply := map[string][]byte{
-					"slave_address": []byte{byte(currentBuffer[2]) | byte(currentBuffer[3])<<7},
-					"register":      []byte{byte(currentBuffer[4]) | byte(currentBuffer[5])<<7},
-					"data":          []byte{byte(currentBuffer[6]) | byte(currentBuffer[7])<<7},
-				}
-				for i := 8; i < len(currentBuffer); i = i + 2 {
-					if currentBuffer[i] == byte(0xF7) {
-						break
-					}
-					if i+2 > len(currentBuffer) {
-						break
-					}
-					i2cReply["data"] = append(i2cReply["data"],
-						byte(currentBuffer[i])|byte(currentBuffer[i+1])<<7,
-					)
-				}
-				gobot.Publish(b.events["i2c_reply"], i2cReply)
-			case firmwareQuery:
-				name := []byte{}
-				for _, val := range currentBuffer[4:(len(currentBuffer) - 1)] {
-					if val != 0 {
-						name = append(name, val)
-					}
-				}
-				b.firmwareName = string(name[:])
-				gobot.Publish(b.events["firmware_query"], b.firmwareName)
-			case stringData:
-				str := currentBuffer[2:len(currentBuffer)]
-				gobot.Publish(b.events["string_data"], string(str[:len(str)]))
-			default:
-				fmt.Println("bad byte", fmt.Sprintf("0x%x", command))
+			state := map[string]int{
+				"pin":   int(currentBuffer[2]),
+				"mode":  int(pin.mode),
+				"value": int(pin.value),
+			}
+			b.mutex.Unlock()
+
+			gobot.Publish(b.event(fmt.Sprintf("pin_%v_state", currentBuffer[2])), state)
+		case i2CReply:
+			reply := Decode7Bit(sysexData(currentBuffer))
+			if len(reply) < 2 {
+				break
+			}
+			i2cReply := map[string][]byte{
+				"slave_address": []byte{reply[0]},
+				"register":      []byte{reply[1]},
+				"data":          reply[2:],
+			}
+			gobot.Publish(b.event("i2c_reply"), i2cReply)
+		case firmwareQuery:
+			data := sysexData(currentBuffer)
+			if len(data) < 2 {
+				break
+			}
+			firmwareName := string(Decode7Bit(data[2:]))
+			b.mutex.Lock()
+			b.firmwareName = firmwareName
+			b.mutex.Unlock()
+			gobot.Publish(b.event("firmware_query"), firmwareName)
+		case stringData:
+			gobot.Publish(b.event("string_data"), string(sysexData(currentBuffer)))
+		default:
+			b.mutex.Lock()
+			handlers, ok := b.sysexHandlers[command]
+			b.mutex.Unlock()
+			if !ok {
+				log.Printf("firmata: no handler for sysex command 0x%x", command)
+				break
+			}
+			for _, handler := range handlers {
+				handler(sysexData(currentBuffer))
 			}
 		}
 	}
@@ -2,27 +2,77 @@ package firmata
 
 import (
 	"fmt"
+	"io"
+	"log"
+	"math"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/i2c"
 	"github.com/tarm/goserial"
 )
 
+// i2cReplyTimeout is how long an I2C read waits for the board to reply. A
+// request and reply of a few bytes take several milliseconds at 57600
+// baud.
+const i2cReplyTimeout = 100 * time.Millisecond
+
+// i2cProbeTimeout is how long the reads of I2cProbe wait. It is enough for
+// a device that is there to reply, and keeps a scan of the bus, which
+// waits on every address without a device, to a few seconds.
+const i2cProbeTimeout = 20 * time.Millisecond
+
+// readTimeout is how long DigitalRead and AnalogRead wait for the board to
+// report.
+const readTimeout = 10 * time.Millisecond
+
+// minServoPulse is the shortest servo value, in microseconds, that
+// StandardFirmata reads as a pulse width rather than an angle.
+const minServoPulse = 544
+
+// FirmataAdaptor talks to a board running Firmata. It is safe for use by
+// several drivers from separate goroutines.
 type FirmataAdaptor struct {
 	gobot.Adaptor
-	board      *board
-	i2cAddress byte
-	connect    func(*FirmataAdaptor)
+	// WriteInterval, when set before connecting, queues writes and sends
+	// them to the board at this interval. Digital and analog writes made
+	// in between replace each other, so only the latest value of a pin is
+	// sent. By default writes are sent immediately.
+	WriteInterval time.Duration
+	board         *board
+	i2cMutex      sync.Mutex
+	i2cReplyMutex sync.Mutex
+	i2cAddress    byte
+	i2cReply      chan []byte
+	sysexHandlers map[byte][]func([]byte)
+	pwmMutex      sync.Mutex
+	pwms          map[byte]*pwmState
+	connect       func(*FirmataAdaptor)
+	readTimeout   time.Duration
+	probeTimeout  time.Duration
 }
 
+// pwmState holds the PwmController settings of a pin.
+type pwmState struct {
+	duty     float64
+	inverted bool
+	disabled bool
+}
+
+// NewFirmataAdaptor creates an adaptor for a board connected to the serial
+// port.
 func NewFirmataAdaptor(name, port string) *FirmataAdaptor {
-	return &FirmataAdaptor{
+	f := &FirmataAdaptor{
 		Adaptor: *gobot.NewAdaptor(
 			name,
 			"FirmataAdaptor",
 			port,
 		),
+		sysexHandlers: make(map[byte][]func([]byte)),
+		readTimeout:   readTimeout,
+		probeTimeout:  i2cProbeTimeout,
 		connect: func(f *FirmataAdaptor) {
 			sp, err := serial.OpenPort(&serial.Config{Name: f.Port(), Baud: 57600})
 			if err != nil {
@@ -31,17 +81,35 @@ func NewFirmataAdaptor(name, port string) *FirmataAdaptor {
 			f.board = newBoard(sp)
 		},
 	}
+
+	f.AddCommand("I2cScan", i2c.ScanCommand(f))
+
+	return f
+}
+
+// NewFirmataAdaptorWithConnection creates an adaptor for a board reached
+// through an already open connection, such as a network socket or a
+// Simulator.
+func NewFirmataAdaptorWithConnection(name string, conn io.ReadWriteCloser) *FirmataAdaptor {
+	f := NewFirmataAdaptor(name, "")
+	f.connect = func(f *FirmataAdaptor) {
+		f.board = newBoard(conn)
+	}
+	return f
 }
 
 func (f *FirmataAdaptor) Connect() bool {
 	f.connect(f)
+	f.board.sysexHandlers = f.sysexHandlers
+	f.board.writeInterval = f.WriteInterval
 	f.board.connect()
+	gobot.On(f.board.event("i2c_reply"), f.handleI2cReply)
 	f.SetConnected(true)
 	return true
 }
 
 func (f *FirmataAdaptor) Disconnect() bool {
-	err := f.board.serial.Close()
+	err := f.board.disconnect()
 	if err != nil {
 		fmt.Println(err)
 	}
@@ -53,63 +121,157 @@ func (f *FirmataAdaptor) InitServo() {}
 func (f *FirmataAdaptor) ServoWrite(pin string, angle byte) {
 	p, _ := strconv.Atoi(pin)
 
-	f.board.setPinMode(byte(p), servo)
-	f.board.analogWrite(byte(p), angle)
+	if f.setPinMode(byte(p), servo) != nil {
+		return
+	}
+	f.board.analogWrite(byte(p), int(angle))
+}
+
+// ServoPulseWrite sets the pulse width of the servo on pin. StandardFirmata
+// treats servo values of 544 and above as microseconds, so shorter pulses
+// are sent as 544µs.
+func (f *FirmataAdaptor) ServoPulseWrite(pin string, pulse time.Duration) {
+	p, _ := strconv.Atoi(pin)
+
+	us := int(pulse / time.Microsecond)
+	if us < minServoPulse {
+		us = minServoPulse
+	}
+	if f.setPinMode(byte(p), servo) != nil {
+		return
+	}
+	f.board.analogWrite(byte(p), us)
 }
 
 func (f *FirmataAdaptor) PwmWrite(pin string, level byte) {
 	p, _ := strconv.Atoi(pin)
 
-	f.board.setPinMode(byte(p), pwm)
-	f.board.analogWrite(byte(p), level)
+	if f.setPinMode(byte(p), pwm) != nil {
+		return
+	}
+	f.board.analogWrite(byte(p), int(level))
+}
+
+// PwmSetPeriod returns an error, as Firmata cannot change the PWM period
+// set by the board.
+func (f *FirmataAdaptor) PwmSetPeriod(pin string, period time.Duration) error {
+	return fmt.Errorf("firmata: the PWM period of pin %v is fixed by the board", pin)
+}
+
+// PwmSetDutyCycle sets the fraction of each period, from 0 to 1, that pin
+// is high, with the PWM resolution the board reports for it.
+func (f *FirmataAdaptor) PwmSetDutyCycle(pin string, duty float64) error {
+	return f.updatePwm(pin, func(s *pwmState) {
+		s.duty = math.Max(0, math.Min(1, duty))
+	})
+}
+
+// PwmSetEnabled turns PWM on pin on or off, leaving it low when off.
+func (f *FirmataAdaptor) PwmSetEnabled(pin string, enabled bool) error {
+	return f.updatePwm(pin, func(s *pwmState) {
+		s.disabled = !enabled
+	})
+}
+
+// PwmSetPolarity inverts the duty cycle of pin when inverted is true.
+func (f *FirmataAdaptor) PwmSetPolarity(pin string, inverted bool) error {
+	return f.updatePwm(pin, func(s *pwmState) {
+		s.inverted = inverted
+	})
+}
+
+// updatePwm changes the PwmController settings of pin and writes them to
+// the board.
+func (f *FirmataAdaptor) updatePwm(pin string, update func(*pwmState)) error {
+	p := pinNumber(pin)
+	if err := f.board.setPinMode(p, pwm); err != nil {
+		return err
+	}
+
+	f.pwmMutex.Lock()
+	defer f.pwmMutex.Unlock()
+	if f.pwms == nil {
+		f.pwms = make(map[byte]*pwmState)
+	}
+	s, ok := f.pwms[p]
+	if !ok {
+		s = &pwmState{}
+		f.pwms[p] = s
+	}
+	update(s)
+
+	duty := s.duty
+	if s.inverted {
+		duty = 1 - duty
+	}
+	if s.disabled {
+		duty = 0
+	}
+	max := float64(int(1)<<f.board.pwmResolution(p) - 1)
+	f.board.analogWrite(p, int(duty*max+0.5))
+	return nil
 }
 
 func (f *FirmataAdaptor) DigitalWrite(pin string, level byte) {
 	p, _ := strconv.Atoi(pin)
 
-	f.board.setPinMode(byte(p), output)
+	if f.setPinMode(byte(p), output) != nil {
+		return
+	}
 	f.board.digitalWrite(byte(p), level)
 }
 
 func (f *FirmataAdaptor) DigitalRead(pin string) int {
-	ret := make(chan int)
+	ret := make(chan int, 1)
 
-	p, _ := strconv.Atoi(pin)
-	f.board.setPinMode(byte(p), input)
-	f.board.togglePinReporting(byte(p), high, reportDigital)
-	f.board.readAndProcess()
-
-	gobot.Once(f.board.events[fmt.Sprintf("digital_read_%v", pin)], func(data interface{}) {
+	gobot.Once(f.board.event(fmt.Sprintf("digital_read_%v", pin)), func(data interface{}) {
 		ret <- int(data.([]byte)[0])
 	})
 
+	p, _ := strconv.Atoi(pin)
+	if f.setPinMode(byte(p), input) != nil {
+		return -1
+	}
+	f.board.togglePinReporting(byte(p/8), high, reportDigital)
+	// the reply is awaited, so the commands cannot wait for WriteInterval
+	f.board.flush()
+
 	select {
 	case data := <-ret:
 		return data
-	case <-time.After(10 * time.Millisecond):
+	case <-time.After(f.readTimeout):
 	}
 	return -1
 }
 
-// NOTE pins are numbered A0-A5, which translate to digital pins 14-19
+// AnalogRead reads analog channel pin, such as "0" for A0, on the pin the
+// board mapped it to. It returns -1 if the channel does not exist or no
+// reading arrives.
 func (f *FirmataAdaptor) AnalogRead(pin string) int {
-	ret := make(chan int)
-
-	p, _ := strconv.Atoi(pin)
-	p = f.digitalPin(p)
-	f.board.setPinMode(byte(p), analog)
-	f.board.togglePinReporting(byte(p), high, reportAnalog)
-	f.board.readAndProcess()
+	ret := make(chan int, 1)
 
-	gobot.Once(f.board.events[fmt.Sprintf("analog_read_%v", pin)], func(data interface{}) {
+	gobot.Once(f.board.event(fmt.Sprintf("analog_read_%v", pin)), func(data interface{}) {
 		b := data.([]byte)
 		ret <- int(uint(b[0])<<24 | uint(b[1])<<16 | uint(b[2])<<8 | uint(b[3]))
 	})
 
+	p, _ := strconv.Atoi(pin)
+	digital, err := f.board.analogPin(byte(p))
+	if err != nil {
+		log.Println(err)
+		return -1
+	}
+	if f.setPinMode(digital, analog) != nil {
+		return -1
+	}
+	f.board.togglePinReporting(byte(p), high, reportAnalog)
+	// the reply is awaited, so the commands cannot wait for WriteInterval
+	f.board.flush()
+
 	select {
 	case data := <-ret:
 		return data
-	case <-time.After(10 * time.Millisecond):
+	case <-time.After(f.readTimeout):
 	}
 	return -1
 }
@@ -118,33 +280,180 @@ func (f *FirmataAdaptor) AnalogWrite(pin string, level byte) {
 	f.PwmWrite(pin, level)
 }
 
-func (f *FirmataAdaptor) digitalPin(pin int) int {
-	return pin + 14
+// I2cOpen returns a handle on the device at address, enabling I2C on the
+// board the first time.
+func (f *FirmataAdaptor) I2cOpen(address byte) (i2c.I2cDevice, error) {
+	return f.i2cOpen(address, i2cReplyTimeout), nil
 }
 
-func (f *FirmataAdaptor) I2cStart(address byte) {
-	f.i2cAddress = address
-	f.board.i2cConfig([]byte{0})
+// I2cProbe returns a handle like I2cOpen, whose reads give up sooner, for
+// i2c.Scan.
+func (f *FirmataAdaptor) I2cProbe(address byte) (i2c.I2cDevice, error) {
+	return f.i2cOpen(address, f.probeTimeout), nil
 }
 
-func (f *FirmataAdaptor) I2cRead(size uint) []byte {
-	ret := make(chan []byte)
-	f.board.i2cReadRequest(f.i2cAddress, size)
+func (f *FirmataAdaptor) i2cOpen(address byte, timeout time.Duration) i2c.I2cDevice {
+	f.board.enableI2c()
+	return i2c.NewI2cDevice(&firmataI2cDevice{adaptor: f, address: address, timeout: timeout})
+}
 
-	f.board.readAndProcess()
+// i2cRead reads size bytes from the device at address, waiting up to
+// timeout for the reply. Reads are made one at a time, since the board
+// replies to each in turn.
+func (f *FirmataAdaptor) i2cRead(address byte, size int, timeout time.Duration) ([]byte, error) {
+	f.i2cMutex.Lock()
+	defer f.i2cMutex.Unlock()
 
-	gobot.Once(f.board.events["i2c_reply"], func(data interface{}) {
-		ret <- data.(map[string][]byte)["data"]
-	})
+	ret := make(chan []byte, 1)
+	f.i2cReplyMutex.Lock()
+	f.i2cAddress = address
+	f.i2cReply = ret
+	f.i2cReplyMutex.Unlock()
+	defer func() {
+		f.i2cReplyMutex.Lock()
+		f.i2cReply = nil
+		f.i2cReplyMutex.Unlock()
+	}()
+
+	f.board.i2cReadRequest(address, uint(size))
+	f.board.flush()
 
 	select {
 	case data := <-ret:
-		return data
-	case <-time.After(10 * time.Millisecond):
+		return data, nil
+	case <-time.After(timeout):
+	}
+	return nil, fmt.Errorf("firmata: no I2C reply from %#02x", address)
+}
+
+// handleI2cReply passes a reply to the read waiting for it, ignoring the
+// late replies of reads that timed out.
+func (f *FirmataAdaptor) handleI2cReply(data interface{}) {
+	reply := data.(map[string][]byte)
+	f.i2cReplyMutex.Lock()
+	defer f.i2cReplyMutex.Unlock()
+	if f.i2cReply == nil || len(reply["slave_address"]) == 0 ||
+		reply["slave_address"][0] != f.i2cAddress {
+		return
+	}
+	select {
+	case f.i2cReply <- reply["data"]:
+	default:
+	}
+}
+
+// firmataI2cDevice is a device on the I2C bus of a board.
+type firmataI2cDevice struct {
+	adaptor *FirmataAdaptor
+	address byte
+	timeout time.Duration
+}
+
+func (d *firmataI2cDevice) Read(data []byte) (int, error) {
+	reply, err := d.adaptor.i2cRead(d.address, len(data), d.timeout)
+	return copy(data, reply), err
+}
+
+func (d *firmataI2cDevice) Write(data []byte) (int, error) {
+	d.adaptor.board.i2cWriteRequest(d.address, data)
+	return len(data), nil
+}
+
+func (d *firmataI2cDevice) Close() error { return nil }
+
+// AddSysexHandler registers f to be called with the payload of every sysex
+// message received for command that the adaptor does not handle itself.
+// Several handlers may be registered for the same command. This is the
+// entry point for custom firmware and Firmata extensions.
+func (f *FirmataAdaptor) AddSysexHandler(command byte, h func(data []byte)) {
+	if f.board != nil {
+		f.board.mutex.Lock()
+		defer f.board.mutex.Unlock()
+	}
+	f.sysexHandlers[command] = append(f.sysexHandlers[command], h)
+}
+
+// SysexWrite sends data to the board as a sysex message for command. Every
+// byte of data must be 7-bit; see Encode7Bit and EncodePacked7Bit.
+func (f *FirmataAdaptor) SysexWrite(command byte, data []byte) {
+	f.board.sysex(command, data)
+}
+
+// PinCapability describes the modes supported by a pin of the board.
+// AnalogChannel is -1 for pins that have no analog input.
+type PinCapability struct {
+	Pin           int      `json:"pin"`
+	Modes         []string `json:"modes"`
+	AnalogChannel int      `json:"analog_channel"`
+}
+
+// FirmwareName returns the name of the firmware reported by the board.
+func (f *FirmataAdaptor) FirmwareName() string {
+	f.board.mutex.Lock()
+	defer f.board.mutex.Unlock()
+	return f.board.firmwareName
+}
+
+// ProtocolVersion returns the Firmata protocol version reported by the
+// board, such as "2.3".
+func (f *FirmataAdaptor) ProtocolVersion() string {
+	return f.board.version()
+}
+
+// Pins returns the capabilities of every pin reported by the board.
+func (f *FirmataAdaptor) Pins() []PinCapability {
+	f.board.mutex.Lock()
+	defer f.board.mutex.Unlock()
+	pins := []PinCapability{}
+	for i, p := range f.board.pins {
+		c := PinCapability{Pin: i, Modes: []string{}, AnalogChannel: -1}
+		for _, m := range p.supportedModes {
+			c.Modes = append(c.Modes, modeName(m))
+		}
+		if p.analogChannel != 127 {
+			c.AnalogChannel = int(p.analogChannel)
+		}
+		pins = append(pins, c)
+	}
+	return pins
+}
+
+// SupportsPinMode returns true if pin supports mode, one of the mode names
+// reported by Pins.
+func (f *FirmataAdaptor) SupportsPinMode(pin string, mode string) bool {
+	for m, name := range modeNames {
+		if name == mode {
+			return f.board.supportsPinMode(pinNumber(pin), m) == nil
+		}
+	}
+	return false
+}
+
+// ToJSON returns the connection details, including the firmware, protocol
+// version and pin capabilities once connected.
+func (f *FirmataAdaptor) ToJSON() *gobot.JSONConnection {
+	json := f.Adaptor.ToJSON()
+	if f.board != nil {
+		json.Details = map[string]interface{}{
+			"firmware": f.FirmwareName(),
+			"version":  f.ProtocolVersion(),
+			"pins":     f.Pins(),
+		}
+	}
+	return json
+}
+
+// setPinMode logs and returns the error when the board does not support
+// mode on pin, for the methods that cannot return it themselves.
+func (f *FirmataAdaptor) setPinMode(pin byte, mode byte) error {
+	err := f.board.setPinMode(pin, mode)
+	if err != nil {
+		log.Println(err)
 	}
-	return []byte{}
+	return err
 }
 
-func (f *FirmataAdaptor) I2cWrite(data []byte) {
-	f.board.i2cWriteRequest(f.i2cAddress, data)
+func pinNumber(pin string) byte {
+	p, _ := strconv.Atoi(pin)
+	return byte(p)
 }
@@ -1,39 +1,70 @@
 package firmata
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/i2c"
 )
 
+var _ i2c.I2cProber = (*FirmataAdaptor)(nil)
+
 func initTestFirmataAdaptor() *FirmataAdaptor {
 	a := NewFirmataAdaptor("board", "/dev/null")
 	a.connect = func(f *FirmataAdaptor) {
-		f.board = newBoard(gobot.NullReadWriteCloser{})
+		serial := newTestReadWriteCloser()
+		f.board = newBoard(serial)
 		f.board.initTimeInterval = 0 * time.Second
+		responses := []byte{}
 		// arduino uno r3 firmware response "StandardFirmata.ino"
-		f.board.process([]byte{240, 121, 2, 3, 83, 0, 116, 0, 97, 0, 110, 0, 100,
-			0, 97, 0, 114, 0, 100, 0, 70, 0, 105, 0, 114, 0, 109, 0, 97, 0, 116, 0,
-			97, 0, 46, 0, 105, 0, 110, 0, 111, 0, 247})
+		responses = append(responses, 240, 121, 2, 3, 83, 0, 116, 0, 97, 0,
+			110, 0, 100, 0, 97, 0, 114, 0, 100, 0, 70, 0, 105, 0, 114, 0, 109, 0,
+			97, 0, 116, 0, 97, 0, 46, 0, 105, 0, 110, 0, 111, 0, 247)
 		// arduino uno r3 capabilities response
-		f.board.process([]byte{240, 108, 127, 127, 0, 1, 1, 1, 4, 14, 127, 0, 1,
-			1, 1, 3, 8, 4, 14, 127, 0, 1, 1, 1, 4, 14, 127, 0, 1, 1, 1, 3, 8, 4, 14,
+		responses = append(responses, 240, 108, 127, 127, 0, 1, 1, 1, 4, 14,
 			127, 0, 1, 1, 1, 3, 8, 4, 14, 127, 0, 1, 1, 1, 4, 14, 127, 0, 1, 1, 1,
-			4, 14, 127, 0, 1, 1, 1, 3, 8, 4, 14, 127, 0, 1, 1, 1, 3, 8, 4, 14, 127,
-			0, 1, 1, 1, 3, 8, 4, 14, 127, 0, 1, 1, 1, 4, 14, 127, 0, 1, 1, 1, 4, 14,
-			127, 0, 1, 1, 1, 2, 10, 127, 0, 1, 1, 1, 2, 10, 127, 0, 1, 1, 1, 2, 10,
-			127, 0, 1, 1, 1, 2, 10, 127, 0, 1, 1, 1, 2, 10, 6, 1, 127, 0, 1, 1, 1,
-			2, 10, 6, 1, 127, 247})
+			3, 8, 4, 14, 127, 0, 1, 1, 1, 3, 8, 4, 14, 127, 0, 1, 1, 1, 4, 14, 127,
+			0, 1, 1, 1, 4, 14, 127, 0, 1, 1, 1, 3, 8, 4, 14, 127, 0, 1, 1, 1, 3, 8,
+			4, 14, 127, 0, 1, 1, 1, 3, 8, 4, 14, 127, 0, 1, 1, 1, 4, 14, 127, 0, 1,
+			1, 1, 4, 14, 127, 0, 1, 1, 1, 2, 10, 127, 0, 1, 1, 1, 2, 10, 127, 0, 1,
+			1, 1, 2, 10, 127, 0, 1, 1, 1, 2, 10, 127, 0, 1, 1, 1, 2, 10, 6, 1, 127,
+			0, 1, 1, 1, 2, 10, 6, 1, 127, 247)
 		// arduino uno r3 analog mapping response
-		f.board.process([]byte{240, 106, 127, 127, 127, 127, 127, 127, 127, 127,
-			127, 127, 127, 127, 127, 127, 0, 1, 2, 3, 4, 5, 247})
+		responses = append(responses, 240, 106, 127, 127, 127, 127, 127, 127,
+			127, 127, 127, 127, 127, 127, 127, 127, 0, 1, 2, 3, 4, 5, 247)
+		// Connect reads them once it has added the handlers that answer them
+		go serial.writer.Write(responses)
 	}
 	a.Connect()
+	// the handlers send the next queries from goroutines of their own, so
+	// wait for them before tests look at what is written
+	serial := a.board.serial.(*testReadWriteCloser)
+	serial.WaitWritten([]byte{startSysex, capabilityQuery, endSysex})
+	serial.WaitWritten([]byte{startSysex, analogMappingQuery, endSysex})
 	return a
 }
 
+// publishWhile keeps publishing data on e while f runs, so that a read in
+// f receives it however the goroutines are scheduled.
+func publishWhile(e *gobot.Event, data interface{}, f func()) {
+	done := make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+				gobot.Publish(e, data)
+			}
+		}
+	}()
+	f()
+	done <- true
+}
+
 func TestFirmataAdaptorFinalize(t *testing.T) {
 	a := initTestFirmataAdaptor()
 	gobot.Assert(t, a.Finalize(), true)
@@ -43,6 +74,57 @@ func TestFirmataAdaptorConnect(t *testing.T) {
 	gobot.Assert(t, a.Connect(), true)
 }
 
+func TestFirmataAdaptorMetadata(t *testing.T) {
+	a := initTestFirmataAdaptor()
+	gobot.Assert(t, a.FirmwareName(), "StandardFirmata.ino")
+	gobot.Assert(t, a.ProtocolVersion(), "0.0")
+	pins := a.Pins()
+	gobot.Assert(t, len(pins), 20)
+	gobot.Assert(t, pins[0], PinCapability{Pin: 0, Modes: []string{}, AnalogChannel: -1})
+	gobot.Assert(t, pins[9], PinCapability{
+		Pin:           9,
+		Modes:         []string{"input", "output", "pwm", "servo"},
+		AnalogChannel: -1,
+	})
+	gobot.Assert(t, pins[18], PinCapability{
+		Pin:           18,
+		Modes:         []string{"input", "output", "analog", "i2c"},
+		AnalogChannel: 4,
+	})
+}
+
+func TestFirmataAdaptorSupportsPinMode(t *testing.T) {
+	a := initTestFirmataAdaptor()
+	gobot.Assert(t, a.SupportsPinMode("9", "servo"), true)
+	gobot.Assert(t, a.SupportsPinMode("14", "servo"), false)
+	gobot.Assert(t, a.SupportsPinMode("20", "output"), false)
+	gobot.Assert(t, a.SupportsPinMode("9", "bogus"), false)
+}
+
+func TestFirmataAdaptorToJSON(t *testing.T) {
+	a := NewFirmataAdaptor("board", "/dev/null")
+	gobot.Assert(t, a.ToJSON().Details == nil, true)
+
+	a = initTestFirmataAdaptor()
+	json := a.ToJSON()
+	gobot.Assert(t, json.Name, "board")
+	gobot.Assert(t, json.Adaptor, "FirmataAdaptor")
+	gobot.Assert(t, json.Details["firmware"], "StandardFirmata.ino")
+	gobot.Assert(t, json.Details["version"], "0.0")
+	gobot.Assert(t, json.Details["pins"], a.Pins())
+}
+
+func TestFirmataAdaptorUnsupportedPinMode(t *testing.T) {
+	a := initTestFirmataAdaptor()
+	mode := a.board.pins[0].mode
+	a.board.serial.(*testReadWriteCloser).Written()
+	a.ServoWrite("14", 90)
+	a.DigitalWrite("20", 1)
+	gobot.Assert(t, len(a.board.serial.(*testReadWriteCloser).Written()), 0)
+	gobot.Assert(t, a.DigitalRead("0"), -1)
+	gobot.Assert(t, a.board.pins[0].mode, mode)
+}
+
 func TestFirmataAdaptorInitServo(t *testing.T) {
 	a := initTestFirmataAdaptor()
 	a.InitServo()
@@ -50,31 +132,55 @@ func TestFirmataAdaptorInitServo(t *testing.T) {
 
 func TestFirmataAdaptorServoWrite(t *testing.T) {
 	a := initTestFirmataAdaptor()
-	a.ServoWrite("1", 50)
+	a.ServoWrite("9", 50)
+}
+
+func TestFirmataAdaptorServoPulseWrite(t *testing.T) {
+	a := initTestFirmataAdaptor()
+	a.ServoPulseWrite("9", 1500*time.Microsecond)
+	gobot.Assert(t, a.board.pins[9].value, 1500)
+	a.ServoPulseWrite("9", 100*time.Microsecond)
+	gobot.Assert(t, a.board.pins[9].value, 544)
 }
 
 func TestFirmataAdaptorPwmWrite(t *testing.T) {
 	a := initTestFirmataAdaptor()
-	a.PwmWrite("1", 50)
+	a.PwmWrite("3", 50)
+}
+
+func TestFirmataAdaptorPwmController(t *testing.T) {
+	a := initTestFirmataAdaptor()
+	gobot.Assert(t, a.board.pwmResolution(3), byte(8))
+	gobot.Assert(t, a.PwmSetDutyCycle("3", 0.2), nil)
+	gobot.Assert(t, a.board.pins[3].value, 51)
+	gobot.Assert(t, a.PwmSetPolarity("3", true), nil)
+	gobot.Assert(t, a.board.pins[3].value, 204)
+	gobot.Assert(t, a.PwmSetEnabled("3", false), nil)
+	gobot.Assert(t, a.board.pins[3].value, 0)
+	gobot.Assert(t, a.PwmSetEnabled("3", true), nil)
+	gobot.Assert(t, a.board.pins[3].value, 204)
+
+	gobot.Refute(t, a.PwmSetPeriod("3", time.Millisecond), nil)
+	gobot.Refute(t, a.PwmSetDutyCycle("2", 0.5), nil)
 }
 
 func TestFirmataAdaptorDigitalWrite(t *testing.T) {
 	a := initTestFirmataAdaptor()
-	a.DigitalWrite("1", 1)
+	a.DigitalWrite("13", 1)
 }
 
 func TestFirmataAdaptorDigitalRead(t *testing.T) {
 	a := initTestFirmataAdaptor()
-	pinNumber := "1"
+	pinNumber := "2"
 	// -1 on no data
 	gobot.Assert(t, a.DigitalRead(pinNumber), -1)
 
-	go func() {
-		<-time.After(5 * time.Millisecond)
-		gobot.Publish(a.board.events[fmt.Sprintf("digital_read_%v", pinNumber)],
-			[]byte{0x01})
-	}()
-	gobot.Assert(t, a.DigitalRead(pinNumber), 0x01)
+	// a report may take a while to arrive on a busy machine
+	a.readTimeout = time.Second
+	publishWhile(a.board.event(fmt.Sprintf("digital_read_%v", pinNumber)),
+		[]byte{0x01}, func() {
+			gobot.Assert(t, a.DigitalRead(pinNumber), 0x01)
+		})
 }
 
 func TestFirmataAdaptorAnalogRead(t *testing.T) {
@@ -82,44 +188,133 @@ func TestFirmataAdaptorAnalogRead(t *testing.T) {
 	pinNumber := "1"
 	// -1 on no data
 	gobot.Assert(t, a.AnalogRead(pinNumber), -1)
+	gobot.Assert(t, a.board.pins[15].mode, byte(analog))
+	// no channel 6 on an uno
+	gobot.Assert(t, a.AnalogRead("6"), -1)
 
+	// a report may take a while to arrive on a busy machine
+	a.readTimeout = time.Second
 	value := 133
-	go func() {
-		<-time.After(5 * time.Millisecond)
-		gobot.Publish(a.board.events[fmt.Sprintf("analog_read_%v", pinNumber)],
-			[]byte{
-				byte(value >> 24),
-				byte(value >> 16),
-				byte(value >> 8),
-				byte(value & 0xff),
-			},
-		)
-	}()
-	gobot.Assert(t, a.AnalogRead(pinNumber), 133)
+	publishWhile(a.board.event(fmt.Sprintf("analog_read_%v", pinNumber)),
+		[]byte{
+			byte(value >> 24),
+			byte(value >> 16),
+			byte(value >> 8),
+			byte(value & 0xff),
+		}, func() {
+			gobot.Assert(t, a.AnalogRead(pinNumber), 133)
+		})
 }
+func TestFirmataAdaptorAnalogReadChannelMapping(t *testing.T) {
+	a := initTestFirmataAdaptor()
+	// A0 is not pin 14 on every board, such as the mega where it is pin 54
+	a.board.process([]byte{240, 106, 127, 127, 127, 127, 127, 127, 127, 127,
+		127, 127, 127, 127, 127, 127, 127, 127, 1, 0, 247})
+	a.board.serial.(*testReadWriteCloser).Written()
+	a.AnalogRead("0")
+	gobot.Assert(t, a.board.serial.(*testReadWriteCloser).Written(),
+		[]byte{pinMode, 17, analog, reportAnalog, high})
+}
+
+func TestFirmataAdaptorReadWithWriteInterval(t *testing.T) {
+	a := initTestFirmataAdaptor()
+	// nothing sends the queued writes but the reads themselves
+	a.board.writeInterval = time.Hour
+	a.board.serial.(*testReadWriteCloser).Written()
+	a.DigitalRead("2")
+	gobot.Assert(t, a.board.serial.(*testReadWriteCloser).Written(),
+		[]byte{pinMode, 2, input, reportDigital, high})
+	a.AnalogRead("1")
+	gobot.Assert(t, a.board.serial.(*testReadWriteCloser).Written(),
+		[]byte{pinMode, 15, analog, reportAnalog | 1, high})
+}
+
 func TestFirmataAdaptorAnalogWrite(t *testing.T) {
 	a := initTestFirmataAdaptor()
-	a.AnalogWrite("1", 50)
+	a.AnalogWrite("3", 50)
 }
-func TestFirmataAdaptorI2cStart(t *testing.T) {
+func TestFirmataAdaptorI2cOpen(t *testing.T) {
 	a := initTestFirmataAdaptor()
-	a.I2cStart(0x00)
+	a.board.serial.(*testReadWriteCloser).Written()
+	_, err := a.I2cOpen(0x09)
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, a.board.serial.(*testReadWriteCloser).Written(),
+		[]byte{startSysex, i2CConfig, 0x00, 0x00, endSysex})
+	// I2C is enabled once per connection
+	a.I2cOpen(0x0a)
+	a.I2cProbe(0x0b)
+	gobot.Assert(t, len(a.board.serial.(*testReadWriteCloser).Written()), 0)
+}
+func TestFirmataAdaptorI2cProbe(t *testing.T) {
+	a := initTestFirmataAdaptor()
+	a.probeTimeout = time.Millisecond
+	device, err := a.I2cProbe(0x09)
+	gobot.Assert(t, err, nil)
+	// absent devices are given up on sooner than by the reads of I2cOpen
+	start := time.Now()
+	_, err = device.ReadByte()
+	gobot.Assert(t, err, errors.New("firmata: no I2C reply from 0x09"))
+	gobot.Assert(t, time.Since(start) < i2cReplyTimeout, true)
+
+	a.probeTimeout = time.Second
+	device, _ = a.I2cProbe(0x09)
+	publishWhile(a.board.event("i2c_reply"), map[string][]byte{
+		"slave_address": []byte{0x09},
+		"data":          []byte{100},
+	}, func() {
+		value, err := device.ReadByte()
+		gobot.Assert(t, err, nil)
+		gobot.Assert(t, value, byte(100))
+	})
 }
 func TestFirmataAdaptorI2cRead(t *testing.T) {
 	a := initTestFirmataAdaptor()
-	// [] on no data
-	gobot.Assert(t, a.I2cRead(1), []byte{})
+	device, _ := a.I2cOpen(0x09)
+	// an error on no reply
+	_, err := device.ReadByte()
+	gobot.Assert(t, err, errors.New("firmata: no I2C reply from 0x09"))
 
-	i := []byte{100}
-	i2cReply := map[string][]byte{}
-	i2cReply["data"] = i
-	go func() {
-		<-time.After(5 * time.Millisecond)
-		gobot.Publish(a.board.events["i2c_reply"], i2cReply)
-	}()
-	gobot.Assert(t, a.I2cRead(1), i)
+	// replies from other devices are ignored
+	publishWhile(a.board.event("i2c_reply"), map[string][]byte{
+		"slave_address": []byte{0x10},
+		"data":          []byte{200},
+	}, func() {
+		_, err := device.ReadByte()
+		gobot.Refute(t, err, nil)
+	})
+
+	publishWhile(a.board.event("i2c_reply"), map[string][]byte{
+		"slave_address": []byte{0x09},
+		"data":          []byte{100},
+	}, func() {
+		value, err := device.ReadByte()
+		gobot.Assert(t, err, nil)
+		gobot.Assert(t, value, byte(100))
+	})
 }
 func TestFirmataAdaptorI2cWrite(t *testing.T) {
 	a := initTestFirmataAdaptor()
-	a.I2cWrite([]byte{0x00, 0x01})
+	device, _ := a.I2cOpen(0x09)
+	a.board.serial.(*testReadWriteCloser).Written()
+	gobot.Assert(t, device.WriteByteData(0x00, 0x01), nil)
+	gobot.Assert(t, a.board.serial.(*testReadWriteCloser).Written(),
+		[]byte{startSysex, i2CRequest, 0x09, 0x00, 0x00, 0x00, 0x01, 0x00, endSysex})
+}
+
+func TestFirmataAdaptorSysexWrite(t *testing.T) {
+	a := initTestFirmataAdaptor()
+	a.board.serial.(*testReadWriteCloser).Written()
+	a.SysexWrite(0x62, []byte{0x05, 0x00})
+	gobot.Assert(t, a.board.serial.(*testReadWriteCloser).Written(),
+		[]byte{0xF0, 0x62, 0x05, 0x00, 0xF7})
+}
+
+func TestFirmataAdaptorAddSysexHandler(t *testing.T) {
+	a := initTestFirmataAdaptor()
+	sem := make(chan []byte, 1)
+	a.AddSysexHandler(0x51, func(data []byte) {
+		sem <- data
+	})
+	a.board.process([]byte{0xF0, 0x51, 0x01, 0x7F, 0xF7})
+	gobot.Assert(t, <-sem, []byte{0x01, 0x7F})
 }
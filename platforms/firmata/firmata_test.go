@@ -8,7 +8,7 @@ import (
 )
 
 func initTestFirmata() *board {
-	b := newBoard(gobot.NullReadWriteCloser{})
+	b := newBoard(newTestReadWriteCloser())
 	b.initTimeInterval = 0 * time.Second
 	// arduino uno r3 firmware response "StandardFirmata.ino"
 	b.process([]byte{240, 121, 2, 3, 83, 0, 116, 0, 97, 0, 110, 0, 100, 0, 97,
@@ -50,14 +50,14 @@ func TestProcess(t *testing.T) {
 	b := initTestFirmata()
 	sem := make(chan bool)
 	//reportVersion
-	gobot.Once(b.events["report_version"], func(data interface{}) {
+	gobot.Once(b.event("report_version"), func(data interface{}) {
 		gobot.Assert(t, data.(string), "1.17")
 		sem <- true
 	})
 	b.process([]byte{0xF9, 0x01, 0x11})
 	<-sem
 	//analogMessageRangeStart
-	gobot.Once(b.events["analog_read_0"], func(data interface{}) {
+	gobot.Once(b.event("analog_read_0"), func(data interface{}) {
 		b := data.([]byte)
 		gobot.Assert(t,
 			int(uint(b[0])<<24|uint(b[1])<<16|uint(b[2])<<8|uint(b[3])),
@@ -66,7 +66,7 @@ func TestProcess(t *testing.T) {
 	})
 	b.process([]byte{0xE0, 0x23, 0x05})
 	<-sem
-	gobot.Once(b.events["analog_read_1"], func(data interface{}) {
+	gobot.Once(b.event("analog_read_1"), func(data interface{}) {
 		b := data.([]byte)
 		gobot.Assert(t,
 			int(uint(b[0])<<24|uint(b[1])<<16|uint(b[2])<<8|uint(b[3])),
@@ -77,21 +77,21 @@ func TestProcess(t *testing.T) {
 	<-sem
 	//digitalMessageRangeStart
 	b.pins[2].mode = input
-	gobot.Once(b.events["digital_read_2"], func(data interface{}) {
+	gobot.Once(b.event("digital_read_2"), func(data interface{}) {
 		gobot.Assert(t, int(data.([]byte)[0]), 1)
 		sem <- true
 	})
 	b.process([]byte{0x90, 0x04, 0x00})
 	<-sem
 	b.pins[4].mode = input
-	gobot.Once(b.events["digital_read_4"], func(data interface{}) {
+	gobot.Once(b.event("digital_read_4"), func(data interface{}) {
 		gobot.Assert(t, int(data.([]byte)[0]), 1)
 		sem <- true
 	})
 	b.process([]byte{0x90, 0x16, 0x00})
 	<-sem
 	//pinStateResponse
-	gobot.Once(b.events["pin_13_state"], func(data interface{}) {
+	gobot.Once(b.event("pin_13_state"), func(data interface{}) {
 		gobot.Assert(t, data, map[string]int{
 			"pin":   13,
 			"mode":  1,
@@ -102,7 +102,7 @@ func TestProcess(t *testing.T) {
 	b.process([]byte{240, 110, 13, 1, 1, 247})
 	<-sem
 	//i2cReply
-	gobot.Once(b.events["i2c_reply"], func(data interface{}) {
+	gobot.Once(b.event("i2c_reply"), func(data interface{}) {
 		i2c_reply := map[string][]byte{
 			"slave_address": []byte{9},
 			"register":      []byte{0},
@@ -113,7 +113,7 @@ func TestProcess(t *testing.T) {
 	b.process([]byte{240, 119, 9, 0, 0, 0, 24, 1, 1, 0, 26, 1, 247})
 	<-sem
 	//firmwareName
-	gobot.Once(b.events["firmware_query"], func(data interface{}) {
+	gobot.Once(b.event("firmware_query"), func(data interface{}) {
 		gobot.Assert(t, data.(string), "StandardFirmata.ino")
 		sem <- true
 	})
@@ -122,10 +122,113 @@ func TestProcess(t *testing.T) {
 		0, 105, 0, 110, 0, 111, 0, 247})
 	<-sem
 	//stringData
-	gobot.Once(b.events["string_data"], func(data interface{}) {
+	gobot.Once(b.event("string_data"), func(data interface{}) {
 		gobot.Assert(t, data.(string), "Hello Firmata!")
 		sem <- true
 	})
-	b.process(append([]byte{240, 0x71}, []byte("Hello Firmata!")...))
+	b.process(append(append([]byte{240, 0x71}, []byte("Hello Firmata!")...), 247))
 	<-sem
 }
+
+func TestProcessIncompleteMessages(t *testing.T) {
+	b := initTestFirmata()
+	gobot.Assert(t, b.process([]byte{0xF9, 0x01}), 0)
+	gobot.Assert(t, b.process([]byte{0xE0, 0x23, 0x05, 240, 110, 13, 1}), 3)
+	// stray data bytes are skipped
+	gobot.Assert(t, b.process([]byte{0x01, 0x02, 0xF9}), 2)
+}
+
+func TestReadAndProcessSplitMessages(t *testing.T) {
+	b := initTestFirmata()
+	serial := b.serial.(*testReadWriteCloser)
+	states := make(chan interface{}, 1)
+	gobot.Once(b.event("pin_13_state"), func(data interface{}) {
+		states <- data
+	})
+	versions := make(chan interface{}, 1)
+	gobot.Once(b.event("report_version"), func(data interface{}) {
+		versions <- data
+	})
+
+	for _, chunk := range [][]byte{{240, 110}, {13, 1, 1}, {247, 0xF9, 0x02}, {0x05}} {
+		go serial.writer.Write(chunk)
+		gobot.Assert(t, b.readAndProcess(), nil)
+	}
+	gobot.Assert(t, <-states, map[string]int{"pin": 13, "mode": 1, "value": 1})
+	gobot.Assert(t, <-versions, "2.5")
+	gobot.Assert(t, len(b.unparsed), 0)
+}
+
+func TestSysex(t *testing.T) {
+	b := initTestFirmata()
+	b.serial.(*testReadWriteCloser).Written()
+	b.sysex(0x62, []byte{0x01, 0x02})
+	gobot.Assert(t, b.serial.(*testReadWriteCloser).Written(),
+		[]byte{startSysex, 0x62, 0x01, 0x02, endSysex})
+}
+
+func TestProcessSysexHandler(t *testing.T) {
+	b := initTestFirmata()
+	received := [][]byte{}
+	b.sysexHandlers[0x62] = []func([]byte){
+		func(data []byte) { received = append(received, data) },
+		func(data []byte) { received = append(received, data) },
+	}
+	b.process([]byte{startSysex, 0x62, 0x06, 0x00, endSysex})
+	gobot.Assert(t, received, [][]byte{{0x06, 0x00}, {0x06, 0x00}})
+}
+
+func TestAnalogWrite(t *testing.T) {
+	b := initTestFirmata()
+	b.serial.(*testReadWriteCloser).Written()
+	b.analogWrite(9, 1500)
+	gobot.Assert(t, b.serial.(*testReadWriteCloser).Written(),
+		[]byte{analogMessage | 9, 0x5C, 0x0B})
+	b.analogWrite(17, 1500)
+	gobot.Assert(t, b.serial.(*testReadWriteCloser).Written(),
+		[]byte{startSysex, extendedAnalog, 17, 0x5C, 0x0B, endSysex})
+	gobot.Assert(t, b.pins[17].value, 1500)
+}
+
+func TestWriteCoalescing(t *testing.T) {
+	b := initTestFirmata()
+	b.writeInterval = time.Hour
+	b.serial.(*testReadWriteCloser).Written()
+	b.digitalWrite(13, 1)
+	b.analogWrite(3, 10)
+	b.digitalWrite(12, 1)
+	b.analogWrite(3, 20)
+	b.queryPinState(13)
+	b.digitalWrite(13, 0)
+	b.digitalWrite(12, 0)
+	gobot.Assert(t, b.serial.(*testReadWriteCloser).Written() == nil, true)
+	gobot.Assert(t, b.pending, [][]byte{
+		{digitalMessage | 1, 0x30, 0x00},
+		{analogMessage | 3, 20, 0},
+		{startSysex, pinStateQuery, 13, endSysex},
+		{digitalMessage | 1, 0x00, 0x00},
+	})
+
+	b.writeMutex.Lock()
+	b.flushPending()
+	b.writeMutex.Unlock()
+	gobot.Assert(t, b.serial.(*testReadWriteCloser).Written(), []byte{
+		digitalMessage | 1, 0x30, 0x00,
+		analogMessage | 3, 20, 0,
+		startSysex, pinStateQuery, 13, endSysex,
+		digitalMessage | 1, 0x00, 0x00,
+	})
+	gobot.Assert(t, len(b.pending), 0)
+}
+
+func TestDisconnectFlushesPendingWrites(t *testing.T) {
+	b := initTestFirmata()
+	b.writeInterval = time.Hour
+	b.serial.(*testReadWriteCloser).Written()
+	b.digitalWrite(13, 1)
+	b.disconnect()
+	gobot.Assert(t, b.serial.(*testReadWriteCloser).Written(),
+		[]byte{digitalMessage | 1, 0x20, 0x00})
+	b.digitalWrite(13, 0)
+	gobot.Assert(t, b.serial.(*testReadWriteCloser).Written() == nil, true)
+}
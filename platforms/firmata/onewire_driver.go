@@ -0,0 +1,145 @@
+package firmata
+
+import (
+	"github.com/edmontongo/gobot"
+)
+
+const (
+	oneWireData                byte = 0x73
+	oneWireSearchRequest       byte = 0x40
+	oneWireConfigRequest       byte = 0x41
+	oneWireSearchReply         byte = 0x42
+	oneWireReadReply           byte = 0x43
+	oneWireSearchAlarmsRequest byte = 0x44
+	oneWireSearchAlarmsReply   byte = 0x45
+	oneWireResetRequestBit     byte = 0x01
+	oneWireSkipRequestBit      byte = 0x02
+	oneWireSelectRequestBit    byte = 0x04
+	oneWireReadRequestBit      byte = 0x08
+	oneWireWriteRequestBit     byte = 0x20
+)
+
+// OneWireDriver talks to devices on a 1-Wire bus attached to a pin of a
+// board running the OneWireFirmata extension of ConfigurableFirmata.
+type OneWireDriver struct {
+	gobot.Driver
+	correlationID uint16
+}
+
+// NewOneWireDriver creates a driver for the 1-Wire bus on pin.
+func NewOneWireDriver(a *FirmataAdaptor, name string, pin string) *OneWireDriver {
+	o := &OneWireDriver{
+		Driver: *gobot.NewDriver(
+			name,
+			"OneWireDriver",
+			a,
+			pin,
+		),
+	}
+
+	o.AddEvent("search")
+	o.AddEvent("alarms")
+	o.AddEvent("data")
+
+	o.AddCommand("Search", func(params map[string]interface{}) interface{} {
+		o.Search()
+		return nil
+	})
+	o.AddCommand("SearchAlarms", func(params map[string]interface{}) interface{} {
+		o.SearchAlarms()
+		return nil
+	})
+
+	return o
+}
+
+func (o *OneWireDriver) adaptor() *FirmataAdaptor {
+	return o.Adaptor().(*FirmataAdaptor)
+}
+
+// Start registers for 1-Wire replies and configures the pin for 1-Wire
+// with parasitic power enabled.
+func (o *OneWireDriver) Start() bool {
+	o.adaptor().AddSysexHandler(oneWireData, o.handleSysex)
+	o.adaptor().SysexWrite(oneWireData,
+		[]byte{oneWireConfigRequest, pinNumber(o.Pin()), 0x01})
+	return true
+}
+func (o *OneWireDriver) Init() bool { return true }
+func (o *OneWireDriver) Halt() bool { return true }
+
+// Search looks for devices on the bus. Their 8 byte addresses are
+// published as a "search" event.
+func (o *OneWireDriver) Search() {
+	o.adaptor().SysexWrite(oneWireData,
+		[]byte{oneWireSearchRequest, pinNumber(o.Pin())})
+}
+
+// SearchAlarms looks for devices on the bus that are in an alarm state.
+// Their addresses are published as an "alarms" event.
+func (o *OneWireDriver) SearchAlarms() {
+	o.adaptor().SysexWrite(oneWireData,
+		[]byte{oneWireSearchAlarmsRequest, pinNumber(o.Pin())})
+}
+
+// Reset sends a reset pulse on the bus.
+func (o *OneWireDriver) Reset() {
+	o.command(oneWireResetRequestBit, []byte{})
+}
+
+// Write resets the bus, selects device and writes data to it. A nil device
+// addresses every device on the bus.
+func (o *OneWireDriver) Write(device []byte, data []byte) {
+	flags, payload := o.selectDevice(device)
+	o.command(flags|oneWireWriteRequestBit, append(payload, data...))
+}
+
+// Read resets the bus, selects device and reads numBytes from it. The
+// bytes read are published as a "data" event.
+func (o *OneWireDriver) Read(device []byte, numBytes uint16) {
+	flags, payload := o.selectDevice(device)
+	o.correlationID++
+	payload = append(payload,
+		byte(numBytes), byte(numBytes>>8),
+		byte(o.correlationID), byte(o.correlationID>>8),
+	)
+	o.command(flags|oneWireReadRequestBit, payload)
+}
+
+func (o *OneWireDriver) selectDevice(device []byte) (byte, []byte) {
+	if device == nil {
+		return oneWireResetRequestBit | oneWireSkipRequestBit, []byte{}
+	}
+	return oneWireResetRequestBit | oneWireSelectRequestBit,
+		append([]byte{}, device...)
+}
+
+func (o *OneWireDriver) command(flags byte, payload []byte) {
+	o.adaptor().SysexWrite(oneWireData,
+		append([]byte{flags, pinNumber(o.Pin())}, EncodePacked7Bit(payload)...))
+}
+
+func (o *OneWireDriver) handleSysex(data []byte) {
+	if len(data) < 2 || data[1] != pinNumber(o.Pin()) {
+		return
+	}
+	payload := DecodePacked7Bit(data[2:])
+	switch data[0] {
+	case oneWireSearchReply:
+		gobot.Publish(o.Event("search"), oneWireAddresses(payload))
+	case oneWireSearchAlarmsReply:
+		gobot.Publish(o.Event("alarms"), oneWireAddresses(payload))
+	case oneWireReadReply:
+		if len(payload) >= 2 {
+			gobot.Publish(o.Event("data"), payload[2:])
+		}
+	}
+}
+
+func oneWireAddresses(data []byte) [][]byte {
+	addresses := [][]byte{}
+	for i := 0; i+8 <= len(data); i += 8 {
+		addresses = append(addresses, data[i:i+8])
+	}
+	return addresses
+}
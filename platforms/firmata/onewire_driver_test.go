@@ -0,0 +1,81 @@
+package firmata
+
+import (
+	"testing"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestOneWireDriver() (*OneWireDriver, *testReadWriteCloser) {
+	a := initTestFirmataAdaptor()
+	d := NewOneWireDriver(a, "onewire", "10")
+	serial := a.board.serial.(*testReadWriteCloser)
+	serial.Written()
+	return d, serial
+}
+
+func TestOneWireDriverStart(t *testing.T) {
+	d, serial := initTestOneWireDriver()
+	gobot.Assert(t, d.Start(), true)
+	gobot.Assert(t, serial.Written(), []byte{0xF0, 0x73, 0x41, 10, 0x01, 0xF7})
+}
+
+func TestOneWireDriverHalt(t *testing.T) {
+	d, _ := initTestOneWireDriver()
+	gobot.Assert(t, d.Halt(), true)
+}
+
+func TestOneWireDriverInit(t *testing.T) {
+	d, _ := initTestOneWireDriver()
+	gobot.Assert(t, d.Init(), true)
+}
+
+func TestOneWireDriverSearch(t *testing.T) {
+	d, serial := initTestOneWireDriver()
+	d.Start()
+	serial.Written()
+	d.Search()
+	gobot.Assert(t, serial.Written(), []byte{0xF0, 0x73, 0x40, 10, 0xF7})
+
+	addresses := []byte{
+		0x28, 0xFF, 0x4C, 0x1D, 0x04, 0x00, 0x00, 0xA1,
+		0x28, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77,
+	}
+	sem := make(chan [][]byte)
+	gobot.Once(d.Event("search"), func(data interface{}) {
+		sem <- data.([][]byte)
+	})
+	d.adaptor().board.process(append(append([]byte{0xF0, 0x73, 0x42, 10},
+		EncodePacked7Bit(addresses)...), 0xF7))
+	gobot.Assert(t, <-sem, [][]byte{addresses[:8], addresses[8:]})
+}
+
+func TestOneWireDriverWrite(t *testing.T) {
+	d, serial := initTestOneWireDriver()
+	device := []byte{0x28, 0xFF, 0x4C, 0x1D, 0x04, 0x00, 0x00, 0xA1}
+	d.Write(device, []byte{0x44})
+	gobot.Assert(t, serial.Written(), append(append([]byte{0xF0, 0x73, 0x25, 10},
+		EncodePacked7Bit(append(device, 0x44))...), 0xF7))
+
+	d.Write(nil, []byte{0xCC})
+	gobot.Assert(t, serial.Written(), append(append([]byte{0xF0, 0x73, 0x23, 10},
+		EncodePacked7Bit([]byte{0xCC})...), 0xF7))
+}
+
+func TestOneWireDriverRead(t *testing.T) {
+	d, serial := initTestOneWireDriver()
+	d.Start()
+	serial.Written()
+	device := []byte{0x28, 0xFF, 0x4C, 0x1D, 0x04, 0x00, 0x00, 0xA1}
+	d.Read(device, 9)
+	gobot.Assert(t, serial.Written(), append(append([]byte{0xF0, 0x73, 0x0D, 10},
+		EncodePacked7Bit(append(device, 9, 0, 1, 0))...), 0xF7))
+
+	sem := make(chan []byte)
+	gobot.Once(d.Event("data"), func(data interface{}) {
+		sem <- data.([]byte)
+	})
+	d.adaptor().board.process(append(append([]byte{0xF0, 0x73, 0x43, 10},
+		EncodePacked7Bit([]byte{1, 0, 0x50, 0x05})...), 0xF7))
+	gobot.Assert(t, <-sem, []byte{0x50, 0x05})
+}
@@ -0,0 +1,533 @@
+package firmata
+
+import (
+	"io"
+	"math/rand"
+	"sync"
+)
+
+// SimulatorI2cDevice is a fake device attached to the I2C bus of a Simulator.
+type SimulatorI2cDevice interface {
+	// I2cWrite receives the data written to the device.
+	I2cWrite(data []byte)
+	// I2cRead returns size bytes read from the device.
+	I2cRead(size int) []byte
+}
+
+// Simulator emulates a board running StandardFirmata, so that the
+// FirmataAdaptor and the drivers using it can be tested without hardware.
+// It answers version, firmware, capability, analog mapping and pin state
+// queries, keeps track of pin modes and values, reports digital and analog
+// inputs and forwards I2C requests to fake devices.
+type Simulator struct {
+	FirmwareName  string
+	MajorVersion  byte
+	MinorVersion  byte
+	mutex         sync.Mutex
+	pins          []*simulatorPin
+	reportDigital map[byte]bool
+	reportAnalog  map[byte]bool
+	i2cDevices    map[int]SimulatorI2cDevice
+	sysexHandlers map[byte]func([]byte)
+	board         io.ReadWriteCloser
+	conn          io.ReadWriteCloser
+	output        *simulatorPipe
+}
+
+type simulatorPin struct {
+	modes         []byte
+	resolutions   []byte
+	analogChannel byte
+	mode          byte
+	value         int
+}
+
+// NewSimulator creates a Simulator with the pin layout of an Arduino Uno
+// and starts serving the protocol on the connection returned by Conn.
+func NewSimulator() *Simulator {
+	toBoard := newSimulatorPipe()
+	toSimulator := newSimulatorPipe()
+
+	s := &Simulator{
+		FirmwareName:  "StandardFirmata.ino",
+		MajorVersion:  2,
+		MinorVersion:  3,
+		pins:          unoPins(),
+		reportDigital: make(map[byte]bool),
+		reportAnalog:  make(map[byte]bool),
+		i2cDevices:    make(map[int]SimulatorI2cDevice),
+		sysexHandlers: make(map[byte]func([]byte)),
+		board:         &simulatorConn{in: toBoard, out: toSimulator},
+		conn:          &simulatorConn{in: toSimulator, out: toBoard},
+		output:        toBoard,
+	}
+
+	go s.serve()
+	return s
+}
+
+func unoPins() []*simulatorPin {
+	pins := []*simulatorPin{}
+	for i := 0; i < 20; i++ {
+		p := &simulatorPin{analogChannel: 127, mode: output}
+		switch {
+		case i < 2:
+			// used by the serial connection
+		case i < 14:
+			p.addMode(input, 1)
+			p.addMode(output, 1)
+			switch i {
+			case 3, 5, 6, 9, 10, 11:
+				p.addMode(pwm, 8)
+			}
+			p.addMode(servo, 14)
+		default:
+			p.addMode(input, 1)
+			p.addMode(output, 1)
+			p.addMode(analog, 10)
+			p.analogChannel = byte(i - 14)
+			if i == 18 || i == 19 {
+				p.addMode(i2cMode, 1)
+			}
+		}
+		pins = append(pins, p)
+	}
+	return pins
+}
+
+func (p *simulatorPin) addMode(mode byte, resolution byte) {
+	p.modes = append(p.modes, mode)
+	p.resolutions = append(p.resolutions, resolution)
+}
+
+// SplitOutput makes every read of the connection returned by Conn return
+// from 1 to max bytes, chosen at random, as a serial port may when the
+// board writes faster than it is read. Messages are then split across
+// reads, which the adaptor must put back together. A max of 0 returns
+// whole writes again.
+func (s *Simulator) SplitOutput(max int) {
+	s.output.mutex.Lock()
+	defer s.output.mutex.Unlock()
+	s.output.maxRead = max
+}
+
+// Conn returns the connection a FirmataAdaptor uses to talk to the
+// simulator, for example through NewFirmataAdaptorWithConnection.
+func (s *Simulator) Conn() io.ReadWriteCloser {
+	return s.board
+}
+
+// AddI2cDevice attaches device to the I2C bus at address.
+func (s *Simulator) AddI2cDevice(address int, device SimulatorI2cDevice) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.i2cDevices[address] = device
+}
+
+// AddSysexHandler registers h to emulate a Firmata extension. It is called
+// with the payload of every sysex message sent to the simulator for command.
+func (s *Simulator) AddSysexHandler(command byte, h func(data []byte)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sysexHandlers[command] = h
+}
+
+// SysexWrite sends a sysex message for command to the adaptor.
+func (s *Simulator) SysexWrite(command byte, data []byte) {
+	s.sysex(command, data)
+}
+
+// PinMode returns the mode last set for pin.
+func (s *Simulator) PinMode(pin int) byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.pins[pin].mode
+}
+
+// PinValue returns the value last written to, or set as input on, pin.
+func (s *Simulator) PinValue(pin int) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.pins[pin].value
+}
+
+// SetDigitalInput drives pin to value, reporting the change if digital
+// reporting is enabled for its port.
+func (s *Simulator) SetDigitalInput(pin int, value int) {
+	s.mutex.Lock()
+	s.pins[pin].value = value
+	port := byte(pin / 8)
+	report := s.reportDigital[port]
+	s.mutex.Unlock()
+
+	if report {
+		s.sendDigitalPort(port)
+	}
+}
+
+// SetAnalogInput sets the value read from pin, reporting the change if
+// analog reporting is enabled for its channel.
+func (s *Simulator) SetAnalogInput(pin int, value int) {
+	s.mutex.Lock()
+	s.pins[pin].value = value
+	channel := s.pins[pin].analogChannel
+	report := s.reportAnalog[channel]
+	s.mutex.Unlock()
+
+	if report {
+		s.sendAnalogChannel(channel)
+	}
+}
+
+func (s *Simulator) serve() {
+	buf := []byte{}
+	data := make([]byte, 1024)
+	for {
+		n, err := s.conn.Read(data)
+		buf = append(buf, data[:n]...)
+		for {
+			length := simulatorMessageLength(buf)
+			if length == 0 {
+				break
+			}
+			s.handle(buf[:length])
+			buf = buf[length:]
+		}
+		if err != nil {
+			s.conn.Close()
+			return
+		}
+	}
+}
+
+// simulatorMessageLength returns the length of the first complete message
+// in buf, or 0 if it does not hold one yet. Stray data bytes are skipped
+// as a single byte message.
+func simulatorMessageLength(buf []byte) int {
+	if len(buf) == 0 {
+		return 0
+	}
+	length := 1
+	switch command := buf[0]; {
+	case command == startSysex:
+		for i, b := range buf {
+			if b == endSysex {
+				return i + 1
+			}
+		}
+		return 0
+	case command == pinMode,
+		command >= digitalMessageRangeStart && command <= digitalMessageRangeEnd,
+		command >= analogMessageRangeStart && command <= analogMessageRangeEnd:
+		length = 3
+	case command&0xF0 == reportAnalog, command&0xF0 == reportDigital:
+		length = 2
+	}
+	if len(buf) < length {
+		return 0
+	}
+	return length
+}
+
+func (s *Simulator) handle(message []byte) {
+	command := message[0]
+	switch {
+	case command == systemReset:
+		s.reset()
+		s.sendVersion()
+		s.sendFirmware()
+	case command == reportVersion:
+		s.sendVersion()
+	case command == pinMode:
+		s.mutex.Lock()
+		if int(message[1]) < len(s.pins) {
+			s.pins[message[1]].mode = message[2]
+		}
+		s.mutex.Unlock()
+	case command >= digitalMessageRangeStart && command <= digitalMessageRangeEnd:
+		port := int(command & 0x0F)
+		value := int(message[1]) | int(message[2])<<7
+		s.mutex.Lock()
+		for i := 0; i < 8 && port*8+i < len(s.pins); i++ {
+			if p := s.pins[port*8+i]; p.mode == output {
+				p.value = (value >> uint(i)) & 0x01
+			}
+		}
+		s.mutex.Unlock()
+	case command >= analogMessageRangeStart && command <= analogMessageRangeEnd:
+		s.mutex.Lock()
+		if pin := int(command & 0x0F); pin < len(s.pins) {
+			s.pins[pin].value = int(message[1]) | int(message[2])<<7
+		}
+		s.mutex.Unlock()
+	case command&0xF0 == reportAnalog:
+		channel := command & 0x0F
+		s.mutex.Lock()
+		s.reportAnalog[channel] = message[1] != 0
+		s.mutex.Unlock()
+		if message[1] != 0 {
+			s.sendAnalogChannel(channel)
+		}
+	case command&0xF0 == reportDigital:
+		port := command & 0x0F
+		s.mutex.Lock()
+		s.reportDigital[port] = message[1] != 0
+		s.mutex.Unlock()
+		if message[1] != 0 {
+			s.sendDigitalPort(port)
+		}
+	case command == startSysex && len(message) > 2:
+		s.handleSysex(message[1], sysexData(message))
+	}
+}
+
+func (s *Simulator) handleSysex(command byte, data []byte) {
+	switch command {
+	case firmwareQuery:
+		s.sendFirmware()
+	case capabilityQuery:
+		s.sendCapabilities()
+	case analogMappingQuery:
+		s.sendAnalogMapping()
+	case pinStateQuery:
+		if len(data) > 0 {
+			s.sendPinState(data[0])
+		}
+	case i2CRequest:
+		s.handleI2cRequest(data)
+	case i2CConfig:
+	case extendedAnalog:
+		if len(data) < 2 {
+			return
+		}
+		value := 0
+		for i, b := range data[1:] {
+			value |= int(b&0x7F) << uint(7*i)
+		}
+		s.mutex.Lock()
+		if pin := int(data[0]); pin < len(s.pins) {
+			s.pins[pin].value = value
+		}
+		s.mutex.Unlock()
+	default:
+		s.mutex.Lock()
+		h, ok := s.sysexHandlers[command]
+		s.mutex.Unlock()
+		if ok {
+			h(data)
+		}
+	}
+}
+
+func (s *Simulator) handleI2cRequest(data []byte) {
+	if len(data) < 2 {
+		return
+	}
+	address := int(data[0]) | int(data[1]&0x07)<<7
+	mode := (data[1] >> 3) & 0x03
+
+	s.mutex.Lock()
+	device, ok := s.i2cDevices[address]
+	s.mutex.Unlock()
+
+	switch mode {
+	case i2CModeWrite:
+		if ok {
+			device.I2cWrite(Decode7Bit(data[2:]))
+		}
+	case i2CModeRead, i2CmodeContinuousRead:
+		values := []int{}
+		for i := 2; i+1 < len(data); i += 2 {
+			values = append(values, int(data[i])|int(data[i+1])<<7)
+		}
+		if len(values) == 0 {
+			return
+		}
+		register := byte(0xFF)
+		if len(values) > 1 {
+			register = byte(values[0])
+		}
+		reply := []byte{byte(address), register}
+		// like StandardFirmata, reply without data when nothing answers
+		if ok {
+			if len(values) > 1 {
+				device.I2cWrite([]byte{register})
+			}
+			reply = append(reply, device.I2cRead(values[len(values)-1])...)
+		}
+		s.sysex(i2CReply, Encode7Bit(reply))
+	}
+}
+
+func (s *Simulator) reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, p := range s.pins {
+		p.mode = output
+		p.value = 0
+	}
+	s.reportDigital = make(map[byte]bool)
+	s.reportAnalog = make(map[byte]bool)
+}
+
+func (s *Simulator) sendVersion() {
+	s.write([]byte{reportVersion, s.MajorVersion, s.MinorVersion})
+}
+
+func (s *Simulator) sendFirmware() {
+	data := []byte{s.MajorVersion, s.MinorVersion}
+	s.sysex(firmwareQuery, append(data, Encode7Bit([]byte(s.FirmwareName))...))
+}
+
+func (s *Simulator) sendCapabilities() {
+	s.mutex.Lock()
+	data := []byte{}
+	for _, p := range s.pins {
+		for i, mode := range p.modes {
+			data = append(data, mode, p.resolutions[i])
+		}
+		data = append(data, 127)
+	}
+	s.mutex.Unlock()
+	s.sysex(capabilityResponse, data)
+}
+
+func (s *Simulator) sendAnalogMapping() {
+	s.mutex.Lock()
+	data := []byte{}
+	for _, p := range s.pins {
+		data = append(data, p.analogChannel)
+	}
+	s.mutex.Unlock()
+	s.sysex(analogMappingResponse, data)
+}
+
+func (s *Simulator) sendPinState(pin byte) {
+	s.mutex.Lock()
+	if int(pin) >= len(s.pins) {
+		s.mutex.Unlock()
+		return
+	}
+	data := []byte{pin, s.pins[pin].mode}
+	value := s.pins[pin].value
+	s.mutex.Unlock()
+
+	data = append(data, byte(value&0x7F))
+	for value >>= 7; value > 0; value >>= 7 {
+		data = append(data, byte(value&0x7F))
+	}
+	s.sysex(pinStateResponse, data)
+}
+
+func (s *Simulator) sendDigitalPort(port byte) {
+	s.mutex.Lock()
+	value := 0
+	for i := 0; i < 8 && int(port)*8+i < len(s.pins); i++ {
+		p := s.pins[int(port)*8+i]
+		if p.mode == input && p.value != 0 {
+			value |= 1 << uint(i)
+		}
+	}
+	s.mutex.Unlock()
+	s.write([]byte{digitalMessage | port, byte(value & 0x7F), byte(value >> 7)})
+}
+
+func (s *Simulator) sendAnalogChannel(channel byte) {
+	s.mutex.Lock()
+	value := -1
+	for _, p := range s.pins {
+		if p.analogChannel == channel && p.mode == analog {
+			value = p.value
+		}
+	}
+	s.mutex.Unlock()
+	if value == -1 {
+		return
+	}
+	s.write([]byte{analogMessage | channel, byte(value & 0x7F), byte((value >> 7) & 0x7F)})
+}
+
+func (s *Simulator) sysex(command byte, data []byte) {
+	ret := append([]byte{startSysex, command}, data...)
+	s.write(append(ret, endSysex))
+}
+
+func (s *Simulator) write(data []byte) {
+	s.conn.Write(data)
+}
+
+// simulatorConn is one end of an in-memory duplex connection.
+type simulatorConn struct {
+	in  *simulatorPipe
+	out *simulatorPipe
+}
+
+func (c *simulatorConn) Read(b []byte) (int, error)  { return c.in.read(b) }
+func (c *simulatorConn) Write(b []byte) (int, error) { return c.out.write(b) }
+func (c *simulatorConn) Close() error {
+	c.in.close()
+	c.out.close()
+	return nil
+}
+
+// simulatorPipe carries data in one direction. Writes never block, and
+// every read returns whole writes whenever they fit, so that messages are
+// delivered the way a serial port usually delivers them, unless maxRead
+// limits reads to random smaller chunks.
+type simulatorPipe struct {
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	chunks  [][]byte
+	closed  bool
+	maxRead int
+	random  *rand.Rand
+}
+
+func newSimulatorPipe() *simulatorPipe {
+	p := &simulatorPipe{random: rand.New(rand.NewSource(1))}
+	p.cond = sync.NewCond(&p.mutex)
+	return p
+}
+
+func (p *simulatorPipe) write(b []byte) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed {
+		return 0, io.ErrClosedPipe
+	}
+	p.chunks = append(p.chunks, append([]byte{}, b...))
+	p.cond.Signal()
+	return len(b), nil
+}
+
+func (p *simulatorPipe) read(b []byte) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for len(p.chunks) == 0 && !p.closed {
+		p.cond.Wait()
+	}
+	if len(p.chunks) == 0 {
+		return 0, io.EOF
+	}
+	if p.maxRead > 0 && len(b) > p.maxRead {
+		b = b[:1+p.random.Intn(p.maxRead)]
+	}
+	n := copy(b, p.chunks[0])
+	if n < len(p.chunks[0]) {
+		p.chunks[0] = p.chunks[0][n:]
+		return n, nil
+	}
+	p.chunks = p.chunks[1:]
+	for len(p.chunks) > 0 && n+len(p.chunks[0]) <= len(b) {
+		n += copy(b[n:], p.chunks[0])
+		p.chunks = p.chunks[1:]
+	}
+	return n, nil
+}
+
+func (p *simulatorPipe) close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.closed = true
+	p.cond.Broadcast()
+}
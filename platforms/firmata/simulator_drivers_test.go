@@ -0,0 +1,106 @@
+package firmata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/gpio"
+	"github.com/edmontongo/gobot/platforms/i2c"
+)
+
+func TestSimulatorLedDriver(t *testing.T) {
+	a, s := initTestSimulatorAdaptor()
+	led := gpio.NewLedDriver(a, "led", "11")
+	led.On()
+	gobot.Assert(t, waitFor(func() bool { return s.PinValue(11) == 1 }), true)
+	led.Toggle()
+	gobot.Assert(t, waitFor(func() bool { return s.PinValue(11) == 0 }), true)
+	led.Brightness(100)
+	gobot.Assert(t, waitFor(func() bool { return s.PinValue(11) == 100 }), true)
+	gobot.Assert(t, s.PinMode(11), pwm)
+}
+
+func TestSimulatorButtonDriver(t *testing.T) {
+	a, s := initTestSimulatorAdaptor()
+	button := gpio.NewButtonDriver(a, "button", "2")
+	sem := make(chan bool, 1)
+	gobot.On(button.Event("push"), func(data interface{}) {
+		sem <- true
+	})
+	button.Start()
+	s.SetDigitalInput(2, 1)
+	select {
+	case <-sem:
+	case <-time.After(time.Second):
+		t.Errorf("button push was not published")
+	}
+}
+
+func TestSimulatorAnalogSensorDriver(t *testing.T) {
+	a, s := initTestSimulatorAdaptor()
+	sensor := gpio.NewAnalogSensorDriver(a, "sensor", "0")
+	s.SetAnalogInput(14, 512)
+	gobot.Assert(t, waitFor(func() bool { return sensor.Read() == 512 }), true)
+}
+
+func TestSimulatorServoDriver(t *testing.T) {
+	a, s := initTestSimulatorAdaptor()
+	servo := gpio.NewServoDriver(a, "servo", "9")
+	servo.Move(45)
+	// a quarter of the way from 544µs to 2400µs
+	gobot.Assert(t, waitFor(func() bool { return s.PinValue(9) == 1008 }), true)
+	servo.MoveTo(90, 50*time.Millisecond)
+	gobot.Assert(t, waitFor(func() bool { return s.PinValue(9) == 1472 }), true)
+}
+
+func TestSimulatorDirectPinDriver(t *testing.T) {
+	a, s := initTestSimulatorAdaptor()
+	pin := gpio.NewDirectPinDriver(a, "pin", "7")
+	gobot.Assert(t, pin.DigitalRead(), 0)
+	s.SetDigitalInput(7, 1)
+	// a report of the earlier level may still be on its way
+	gobot.Assert(t, waitFor(func() bool { return pin.DigitalRead() == 1 }), true)
+	pin.DigitalWrite(0)
+	gobot.Assert(t, waitFor(func() bool { return s.PinValue(7) == 0 }), true)
+	gobot.Assert(t, s.PinMode(7), output)
+}
+
+func TestSimulatorBlinkMDriver(t *testing.T) {
+	a, s := initTestSimulatorAdaptor()
+	device := newTestI2cDevice([]byte{'a', 'b', 'c'})
+	s.AddI2cDevice(0x09, device)
+	blinkm := i2c.NewBlinkMDriver(a, "blinkm")
+	blinkm.Start()
+	gobot.Assert(t, <-device.written, []byte("o"))
+	gobot.Assert(t, <-device.written, []byte("n"))
+	gobot.Assert(t, <-device.written, []byte{0, 0, 0})
+
+	blinkm.Rgb(1, 2, 3)
+	gobot.Assert(t, <-device.written, []byte("n"))
+	gobot.Assert(t, <-device.written, []byte{1, 2, 3})
+	version, err := blinkm.FirmwareVersion()
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, version, "97.98")
+	<-device.written
+	color, err := blinkm.Color()
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, color, []byte{'a', 'b', 'c'})
+}
+
+func TestSimulatorWiichuckDriver(t *testing.T) {
+	a, s := initTestSimulatorAdaptor()
+	s.AddI2cDevice(0x52, newTestI2cDevice([]byte{0x97, 0x98, 0x00, 0x00, 0x00, 0x17}))
+	wiichuck := i2c.NewWiichuckDriver(a, "wiichuck")
+	sem := make(chan map[string]float64, 1)
+	gobot.Once(wiichuck.Event("joystick"), func(data interface{}) {
+		sem <- data.(map[string]float64)
+	})
+	wiichuck.Start()
+	select {
+	case data := <-sem:
+		gobot.Assert(t, data, map[string]float64{"x": 0, "y": 0})
+	case <-time.After(time.Second):
+		t.Errorf("joystick event was not published")
+	}
+}
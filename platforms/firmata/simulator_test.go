@@ -0,0 +1,304 @@
+package firmata
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/i2c"
+)
+
+type testI2cDevice struct {
+	written chan []byte
+	data    []byte
+}
+
+func newTestI2cDevice(data []byte) *testI2cDevice {
+	return &testI2cDevice{
+		written: make(chan []byte, 10),
+		data:    data,
+	}
+}
+
+func (t *testI2cDevice) I2cWrite(data []byte) { t.written <- data }
+func (t *testI2cDevice) I2cRead(size int) []byte {
+	return t.data[:size]
+}
+
+// newTestSimulator returns a Simulator whose output is split across reads,
+// so that end-to-end tests also check that messages are put back together.
+func newTestSimulator() *Simulator {
+	s := NewSimulator()
+	s.SplitOutput(5)
+	return s
+}
+
+func initTestSimulatorAdaptor() (*FirmataAdaptor, *Simulator) {
+	s := newTestSimulator()
+	a := NewFirmataAdaptorWithConnection("board", s.Conn())
+	a.connect = func(f *FirmataAdaptor) {
+		f.board = newBoard(s.Conn())
+		f.board.initTimeInterval = 10 * time.Millisecond
+	}
+	// the simulator may take a while to reply on a busy machine
+	a.readTimeout = time.Second
+	a.probeTimeout = time.Second
+	a.Connect()
+	return a, s
+}
+
+// waitFor polls f until it returns true or a second has passed.
+func waitFor(f func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if f() {
+			return true
+		}
+		<-time.After(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestSimulatorCapabilities(t *testing.T) {
+	s := NewSimulator()
+	conn := s.Conn()
+	conn.Write([]byte{startSysex, capabilityQuery, endSysex})
+	buf := make([]byte, 1024)
+	n, _ := conn.Read(buf)
+	// arduino uno r3 capabilities response
+	gobot.Assert(t, buf[:n], []byte{240, 108, 127, 127, 0, 1, 1, 1, 4, 14, 127,
+		0, 1, 1, 1, 3, 8, 4, 14, 127, 0, 1, 1, 1, 4, 14, 127, 0, 1, 1, 1, 3, 8, 4,
+		14, 127, 0, 1, 1, 1, 3, 8, 4, 14, 127, 0, 1, 1, 1, 4, 14, 127, 0, 1, 1, 1,
+		4, 14, 127, 0, 1, 1, 1, 3, 8, 4, 14, 127, 0, 1, 1, 1, 3, 8, 4, 14, 127, 0,
+		1, 1, 1, 3, 8, 4, 14, 127, 0, 1, 1, 1, 4, 14, 127, 0, 1, 1, 1, 4, 14, 127,
+		0, 1, 1, 1, 2, 10, 127, 0, 1, 1, 1, 2, 10, 127, 0, 1, 1, 1, 2, 10, 127, 0,
+		1, 1, 1, 2, 10, 127, 0, 1, 1, 1, 2, 10, 6, 1, 127, 0, 1, 1, 1, 2, 10, 6, 1,
+		127, 247})
+
+	conn.Write([]byte{startSysex, analogMappingQuery, endSysex})
+	n, _ = conn.Read(buf)
+	// arduino uno r3 analog mapping response
+	gobot.Assert(t, buf[:n], []byte{240, 106, 127, 127, 127, 127, 127, 127, 127,
+		127, 127, 127, 127, 127, 127, 127, 0, 1, 2, 3, 4, 5, 247})
+}
+
+func TestSimulatorSplitMessages(t *testing.T) {
+	s := NewSimulator()
+	conn := s.Conn()
+	conn.Write([]byte{pinMode, 13})
+	conn.Write([]byte{output, digitalMessage | 1})
+	conn.Write([]byte{0x20, 0x00, reportVersion})
+	buf := make([]byte, 1024)
+	n, _ := conn.Read(buf)
+	gobot.Assert(t, buf[:n], []byte{reportVersion, 2, 3})
+	gobot.Assert(t, s.PinValue(13), 1)
+}
+
+func TestSimulatorSplitOutput(t *testing.T) {
+	s := NewSimulator()
+	s.SplitOutput(2)
+	conn := s.Conn()
+	conn.Write([]byte{reportVersion})
+	buf := make([]byte, 1024)
+	received := []byte{}
+	for len(received) < 3 {
+		n, _ := conn.Read(buf)
+		gobot.Assert(t, n >= 1 && n <= 2, true)
+		received = append(received, buf[:n]...)
+	}
+	gobot.Assert(t, received, []byte{reportVersion, 2, 3})
+}
+
+func TestSimulatorConnect(t *testing.T) {
+	a, _ := initTestSimulatorAdaptor()
+	gobot.Assert(t, a.board.firmwareName, "StandardFirmata.ino")
+	gobot.Assert(t, a.board.version(), "2.3")
+	gobot.Assert(t, len(a.board.pins), 20)
+	gobot.Assert(t, a.board.analogPins, []byte{14, 15, 16, 17, 18, 19})
+	gobot.Assert(t, a.board.pins[19].supportedModes, []byte{input, output, analog, i2cMode})
+	gobot.Assert(t, a.Finalize(), true)
+}
+
+func TestSimulatorDigitalWrite(t *testing.T) {
+	a, s := initTestSimulatorAdaptor()
+	a.DigitalWrite("13", 1)
+	a.DigitalWrite("19", 1)
+	gobot.Assert(t, waitFor(func() bool {
+		return s.PinValue(13) == 1 && s.PinValue(19) == 1
+	}), true)
+	gobot.Assert(t, s.PinMode(13), output)
+	a.DigitalWrite("13", 0)
+	gobot.Assert(t, waitFor(func() bool { return s.PinValue(13) == 0 }), true)
+}
+
+func TestSimulatorConcurrentDigitalWrite(t *testing.T) {
+	a, s := initTestSimulatorAdaptor()
+	done := make(chan bool)
+	for pin := 2; pin <= 13; pin++ {
+		go func(pin int) {
+			for i := 0; i < 10; i++ {
+				a.DigitalWrite(strconv.Itoa(pin), byte(i%2))
+			}
+			a.DigitalWrite(strconv.Itoa(pin), 1)
+			done <- true
+		}(pin)
+	}
+	for pin := 2; pin <= 13; pin++ {
+		<-done
+	}
+	for pin := 2; pin <= 13; pin++ {
+		pin := pin
+		gobot.Assert(t, waitFor(func() bool { return s.PinValue(pin) == 1 }), true)
+	}
+}
+
+func TestSimulatorWriteInterval(t *testing.T) {
+	s := newTestSimulator()
+	a := NewFirmataAdaptorWithConnection("board", s.Conn())
+	a.WriteInterval = 20 * time.Millisecond
+	a.connect = func(f *FirmataAdaptor) {
+		f.board = newBoard(s.Conn())
+		f.board.initTimeInterval = 10 * time.Millisecond
+	}
+	a.Connect()
+
+	for i := 0; i < 100; i++ {
+		a.PwmWrite("3", byte(i))
+	}
+	gobot.Assert(t, waitFor(func() bool { return s.PinValue(3) == 99 }), true)
+	a.Disconnect()
+}
+
+func TestSimulatorReadWithWriteInterval(t *testing.T) {
+	s := newTestSimulator()
+	a := NewFirmataAdaptorWithConnection("board", s.Conn())
+	a.WriteInterval = 20 * time.Millisecond
+	a.connect = func(f *FirmataAdaptor) {
+		f.board = newBoard(s.Conn())
+		f.board.initTimeInterval = 10 * time.Millisecond
+	}
+	a.readTimeout = time.Second
+	a.Connect()
+
+	gobot.Assert(t, a.DigitalRead("2"), 0)
+	s.SetDigitalInput(2, 1)
+	// a report of the earlier level may still be on its way
+	gobot.Assert(t, waitFor(func() bool { return a.DigitalRead("2") == 1 }), true)
+	s.SetAnalogInput(15, 675)
+	gobot.Assert(t, waitFor(func() bool { return a.AnalogRead("1") == 675 }), true)
+	a.Disconnect()
+}
+
+func TestSimulatorDigitalRead(t *testing.T) {
+	a, s := initTestSimulatorAdaptor()
+	gobot.Assert(t, a.DigitalRead("2"), 0)
+	gobot.Assert(t, s.PinMode(2), input)
+	s.SetDigitalInput(2, 1)
+	// a report of the earlier level may still be on its way
+	gobot.Assert(t, waitFor(func() bool { return a.DigitalRead("2") == 1 }), true)
+	gobot.Assert(t, a.DigitalRead("12"), 0)
+	s.SetDigitalInput(12, 1)
+	gobot.Assert(t, waitFor(func() bool { return a.DigitalRead("12") == 1 }), true)
+}
+
+func TestSimulatorAnalogRead(t *testing.T) {
+	a, s := initTestSimulatorAdaptor()
+	s.SetAnalogInput(15, 675)
+	gobot.Assert(t, waitFor(func() bool { return a.AnalogRead("1") == 675 }), true)
+	gobot.Assert(t, s.PinMode(15), analog)
+	s.SetAnalogInput(19, 1023)
+	gobot.Assert(t, waitFor(func() bool { return a.AnalogRead("5") == 1023 }), true)
+}
+
+func TestSimulatorPwmAndServoWrite(t *testing.T) {
+	a, s := initTestSimulatorAdaptor()
+	a.PwmWrite("3", 200)
+	a.ServoWrite("9", 90)
+	gobot.Assert(t, waitFor(func() bool {
+		return s.PinValue(3) == 200 && s.PinValue(9) == 90
+	}), true)
+	gobot.Assert(t, s.PinMode(3), pwm)
+	gobot.Assert(t, s.PinMode(9), servo)
+}
+
+func TestSimulatorPwmResolution(t *testing.T) {
+	s := newTestSimulator()
+	s.pins[5].resolutions[2] = 10
+	a := NewFirmataAdaptorWithConnection("board", s.Conn())
+	a.connect = func(f *FirmataAdaptor) {
+		f.board = newBoard(s.Conn())
+		f.board.initTimeInterval = 10 * time.Millisecond
+	}
+	a.Connect()
+	gobot.Assert(t, a.board.pwmResolution(5), byte(10))
+	a.PwmSetDutyCycle("5", 1)
+	gobot.Assert(t, waitFor(func() bool { return s.PinValue(5) == 1023 }), true)
+}
+
+func TestSimulatorServoPulseWrite(t *testing.T) {
+	a, s := initTestSimulatorAdaptor()
+	a.ServoPulseWrite("10", 2000*time.Microsecond)
+	gobot.Assert(t, waitFor(func() bool { return s.PinValue(10) == 2000 }), true)
+}
+
+func TestSimulatorExtendedAnalog(t *testing.T) {
+	_, s := initTestSimulatorAdaptor()
+	s.handle([]byte{startSysex, extendedAnalog, 17, 0x5C, 0x0B, endSysex})
+	gobot.Assert(t, s.PinValue(17), 1500)
+}
+
+func TestSimulatorPinState(t *testing.T) {
+	a, _ := initTestSimulatorAdaptor()
+	a.PwmWrite("5", 150)
+	sem := make(chan map[string]int, 1)
+	gobot.Once(a.board.event("pin_5_state"), func(data interface{}) {
+		sem <- data.(map[string]int)
+	})
+	a.board.queryPinState(5)
+	select {
+	case data := <-sem:
+		gobot.Assert(t, data, map[string]int{"pin": 5, "mode": 3, "value": 150})
+	case <-time.After(time.Second):
+		t.Errorf("pin state was not reported")
+	}
+}
+
+func TestSimulatorI2c(t *testing.T) {
+	a, s := initTestSimulatorAdaptor()
+	device := newTestI2cDevice([]byte{0x01, 0xFF, 0x80})
+	s.AddI2cDevice(0x09, device)
+	i2cDevice, err := a.I2cOpen(0x09)
+	gobot.Assert(t, err, nil)
+	i2cDevice.Write([]byte{0x6E, 0xFF})
+	gobot.Assert(t, <-device.written, []byte{0x6E, 0xFF})
+	data := make([]byte, 3)
+	_, err = i2cDevice.Read(data)
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, data, []byte{0x01, 0xFF, 0x80})
+}
+
+func TestSimulatorI2cScan(t *testing.T) {
+	a, s := initTestSimulatorAdaptor()
+	s.AddI2cDevice(0x52, newTestI2cDevice([]byte{0x00}))
+	gobot.Assert(t, a.Command("I2cScan")(map[string]interface{}{}), []i2c.ScanResult{
+		{Address: 0x52, Drivers: []string{"WiichuckDriver"}},
+	})
+}
+
+func TestSimulatorSysex(t *testing.T) {
+	a, s := initTestSimulatorAdaptor()
+	s.AddSysexHandler(0x51, func(data []byte) {
+		s.SysexWrite(0x51, append([]byte{0x7F}, data...))
+	})
+	sem := make(chan []byte, 1)
+	a.AddSysexHandler(0x51, func(data []byte) {
+		sem <- data
+	})
+	a.SysexWrite(0x51, []byte{0x01, 0x02})
+	select {
+	case data := <-sem:
+		gobot.Assert(t, data, []byte{0x7F, 0x01, 0x02})
+	case <-time.After(time.Second):
+		t.Errorf("sysex reply was not received")
+	}
+}
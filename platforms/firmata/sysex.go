@@ -0,0 +1,78 @@
+package firmata
+
+// Encode7Bit splits every byte of data into two 7-bit bytes, least
+// significant bits first, as required for 8-bit values inside sysex messages.
+func Encode7Bit(data []byte) []byte {
+	ret := []byte{}
+	for _, val := range data {
+		ret = append(ret, val&0x7F, (val>>7)&0x7F)
+	}
+	return ret
+}
+
+// Decode7Bit joins pairs of 7-bit bytes produced by Encode7Bit back into
+// 8-bit values. A trailing unpaired byte is ignored.
+func Decode7Bit(data []byte) []byte {
+	ret := []byte{}
+	for i := 0; i+1 < len(data); i += 2 {
+		ret = append(ret, data[i]|data[i+1]<<7)
+	}
+	return ret
+}
+
+// EncodePacked7Bit packs data into a stream of 7-bit bytes, using 8 output
+// bytes for every 7 input bytes. This is the encoding used by extensions
+// such as OneWire that transfer larger payloads.
+func EncodePacked7Bit(data []byte) []byte {
+	ret := []byte{}
+	shift := uint(0)
+	previous := byte(0)
+	for _, val := range data {
+		if shift == 0 {
+			ret = append(ret, val&0x7F)
+			shift++
+			previous = val >> 7
+		} else {
+			ret = append(ret, ((val<<shift)&0x7F)|previous)
+			if shift == 6 {
+				ret = append(ret, val>>1)
+				shift = 0
+			} else {
+				shift++
+				previous = val >> (8 - shift)
+			}
+		}
+	}
+	if shift > 0 {
+		ret = append(ret, previous)
+	}
+	return ret
+}
+
+// DecodePacked7Bit reverses EncodePacked7Bit.
+func DecodePacked7Bit(data []byte) []byte {
+	ret := make([]byte, len(data)*7/8)
+	for i := range ret {
+		j := uint(i) << 3
+		pos := j / 7
+		shift := j % 7
+		ret[i] = data[pos] >> shift
+		if int(pos)+1 < len(data) {
+			ret[i] |= data[pos+1] << (7 - shift)
+		}
+	}
+	return ret
+}
+
+// sysexData returns the payload of a sysex message, without the start byte,
+// command byte and trailing end byte.
+func sysexData(message []byte) []byte {
+	if len(message) < 2 {
+		return []byte{}
+	}
+	data := message[2:]
+	if len(data) > 0 && data[len(data)-1] == endSysex {
+		data = data[:len(data)-1]
+	}
+	return data
+}
@@ -0,0 +1,35 @@
+package firmata
+
+import (
+	"testing"
+
+	"github.com/edmontongo/gobot"
+)
+
+func TestEncode7Bit(t *testing.T) {
+	gobot.Assert(t, Encode7Bit([]byte{0x01, 0xFF, 0x80}),
+		[]byte{0x01, 0x00, 0x7F, 0x01, 0x00, 0x01})
+	gobot.Assert(t, Decode7Bit([]byte{0x01, 0x00, 0x7F, 0x01, 0x00, 0x01}),
+		[]byte{0x01, 0xFF, 0x80})
+	// trailing unpaired byte is ignored
+	gobot.Assert(t, Decode7Bit([]byte{0x7F, 0x01, 0x05}), []byte{0xFF})
+}
+
+func TestEncodePacked7Bit(t *testing.T) {
+	data := []byte{0x28, 0xFF, 0x4C, 0x1D, 0x04, 0x00, 0x00, 0xA1}
+	encoded := EncodePacked7Bit(data)
+	gobot.Assert(t, len(encoded), 10)
+	for _, b := range encoded {
+		gobot.Assert(t, b&0x80, byte(0))
+	}
+	gobot.Assert(t, DecodePacked7Bit(encoded), data)
+	gobot.Assert(t, EncodePacked7Bit([]byte{0xFF}), []byte{0x7F, 0x01})
+	gobot.Assert(t, DecodePacked7Bit([]byte{0x7F, 0x01}), []byte{0xFF})
+}
+
+func TestSysexData(t *testing.T) {
+	gobot.Assert(t, sysexData([]byte{startSysex, 0x62, 0x01, 0x02, endSysex}),
+		[]byte{0x01, 0x02})
+	gobot.Assert(t, sysexData([]byte{startSysex, 0x62, 0x01}), []byte{0x01})
+	gobot.Assert(t, sysexData([]byte{startSysex}), []byte{})
+}
@@ -0,0 +1,61 @@
+package firmata
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// testReadWriteCloser stands in for a serial port. Everything written to it
+// is recorded, and reads block until the port is closed.
+type testReadWriteCloser struct {
+	mutex   sync.Mutex
+	written []byte
+	reader  *io.PipeReader
+	writer  *io.PipeWriter
+}
+
+func newTestReadWriteCloser() *testReadWriteCloser {
+	t := &testReadWriteCloser{}
+	t.reader, t.writer = io.Pipe()
+	return t
+}
+
+func (t *testReadWriteCloser) Write(p []byte) (int, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.written = append(t.written, p...)
+	return len(p), nil
+}
+
+func (t *testReadWriteCloser) Read(b []byte) (int, error) {
+	return t.reader.Read(b)
+}
+
+func (t *testReadWriteCloser) Close() error {
+	return t.writer.Close()
+}
+
+// Written returns and forgets everything written so far.
+func (t *testReadWriteCloser) Written() []byte {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	w := t.written
+	t.written = nil
+	return w
+}
+
+// WaitWritten waits up to a second for data to be written, by goroutines
+// such as event handlers.
+func (t *testReadWriteCloser) WaitWritten(data []byte) {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); {
+		t.mutex.Lock()
+		written := bytes.Contains(t.written, data)
+		t.mutex.Unlock()
+		if written {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
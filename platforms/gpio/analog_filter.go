@@ -0,0 +1,85 @@
+package gpio
+
+import (
+	"sort"
+)
+
+// AnalogFilter smooths the readings of an AnalogSensorDriver. Filter is
+// given each reading in turn and returns the smoothed value.
+type AnalogFilter interface {
+	Filter(value float64) float64
+}
+
+// MovingAverageFilter averages the last Size readings.
+type MovingAverageFilter struct {
+	Size   int
+	values []float64
+	sum    float64
+}
+
+// NewMovingAverageFilter creates a filter that averages the last size
+// readings.
+func NewMovingAverageFilter(size int) *MovingAverageFilter {
+	return &MovingAverageFilter{Size: size}
+}
+
+func (f *MovingAverageFilter) Filter(value float64) float64 {
+	f.values = append(f.values, value)
+	f.sum += value
+	for len(f.values) > f.Size && len(f.values) > 1 {
+		f.sum -= f.values[0]
+		f.values = f.values[1:]
+	}
+	return f.sum / float64(len(f.values))
+}
+
+// MedianFilter takes the median of the last Size readings, which ignores
+// the odd reading far from the rest.
+type MedianFilter struct {
+	Size   int
+	values []float64
+}
+
+// NewMedianFilter creates a filter that takes the median of the last size
+// readings.
+func NewMedianFilter(size int) *MedianFilter {
+	return &MedianFilter{Size: size}
+}
+
+func (f *MedianFilter) Filter(value float64) float64 {
+	f.values = append(f.values, value)
+	if len(f.values) > f.Size && len(f.values) > 1 {
+		f.values = f.values[len(f.values)-f.Size:]
+	}
+	sorted := make([]float64, len(f.values))
+	copy(sorted, f.values)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// ExponentialFilter moves a fraction Alpha, from 0 to 1, of the way from
+// its last value towards each reading. Smaller values smooth more.
+type ExponentialFilter struct {
+	Alpha   float64
+	value   float64
+	started bool
+}
+
+// NewExponentialFilter creates an exponential filter with the given alpha.
+func NewExponentialFilter(alpha float64) *ExponentialFilter {
+	return &ExponentialFilter{Alpha: alpha}
+}
+
+func (f *ExponentialFilter) Filter(value float64) float64 {
+	if !f.started {
+		f.value = value
+		f.started = true
+	} else {
+		f.value += f.Alpha * (value - f.value)
+	}
+	return f.value
+}
@@ -0,0 +1,30 @@
+package gpio
+
+import (
+	"testing"
+
+	"github.com/edmontongo/gobot"
+)
+
+func filterAll(f AnalogFilter, values ...float64) []float64 {
+	filtered := []float64{}
+	for _, value := range values {
+		filtered = append(filtered, f.Filter(value))
+	}
+	return filtered
+}
+
+func TestMovingAverageFilter(t *testing.T) {
+	f := NewMovingAverageFilter(3)
+	gobot.Assert(t, filterAll(f, 3, 6, 9, 12, 0), []float64{3, 4.5, 6, 9, 7})
+}
+
+func TestMedianFilter(t *testing.T) {
+	f := NewMedianFilter(3)
+	gobot.Assert(t, filterAll(f, 10, 500, 12, 11, 14), []float64{10, 255, 12, 12, 12})
+}
+
+func TestExponentialFilter(t *testing.T) {
+	f := NewExponentialFilter(0.5)
+	gobot.Assert(t, filterAll(f, 100, 0, 0, 100), []float64{100, 50, 25, 62.5})
+}
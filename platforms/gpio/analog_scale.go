@@ -0,0 +1,97 @@
+package gpio
+
+import (
+	"math"
+	"sort"
+)
+
+// AnalogScale converts a reading of an AnalogSensorDriver to engineering
+// units, such as degrees or lux.
+type AnalogScale func(raw float64) float64
+
+// LinearScale maps readings from fromMin to fromMax linearly onto toMin to
+// toMax. Readings outside the range are extrapolated.
+func LinearScale(fromMin float64, fromMax float64, toMin float64, toMax float64) AnalogScale {
+	return func(raw float64) float64 {
+		if fromMax == fromMin {
+			return toMin
+		}
+		return toMin + (raw-fromMin)*(toMax-toMin)/(fromMax-fromMin)
+	}
+}
+
+// CalibrationPoint is a reading and the value it stands for.
+type CalibrationPoint struct {
+	Raw   float64
+	Value float64
+}
+
+// TableScale interpolates linearly between calibration points, in any
+// order. Readings beyond the first and last points take their values.
+func TableScale(points ...CalibrationPoint) AnalogScale {
+	table := make([]CalibrationPoint, len(points))
+	copy(table, points)
+	sort.Sort(byRaw(table))
+
+	return func(raw float64) float64 {
+		if len(table) == 0 {
+			return raw
+		}
+		i := sort.Search(len(table), func(i int) bool { return table[i].Raw >= raw })
+		switch {
+		case i == 0:
+			return table[0].Value
+		case i == len(table):
+			return table[len(table)-1].Value
+		}
+		lo, hi := table[i-1], table[i]
+		return lo.Value + (raw-lo.Raw)*(hi.Value-lo.Value)/(hi.Raw-lo.Raw)
+	}
+}
+
+type byRaw []CalibrationPoint
+
+func (p byRaw) Len() int           { return len(p) }
+func (p byRaw) Less(i, j int) bool { return p[i].Raw < p[j].Raw }
+func (p byRaw) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// ThermistorScale converts readings to degrees Celsius for an NTC
+// thermistor wired from the pin to ground, with seriesResistor ohms from
+// the pin to the supply. The thermistor has resistance r0 ohms at t0
+// degrees Celsius and the given beta coefficient. maxRaw is the reading
+// at the supply voltage, such as 1023 for a 10 bit converter.
+func ThermistorScale(maxRaw float64, seriesResistor float64, r0 float64, t0 float64, beta float64) AnalogScale {
+	return func(raw float64) float64 {
+		if raw <= 0 || raw >= maxRaw {
+			return math.NaN()
+		}
+		r := seriesResistor * raw / (maxRaw - raw)
+		kelvin := 1 / (1/(t0+273.15) + math.Log(r/r0)/beta)
+		return kelvin - 273.15
+	}
+}
+
+// LightDependentResistorScale converts readings to an approximate light
+// level in lux for an LDR wired from the pin to the supply, with
+// seriesResistor ohms from the pin to ground. r10 is the resistance of the
+// LDR at 10 lux and gamma the slope from its datasheet, about 10000 and
+// 0.7 for a GL5528. maxRaw is the reading at the supply voltage.
+func LightDependentResistorScale(maxRaw float64, seriesResistor float64, r10 float64, gamma float64) AnalogScale {
+	return func(raw float64) float64 {
+		if raw <= 0 {
+			return 0
+		}
+		if raw >= maxRaw {
+			return math.Inf(1)
+		}
+		r := seriesResistor * (maxRaw - raw) / raw
+		return 10 * math.Pow(r10/r, 1/gamma)
+	}
+}
+
+// PotentiometerAngleScale converts readings of a potentiometer to its angle
+// in degrees, where it turns through degrees from a reading of 0 to
+// maxRaw.
+func PotentiometerAngleScale(maxRaw float64, degrees float64) AnalogScale {
+	return LinearScale(0, maxRaw, 0, degrees)
+}
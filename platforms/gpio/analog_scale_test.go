@@ -0,0 +1,53 @@
+package gpio
+
+import (
+	"math"
+	"testing"
+
+	"github.com/edmontongo/gobot"
+)
+
+func TestLinearScale(t *testing.T) {
+	s := LinearScale(0, 1023, -40, 125)
+	gobot.Assert(t, s(0), -40.0)
+	gobot.Assert(t, s(1023), 125.0)
+	gobot.Assert(t, LinearScale(5, 5, 1, 2)(5), 1.0)
+}
+
+func TestTableScale(t *testing.T) {
+	s := TableScale(
+		CalibrationPoint{Raw: 800, Value: 100},
+		CalibrationPoint{Raw: 0, Value: 0},
+		CalibrationPoint{Raw: 400, Value: 20},
+	)
+	gobot.Assert(t, s(-5), 0.0)
+	gobot.Assert(t, s(200), 10.0)
+	gobot.Assert(t, s(400), 20.0)
+	gobot.Assert(t, s(600), 60.0)
+	gobot.Assert(t, s(1000), 100.0)
+	gobot.Assert(t, TableScale()(12), 12.0)
+}
+
+func TestThermistorScale(t *testing.T) {
+	s := ThermistorScale(1023, 10000, 10000, 25, 3950)
+	// equal resistances read half way, at the nominal temperature
+	gobot.Assert(t, math.Abs(s(511.5)-25) < 1e-9, true)
+	// a lower reading means less resistance, so a higher temperature
+	gobot.Assert(t, s(300) > 25, true)
+	gobot.Assert(t, math.IsNaN(s(0)), true)
+	gobot.Assert(t, math.IsNaN(s(1023)), true)
+}
+
+func TestLightDependentResistorScale(t *testing.T) {
+	s := LightDependentResistorScale(1023, 10000, 10000, 0.7)
+	gobot.Assert(t, math.Abs(s(511.5)-10) < 1e-9, true)
+	gobot.Assert(t, s(800) > 10, true)
+	gobot.Assert(t, s(0), 0.0)
+	gobot.Assert(t, math.IsInf(s(1023), 1), true)
+}
+
+func TestPotentiometerAngleScale(t *testing.T) {
+	s := PotentiometerAngleScale(1023, 270)
+	gobot.Assert(t, s(1023), 270.0)
+	gobot.Assert(t, s(0), 0.0)
+}
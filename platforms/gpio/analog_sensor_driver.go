@@ -1,11 +1,32 @@
 package gpio
 
 import (
+	"math"
+	"sync"
+
 	"github.com/edmontongo/gobot"
 )
 
+// AnalogSensorDriver polls an analog input. Each reading is passed through
+// Filter, if set, and converted to engineering units by Scale, if set.
 type AnalogSensorDriver struct {
 	gobot.Driver
+	Filter AnalogFilter
+	Scale  AnalogScale
+	// Deadband is how far the value must move, in the units of Scale,
+	// before new "data" and "value" events are published. At 0 every
+	// change is published.
+	Deadband   float64
+	mutex      sync.Mutex
+	threshold  float64
+	hysteresis float64
+	thresholds bool
+	above      int
+	value      float64
+	published  bool
+	current    float64
+	read       bool
+	halt       chan bool
 }
 
 func NewAnalogSensorDriver(a AnalogReader, name string, pin string) *AnalogSensorDriver {
@@ -19,9 +40,19 @@ func NewAnalogSensorDriver(a AnalogReader, name string, pin string) *AnalogSenso
 	}
 
 	d.AddEvent("data")
+	d.AddEvent("value")
+	d.AddEvent("above")
+	d.AddEvent("below")
 	d.AddCommand("Read", func(params map[string]interface{}) interface{} {
 		return d.Read()
 	})
+	d.AddCommand("Value", func(params map[string]interface{}) interface{} {
+		return d.Value()
+	})
+	d.AddCommand("SetThreshold", func(params map[string]interface{}) interface{} {
+		d.SetThreshold(params["threshold"].(float64), params["hysteresis"].(float64))
+		return nil
+	})
 
 	return d
 }
@@ -30,20 +61,109 @@ func (a *AnalogSensorDriver) adaptor() AnalogReader {
 	return a.Adaptor().(AnalogReader)
 }
 
+// Start polls the sensor every interval.
 func (a *AnalogSensorDriver) Start() bool {
-	value := 0
-	gobot.Every(a.Interval(), func() {
-		newValue := a.Read()
-		if newValue != value && newValue != -1 {
-			value = newValue
-			gobot.Publish(a.Event("data"), value)
-		}
-	})
+	halt := gobot.Poll(a.Interval(), a.poll)
+	a.mutex.Lock()
+	a.halt = halt
+	a.mutex.Unlock()
 	return true
 }
 func (a *AnalogSensorDriver) Init() bool { return true }
-func (a *AnalogSensorDriver) Halt() bool { return true }
 
+// Halt stops polling the sensor.
+func (a *AnalogSensorDriver) Halt() bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.halt != nil {
+		close(a.halt)
+		a.halt = nil
+	}
+	return true
+}
+
+// Read returns a raw reading, without filtering or scaling.
 func (a *AnalogSensorDriver) Read() int {
 	return a.adaptor().AnalogRead(a.Pin())
 }
+
+// Value returns the filtered and scaled value of the last poll, or of a
+// new reading if the sensor has not been polled.
+func (a *AnalogSensorDriver) Value() float64 {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.read {
+		return a.current
+	}
+	return a.scale(float64(a.Read()))
+}
+
+// SetThreshold publishes an "above" event when the value rises above
+// threshold plus hysteresis, and a "below" event when it falls below
+// threshold minus hysteresis.
+func (a *AnalogSensorDriver) SetThreshold(threshold float64, hysteresis float64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.threshold = threshold
+	a.hysteresis = math.Abs(hysteresis)
+	a.thresholds = true
+	a.above = 0
+}
+
+func (a *AnalogSensorDriver) poll() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	raw := a.Read()
+	if raw == -1 {
+		return
+	}
+	a.update(float64(raw))
+}
+
+// update filters and scales a reading, and publishes the events it calls
+// for.
+func (a *AnalogSensorDriver) update(raw float64) {
+	filtered := raw
+	if a.Filter != nil {
+		filtered = a.Filter.Filter(raw)
+	}
+	value := a.scale(filtered)
+	a.current = value
+	a.read = true
+
+	if a.moved(value) {
+		a.value = value
+		a.published = true
+		gobot.Publish(a.Event("data"), int(filtered+math.Copysign(0.5, filtered)))
+		gobot.Publish(a.Event("value"), value)
+	}
+
+	if !a.thresholds {
+		return
+	}
+	switch {
+	case a.above != 1 && value > a.threshold+a.hysteresis:
+		a.above = 1
+		gobot.Publish(a.Event("above"), value)
+	case a.above != -1 && value < a.threshold-a.hysteresis:
+		a.above = -1
+		gobot.Publish(a.Event("below"), value)
+	}
+}
+
+// moved returns true if value is far enough from the last value published
+// to publish it.
+func (a *AnalogSensorDriver) moved(value float64) bool {
+	if !a.published || math.IsNaN(value) != math.IsNaN(a.value) {
+		return true
+	}
+	return math.Abs(value-a.value) > a.Deadband
+}
+
+func (a *AnalogSensorDriver) scale(raw float64) float64 {
+	if a.Scale == nil {
+		return raw
+	}
+	return a.Scale(raw)
+}
@@ -1,8 +1,10 @@
 package gpio
 
 import (
-	"github.com/edmontongo/gobot"
 	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
 )
 
 func initTestAnalogSensorDriver() *AnalogSensorDriver {
@@ -28,3 +30,139 @@ func TestAnalogSensorDriverRead(t *testing.T) {
 	d := initTestAnalogSensorDriver()
 	gobot.Assert(t, d.Read(), 99)
 }
+
+// analogEvents collects the events the sensor publishes.
+func analogEvents(d *AnalogSensorDriver, names ...string) chan []interface{} {
+	events := make(chan []interface{}, 100)
+	for _, name := range names {
+		name := name
+		gobot.On(d.Event(name), func(data interface{}) {
+			events <- []interface{}{name, data}
+		})
+	}
+	return events
+}
+
+func nextAnalogEvent(events chan []interface{}) []interface{} {
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(100 * time.Millisecond):
+		return nil
+	}
+}
+
+func TestAnalogSensorDriverData(t *testing.T) {
+	d := initTestAnalogSensorDriver()
+	events := analogEvents(d, "data")
+	d.update(100)
+	gobot.Assert(t, nextAnalogEvent(events), []interface{}{"data", 100})
+	d.update(100)
+	gobot.Assert(t, nextAnalogEvent(events) == nil, true)
+	d.update(101)
+	gobot.Assert(t, nextAnalogEvent(events), []interface{}{"data", 101})
+}
+
+func TestAnalogSensorDriverScaleAndDeadband(t *testing.T) {
+	d := initTestAnalogSensorDriver()
+	d.Scale = LinearScale(0, 1000, 0, 100)
+	d.Deadband = 1
+	events := analogEvents(d, "value")
+	d.update(500)
+	gobot.Assert(t, nextAnalogEvent(events), []interface{}{"value", 50.0})
+	d.update(505)
+	gobot.Assert(t, nextAnalogEvent(events) == nil, true)
+	d.update(480)
+	gobot.Assert(t, nextAnalogEvent(events), []interface{}{"value", 48.0})
+	gobot.Assert(t, d.Value(), 48.0)
+}
+
+func TestAnalogSensorDriverFilter(t *testing.T) {
+	d := initTestAnalogSensorDriver()
+	d.Filter = NewMovingAverageFilter(2)
+	events := analogEvents(d, "data")
+	d.update(10)
+	gobot.Assert(t, nextAnalogEvent(events), []interface{}{"data", 10})
+	d.update(21)
+	gobot.Assert(t, nextAnalogEvent(events), []interface{}{"data", 16})
+}
+
+func TestAnalogSensorDriverThreshold(t *testing.T) {
+	d := initTestAnalogSensorDriver()
+	d.SetThreshold(50, 5)
+	events := analogEvents(d, "above", "below")
+	d.update(50)
+	gobot.Assert(t, nextAnalogEvent(events) == nil, true)
+	d.update(56)
+	gobot.Assert(t, nextAnalogEvent(events), []interface{}{"above", 56.0})
+	d.update(48)
+	d.update(57)
+	gobot.Assert(t, nextAnalogEvent(events) == nil, true)
+	d.update(44)
+	gobot.Assert(t, nextAnalogEvent(events), []interface{}{"below", 44.0})
+	d.update(52)
+	gobot.Assert(t, nextAnalogEvent(events) == nil, true)
+}
+
+func TestAnalogSensorDriverNaN(t *testing.T) {
+	d := initTestAnalogSensorDriver()
+	d.Scale = ThermistorScale(1023, 10000, 10000, 25, 3950)
+	events := analogEvents(d, "value")
+	d.update(0)
+	gobot.Assert(t, nextAnalogEvent(events) != nil, true)
+	d.update(511.5)
+	gobot.Assert(t, nextAnalogEvent(events) != nil, true)
+}
+
+func TestAnalogSensorDriverPoll(t *testing.T) {
+	d := initTestAnalogSensorDriver()
+	d.poll()
+	gobot.Assert(t, d.Value(), 99.0)
+}
+
+// readCountingAdaptor passes each AnalogRead to reads.
+type readCountingAdaptor struct {
+	gobot.Adaptor
+	reads chan bool
+}
+
+func (r *readCountingAdaptor) AnalogRead(string) int {
+	r.reads <- true
+	return 99
+}
+func (r *readCountingAdaptor) Connect() bool  { return true }
+func (r *readCountingAdaptor) Finalize() bool { return true }
+
+func TestAnalogSensorDriverHaltStopsPolling(t *testing.T) {
+	a := &readCountingAdaptor{reads: make(chan bool)}
+	d := NewAnalogSensorDriver(a, "bot", "1")
+	d.SetInterval(time.Millisecond)
+	gobot.Assert(t, d.Start(), true)
+	<-a.reads
+	<-a.reads
+	halted := make(chan bool)
+	go func() { halted <- d.Halt() }()
+	// Halt waits for a read in progress
+	for done := false; !done; {
+		select {
+		case <-a.reads:
+		case done = <-halted:
+		}
+	}
+	select {
+	case <-a.reads:
+		t.Errorf("the sensor was read after Halt")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestAnalogSensorDriverCommands(t *testing.T) {
+	d := initTestAnalogSensorDriver()
+	d.Scale = LinearScale(0, 99, 0, 1)
+	gobot.Assert(t, d.Command("Read")(map[string]interface{}{}), 99)
+	gobot.Assert(t, d.Command("Value")(map[string]interface{}{}), 1.0)
+	d.Command("SetThreshold")(map[string]interface{}{"threshold": 0.5, "hysteresis": 0.1})
+	events := analogEvents(d, "above")
+	d.poll()
+	gobot.Assert(t, nextAnalogEvent(events), []interface{}{"above", 1.0})
+}
@@ -1,27 +1,74 @@
 package gpio
 
 import (
+	"sync"
+	"time"
+
 	"github.com/edmontongo/gobot"
 )
 
+// ButtonDriver reports the state of a push button. Besides "push" and
+// "release" it recognises clicks, double clicks and long presses.
 type ButtonDriver struct {
 	gobot.Driver
 	Active bool
+	// ActiveLow is set for buttons that read 0 when pushed.
+	ActiveLow bool
+	// DebounceTime is how long a new reading must stay the same before the
+	// button is considered pushed or released.
+	DebounceTime time.Duration
+	// LongPressTime is how long the button must be held for a "long_press"
+	// event. Releasing it sooner publishes a "click" event.
+	LongPressTime time.Duration
+	// HoldInterval is how often "hold" events are repeated after a long
+	// press while the button is still held. Zero disables them.
+	HoldInterval time.Duration
+	// DoubleClickTime is the longest gap between two clicks that makes
+	// them a double click.
+	DoubleClickTime time.Duration
+	now             func() time.Time
+	mutex           sync.Mutex
+	reading         int
+	readingSince    time.Time
+	pushedAt        time.Time
+	longPressed     bool
+	lastHold        time.Time
+	lastClick       time.Time
+	watching        bool
+	timer           *time.Timer
+	halt            chan bool
 }
 
+// NewButtonDriver creates a driver for a button on pin that reads 1 when
+// pushed.
 func NewButtonDriver(a DigitalReader, name string, pin string) *ButtonDriver {
+	return newButtonDriver(a, name, "ButtonDriver", pin, false)
+}
+
+func newButtonDriver(a DigitalReader, name string, driverType string, pin string, activeLow bool) *ButtonDriver {
 	b := &ButtonDriver{
 		Driver: *gobot.NewDriver(
 			name,
-			"ButtonDriver",
+			driverType,
 			a.(gobot.AdaptorInterface),
 			pin,
 		),
-		Active: false,
+		Active:          false,
+		ActiveLow:       activeLow,
+		DebounceTime:    10 * time.Millisecond,
+		LongPressTime:   1 * time.Second,
+		HoldInterval:    250 * time.Millisecond,
+		DoubleClickTime: 300 * time.Millisecond,
+		now:             time.Now,
+		reading:         -1,
 	}
 
 	b.AddEvent("push")
 	b.AddEvent("release")
+	b.AddEvent("click")
+	b.AddEvent("double_click")
+	b.AddEvent("long_press")
+	b.AddEvent("hold")
 
 	return b
 }
@@ -30,30 +77,165 @@ func (b *ButtonDriver) adaptor() DigitalReader {
 	return b.Adaptor().(DigitalReader)
 }
 
+// Start watches the button for changes with the adaptor's DigitalWatcher
+// when it has one, and otherwise polls it every interval.
 func (b *ButtonDriver) Start() bool {
-	state := 0
-	gobot.Every(b.Interval(), func() {
-		newValue := b.readState()
-		if newValue != state && newValue != -1 {
-			state = newValue
-			b.update(newValue)
+	if w, ok := b.Adaptor().(DigitalWatcher); ok {
+		b.mutex.Lock()
+		b.watching = true
+		b.mutex.Unlock()
+		if w.WatchDigital(b.Pin(), EdgeBoth, b.change) == nil {
+			return true
 		}
-	})
+		b.mutex.Lock()
+		b.watching = false
+		b.mutex.Unlock()
+	}
+	halt := gobot.Poll(b.Interval(), b.poll)
+	b.mutex.Lock()
+	b.halt = halt
+	b.mutex.Unlock()
+	return true
+}
+
+// Halt stops watching or polling the button.
+func (b *ButtonDriver) Halt() bool {
+	b.mutex.Lock()
+	watching := b.watching
+	b.watching = false
+	if b.halt != nil {
+		close(b.halt)
+		b.halt = nil
+	}
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.mutex.Unlock()
+	if watching {
+		b.Adaptor().(DigitalWatcher).UnwatchDigital(b.Pin())
+	}
 	return true
 }
-func (b *ButtonDriver) Halt() bool { return true }
 func (b *ButtonDriver) Init() bool { return true }
 
 func (b *ButtonDriver) readState() int {
 	return b.adaptor().DigitalRead(b.Pin())
 }
 
+func (b *ButtonDriver) poll() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	value := b.readState()
+	if value == -1 {
+		return
+	}
+	b.debounce(value)
+	b.checkHold()
+}
+
+// change handles a change reported by the adaptor's DigitalWatcher.
+func (b *ButtonDriver) change(value int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if !b.watching || value == -1 {
+		return
+	}
+	b.debounce(value)
+	b.checkHold()
+	b.schedule()
+}
+
+// wake is called by the timer set by schedule.
+func (b *ButtonDriver) wake() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if !b.watching {
+		return
+	}
+	b.debounce(b.reading)
+	b.checkHold()
+	b.schedule()
+}
+
+// schedule sets a timer for when the button next needs checking while it
+// is watched rather than polled: when a new reading has lasted for
+// DebounceTime, or when a long press or hold is due.
+func (b *ButtonDriver) schedule() {
+	var at time.Time
+	switch {
+	case b.isPushed(b.reading) != b.Active:
+		at = b.readingSince.Add(b.DebounceTime)
+	case b.Active && !b.longPressed:
+		at = b.pushedAt.Add(b.LongPressTime)
+	case b.Active && b.HoldInterval > 0:
+		at = b.lastHold.Add(b.HoldInterval)
+	default:
+		return
+	}
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.timer = time.AfterFunc(at.Sub(b.now()), b.wake)
+}
+
+// debounce passes value on to update once it has been read consistently
+// for DebounceTime.
+func (b *ButtonDriver) debounce(value int) {
+	now := b.now()
+	if value != b.reading {
+		b.reading = value
+		b.readingSince = now
+	}
+	if b.isPushed(value) != b.Active && now.Sub(b.readingSince) >= b.DebounceTime {
+		b.update(value)
+	}
+}
+
+func (b *ButtonDriver) isPushed(value int) bool {
+	return (value == 1) != b.ActiveLow
+}
+
 func (b *ButtonDriver) update(newVal int) {
-	if newVal == 1 {
+	now := b.now()
+	if b.isPushed(newVal) {
 		b.Active = true
+		b.pushedAt = now
+		b.longPressed = false
 		gobot.Publish(b.Event("push"), newVal)
+		return
+	}
+
+	b.Active = false
+	gobot.Publish(b.Event("release"), newVal)
+	if b.longPressed || b.pushedAt.IsZero() {
+		b.lastClick = time.Time{}
+		return
+	}
+	gobot.Publish(b.Event("click"), now.Sub(b.pushedAt))
+	if !b.lastClick.IsZero() && b.pushedAt.Sub(b.lastClick) <= b.DoubleClickTime {
+		gobot.Publish(b.Event("double_click"), now.Sub(b.pushedAt))
+		b.lastClick = time.Time{}
 	} else {
-		b.Active = false
-		gobot.Publish(b.Event("release"), newVal)
+		b.lastClick = now
+	}
+}
+
+// checkHold publishes "long_press" once the button has been held for
+// LongPressTime, followed by "hold" every HoldInterval.
+func (b *ButtonDriver) checkHold() {
+	if !b.Active {
+		return
+	}
+	now := b.now()
+	held := now.Sub(b.pushedAt)
+	switch {
+	case !b.longPressed && held >= b.LongPressTime:
+		b.longPressed = true
+		b.lastHold = now
+		gobot.Publish(b.Event("long_press"), held)
+	case b.longPressed && b.HoldInterval > 0 && now.Sub(b.lastHold) >= b.HoldInterval:
+		b.lastHold = now
+		gobot.Publish(b.Event("hold"), held)
 	}
 }
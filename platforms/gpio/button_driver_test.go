@@ -1,8 +1,10 @@
 package gpio
 
 import (
-	"github.com/edmontongo/gobot"
 	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
 )
 
 func initTestButtonDriver() *ButtonDriver {
@@ -37,3 +39,178 @@ func TestButtonDriverActive(t *testing.T) {
 	d.update(0)
 	gobot.Assert(t, d.Active, false)
 }
+
+// testClock returns a clock for the driver that only moves when advanced.
+func testClock(d *ButtonDriver) func(time.Duration) {
+	now := time.Unix(0, 0)
+	d.now = func() time.Time { return now }
+	return func(dt time.Duration) { now = now.Add(dt) }
+}
+
+// testEvents records the events published by the driver.
+func testEvents(d *ButtonDriver, names ...string) chan string {
+	events := make(chan string, 10)
+	for _, name := range names {
+		name := name
+		gobot.On(d.Event(name), func(data interface{}) { events <- name })
+	}
+	return events
+}
+
+func assertEvent(t *testing.T, events chan string, name string) {
+	select {
+	case e := <-events:
+		gobot.Assert(t, e, name)
+	case <-time.After(100 * time.Millisecond):
+		t.Errorf("%v was not published", name)
+	}
+}
+
+func TestButtonDriverActiveLow(t *testing.T) {
+	d := initTestButtonDriver()
+	d.ActiveLow = true
+	d.update(0)
+	gobot.Assert(t, d.Active, true)
+
+	d.update(1)
+	gobot.Assert(t, d.Active, false)
+}
+
+func TestButtonDriverDebounce(t *testing.T) {
+	d := initTestButtonDriver()
+	advance := testClock(d)
+	d.DebounceTime = 20 * time.Millisecond
+
+	d.debounce(1)
+	gobot.Assert(t, d.Active, false)
+	advance(10 * time.Millisecond)
+	d.debounce(0)
+	advance(10 * time.Millisecond)
+	d.debounce(1)
+	advance(10 * time.Millisecond)
+	d.debounce(1)
+	gobot.Assert(t, d.Active, false)
+	advance(10 * time.Millisecond)
+	d.debounce(1)
+	gobot.Assert(t, d.Active, true)
+}
+
+func TestButtonDriverClick(t *testing.T) {
+	d := initTestButtonDriver()
+	advance := testClock(d)
+	var duration interface{}
+	sem := make(chan bool, 1)
+	gobot.On(d.Event("click"), func(data interface{}) {
+		duration = data
+		sem <- true
+	})
+
+	d.update(1)
+	advance(100 * time.Millisecond)
+	d.checkHold()
+	d.update(0)
+	<-sem
+	gobot.Assert(t, duration, 100*time.Millisecond)
+}
+
+func TestButtonDriverDoubleClick(t *testing.T) {
+	d := initTestButtonDriver()
+	advance := testClock(d)
+	events := testEvents(d, "click", "double_click")
+
+	d.update(1)
+	advance(50 * time.Millisecond)
+	d.update(0)
+	assertEvent(t, events, "click")
+	advance(100 * time.Millisecond)
+	d.update(1)
+	advance(50 * time.Millisecond)
+	d.update(0)
+	// events are delivered by separate goroutines, in any order
+	received := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case e := <-events:
+			received[e] = true
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	gobot.Assert(t, received, map[string]bool{"click": true, "double_click": true})
+
+	advance(time.Second)
+	d.update(1)
+	advance(50 * time.Millisecond)
+	d.update(0)
+	assertEvent(t, events, "click")
+	gobot.Assert(t, len(events), 0)
+}
+
+func TestButtonDriverLongPressAndHold(t *testing.T) {
+	d := initTestButtonDriver()
+	advance := testClock(d)
+	events := testEvents(d, "click", "long_press", "hold")
+
+	d.update(1)
+	advance(900 * time.Millisecond)
+	d.checkHold()
+	gobot.Assert(t, len(events), 0)
+	advance(100 * time.Millisecond)
+	d.checkHold()
+	assertEvent(t, events, "long_press")
+	advance(200 * time.Millisecond)
+	d.checkHold()
+	gobot.Assert(t, len(events), 0)
+	advance(50 * time.Millisecond)
+	d.checkHold()
+	assertEvent(t, events, "hold")
+	d.update(0)
+	<-time.After(10 * time.Millisecond)
+	gobot.Assert(t, len(events), 0)
+}
+
+func TestButtonDriverPoll(t *testing.T) {
+	d := initTestButtonDriver()
+	advance := testClock(d)
+	d.poll()
+	advance(d.DebounceTime)
+	d.poll()
+	gobot.Assert(t, d.Active, true)
+}
+
+func TestButtonDriverWatch(t *testing.T) {
+	a := newWatchTestAdaptor()
+	a.setInput("1", 0)
+	d := NewButtonDriver(a, "bot", "1")
+	d.DebounceTime = 5 * time.Millisecond
+	d.LongPressTime = 30 * time.Millisecond
+	d.HoldInterval = 0
+	events := testEvents(d, "push", "release", "click", "long_press")
+
+	gobot.Assert(t, d.Start(), true)
+	gobot.Assert(t, a.watching("1"), true)
+	a.change("1", 1)
+	a.change("1", 0)
+	a.change("1", 1)
+	assertEvent(t, events, "push")
+	assertEvent(t, events, "long_press")
+	a.change("1", 0)
+	assertEvent(t, events, "release")
+
+	a.change("1", 1)
+	<-time.After(10 * time.Millisecond)
+	a.change("1", 0)
+	assertEvent(t, events, "push")
+	// release and click are published together, in either order
+	released := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case e := <-events:
+			released[e] = true
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	gobot.Assert(t, released, map[string]bool{"release": true, "click": true})
+
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, a.watching("1"), false)
+}
@@ -0,0 +1,273 @@
+package gpio
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+// Note is a tone of Frequency hertz lasting Duration. A Frequency of 0 is a
+// rest.
+type Note struct {
+	Frequency float64
+	Duration  time.Duration
+}
+
+var noteSemitones = map[byte]int{
+	'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11,
+}
+
+// NoteFrequency returns the frequency in hertz of a note written as a
+// letter, an optional "#" or "b", and an octave, such as "A4" for 440Hz or
+// "C#5". "R" is a rest, with a frequency of 0.
+func NoteFrequency(name string) (float64, error) {
+	if strings.ToUpper(name) == "R" {
+		return 0, nil
+	}
+	if len(name) < 2 {
+		return 0, fmt.Errorf("invalid note %q", name)
+	}
+	semitone, ok := noteSemitones[strings.ToUpper(name[:1])[0]]
+	if !ok {
+		return 0, fmt.Errorf("invalid note %q", name)
+	}
+	rest := name[1:]
+	switch rest[0] {
+	case '#':
+		semitone++
+		rest = rest[1:]
+	case 'b':
+		semitone--
+		rest = rest[1:]
+	}
+	octave, err := strconv.Atoi(rest)
+	if err != nil {
+		return 0, fmt.Errorf("invalid note %q", name)
+	}
+	midi := (octave+1)*12 + semitone
+	return 440 * math.Pow(2, float64(midi-69)/12), nil
+}
+
+// ParseMelody parses notes separated by spaces, each a note name as taken
+// by NoteFrequency, a "/" and its length as a fraction of a whole note,
+// such as "C4/4" for a quarter note. A trailing "." makes a dotted note,
+// half as long again. tempo is in quarter notes per minute.
+func ParseMelody(melody string, tempo float64) ([]Note, error) {
+	if tempo <= 0 {
+		return nil, fmt.Errorf("invalid tempo %v", tempo)
+	}
+	whole := 4 * time.Duration(float64(time.Minute)/tempo)
+	notes := []Note{}
+	for _, field := range strings.Fields(melody) {
+		parts := strings.SplitN(field, "/", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid note %q", field)
+		}
+		frequency, err := NoteFrequency(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		length := parts[1]
+		dotted := strings.HasSuffix(length, ".")
+		division, err := strconv.Atoi(strings.TrimSuffix(length, "."))
+		if err != nil || division <= 0 {
+			return nil, fmt.Errorf("invalid note %q", field)
+		}
+		duration := whole / time.Duration(division)
+		if dotted {
+			duration += duration / 2
+		}
+		notes = append(notes, Note{Frequency: frequency, Duration: duration})
+	}
+	return notes, nil
+}
+
+// BuzzerDriver plays tones and melodies on a piezo buzzer. It uses the
+// adaptor's ToneWriter or PwmController when it has one, and otherwise
+// toggles the pin itself, which only suits adaptors with fast digital
+// writes.
+type BuzzerDriver struct {
+	gobot.Driver
+	// Gap is a short silence at the end of each note of a melody, so that
+	// repeated notes are heard separately.
+	Gap       time.Duration
+	mutex     sync.Mutex
+	melody    int
+	wave      int
+	frequency float64
+}
+
+// NewBuzzerDriver creates a driver for a buzzer on pin.
+func NewBuzzerDriver(a DigitalWriter, name string, pin string) *BuzzerDriver {
+	b := &BuzzerDriver{
+		Driver: *gobot.NewDriver(
+			name,
+			"BuzzerDriver",
+			a.(gobot.AdaptorInterface),
+			pin,
+		),
+		Gap: 10 * time.Millisecond,
+	}
+
+	b.AddEvent("melody_complete")
+
+	b.AddCommand("Tone", func(params map[string]interface{}) interface{} {
+		b.Tone(params["frequency"].(float64),
+			time.Duration(params["duration"].(float64))*time.Millisecond)
+		return nil
+	})
+	b.AddCommand("Play", func(params map[string]interface{}) interface{} {
+		notes, err := ParseMelody(params["melody"].(string), params["tempo"].(float64))
+		if err != nil {
+			return err.Error()
+		}
+		b.Play(notes)
+		return nil
+	})
+	b.AddCommand("Stop", func(params map[string]interface{}) interface{} {
+		b.Stop()
+		return nil
+	})
+	b.AddCommand("Frequency", func(params map[string]interface{}) interface{} {
+		return b.Frequency()
+	})
+
+	return b
+}
+
+func (b *BuzzerDriver) adaptor() DigitalWriter {
+	return b.Adaptor().(DigitalWriter)
+}
+
+func (b *BuzzerDriver) Start() bool { return true }
+func (b *BuzzerDriver) Init() bool  { return true }
+
+// Halt silences the buzzer.
+func (b *BuzzerDriver) Halt() bool {
+	b.Stop()
+	return true
+}
+
+// Tone plays frequency for duration, or until Stop if duration is 0. It
+// returns at once and cancels any melody.
+func (b *BuzzerDriver) Tone(frequency float64, duration time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.melody++
+	melody := b.melody
+	b.setTone(frequency)
+	if duration > 0 {
+		time.AfterFunc(duration, func() { b.sound(melody, 0) })
+	}
+}
+
+// Play plays notes in turn and publishes a "melody_complete" event at the
+// end. It returns at once; another melody, Tone or Stop cancels it.
+func (b *BuzzerDriver) Play(notes []Note) {
+	b.mutex.Lock()
+	b.melody++
+	melody := b.melody
+	gap := b.Gap
+	b.mutex.Unlock()
+
+	go func() {
+		for _, note := range notes {
+			rest := gap
+			if rest > note.Duration {
+				rest = 0
+			}
+			if !b.sound(melody, note.Frequency) {
+				return
+			}
+			<-time.After(note.Duration - rest)
+			if !b.sound(melody, 0) {
+				return
+			}
+			<-time.After(rest)
+		}
+		gobot.Publish(b.Event("melody_complete"), b.Name())
+	}()
+}
+
+// Stop silences the buzzer and cancels any melody.
+func (b *BuzzerDriver) Stop() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.melody++
+	b.setTone(0)
+}
+
+// Frequency returns the frequency being played, or 0 when silent.
+func (b *BuzzerDriver) Frequency() float64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.frequency
+}
+
+// sound plays frequency if melody has not been cancelled, and returns
+// whether it was.
+func (b *BuzzerDriver) sound(melody int, frequency float64) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.melody != melody {
+		return false
+	}
+	b.setTone(frequency)
+	return true
+}
+
+func (b *BuzzerDriver) setTone(frequency float64) {
+	b.frequency = frequency
+	// stops any wave toggled by the driver
+	b.wave++
+	if t, ok := b.Adaptor().(ToneWriter); ok {
+		t.ToneWrite(b.Pin(), frequency)
+		return
+	}
+	if b.pwmTone(frequency) {
+		return
+	}
+
+	if frequency <= 0 {
+		b.adaptor().DigitalWrite(b.Pin(), 0)
+		return
+	}
+	wave := b.wave
+	half := time.Duration(float64(time.Second) / frequency / 2)
+	go func() {
+		level := byte(1)
+		for {
+			b.mutex.Lock()
+			if b.wave != wave {
+				b.mutex.Unlock()
+				return
+			}
+			b.adaptor().DigitalWrite(b.Pin(), level)
+			b.mutex.Unlock()
+			level ^= 1
+			<-time.After(half)
+		}
+	}()
+}
+
+// pwmTone plays frequency as a square wave with the adaptor's
+// PwmController, and returns false if it has none or cannot set the
+// period.
+func (b *BuzzerDriver) pwmTone(frequency float64) bool {
+	p, ok := b.Adaptor().(PwmController)
+	if !ok {
+		return false
+	}
+	if frequency <= 0 {
+		return p.PwmSetDutyCycle(b.Pin(), 0) == nil
+	}
+	if p.PwmSetPeriod(b.Pin(), PwmFrequency(frequency)) != nil {
+		return false
+	}
+	return p.PwmSetDutyCycle(b.Pin(), 0.5) == nil
+}
@@ -0,0 +1,174 @@
+package gpio
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestBuzzerDriver() *BuzzerDriver {
+	return NewBuzzerDriver(newGpioTestAdaptor("adaptor"), "bot", "1")
+}
+
+// digitalOnlyAdaptor hides the ToneWriter of the test adaptor.
+type digitalOnlyAdaptor struct {
+	gobot.Adaptor
+	adaptor *gpioTestAdaptor
+}
+
+func (d *digitalOnlyAdaptor) DigitalWrite(pin string, level byte) {
+	d.adaptor.DigitalWrite(pin, level)
+}
+func (d *digitalOnlyAdaptor) Connect() bool  { return true }
+func (d *digitalOnlyAdaptor) Finalize() bool { return true }
+
+// pwmOnlyAdaptor hides the ToneWriter of the PWM test adaptor.
+type pwmOnlyAdaptor struct {
+	digitalOnlyAdaptor
+	pwm *pwmTestAdaptor
+}
+
+func (p *pwmOnlyAdaptor) PwmSetPeriod(pin string, period time.Duration) error {
+	return p.pwm.PwmSetPeriod(pin, period)
+}
+func (p *pwmOnlyAdaptor) PwmSetDutyCycle(pin string, duty float64) error {
+	return p.pwm.PwmSetDutyCycle(pin, duty)
+}
+func (p *pwmOnlyAdaptor) PwmSetEnabled(pin string, enabled bool) error {
+	return p.pwm.PwmSetEnabled(pin, enabled)
+}
+func (p *pwmOnlyAdaptor) PwmSetPolarity(pin string, inverted bool) error {
+	return p.pwm.PwmSetPolarity(pin, inverted)
+}
+
+func TestBuzzerDriverStart(t *testing.T) {
+	d := initTestBuzzerDriver()
+	gobot.Assert(t, d.Start(), true)
+}
+
+func TestBuzzerDriverHalt(t *testing.T) {
+	d := initTestBuzzerDriver()
+	gobot.Assert(t, d.Halt(), true)
+}
+
+func TestBuzzerDriverInit(t *testing.T) {
+	d := initTestBuzzerDriver()
+	gobot.Assert(t, d.Init(), true)
+}
+
+func TestNoteFrequency(t *testing.T) {
+	f, err := NoteFrequency("A4")
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, f, 440.0)
+	f, _ = NoteFrequency("A5")
+	gobot.Assert(t, f, 880.0)
+	sharp, _ := NoteFrequency("C#5")
+	flat, _ := NoteFrequency("Db5")
+	gobot.Assert(t, sharp, flat)
+	gobot.Assert(t, sharp > 554.3 && sharp < 554.4, true)
+	f, _ = NoteFrequency("r")
+	gobot.Assert(t, f, 0.0)
+	_, err = NoteFrequency("H2")
+	gobot.Assert(t, err, errors.New("invalid note \"H2\""))
+	_, err = NoteFrequency("A")
+	gobot.Assert(t, err, errors.New("invalid note \"A\""))
+}
+
+func TestParseMelody(t *testing.T) {
+	notes, err := ParseMelody("A4/4 R/8 A5/2.", 120)
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, notes, []Note{
+		{440, 500 * time.Millisecond},
+		{0, 250 * time.Millisecond},
+		{880, 1500 * time.Millisecond},
+	})
+	_, err = ParseMelody("A4", 120)
+	gobot.Assert(t, err, errors.New("invalid note \"A4\""))
+	_, err = ParseMelody("A4/0", 120)
+	gobot.Assert(t, err, errors.New("invalid note \"A4/0\""))
+	_, err = ParseMelody("A4/4", 0)
+	gobot.Assert(t, err, errors.New("invalid tempo 0"))
+}
+
+func TestBuzzerDriverTone(t *testing.T) {
+	d := initTestBuzzerDriver()
+	a := d.adaptor().(*gpioTestAdaptor)
+	d.Tone(440, 10*time.Millisecond)
+	gobot.Assert(t, d.Frequency(), 440.0)
+	<-time.After(30 * time.Millisecond)
+	gobot.Assert(t, d.Frequency(), 0.0)
+	gobot.Assert(t, a.playedTones(), []float64{440, 0})
+
+	d.Tone(220, 0)
+	d.Stop()
+	gobot.Assert(t, a.playedTones(), []float64{440, 0, 220, 0})
+}
+
+func TestBuzzerDriverPlay(t *testing.T) {
+	d := initTestBuzzerDriver()
+	d.Gap = time.Millisecond
+	sem := make(chan bool, 1)
+	gobot.Once(d.Event("melody_complete"), func(data interface{}) {
+		sem <- true
+	})
+	d.Play([]Note{{440, 5 * time.Millisecond}, {0, 5 * time.Millisecond}, {880, 5 * time.Millisecond}})
+	select {
+	case <-sem:
+	case <-time.After(time.Second):
+		t.Errorf("melody_complete was not published")
+	}
+	gobot.Assert(t, d.adaptor().(*gpioTestAdaptor).playedTones(),
+		[]float64{440, 0, 0, 0, 880, 0})
+}
+
+func TestBuzzerDriverStopMelody(t *testing.T) {
+	d := initTestBuzzerDriver()
+	d.Play([]Note{{440, time.Hour}})
+	<-time.After(5 * time.Millisecond)
+	d.Stop()
+	gobot.Assert(t, d.Frequency(), 0.0)
+	gobot.Assert(t, d.adaptor().(*gpioTestAdaptor).playedTones(), []float64{440, 0})
+}
+
+func TestBuzzerDriverSoftwareTone(t *testing.T) {
+	a := newGpioTestAdaptor("adaptor")
+	d := NewBuzzerDriver(&digitalOnlyAdaptor{a.Adaptor, a}, "bot", "1")
+	d.Tone(1000, 0)
+	toggled := map[int]bool{}
+	timeout := time.After(time.Second)
+	for len(toggled) < 2 {
+		select {
+		case <-timeout:
+			t.Fatalf("the pin was not toggled")
+		case <-time.After(100 * time.Microsecond):
+			toggled[a.pinValue("1")] = true
+		}
+	}
+	d.Stop()
+	<-time.After(5 * time.Millisecond)
+	gobot.Assert(t, a.pinValue("1"), 0)
+}
+
+func TestBuzzerDriverPwmTone(t *testing.T) {
+	a := newPwmTestAdaptor()
+	d := NewBuzzerDriver(&pwmOnlyAdaptor{digitalOnlyAdaptor{a.Adaptor, a.gpioTestAdaptor}, a}, "bot", "1")
+	d.Tone(1000, 0)
+	gobot.Assert(t, a.period("1"), time.Millisecond)
+	gobot.Assert(t, a.duty("1"), 0.5)
+	d.Stop()
+	gobot.Assert(t, a.duty("1"), 0.0)
+	gobot.Assert(t, a.pinValue("1"), -1)
+}
+
+func TestBuzzerDriverCommands(t *testing.T) {
+	d := initTestBuzzerDriver()
+	d.Command("Tone")(map[string]interface{}{"frequency": 300.0, "duration": 0.0})
+	gobot.Assert(t, d.Command("Frequency")(map[string]interface{}{}), 300.0)
+	d.Command("Stop")(map[string]interface{}{})
+	gobot.Assert(t, d.Command("Play")(map[string]interface{}{"melody": "C4/4", "tempo": 6000.0}), nil)
+	gobot.Assert(t, d.Command("Play")(map[string]interface{}{"melody": "X4/4", "tempo": 120.0}),
+		"invalid note \"X4\"")
+	d.Command("Stop")(map[string]interface{}{})
+}
@@ -0,0 +1,135 @@
+package gpio
+
+import (
+	"math"
+	"sync"
+
+	"github.com/edmontongo/gobot"
+)
+
+// DifferentialDriveDriver steers a two wheeled robot, such as a rover with
+// tank steering, by driving a motor on each side. Speeds range from -1 for
+// full speed backward to 1 for full speed forward.
+type DifferentialDriveDriver struct {
+	gobot.Driver
+	Left  *MotorDriver
+	Right *MotorDriver
+	// LeftTrim and RightTrim scale the speed of each side, to make up for
+	// motors that do not turn at the same speed. They default to 1.
+	LeftTrim  float64
+	RightTrim float64
+	// LeftInverted and RightInverted reverse a side, for motors that are
+	// mounted facing the other way.
+	LeftInverted  bool
+	RightInverted bool
+	// TrackWidth is the distance between the wheels, in the unit used for
+	// the radius passed to Arc.
+	TrackWidth float64
+	// MaxAcceleration limits how fast the speed of each side changes, in
+	// full speeds per second. Zero changes the speed at once.
+	MaxAcceleration float64
+	mutex           sync.Mutex
+}
+
+// NewDifferentialDriveDriver creates a driver for a robot with the left
+// and right motors.
+func NewDifferentialDriveDriver(name string, left *MotorDriver, right *MotorDriver) *DifferentialDriveDriver {
+	d := &DifferentialDriveDriver{
+		Driver: *gobot.NewDriver(
+			name,
+			"DifferentialDriveDriver",
+			left.Adaptor(),
+		),
+		Left:       left,
+		Right:      right,
+		LeftTrim:   1,
+		RightTrim:  1,
+		TrackWidth: 1,
+	}
+
+	d.AddEvent("drive")
+
+	d.AddCommand("Drive", func(params map[string]interface{}) interface{} {
+		d.Drive(params["linear"].(float64), params["angular"].(float64))
+		return nil
+	})
+	d.AddCommand("Arc", func(params map[string]interface{}) interface{} {
+		d.Arc(params["speed"].(float64), params["radius"].(float64))
+		return nil
+	})
+	d.AddCommand("Spin", func(params map[string]interface{}) interface{} {
+		d.Spin(params["speed"].(float64))
+		return nil
+	})
+	d.AddCommand("Stop", func(params map[string]interface{}) interface{} {
+		d.Stop()
+		return nil
+	})
+
+	return d
+}
+
+func (d *DifferentialDriveDriver) Start() bool { return true }
+func (d *DifferentialDriveDriver) Init() bool  { return true }
+
+// Halt stops both motors.
+func (d *DifferentialDriveDriver) Halt() bool {
+	d.Stop()
+	return true
+}
+
+// Drive moves the robot at the linear speed while turning at the angular
+// speed, both from -1 to 1. Positive angular speeds turn left. When the
+// combination is more than a side can do, both sides are scaled down to
+// keep the turn.
+func (d *DifferentialDriveDriver) Drive(linear float64, angular float64) {
+	d.wheels(linear-angular, linear+angular)
+}
+
+// Arc drives at speed along a circle of radius, in the unit of TrackWidth.
+// Positive radii turn left and negative ones right. A radius of 0 spins
+// in place.
+func (d *DifferentialDriveDriver) Arc(speed float64, radius float64) {
+	if radius == 0 {
+		d.Spin(speed)
+		return
+	}
+	half := d.TrackWidth / 2
+	d.wheels(speed*(radius-half)/radius, speed*(radius+half)/radius)
+}
+
+// Spin turns the robot in place at speed. Positive speeds turn left.
+func (d *DifferentialDriveDriver) Spin(speed float64) {
+	d.wheels(-speed, speed)
+}
+
+// Stop stops both motors at once, ignoring MaxAcceleration.
+func (d *DifferentialDriveDriver) Stop() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.Left.Stop()
+	d.Right.Stop()
+	gobot.Publish(d.Event("drive"), map[string]float64{"left": 0, "right": 0})
+}
+
+// wheels sets the speed of each side, scaling both down when either is
+// out of range, and applies trim and acceleration limits.
+func (d *DifferentialDriveDriver) wheels(left float64, right float64) {
+	if max := math.Max(math.Abs(left), math.Abs(right)); max > 1 {
+		left /= max
+		right /= max
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.setMotor(d.Left, left*d.LeftTrim, d.LeftInverted)
+	d.setMotor(d.Right, right*d.RightTrim, d.RightInverted)
+	gobot.Publish(d.Event("drive"), map[string]float64{"left": left, "right": right})
+}
+
+func (d *DifferentialDriveDriver) setMotor(m *MotorDriver, speed float64, inverted bool) {
+	if inverted {
+		speed = -speed
+	}
+	m.setSpeed(int(speed*255+math.Copysign(0.5, speed)), d.MaxAcceleration*255)
+}
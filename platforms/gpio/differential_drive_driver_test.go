@@ -0,0 +1,108 @@
+package gpio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestDifferentialDriveDriver() *DifferentialDriveDriver {
+	a := newGpioTestAdaptor("adaptor")
+	return NewDifferentialDriveDriver("drive",
+		NewHBridgeMotorDriver(a, "left", "3", "4", "5"),
+		NewHBridgeMotorDriver(a, "right", "6", "7", "8"),
+	)
+}
+
+func TestDifferentialDriveDriverStart(t *testing.T) {
+	d := initTestDifferentialDriveDriver()
+	gobot.Assert(t, d.Start(), true)
+}
+
+func TestDifferentialDriveDriverHalt(t *testing.T) {
+	d := initTestDifferentialDriveDriver()
+	d.Drive(1, 0)
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, d.Left.SignedSpeed(), 0)
+	gobot.Assert(t, d.Right.SignedSpeed(), 0)
+}
+
+func TestDifferentialDriveDriverDrive(t *testing.T) {
+	d := initTestDifferentialDriveDriver()
+	d.Drive(1, 0)
+	gobot.Assert(t, d.Left.SignedSpeed(), 255)
+	gobot.Assert(t, d.Right.SignedSpeed(), 255)
+	d.Drive(0.5, 0.25)
+	gobot.Assert(t, d.Left.SignedSpeed(), 64)
+	gobot.Assert(t, d.Right.SignedSpeed(), 191)
+	// scaled down to keep the turn
+	d.Drive(1, 1)
+	gobot.Assert(t, d.Left.SignedSpeed(), 0)
+	gobot.Assert(t, d.Right.SignedSpeed(), 255)
+	d.Drive(-1, 0)
+	gobot.Assert(t, d.Left.SignedSpeed(), -255)
+	gobot.Assert(t, d.Right.SignedSpeed(), -255)
+}
+
+func TestDifferentialDriveDriverSpin(t *testing.T) {
+	d := initTestDifferentialDriveDriver()
+	d.Spin(0.5)
+	gobot.Assert(t, d.Left.SignedSpeed(), -128)
+	gobot.Assert(t, d.Right.SignedSpeed(), 128)
+}
+
+func TestDifferentialDriveDriverArc(t *testing.T) {
+	d := initTestDifferentialDriveDriver()
+	d.TrackWidth = 0.2
+	d.Arc(0.5, 0.2)
+	gobot.Assert(t, d.Left.SignedSpeed(), 64)
+	gobot.Assert(t, d.Right.SignedSpeed(), 191)
+	d.Arc(0.5, -0.2)
+	gobot.Assert(t, d.Left.SignedSpeed(), 191)
+	gobot.Assert(t, d.Right.SignedSpeed(), 64)
+	// the outer wheel is limited to full speed
+	d.Arc(1, 0.2)
+	gobot.Assert(t, d.Left.SignedSpeed(), 85)
+	gobot.Assert(t, d.Right.SignedSpeed(), 255)
+	d.Arc(1, 0)
+	gobot.Assert(t, d.Left.SignedSpeed(), -255)
+	gobot.Assert(t, d.Right.SignedSpeed(), 255)
+}
+
+func TestDifferentialDriveDriverTrim(t *testing.T) {
+	d := initTestDifferentialDriveDriver()
+	d.RightTrim = 0.9
+	d.LeftInverted = true
+	d.Drive(1, 0)
+	gobot.Assert(t, d.Left.SignedSpeed(), -255)
+	gobot.Assert(t, d.Right.SignedSpeed(), 230)
+}
+
+func TestDifferentialDriveDriverMaxAcceleration(t *testing.T) {
+	d := initTestDifferentialDriveDriver()
+	d.Left.RampInterval = time.Millisecond
+	d.Right.RampInterval = time.Millisecond
+	d.Left.Acceleration = 2000
+	d.MaxAcceleration = 10
+	d.Drive(1, 0)
+	gobot.Assert(t, d.Left.SignedSpeed() < 255, true)
+	<-time.After(200 * time.Millisecond)
+	gobot.Assert(t, d.Left.SignedSpeed(), 255)
+	gobot.Assert(t, d.Right.SignedSpeed(), 255)
+	// the motors keep their own acceleration for SetSpeed
+	gobot.Assert(t, d.Left.Acceleration, 2000.0)
+	gobot.Assert(t, d.Right.Acceleration, 0.0)
+}
+
+func TestDifferentialDriveDriverCommands(t *testing.T) {
+	d := initTestDifferentialDriveDriver()
+	d.Command("Drive")(map[string]interface{}{"linear": 1.0, "angular": 0.0})
+	gobot.Assert(t, d.Left.SignedSpeed(), 255)
+	d.Command("Spin")(map[string]interface{}{"speed": 1.0})
+	gobot.Assert(t, d.Left.SignedSpeed(), -255)
+	d.Command("Arc")(map[string]interface{}{"speed": 0.5, "radius": 1.5})
+	gobot.Assert(t, d.Left.SignedSpeed(), 85)
+	d.Command("Stop")(map[string]interface{}{})
+	gobot.Assert(t, d.Right.SignedSpeed(), 0)
+}
@@ -0,0 +1,297 @@
+package gpio
+
+import (
+	"sync"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+// HD44780 commands and their flags.
+const (
+	lcdClearDisplay   byte = 0x01
+	lcdReturnHome     byte = 0x02
+	lcdEntryModeSet   byte = 0x04
+	lcdDisplayControl byte = 0x08
+	lcdCursorShift    byte = 0x10
+	lcdFunctionSet    byte = 0x20
+	lcdSetCGRAMAddr   byte = 0x40
+	lcdSetDDRAMAddr   byte = 0x80
+
+	lcdEntryLeft           byte = 0x02
+	lcdEntryShiftIncrement byte = 0x01
+
+	lcdDisplayOn byte = 0x04
+	lcdCursorOn  byte = 0x02
+	lcdBlinkOn   byte = 0x01
+
+	lcdDisplayMove byte = 0x08
+	lcdMoveRight   byte = 0x04
+
+	lcd2Line byte = 0x08
+)
+
+// LcdDriver drives an HD44780 compatible character LCD in 4-bit mode,
+// with its RS, E and D4 to D7 pins on digital outputs and RW tied to
+// ground.
+type LcdDriver struct {
+	gobot.Driver
+	RSPin     string
+	EnablePin string
+	DataPins  []string
+	Columns   int
+	Rows      int
+	mutex     sync.Mutex
+	control   byte
+	entryMode byte
+	sleep     func(time.Duration)
+}
+
+// NewLcdDriver creates a driver for a 16x2 LCD. Set Columns and Rows for
+// other sizes before starting it.
+func NewLcdDriver(a DigitalWriter, name string, rsPin string, enablePin string, d4 string, d5 string, d6 string, d7 string) *LcdDriver {
+	l := &LcdDriver{
+		Driver: *gobot.NewDriver(
+			name,
+			"LcdDriver",
+			a.(gobot.AdaptorInterface),
+			rsPin,
+		),
+		RSPin:     rsPin,
+		EnablePin: enablePin,
+		DataPins:  []string{d4, d5, d6, d7},
+		Columns:   16,
+		Rows:      2,
+		control:   lcdDisplayOn,
+		entryMode: lcdEntryLeft,
+		sleep:     time.Sleep,
+	}
+
+	l.AddCommand("Write", func(params map[string]interface{}) interface{} {
+		l.Write(params["text"].(string))
+		return nil
+	})
+	l.AddCommand("Clear", func(params map[string]interface{}) interface{} {
+		l.Clear()
+		return nil
+	})
+	l.AddCommand("Home", func(params map[string]interface{}) interface{} {
+		l.Home()
+		return nil
+	})
+	l.AddCommand("SetCursor", func(params map[string]interface{}) interface{} {
+		l.SetCursor(int(params["column"].(float64)), int(params["row"].(float64)))
+		return nil
+	})
+	l.AddCommand("WriteAt", func(params map[string]interface{}) interface{} {
+		l.SetCursor(int(params["column"].(float64)), int(params["row"].(float64)))
+		l.Write(params["text"].(string))
+		return nil
+	})
+	l.AddCommand("ScrollLeft", func(params map[string]interface{}) interface{} {
+		l.ScrollLeft()
+		return nil
+	})
+	l.AddCommand("ScrollRight", func(params map[string]interface{}) interface{} {
+		l.ScrollRight()
+		return nil
+	})
+	l.AddCommand("Display", func(params map[string]interface{}) interface{} {
+		l.Display(params["on"].(bool))
+		return nil
+	})
+	l.AddCommand("Cursor", func(params map[string]interface{}) interface{} {
+		l.Cursor(params["on"].(bool))
+		return nil
+	})
+	l.AddCommand("Blink", func(params map[string]interface{}) interface{} {
+		l.Blink(params["on"].(bool))
+		return nil
+	})
+	l.AddCommand("Autoscroll", func(params map[string]interface{}) interface{} {
+		l.Autoscroll(params["on"].(bool))
+		return nil
+	})
+
+	return l
+}
+
+func (l *LcdDriver) adaptor() DigitalWriter {
+	return l.Adaptor().(DigitalWriter)
+}
+
+// Start puts the LCD into 4-bit mode and clears it, following the
+// initialization by instruction sequence from the HD44780 datasheet.
+func (l *LcdDriver) Start() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.adaptor().DigitalWrite(l.RSPin, 0)
+	l.adaptor().DigitalWrite(l.EnablePin, 0)
+	l.sleep(50 * time.Millisecond)
+
+	// the LCD may be in 8-bit mode, or half way through a byte in 4-bit
+	// mode, so it is set to 8-bit mode three times before 4-bit mode
+	l.writeNibble(0x03)
+	l.sleep(4500 * time.Microsecond)
+	l.writeNibble(0x03)
+	l.sleep(150 * time.Microsecond)
+	l.writeNibble(0x03)
+	l.writeNibble(0x02)
+
+	function := lcdFunctionSet
+	if l.Rows > 1 {
+		function |= lcd2Line
+	}
+	l.command(function)
+	l.command(lcdDisplayControl | l.control)
+	l.command(lcdClearDisplay)
+	l.sleep(2 * time.Millisecond)
+	l.command(lcdEntryModeSet | l.entryMode)
+	return true
+}
+func (l *LcdDriver) Init() bool { return true }
+
+// Halt clears the LCD.
+func (l *LcdDriver) Halt() bool {
+	l.Clear()
+	return true
+}
+
+// Write writes text at the cursor. Bytes 0 to 7 show the custom
+// characters made with CreateChar.
+func (l *LcdDriver) Write(text string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for i := 0; i < len(text); i++ {
+		l.write(text[i], 1)
+	}
+}
+
+// Clear clears the LCD and moves the cursor home.
+func (l *LcdDriver) Clear() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.command(lcdClearDisplay)
+	l.sleep(2 * time.Millisecond)
+}
+
+// Home moves the cursor to the top left, and undoes any scrolling.
+func (l *LcdDriver) Home() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.command(lcdReturnHome)
+	l.sleep(2 * time.Millisecond)
+}
+
+// SetCursor moves the cursor to column and row, counted from 0. Positions
+// off the LCD are limited to its last column and row.
+func (l *LcdDriver) SetCursor(column int, row int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	offsets := []int{0x00, 0x40, l.Columns, 0x40 + l.Columns}
+	row = clampInt(row, 0, clampInt(l.Rows, 1, len(offsets))-1)
+	column = clampInt(column, 0, l.Columns-1)
+	l.command(lcdSetDDRAMAddr | byte(offsets[row]+column))
+}
+
+// CreateChar defines custom character location, 0 to 7, from 8 rows of 5
+// pixels each, top first, with the rightmost pixel in bit 0. Write it as
+// the byte location.
+func (l *LcdDriver) CreateChar(location byte, rows [8]byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.command(lcdSetCGRAMAddr | (location&0x07)<<3)
+	for _, row := range rows {
+		l.write(row&0x1F, 1)
+	}
+	// go back to writing text, which the CGRAM address left behind
+	l.command(lcdSetDDRAMAddr)
+}
+
+// ScrollLeft moves the text one column left, without changing it.
+func (l *LcdDriver) ScrollLeft() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.command(lcdCursorShift | lcdDisplayMove)
+}
+
+// ScrollRight moves the text one column right, without changing it.
+func (l *LcdDriver) ScrollRight() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.command(lcdCursorShift | lcdDisplayMove | lcdMoveRight)
+}
+
+// Display turns the display on or off, keeping the text.
+func (l *LcdDriver) Display(on bool) {
+	l.setControl(lcdDisplayOn, on)
+}
+
+// Cursor shows or hides the underline cursor.
+func (l *LcdDriver) Cursor(on bool) {
+	l.setControl(lcdCursorOn, on)
+}
+
+// Blink turns blinking of the cursor position on or off.
+func (l *LcdDriver) Blink(on bool) {
+	l.setControl(lcdBlinkOn, on)
+}
+
+// Autoscroll makes the text scroll left as each character is written, so
+// that new text appears at the cursor.
+func (l *LcdDriver) Autoscroll(on bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if on {
+		l.entryMode |= lcdEntryShiftIncrement
+	} else {
+		l.entryMode &^= lcdEntryShiftIncrement
+	}
+	l.command(lcdEntryModeSet | l.entryMode)
+}
+
+func (l *LcdDriver) setControl(flag byte, on bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if on {
+		l.control |= flag
+	} else {
+		l.control &^= flag
+	}
+	l.command(lcdDisplayControl | l.control)
+}
+
+func (l *LcdDriver) command(value byte) {
+	l.write(value, 0)
+}
+
+// write sends a byte, as a command when rs is 0 or as data when it is 1,
+// high nibble first.
+func (l *LcdDriver) write(value byte, rs byte) {
+	l.adaptor().DigitalWrite(l.RSPin, rs)
+	l.writeNibble(value >> 4)
+	l.writeNibble(value & 0x0F)
+	// most commands take 37µs
+	l.sleep(50 * time.Microsecond)
+}
+
+// writeNibble puts the low 4 bits of value on D4 to D7 and pulses E, which
+// the LCD reads on the falling edge.
+func (l *LcdDriver) writeNibble(value byte) {
+	for i, pin := range l.DataPins {
+		l.adaptor().DigitalWrite(pin, (value>>uint(i))&1)
+	}
+	l.adaptor().DigitalWrite(l.EnablePin, 1)
+	l.adaptor().DigitalWrite(l.EnablePin, 0)
+}
+
+func clampInt(value int, min int, max int) int {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
@@ -0,0 +1,132 @@
+package gpio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestLcdDriver() *LcdDriver {
+	d := NewLcdDriver(newGpioTestAdaptor("adaptor"), "bot", "rs", "e", "4", "5", "6", "7")
+	d.sleep = func(time.Duration) {}
+	return d
+}
+
+// lcdNibbles decodes the nibbles the LCD reads, each with the level of RS,
+// on the falling edges of E.
+func lcdNibbles(writes []pinWrite) [][2]int {
+	pins := map[string]int{}
+	nibbles := [][2]int{}
+	for _, w := range writes {
+		if w.pin == "e" && w.value == 0 && pins["e"] == 1 {
+			nibble := pins["4"] | pins["5"]<<1 | pins["6"]<<2 | pins["7"]<<3
+			nibbles = append(nibbles, [2]int{pins["rs"], nibble})
+		}
+		pins[w.pin] = w.value
+	}
+	return nibbles
+}
+
+// lcdBytes decodes the bytes the LCD reads from pairs of nibbles, with
+// data bytes offset by 0x100 to tell them from commands.
+func lcdBytes(writes []pinWrite) []int {
+	nibbles := lcdNibbles(writes)
+	bytes := []int{}
+	for i := 0; i+1 < len(nibbles); i += 2 {
+		bytes = append(bytes, nibbles[i][0]<<8|nibbles[i][1]<<4|nibbles[i+1][1])
+	}
+	return bytes
+}
+
+func TestLcdDriverStart(t *testing.T) {
+	d := initTestLcdDriver()
+	gobot.Assert(t, d.Start(), true)
+	nibbles := lcdNibbles(d.adaptor().(*gpioTestAdaptor).pinWrites())
+	gobot.Assert(t, nibbles[:4], [][2]int{{0, 3}, {0, 3}, {0, 3}, {0, 2}})
+	gobot.Assert(t, nibbles[4:], [][2]int{
+		{0, 2}, {0, 8}, // 4-bit, 2 lines
+		{0, 0}, {0, 0xC}, // display on
+		{0, 0}, {0, 1}, // clear
+		{0, 0}, {0, 6}, // left to right
+	})
+}
+
+func TestLcdDriverHalt(t *testing.T) {
+	d := initTestLcdDriver()
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, lcdBytes(d.adaptor().(*gpioTestAdaptor).pinWrites()), []int{0x01})
+}
+
+func TestLcdDriverInit(t *testing.T) {
+	d := initTestLcdDriver()
+	gobot.Assert(t, d.Init(), true)
+}
+
+func TestLcdDriverWrite(t *testing.T) {
+	d := initTestLcdDriver()
+	d.Write("Hi")
+	gobot.Assert(t, lcdBytes(d.adaptor().(*gpioTestAdaptor).pinWrites()), []int{0x148, 0x169})
+}
+
+func TestLcdDriverSetCursor(t *testing.T) {
+	d := initTestLcdDriver()
+	a := d.adaptor().(*gpioTestAdaptor)
+	d.SetCursor(3, 1)
+	gobot.Assert(t, lcdBytes(a.pinWrites()), []int{0x80 | 0x43})
+	d.SetCursor(20, 5)
+	gobot.Assert(t, lcdBytes(a.pinWrites()), []int{0x80 | 0x4F})
+
+	d.Columns = 20
+	d.Rows = 4
+	d.SetCursor(0, 2)
+	gobot.Assert(t, lcdBytes(a.pinWrites()), []int{0x80 | 0x14})
+	d.SetCursor(1, 3)
+	gobot.Assert(t, lcdBytes(a.pinWrites()), []int{0x80 | 0x55})
+}
+
+func TestLcdDriverCreateChar(t *testing.T) {
+	d := initTestLcdDriver()
+	d.CreateChar(2, [8]byte{0x1F, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0xFF})
+	gobot.Assert(t, lcdBytes(d.adaptor().(*gpioTestAdaptor).pinWrites()), []int{
+		0x40 | 2<<3,
+		0x11F, 0x111, 0x111, 0x111, 0x111, 0x111, 0x111, 0x11F,
+		0x80,
+	})
+}
+
+func TestLcdDriverControl(t *testing.T) {
+	d := initTestLcdDriver()
+	a := d.adaptor().(*gpioTestAdaptor)
+	d.Cursor(true)
+	d.Blink(true)
+	d.Display(false)
+	d.ScrollLeft()
+	d.ScrollRight()
+	d.Autoscroll(true)
+	d.Autoscroll(false)
+	d.Home()
+	d.Clear()
+	gobot.Assert(t, lcdBytes(a.pinWrites()), []int{
+		0x0E, 0x0F, 0x0B, 0x18, 0x1C, 0x07, 0x06, 0x02, 0x01,
+	})
+}
+
+func TestLcdDriverCommands(t *testing.T) {
+	d := initTestLcdDriver()
+	a := d.adaptor().(*gpioTestAdaptor)
+	d.Command("WriteAt")(map[string]interface{}{"text": "A", "column": 1.0, "row": 0.0})
+	d.Command("Write")(map[string]interface{}{"text": "B"})
+	d.Command("SetCursor")(map[string]interface{}{"column": 0.0, "row": 1.0})
+	d.Command("Clear")(map[string]interface{}{})
+	d.Command("Home")(map[string]interface{}{})
+	d.Command("ScrollLeft")(map[string]interface{}{})
+	d.Command("ScrollRight")(map[string]interface{}{})
+	d.Command("Display")(map[string]interface{}{"on": true})
+	d.Command("Cursor")(map[string]interface{}{"on": false})
+	d.Command("Blink")(map[string]interface{}{"on": false})
+	d.Command("Autoscroll")(map[string]interface{}{"on": false})
+	gobot.Assert(t, lcdBytes(a.pinWrites()), []int{
+		0x81, 0x141, 0x142, 0xC0, 0x01, 0x02, 0x18, 0x1C, 0x0C, 0x0C, 0x0C, 0x06,
+	})
+}
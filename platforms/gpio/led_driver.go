@@ -1,12 +1,21 @@
 package gpio
 
 import (
+	"sync"
+	"time"
+
 	"github.com/edmontongo/gobot"
 )
 
+// LedDriver drives an LED on a PWM pin. Besides turning it on and off it
+// can run effects, such as blinking or fading, which are updated every
+// interval of the driver. Starting an effect, or any other change to the
+// LED, cancels the running effect.
 type LedDriver struct {
 	gobot.Driver
-	High bool
+	High   bool
+	mutex  sync.Mutex
+	effect ledEffect
 }
 
 func NewLedDriver(a PwmDigitalWriter, name string, pin string) *LedDriver {
@@ -21,12 +30,19 @@ func NewLedDriver(a PwmDigitalWriter, name string, pin string) *LedDriver {
 		High: false,
 	}
 
+	l.AddEvent("effect_complete")
+
 	l.AddCommand("Brightness", func(params map[string]interface{}) interface{} {
 		level := byte(params["level"].(float64))
 		l.Brightness(level)
 		return nil
 	})
 
+	l.AddCommand("DutyCycle", func(params map[string]interface{}) interface{} {
+		l.DutyCycle(params["duty"].(float64))
+		return nil
+	})
+
 	l.AddCommand("Toggle", func(params map[string]interface{}) interface{} {
 		l.Toggle()
 		return nil
@@ -42,6 +58,28 @@ func NewLedDriver(a PwmDigitalWriter, name string, pin string) *LedDriver {
 		return nil
 	})
 
+	l.AddCommand("Blink", func(params map[string]interface{}) interface{} {
+		l.Blink(time.Duration(params["period"].(float64))*time.Millisecond,
+			params["duty"].(float64))
+		return nil
+	})
+
+	l.AddCommand("Fade", func(params map[string]interface{}) interface{} {
+		l.Fade(byte(params["from"].(float64)), byte(params["to"].(float64)),
+			time.Duration(params["duration"].(float64))*time.Millisecond)
+		return nil
+	})
+
+	l.AddCommand("Breathe", func(params map[string]interface{}) interface{} {
+		l.Breathe(time.Duration(params["period"].(float64)) * time.Millisecond)
+		return nil
+	})
+
+	l.AddCommand("StopEffect", func(params map[string]interface{}) interface{} {
+		l.StopEffect()
+		return nil
+	})
+
 	return l
 }
 
@@ -50,10 +88,17 @@ func (l *LedDriver) adaptor() PwmDigitalWriter {
 }
 
 func (l *LedDriver) Start() bool { return true }
-func (l *LedDriver) Halt() bool  { return true }
 func (l *LedDriver) Init() bool  { return true }
 
+// Halt stops the running effect.
+func (l *LedDriver) Halt() bool {
+	l.StopEffect()
+	return true
+}
+
 func (l *LedDriver) IsOn() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
 	return l.High
 }
 
@@ -62,14 +107,14 @@ func (l *LedDriver) IsOff() bool {
 }
 
 func (l *LedDriver) On() bool {
+	l.effect.stop()
 	l.changeState(1)
-	l.High = true
 	return true
 }
 
 func (l *LedDriver) Off() bool {
+	l.effect.stop()
 	l.changeState(0)
-	l.High = false
 	return true
 }
 
@@ -81,10 +126,69 @@ func (l *LedDriver) Toggle() {
 	}
 }
 
+// Brightness sets the LED to level with PWM. The LED is on for any level
+// above 0.
 func (l *LedDriver) Brightness(level byte) {
-	l.adaptor().PwmWrite(l.Pin(), level)
+	l.effect.stop()
+	l.brightness(float64(level) / 255)
+}
+
+// DutyCycle sets the LED to duty, from 0 to 1, of full brightness. Adaptors
+// with a PwmController set it with their full resolution, and others with
+// the nearest of the 256 levels of Brightness.
+func (l *LedDriver) DutyCycle(duty float64) {
+	l.effect.stop()
+	l.brightness(duty)
+}
+
+// Blink turns the LED on for duty, from 0 to 1, of every period, and off
+// for the rest, until cancelled.
+func (l *LedDriver) Blink(period time.Duration, duty float64) {
+	l.effect.start(l.Interval(), blinkStep(period, duty, func(on bool) {
+		if on {
+			l.changeState(1)
+		} else {
+			l.changeState(0)
+		}
+	}), l.effectComplete)
+}
+
+// Fade changes the brightness from one level to another over duration,
+// and publishes an "effect_complete" event when it is done.
+func (l *LedDriver) Fade(from byte, to byte, duration time.Duration) {
+	l.effect.start(l.Interval(), fadeStep(duration, func(t float64) {
+		l.brightness((float64(from) + (float64(to)-float64(from))*t) / 255)
+	}), l.effectComplete)
+}
+
+// Breathe fades the LED smoothly up to full brightness and back down
+// again every period, until cancelled.
+func (l *LedDriver) Breathe(period time.Duration) {
+	l.effect.start(l.Interval(), func(elapsed time.Duration) bool {
+		l.brightness(breatheLevel(elapsed, period))
+		return true
+	}, l.effectComplete)
+}
+
+// StopEffect cancels the running effect, leaving the LED as it is.
+func (l *LedDriver) StopEffect() {
+	l.effect.stop()
+}
+
+func (l *LedDriver) effectComplete() {
+	gobot.Publish(l.Event("effect_complete"), l.Name())
+}
+
+func (l *LedDriver) brightness(duty float64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	pwmDutyWrite(l.adaptor(), l.Pin(), duty)
+	l.High = duty > 0
 }
 
 func (l *LedDriver) changeState(level byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
 	l.adaptor().DigitalWrite(l.Pin(), level)
+	l.High = level != 0
 }
@@ -2,6 +2,7 @@ package gpio
 
 import (
 	"testing"
+	"time"
 
 	"github.com/edmontongo/gobot"
 )
@@ -49,4 +50,98 @@ func TestLedDriverToggle(t *testing.T) {
 func TestLedDriverBrightness(t *testing.T) {
 	d := initTestLedDriver()
 	d.Brightness(150)
+	gobot.Assert(t, d.IsOn(), true)
+	gobot.Assert(t, d.adaptor().(*gpioTestAdaptor).pinValue("1"), 150)
+	d.Brightness(0)
+	gobot.Assert(t, d.IsOff(), true)
+}
+
+func TestLedDriverDutyCycle(t *testing.T) {
+	d := initTestLedDriver()
+	d.DutyCycle(0.5)
+	gobot.Assert(t, d.IsOn(), true)
+	gobot.Assert(t, d.adaptor().(*gpioTestAdaptor).pinValue("1"), 128)
+	d.DutyCycle(2)
+	gobot.Assert(t, d.adaptor().(*gpioTestAdaptor).pinValue("1"), 255)
+	d.Command("DutyCycle")(map[string]interface{}{"duty": 0.0})
+	gobot.Assert(t, d.IsOff(), true)
+}
+
+func TestLedDriverPwmController(t *testing.T) {
+	a := newPwmTestAdaptor()
+	d := NewLedDriver(a, "bot", "1")
+	d.DutyCycle(PwmDuty16(1000))
+	gobot.Assert(t, a.duty("1"), 1000.0/65535)
+	gobot.Assert(t, a.pinValue("1"), -1)
+	d.Brightness(51)
+	gobot.Assert(t, a.duty("1"), 0.2)
+}
+
+func TestLedDriverBlink(t *testing.T) {
+	d := initTestLedDriver()
+	d.SetInterval(time.Millisecond)
+	d.Blink(60*time.Millisecond, 0.5)
+	gobot.Assert(t, d.IsOn(), true)
+	<-time.After(45 * time.Millisecond)
+	gobot.Assert(t, d.IsOn(), false)
+	<-time.After(30 * time.Millisecond)
+	gobot.Assert(t, d.IsOn(), true)
+	d.Off()
+	<-time.After(20 * time.Millisecond)
+	gobot.Assert(t, d.IsOff(), true)
+}
+
+func TestLedDriverFade(t *testing.T) {
+	d := initTestLedDriver()
+	d.SetInterval(time.Millisecond)
+	done := make(chan bool, 1)
+	gobot.Once(d.Event("effect_complete"), func(data interface{}) {
+		done <- true
+	})
+	d.Fade(0, 200, 20*time.Millisecond)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("effect_complete was not published")
+	}
+	gobot.Assert(t, d.adaptor().(*gpioTestAdaptor).pinValue("1"), 200)
+	gobot.Assert(t, d.IsOn(), true)
+}
+
+func TestLedDriverBreathe(t *testing.T) {
+	d := initTestLedDriver()
+	d.SetInterval(time.Millisecond)
+	d.Breathe(time.Hour)
+	gobot.Assert(t, d.adaptor().(*gpioTestAdaptor).pinValue("1"), 0)
+	gobot.Assert(t, d.Halt(), true)
+	d.Brightness(10)
+	<-time.After(5 * time.Millisecond)
+	gobot.Assert(t, d.adaptor().(*gpioTestAdaptor).pinValue("1"), 10)
+}
+
+func TestLedDriverEffectCommands(t *testing.T) {
+	d := initTestLedDriver()
+	d.Command("Fade")(map[string]interface{}{"from": 0.0, "to": 40.0, "duration": 0.0})
+	gobot.Assert(t, d.adaptor().(*gpioTestAdaptor).pinValue("1"), 40)
+	d.Command("Blink")(map[string]interface{}{"period": 1000.0, "duty": 0.5})
+	gobot.Assert(t, d.IsOn(), true)
+	d.Command("Breathe")(map[string]interface{}{"period": 1000.0})
+	d.Command("StopEffect")(map[string]interface{}{})
+	d.Command("Brightness")(map[string]interface{}{"level": 5.0})
+	gobot.Assert(t, d.adaptor().(*gpioTestAdaptor).pinValue("1"), 5)
+}
+
+func TestLedEffectSteps(t *testing.T) {
+	states := []bool{}
+	blink := blinkStep(100*time.Millisecond, 0.25, func(on bool) {
+		states = append(states, on)
+	})
+	for _, ms := range []time.Duration{0, 10, 30, 90, 110, 130} {
+		blink(ms * time.Millisecond)
+	}
+	gobot.Assert(t, states, []bool{true, false, true, false})
+
+	gobot.Assert(t, breatheLevel(0, time.Second), 0.0)
+	gobot.Assert(t, breatheLevel(500*time.Millisecond, time.Second), 1.0)
+	gobot.Assert(t, scaleLevel(200, 100, 0.5), byte(150))
 }
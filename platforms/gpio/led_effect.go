@@ -0,0 +1,98 @@
+package gpio
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// ledEffect runs one LED effect at a time. Starting an effect cancels the
+// one before it.
+type ledEffect struct {
+	mutex   sync.Mutex
+	current int
+}
+
+// start calls step at once and then every interval with the time since the
+// effect began, until step returns false or the effect is cancelled. done
+// is called after the last step of an effect that was not cancelled.
+func (e *ledEffect) start(interval time.Duration, step func(elapsed time.Duration) bool, done func()) {
+	e.mutex.Lock()
+	e.current++
+	effect := e.current
+	began := time.Now()
+	more := step(0)
+	e.mutex.Unlock()
+
+	if !more {
+		done()
+		return
+	}
+	go func() {
+		for {
+			<-time.After(interval)
+			e.mutex.Lock()
+			if e.current != effect {
+				e.mutex.Unlock()
+				return
+			}
+			more := step(time.Since(began))
+			e.mutex.Unlock()
+			if !more {
+				done()
+				return
+			}
+		}
+	}()
+}
+
+// stop cancels the running effect. No step of it runs after stop returns.
+func (e *ledEffect) stop() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.current++
+}
+
+// blinkStep returns a step that calls set with true for duty of every
+// period and false for the rest, only when the state changes.
+func blinkStep(period time.Duration, duty float64, set func(on bool)) func(time.Duration) bool {
+	first := true
+	last := false
+	return func(elapsed time.Duration) bool {
+		on := period > 0 && float64(elapsed%period) < duty*float64(period)
+		if first || on != last {
+			set(on)
+			first = false
+			last = on
+		}
+		return true
+	}
+}
+
+// fadeStep returns a step that calls set with the fraction, from 0 to 1, of
+// duration that has passed, and stops once it reaches 1.
+func fadeStep(duration time.Duration, set func(t float64)) func(time.Duration) bool {
+	return func(elapsed time.Duration) bool {
+		t := 1.0
+		if duration > 0 {
+			t = math.Min(1, float64(elapsed)/float64(duration))
+		}
+		set(t)
+		return t < 1
+	}
+}
+
+// breatheLevel returns the brightness, from 0 to 1, at elapsed into a
+// breath lasting period, rising smoothly from 0 to 1 and back.
+func breatheLevel(elapsed time.Duration, period time.Duration) float64 {
+	if period <= 0 {
+		return 1
+	}
+	phase := float64(elapsed%period) / float64(period)
+	return (1 - math.Cos(2*math.Pi*phase)) / 2
+}
+
+// scaleLevel returns the level at t of the way from one level to another.
+func scaleLevel(from byte, to byte, t float64) byte {
+	return byte(float64(from) + (float64(to)-float64(from))*t + 0.5)
+}
@@ -0,0 +1,31 @@
+package gpio
+
+import (
+	"testing"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestMakeyButtonDriver() *MakeyButtonDriver {
+	return NewMakeyButtonDriver(newGpioTestAdaptor("adaptor"), "bot", "1")
+}
+
+func TestMakeyButtonDriverStart(t *testing.T) {
+	d := initTestMakeyButtonDriver()
+	gobot.Assert(t, d.Start(), true)
+}
+
+func TestMakeyButtonDriverType(t *testing.T) {
+	d := initTestMakeyButtonDriver()
+	gobot.Assert(t, d.Type(), "MakeyButtonDriver")
+	gobot.Assert(t, d.ActiveLow, true)
+}
+
+func TestMakeyButtonDriverActive(t *testing.T) {
+	d := initTestMakeyButtonDriver()
+	d.update(0)
+	gobot.Assert(t, d.Active, true)
+
+	d.update(1)
+	gobot.Assert(t, d.Active, false)
+}
@@ -1,9 +1,30 @@
 package gpio
 
 import (
+	"sync"
+	"time"
+
 	"github.com/edmontongo/gobot"
 )
 
+// Motor directions.
+const (
+	MotorForward  = "forward"
+	MotorBackward = "backward"
+	MotorNone     = "none"
+)
+
+// MotorDriver drives a DC motor. How the motor is wired is given by the
+// pins that are set, normally by one of the constructors:
+//
+//   - SpeedPin: a single PWM pin driving the motor in one direction
+//   - SpeedPin and DirectionPin: a PWM pin and a direction pin
+//   - ForwardPin and BackwardPin: a dual PWM H-bridge
+//   - SpeedPin, ForwardPin and BackwardPin: an H-bridge with a PWM enable
+//     pin and two direction inputs, such as an L298N or TB6612
+//
+// Speeds range from 0 to 255, or from -255 to 255 where a sign gives the
+// direction.
 type MotorDriver struct {
 	gobot.Driver
 	SpeedPin         string
@@ -15,20 +36,125 @@ type MotorDriver struct {
 	CurrentSpeed     byte
 	CurrentMode      string
 	CurrentDirection string
+	// Braking makes Stop, Off and Halt short the motor on wirings that
+	// support it, instead of letting it coast.
+	Braking bool
+	// Acceleration limits how fast SetSpeed changes the speed, in speed
+	// units per second. Zero changes the speed at once.
+	Acceleration float64
+	// RampInterval is how often the speed is updated while ramping.
+	RampInterval time.Duration
+	// Frequency is the PWM frequency in hertz, set on adaptors with a
+	// PwmController. Zero leaves the adaptor's default. Many motors whine
+	// at the low frequencies of most boards, and are quiet above 20kHz.
+	Frequency   float64
+	mutex       sync.Mutex
+	frequencies map[string]float64
+	ramp        int
+	published   int
 }
 
+// NewMotorDriver creates a driver for a motor switched by a transistor on
+// the PWM pin, which only turns one way.
 func NewMotorDriver(a PwmDigitalWriter, name string, pin string) *MotorDriver {
-	return &MotorDriver{
+	m := &MotorDriver{
 		Driver: *gobot.NewDriver(
 			name,
 			"MotorDriver",
 			a.(gobot.AdaptorInterface),
+			pin,
 		),
+		SpeedPin:         pin,
 		CurrentState:     0,
 		CurrentSpeed:     0,
 		CurrentMode:      "digital",
-		CurrentDirection: "forward",
+		CurrentDirection: MotorForward,
+		RampInterval:     20 * time.Millisecond,
 	}
+
+	m.AddEvent("speed")
+	m.AddEvent("ramp_complete")
+
+	m.AddCommand("On", func(params map[string]interface{}) interface{} {
+		m.On()
+		return nil
+	})
+	m.AddCommand("Off", func(params map[string]interface{}) interface{} {
+		m.Off()
+		return nil
+	})
+	m.AddCommand("Toggle", func(params map[string]interface{}) interface{} {
+		m.Toggle()
+		return nil
+	})
+	m.AddCommand("Speed", func(params map[string]interface{}) interface{} {
+		m.Speed(byte(params["speed"].(float64)))
+		return nil
+	})
+	m.AddCommand("Forward", func(params map[string]interface{}) interface{} {
+		m.Forward(byte(params["speed"].(float64)))
+		return nil
+	})
+	m.AddCommand("Backward", func(params map[string]interface{}) interface{} {
+		m.Backward(byte(params["speed"].(float64)))
+		return nil
+	})
+	m.AddCommand("Direction", func(params map[string]interface{}) interface{} {
+		m.Direction(params["direction"].(string))
+		return nil
+	})
+	m.AddCommand("SetSpeed", func(params map[string]interface{}) interface{} {
+		m.SetSpeed(int(params["speed"].(float64)))
+		return nil
+	})
+	m.AddCommand("RampTo", func(params map[string]interface{}) interface{} {
+		m.RampTo(int(params["speed"].(float64)),
+			time.Duration(params["duration"].(float64))*time.Millisecond)
+		return nil
+	})
+	m.AddCommand("Brake", func(params map[string]interface{}) interface{} {
+		m.Brake()
+		return nil
+	})
+	m.AddCommand("Coast", func(params map[string]interface{}) interface{} {
+		m.Coast()
+		return nil
+	})
+	m.AddCommand("Stop", func(params map[string]interface{}) interface{} {
+		m.Stop()
+		return nil
+	})
+	m.AddCommand("SignedSpeed", func(params map[string]interface{}) interface{} {
+		return m.SignedSpeed()
+	})
+
+	return m
+}
+
+// NewDirectionMotorDriver creates a driver for a motor controller with a
+// PWM speed pin and a direction pin that is high for forward.
+func NewDirectionMotorDriver(a PwmDigitalWriter, name string, speedPin string, directionPin string) *MotorDriver {
+	m := NewMotorDriver(a, name, speedPin)
+	m.DirectionPin = directionPin
+	return m
+}
+
+// NewDualPwmMotorDriver creates a driver for an H-bridge driven by a PWM
+// pin for each direction.
+func NewDualPwmMotorDriver(a PwmDigitalWriter, name string, forwardPin string, backwardPin string) *MotorDriver {
+	m := NewMotorDriver(a, name, "")
+	m.ForwardPin = forwardPin
+	m.BackwardPin = backwardPin
+	return m
+}
+
+// NewHBridgeMotorDriver creates a driver for an H-bridge with a PWM enable
+// pin and two direction inputs, such as an L298N or TB6612.
+func NewHBridgeMotorDriver(a PwmDigitalWriter, name string, speedPin string, forwardPin string, backwardPin string) *MotorDriver {
+	m := NewMotorDriver(a, name, speedPin)
+	m.ForwardPin = forwardPin
+	m.BackwardPin = backwardPin
+	return m
 }
 
 func (m *MotorDriver) adaptor() PwmDigitalWriter {
@@ -36,26 +162,24 @@ func (m *MotorDriver) adaptor() PwmDigitalWriter {
 }
 
 func (m *MotorDriver) Start() bool { return true }
-func (m *MotorDriver) Halt() bool  { return true }
 func (m *MotorDriver) Init() bool  { return true }
 
+// Halt stops the motor.
+func (m *MotorDriver) Halt() bool {
+	m.Stop()
+	return true
+}
+
 func (m *MotorDriver) Off() {
-	if m.isDigital() {
-		m.changeState(0)
-	} else {
-		m.Speed(0)
-	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.off()
 }
 
 func (m *MotorDriver) On() {
-	if m.isDigital() {
-		m.changeState(1)
-	} else {
-		if m.CurrentSpeed == 0 {
-			m.CurrentSpeed = 255
-		}
-		m.Speed(m.CurrentSpeed)
-	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.on()
 }
 
 func (m *MotorDriver) Min() {
@@ -67,10 +191,9 @@ func (m *MotorDriver) Max() {
 }
 
 func (m *MotorDriver) IsOn() bool {
-	if m.isDigital() {
-		return m.CurrentState == 1
-	}
-	return m.CurrentSpeed > 0
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.isOn()
 }
 
 func (m *MotorDriver) IsOff() bool {
@@ -78,57 +201,166 @@ func (m *MotorDriver) IsOff() bool {
 }
 
 func (m *MotorDriver) Toggle() {
-	if m.IsOn() {
-		m.Off()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.isOn() {
+		m.off()
 	} else {
-		m.On()
+		m.on()
 	}
 }
 
+// Speed sets the speed of the motor in its current direction.
 func (m *MotorDriver) Speed(value byte) {
-	m.CurrentMode = "analog"
-	m.CurrentSpeed = value
-	m.adaptor().PwmWrite(m.SpeedPin, value)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.ramp++
+	m.speed(value)
 }
 
 func (m *MotorDriver) Forward(speed byte) {
-	m.Direction("forward")
-	m.Speed(speed)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.ramp++
+	m.CurrentDirection = MotorForward
+	m.speed(speed)
 }
 
 func (m *MotorDriver) Backward(speed byte) {
-	m.Direction("backward")
-	m.Speed(speed)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.ramp++
+	m.CurrentDirection = MotorBackward
+	m.speed(speed)
 }
 
+// Direction sets the direction of the motor to MotorForward,
+// MotorBackward or MotorNone. Other values are ignored.
 func (m *MotorDriver) Direction(direction string) {
-	m.CurrentDirection = direction
-	if m.DirectionPin != "" {
-		var level byte
-		if direction == "forward" {
-			level = 1
-		} else {
-			level = 0
-		}
-		m.adaptor().DigitalWrite(m.DirectionPin, level)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	switch direction {
+	case MotorForward, MotorBackward, MotorNone:
+		m.CurrentDirection = direction
+		m.apply()
+	}
+}
+
+// SetSpeed sets a signed speed from -255 to 255, where negative speeds
+// turn the motor backward. The change is ramped when Acceleration is set.
+func (m *MotorDriver) SetSpeed(speed int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.accelerate(speed, m.Acceleration)
+}
+
+// setSpeed is SetSpeed limited by the acceleration of a driver using the
+// motor, such as DifferentialDriveDriver, rather than Acceleration.
+func (m *MotorDriver) setSpeed(speed int, acceleration float64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.accelerate(speed, acceleration)
+}
+
+// accelerate changes the speed at acceleration, or at once when it is not
+// positive.
+func (m *MotorDriver) accelerate(speed int, acceleration float64) {
+	speed = clampSpeed(speed)
+	if acceleration <= 0 {
+		m.ramp++
+		m.setSignedSpeed(speed)
+		return
+	}
+	change := speed - m.signedSpeed()
+	if change < 0 {
+		change = -change
+	}
+	m.startRamp(speed,
+		time.Duration(float64(change)/acceleration*float64(time.Second)))
+}
+
+// RampTo changes the speed linearly to the signed speed over duration. A
+// "ramp_complete" event is published when it gets there. Any other change
+// of speed cancels the ramp.
+func (m *MotorDriver) RampTo(speed int, duration time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.startRamp(clampSpeed(speed), duration)
+}
+
+// SignedSpeed returns the speed from -255 to 255, negative when turning
+// backward.
+func (m *MotorDriver) SignedSpeed() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.signedSpeed()
+}
+
+// Brake stops the motor by shorting its terminals, which stops it faster
+// than coasting. Wirings without an H-bridge can only coast.
+func (m *MotorDriver) Brake() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.ramp++
+	m.brake()
+}
+
+// Coast stops driving the motor and lets it spin down freely.
+func (m *MotorDriver) Coast() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.ramp++
+	m.coast()
+}
+
+// Stop brakes or coasts the motor, depending on Braking.
+func (m *MotorDriver) Stop() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stop()
+}
+
+func (m *MotorDriver) on() {
+	if m.isDigital() {
+		m.changeState(1)
 	} else {
-		var forwardLevel, backwardLevel byte
-		switch direction {
-		case "forward":
-			forwardLevel = 1
-			backwardLevel = 0
-		case "backward":
-			forwardLevel = 0
-			backwardLevel = 1
-		case "none":
-			forwardLevel = 0
-			backwardLevel = 0
+		if m.CurrentSpeed == 0 {
+			m.CurrentSpeed = 255
 		}
-		m.adaptor().DigitalWrite(m.ForwardPin, forwardLevel)
-		m.adaptor().DigitalWrite(m.BackwardPin, backwardLevel)
+		m.speed(m.CurrentSpeed)
+	}
+}
+
+func (m *MotorDriver) off() {
+	if m.isDigital() {
+		m.changeState(0)
+	} else {
+		m.stop()
+	}
+}
+
+func (m *MotorDriver) isOn() bool {
+	if m.isDigital() {
+		return m.CurrentState == 1
+	}
+	return m.CurrentSpeed > 0
+}
+
+func (m *MotorDriver) stop() {
+	m.ramp++
+	if m.Braking {
+		m.brake()
+	} else {
+		m.coast()
 	}
 }
 
+func (m *MotorDriver) speed(value byte) {
+	m.CurrentMode = "analog"
+	m.CurrentSpeed = value
+	m.apply()
+}
+
 func (m *MotorDriver) isDigital() bool {
 	if m.CurrentMode == "digital" {
 		return true
@@ -136,23 +368,180 @@ func (m *MotorDriver) isDigital() bool {
 	return false
 }
 
+func (m *MotorDriver) isDualPwm() bool {
+	return m.SpeedPin == "" && m.ForwardPin != "" && m.BackwardPin != ""
+}
+
+func (m *MotorDriver) isHBridge() bool {
+	return m.SpeedPin != "" && m.ForwardPin != "" && m.BackwardPin != ""
+}
+
 func (m *MotorDriver) changeState(state byte) {
 	m.CurrentState = state
 	if state == 1 {
-		m.CurrentSpeed = 0
-	} else {
 		m.CurrentSpeed = 255
+	} else {
+		m.CurrentSpeed = 0
+	}
+	m.apply()
+}
+
+func (m *MotorDriver) signedSpeed() int {
+	switch m.CurrentDirection {
+	case MotorBackward:
+		return -int(m.CurrentSpeed)
+	case MotorNone:
+		return 0
+	}
+	return int(m.CurrentSpeed)
+}
+
+func (m *MotorDriver) setSignedSpeed(speed int) {
+	if speed > 0 {
+		m.CurrentDirection = MotorForward
+	} else if speed < 0 {
+		m.CurrentDirection = MotorBackward
+		speed = -speed
 	}
-	if m.ForwardPin != "" {
-		if state == 0 {
-			m.Direction(m.CurrentDirection)
-			if m.SpeedPin != "" {
-				m.Speed(m.CurrentSpeed)
+	m.speed(byte(speed))
+}
+
+// startRamp cancels any running ramp and starts one to speed.
+func (m *MotorDriver) startRamp(speed int, duration time.Duration) {
+	m.ramp++
+	ramp := m.ramp
+	start := m.signedSpeed()
+	interval := m.RampInterval
+	began := time.Now()
+	if duration <= 0 || interval <= 0 || start == speed {
+		m.setSignedSpeed(speed)
+		gobot.Publish(m.Event("ramp_complete"), speed)
+		return
+	}
+	go func() {
+		for {
+			<-time.After(interval)
+			m.mutex.Lock()
+			if m.ramp != ramp {
+				m.mutex.Unlock()
+				return
+			}
+			elapsed := time.Since(began)
+			if elapsed >= duration {
+				m.setSignedSpeed(speed)
+				m.mutex.Unlock()
+				gobot.Publish(m.Event("ramp_complete"), speed)
+				return
 			}
-		} else {
-			m.Direction("none")
+			m.setSignedSpeed(start + int(float64(speed-start)*float64(elapsed)/float64(duration)))
+			m.mutex.Unlock()
+		}
+	}()
+}
+
+func (m *MotorDriver) brake() {
+	m.CurrentState = 0
+	m.CurrentSpeed = 0
+	switch {
+	case m.isDualPwm():
+		m.write(m.ForwardPin, 255)
+		m.write(m.BackwardPin, 255)
+	case m.isHBridge():
+		m.adaptor().DigitalWrite(m.ForwardPin, 1)
+		m.adaptor().DigitalWrite(m.BackwardPin, 1)
+		m.write(m.SpeedPin, 255)
+	default:
+		m.apply()
+		return
+	}
+	m.publishSpeed()
+}
+
+func (m *MotorDriver) coast() {
+	m.CurrentState = 0
+	m.CurrentSpeed = 0
+	m.apply()
+}
+
+// apply writes the current direction and speed to the motor's pins.
+func (m *MotorDriver) apply() {
+	speed := m.CurrentSpeed
+	forward, backward := byte(0), byte(0)
+	switch m.CurrentDirection {
+	case MotorForward:
+		forward = 1
+	case MotorBackward:
+		backward = 1
+	default:
+		speed = 0
+	}
+	if speed == 0 {
+		forward, backward = 0, 0
+	}
+
+	switch {
+	case m.isDualPwm():
+		m.write(m.ForwardPin, forward*speed)
+		m.write(m.BackwardPin, backward*speed)
+	case m.isHBridge():
+		m.adaptor().DigitalWrite(m.ForwardPin, forward)
+		m.adaptor().DigitalWrite(m.BackwardPin, backward)
+		m.write(m.SpeedPin, speed)
+	case m.DirectionPin != "":
+		switch m.CurrentDirection {
+		case MotorForward:
+			m.adaptor().DigitalWrite(m.DirectionPin, 1)
+		case MotorBackward:
+			m.adaptor().DigitalWrite(m.DirectionPin, 0)
+		}
+		m.write(m.SpeedPin, speed)
+	default:
+		m.write(m.SpeedPin, speed)
+	}
+	m.publishSpeed()
+}
+
+// write sets pin to speed, or just on or off in digital mode.
+func (m *MotorDriver) write(pin string, speed byte) {
+	if m.isDigital() {
+		level := byte(0)
+		if speed > 0 {
+			level = 1
 		}
+		m.adaptor().DigitalWrite(pin, level)
 	} else {
-		m.adaptor().DigitalWrite(m.SpeedPin, state)
+		m.setFrequency(pin)
+		pwmDutyWrite(m.adaptor(), pin, float64(speed)/255)
+	}
+}
+
+// setFrequency sets the PWM period of pin to match Frequency, when it has
+// changed.
+func (m *MotorDriver) setFrequency(pin string) {
+	p, ok := m.Adaptor().(PwmController)
+	if !ok || m.Frequency <= 0 || m.frequencies[pin] == m.Frequency {
+		return
+	}
+	if p.PwmSetPeriod(pin, PwmFrequency(m.Frequency)) == nil {
+		if m.frequencies == nil {
+			m.frequencies = map[string]float64{}
+		}
+		m.frequencies[pin] = m.Frequency
+	}
+}
+
+func (m *MotorDriver) publishSpeed() {
+	if speed := m.signedSpeed(); speed != m.published {
+		m.published = speed
+		gobot.Publish(m.Event("speed"), speed)
+	}
+}
+
+func clampSpeed(speed int) int {
+	if speed > 255 {
+		return 255
+	} else if speed < -255 {
+		return -255
 	}
+	return speed
 }
@@ -1,8 +1,10 @@
 package gpio
 
 import (
-	"github.com/edmontongo/gobot"
 	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
 )
 
 func initTestMotorDriver() *MotorDriver {
@@ -106,3 +108,152 @@ func TestMotorDriverDirection(t *testing.T) {
 	d.Direction("forward")
 	d.Direction("backward")
 }
+
+func TestMotorDriverPin(t *testing.T) {
+	a := newGpioTestAdaptor("adaptor")
+	d := NewMotorDriver(a, "bot", "3")
+	gobot.Assert(t, d.SpeedPin, "3")
+	d.Max()
+	gobot.Assert(t, a.pinValue("3"), 255)
+}
+
+func TestMotorDriverPwmController(t *testing.T) {
+	a := newPwmTestAdaptor()
+	d := NewMotorDriver(a, "bot", "3")
+	d.Speed(51)
+	gobot.Assert(t, a.duty("3"), 0.2)
+	gobot.Assert(t, a.period("3"), time.Duration(0))
+	d.Frequency = 20000
+	d.Max()
+	gobot.Assert(t, a.duty("3"), 1.0)
+	gobot.Assert(t, a.period("3"), 50*time.Microsecond)
+}
+
+func TestMotorDriverDigitalOn(t *testing.T) {
+	a := newGpioTestAdaptor("adaptor")
+	d := NewHBridgeMotorDriver(a, "bot", "3", "4", "5")
+	d.On()
+	gobot.Assert(t, d.CurrentSpeed, uint8(255))
+	gobot.Assert(t, a.pinValue("3"), 1)
+	gobot.Assert(t, a.pinValue("4"), 1)
+	gobot.Assert(t, a.pinValue("5"), 0)
+	d.Off()
+	gobot.Assert(t, d.CurrentSpeed, uint8(0))
+	gobot.Assert(t, a.pinValue("3"), 0)
+	gobot.Assert(t, a.pinValue("4"), 0)
+}
+
+func TestMotorDriverDirectionWiring(t *testing.T) {
+	a := newGpioTestAdaptor("adaptor")
+	d := NewDirectionMotorDriver(a, "bot", "3", "4")
+	d.SetSpeed(-50)
+	gobot.Assert(t, d.SignedSpeed(), -50)
+	gobot.Assert(t, a.pinValue("3"), 50)
+	gobot.Assert(t, a.pinValue("4"), 0)
+	d.SetSpeed(300)
+	gobot.Assert(t, d.SignedSpeed(), 255)
+	gobot.Assert(t, a.pinValue("3"), 255)
+	gobot.Assert(t, a.pinValue("4"), 1)
+}
+
+func TestMotorDriverHBridge(t *testing.T) {
+	a := newGpioTestAdaptor("adaptor")
+	d := NewHBridgeMotorDriver(a, "bot", "3", "4", "5")
+	d.SetSpeed(-100)
+	gobot.Assert(t, d.CurrentDirection, MotorBackward)
+	gobot.Assert(t, a.pinValue("3"), 100)
+	gobot.Assert(t, a.pinValue("4"), 0)
+	gobot.Assert(t, a.pinValue("5"), 1)
+	d.Brake()
+	gobot.Assert(t, d.SignedSpeed(), 0)
+	gobot.Assert(t, a.pinValue("3"), 255)
+	gobot.Assert(t, a.pinValue("4"), 1)
+	gobot.Assert(t, a.pinValue("5"), 1)
+	d.Coast()
+	gobot.Assert(t, a.pinValue("3"), 0)
+	gobot.Assert(t, a.pinValue("4"), 0)
+	gobot.Assert(t, a.pinValue("5"), 0)
+}
+
+func TestMotorDriverDualPwm(t *testing.T) {
+	a := newGpioTestAdaptor("adaptor")
+	d := NewDualPwmMotorDriver(a, "bot", "5", "6")
+	d.Forward(100)
+	gobot.Assert(t, a.pinValue("5"), 100)
+	gobot.Assert(t, a.pinValue("6"), 0)
+	d.Backward(80)
+	gobot.Assert(t, a.pinValue("5"), 0)
+	gobot.Assert(t, a.pinValue("6"), 80)
+	d.Braking = true
+	d.Stop()
+	gobot.Assert(t, a.pinValue("5"), 255)
+	gobot.Assert(t, a.pinValue("6"), 255)
+}
+
+func TestMotorDriverHaltStops(t *testing.T) {
+	a := newGpioTestAdaptor("adaptor")
+	d := NewMotorDriver(a, "bot", "3")
+	d.Speed(100)
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, d.IsOff(), true)
+	gobot.Assert(t, a.pinValue("3"), 0)
+}
+
+func TestMotorDriverRampTo(t *testing.T) {
+	d := initTestMotorDriver()
+	d.RampInterval = time.Millisecond
+	sem := make(chan interface{}, 1)
+	gobot.Once(d.Event("ramp_complete"), func(data interface{}) {
+		sem <- data
+	})
+	d.RampTo(-100, 20*time.Millisecond)
+	select {
+	case data := <-sem:
+		gobot.Assert(t, data, -100)
+	case <-time.After(time.Second):
+		t.Errorf("ramp_complete was not published")
+	}
+	gobot.Assert(t, d.SignedSpeed(), -100)
+}
+
+func TestMotorDriverRampCancelled(t *testing.T) {
+	d := initTestMotorDriver()
+	d.RampInterval = time.Millisecond
+	d.RampTo(200, 50*time.Millisecond)
+	d.Speed(10)
+	<-time.After(70 * time.Millisecond)
+	gobot.Assert(t, d.SignedSpeed(), 10)
+}
+
+func TestMotorDriverAcceleration(t *testing.T) {
+	d := initTestMotorDriver()
+	d.RampInterval = time.Millisecond
+	d.Acceleration = 2000
+	d.SetSpeed(100)
+	gobot.Assert(t, d.SignedSpeed() < 100, true)
+	<-time.After(100 * time.Millisecond)
+	gobot.Assert(t, d.SignedSpeed(), 100)
+}
+
+func TestMotorDriverSpeedEvent(t *testing.T) {
+	d := initTestMotorDriver()
+	sem := make(chan interface{}, 1)
+	gobot.Once(d.Event("speed"), func(data interface{}) {
+		sem <- data
+	})
+	d.SetSpeed(-30)
+	select {
+	case data := <-sem:
+		gobot.Assert(t, data, -30)
+	case <-time.After(time.Second):
+		t.Errorf("speed was not published")
+	}
+}
+
+func TestMotorDriverCommands(t *testing.T) {
+	d := initTestMotorDriver()
+	d.Command("SetSpeed")(map[string]interface{}{"speed": -20.0})
+	gobot.Assert(t, d.Command("SignedSpeed")(map[string]interface{}{}), -20)
+	d.Command("Stop")(map[string]interface{}{})
+	gobot.Assert(t, d.SignedSpeed(), 0)
+}
@@ -0,0 +1,103 @@
+package gpio
+
+import (
+	"sync"
+
+	"github.com/edmontongo/gobot"
+)
+
+// PIRMotionDriver reports motion seen by a passive infrared sensor, which
+// holds its output high while it sees motion.
+type PIRMotionDriver struct {
+	gobot.Driver
+	Active   bool
+	mutex    sync.Mutex
+	watching bool
+	halt     chan bool
+}
+
+// NewPIRMotionDriver creates a driver for a motion sensor on pin.
+func NewPIRMotionDriver(a DigitalReader, name string, pin string) *PIRMotionDriver {
+	p := &PIRMotionDriver{
+		Driver: *gobot.NewDriver(
+			name,
+			"PIRMotionDriver",
+			a.(gobot.AdaptorInterface),
+			pin,
+		),
+	}
+
+	p.AddEvent("motion_detected")
+	p.AddEvent("motion_stopped")
+
+	p.AddCommand("IsMotion", func(params map[string]interface{}) interface{} {
+		return p.IsMotion()
+	})
+
+	return p
+}
+
+func (p *PIRMotionDriver) adaptor() DigitalReader {
+	return p.Adaptor().(DigitalReader)
+}
+
+// Start watches the sensor with the adaptor's DigitalWatcher when it has
+// one, and otherwise polls it every interval, publishing "motion_detected"
+// when it starts seeing motion and "motion_stopped" when it stops.
+func (p *PIRMotionDriver) Start() bool {
+	if w, ok := p.Adaptor().(DigitalWatcher); ok {
+		if w.WatchDigital(p.Pin(), EdgeBoth, p.change) == nil {
+			p.mutex.Lock()
+			p.watching = true
+			p.mutex.Unlock()
+			return true
+		}
+	}
+	halt := gobot.Poll(p.Interval(), p.poll)
+	p.mutex.Lock()
+	p.halt = halt
+	p.mutex.Unlock()
+	return true
+}
+func (p *PIRMotionDriver) Init() bool { return true }
+
+// Halt stops watching or polling the sensor.
+func (p *PIRMotionDriver) Halt() bool {
+	p.mutex.Lock()
+	watching := p.watching
+	p.watching = false
+	if p.halt != nil {
+		close(p.halt)
+		p.halt = nil
+	}
+	p.mutex.Unlock()
+	if watching {
+		p.Adaptor().(DigitalWatcher).UnwatchDigital(p.Pin())
+	}
+	return true
+}
+
+// IsMotion returns true while the sensor sees motion.
+func (p *PIRMotionDriver) IsMotion() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.Active
+}
+
+func (p *PIRMotionDriver) poll() {
+	p.change(p.adaptor().DigitalRead(p.Pin()))
+}
+
+func (p *PIRMotionDriver) change(value int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	switch {
+	case value == 1 && !p.Active:
+		p.Active = true
+		gobot.Publish(p.Event("motion_detected"), value)
+	case value == 0 && p.Active:
+		p.Active = false
+		gobot.Publish(p.Event("motion_stopped"), value)
+	}
+}
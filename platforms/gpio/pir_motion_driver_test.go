@@ -0,0 +1,72 @@
+package gpio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestPIRMotionDriver() *PIRMotionDriver {
+	return NewPIRMotionDriver(newGpioTestAdaptor("adaptor"), "bot", "1")
+}
+
+func TestPIRMotionDriverStart(t *testing.T) {
+	d := initTestPIRMotionDriver()
+	gobot.Assert(t, d.Start(), true)
+}
+
+func TestPIRMotionDriverHalt(t *testing.T) {
+	d := initTestPIRMotionDriver()
+	gobot.Assert(t, d.Halt(), true)
+}
+
+func TestPIRMotionDriverInit(t *testing.T) {
+	d := initTestPIRMotionDriver()
+	gobot.Assert(t, d.Init(), true)
+}
+
+func TestPIRMotionDriverEvents(t *testing.T) {
+	d := initTestPIRMotionDriver()
+	a := d.adaptor().(*gpioTestAdaptor)
+	sem := make(chan string, 2)
+	gobot.On(d.Event("motion_detected"), func(data interface{}) {
+		sem <- "motion_detected"
+	})
+	gobot.On(d.Event("motion_stopped"), func(data interface{}) {
+		sem <- "motion_stopped"
+	})
+
+	a.setInput("1", 1)
+	d.poll()
+	d.poll()
+	gobot.Assert(t, <-sem, "motion_detected")
+	gobot.Assert(t, d.IsMotion(), true)
+	gobot.Assert(t, d.Command("IsMotion")(map[string]interface{}{}), true)
+
+	a.setInput("1", 0)
+	d.poll()
+	gobot.Assert(t, <-sem, "motion_stopped")
+	gobot.Assert(t, d.IsMotion(), false)
+
+	select {
+	case e := <-sem:
+		t.Errorf("unexpected %v event", e)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestPIRMotionDriverWatch(t *testing.T) {
+	a := newWatchTestAdaptor()
+	a.setInput("1", 0)
+	d := NewPIRMotionDriver(a, "bot", "1")
+	gobot.Assert(t, d.Start(), true)
+	gobot.Assert(t, a.watching("1"), true)
+	gobot.Assert(t, d.IsMotion(), false)
+	a.change("1", 1)
+	gobot.Assert(t, d.IsMotion(), true)
+	a.change("1", 0)
+	gobot.Assert(t, d.IsMotion(), false)
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, a.watching("1"), false)
+}
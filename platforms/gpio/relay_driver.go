@@ -0,0 +1,106 @@
+package gpio
+
+import (
+	"sync"
+
+	"github.com/edmontongo/gobot"
+)
+
+// RelayDriver switches a relay. Many relay boards close the relay when
+// their input is low; set Inverted for those.
+type RelayDriver struct {
+	gobot.Driver
+	Inverted bool
+	mutex    sync.Mutex
+	on       bool
+}
+
+// NewRelayDriver creates a driver for a relay on pin, which is closed when
+// the pin is high.
+func NewRelayDriver(a DigitalWriter, name string, pin string) *RelayDriver {
+	r := &RelayDriver{
+		Driver: *gobot.NewDriver(
+			name,
+			"RelayDriver",
+			a.(gobot.AdaptorInterface),
+			pin,
+		),
+	}
+
+	r.AddEvent("state")
+
+	r.AddCommand("On", func(params map[string]interface{}) interface{} {
+		r.On()
+		return nil
+	})
+	r.AddCommand("Off", func(params map[string]interface{}) interface{} {
+		r.Off()
+		return nil
+	})
+	r.AddCommand("Toggle", func(params map[string]interface{}) interface{} {
+		r.Toggle()
+		return nil
+	})
+	r.AddCommand("IsOn", func(params map[string]interface{}) interface{} {
+		return r.IsOn()
+	})
+
+	return r
+}
+
+func (r *RelayDriver) adaptor() DigitalWriter {
+	return r.Adaptor().(DigitalWriter)
+}
+
+func (r *RelayDriver) Start() bool { return true }
+func (r *RelayDriver) Init() bool  { return true }
+
+// Halt turns the relay off.
+func (r *RelayDriver) Halt() bool {
+	r.Off()
+	return true
+}
+
+// On closes the relay.
+func (r *RelayDriver) On() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.set(true)
+}
+
+// Off opens the relay.
+func (r *RelayDriver) Off() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.set(false)
+}
+
+func (r *RelayDriver) Toggle() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.set(!r.on)
+}
+
+func (r *RelayDriver) IsOn() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.on
+}
+
+func (r *RelayDriver) IsOff() bool {
+	return !r.IsOn()
+}
+
+// set switches the relay, and publishes a "state" event when that changes
+// it.
+func (r *RelayDriver) set(on bool) {
+	level := byte(0)
+	if on != r.Inverted {
+		level = 1
+	}
+	r.adaptor().DigitalWrite(r.Pin(), level)
+	if on != r.on {
+		r.on = on
+		gobot.Publish(r.Event("state"), on)
+	}
+}
@@ -0,0 +1,61 @@
+package gpio
+
+import (
+	"testing"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestRelayDriver() *RelayDriver {
+	return NewRelayDriver(newGpioTestAdaptor("adaptor"), "bot", "1")
+}
+
+func TestRelayDriverStart(t *testing.T) {
+	d := initTestRelayDriver()
+	gobot.Assert(t, d.Start(), true)
+}
+
+func TestRelayDriverHalt(t *testing.T) {
+	d := initTestRelayDriver()
+	d.On()
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, d.IsOff(), true)
+}
+
+func TestRelayDriverInit(t *testing.T) {
+	d := initTestRelayDriver()
+	gobot.Assert(t, d.Init(), true)
+}
+
+func TestRelayDriverOnOff(t *testing.T) {
+	d := initTestRelayDriver()
+	d.On()
+	gobot.Assert(t, d.IsOn(), true)
+	gobot.Assert(t, d.adaptor().(*gpioTestAdaptor).pinValue("1"), 1)
+	d.Toggle()
+	gobot.Assert(t, d.IsOff(), true)
+	gobot.Assert(t, d.adaptor().(*gpioTestAdaptor).pinValue("1"), 0)
+}
+
+func TestRelayDriverInverted(t *testing.T) {
+	d := initTestRelayDriver()
+	d.Inverted = true
+	d.On()
+	gobot.Assert(t, d.adaptor().(*gpioTestAdaptor).pinValue("1"), 0)
+	d.Off()
+	gobot.Assert(t, d.adaptor().(*gpioTestAdaptor).pinValue("1"), 1)
+}
+
+func TestRelayDriverState(t *testing.T) {
+	d := initTestRelayDriver()
+	sem := make(chan interface{}, 1)
+	gobot.Once(d.Event("state"), func(data interface{}) {
+		sem <- data
+	})
+	d.Command("On")(map[string]interface{}{})
+	gobot.Assert(t, <-sem, true)
+	gobot.Assert(t, d.Command("IsOn")(map[string]interface{}{}), true)
+	d.Command("Toggle")(map[string]interface{}{})
+	d.Command("Off")(map[string]interface{}{})
+	gobot.Assert(t, d.Command("IsOn")(map[string]interface{}{}), false)
+}
@@ -0,0 +1,337 @@
+package gpio
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+// RgbLedDriver drives an RGB LED with its red, green and blue legs on PWM
+// pins. Like LedDriver it runs effects every interval of the driver, and
+// any other change to the LED cancels the running effect.
+type RgbLedDriver struct {
+	gobot.Driver
+	RedPin   string
+	GreenPin string
+	BluePin  string
+	// CommonAnode is set for LEDs whose legs share the positive supply, so
+	// that a pin turns its colour on when low.
+	CommonAnode bool
+	mutex       sync.Mutex
+	red         byte
+	green       byte
+	blue        byte
+	// color is the last colour set, which On restores.
+	color  [3]byte
+	effect ledEffect
+}
+
+// NewRgbLedDriver creates a driver for a common cathode RGB LED.
+func NewRgbLedDriver(a Pwm, name string, redPin string, greenPin string, bluePin string) *RgbLedDriver {
+	l := &RgbLedDriver{
+		Driver: *gobot.NewDriver(
+			name,
+			"RgbLedDriver",
+			a.(gobot.AdaptorInterface),
+		),
+		RedPin:   redPin,
+		GreenPin: greenPin,
+		BluePin:  bluePin,
+		color:    [3]byte{255, 255, 255},
+	}
+
+	l.AddEvent("effect_complete")
+
+	l.AddCommand("SetRGB", func(params map[string]interface{}) interface{} {
+		l.SetRGB(byte(params["r"].(float64)), byte(params["g"].(float64)),
+			byte(params["b"].(float64)))
+		return nil
+	})
+	l.AddCommand("SetHSV", func(params map[string]interface{}) interface{} {
+		l.SetHSV(params["h"].(float64), params["s"].(float64), params["v"].(float64))
+		return nil
+	})
+	l.AddCommand("SetHex", func(params map[string]interface{}) interface{} {
+		if err := l.SetHex(params["color"].(string)); err != nil {
+			return err.Error()
+		}
+		return nil
+	})
+	l.AddCommand("RGB", func(params map[string]interface{}) interface{} {
+		r, g, b := l.RGB()
+		return map[string]byte{"r": r, "g": g, "b": b}
+	})
+	l.AddCommand("On", func(params map[string]interface{}) interface{} {
+		l.On()
+		return nil
+	})
+	l.AddCommand("Off", func(params map[string]interface{}) interface{} {
+		l.Off()
+		return nil
+	})
+	l.AddCommand("Toggle", func(params map[string]interface{}) interface{} {
+		l.Toggle()
+		return nil
+	})
+	l.AddCommand("Blink", func(params map[string]interface{}) interface{} {
+		l.Blink(time.Duration(params["period"].(float64))*time.Millisecond,
+			params["duty"].(float64))
+		return nil
+	})
+	l.AddCommand("FadeTo", func(params map[string]interface{}) interface{} {
+		l.FadeTo(byte(params["r"].(float64)), byte(params["g"].(float64)),
+			byte(params["b"].(float64)),
+			time.Duration(params["duration"].(float64))*time.Millisecond)
+		return nil
+	})
+	l.AddCommand("Breathe", func(params map[string]interface{}) interface{} {
+		l.Breathe(time.Duration(params["period"].(float64)) * time.Millisecond)
+		return nil
+	})
+	l.AddCommand("ColorCycle", func(params map[string]interface{}) interface{} {
+		l.ColorCycle(time.Duration(params["period"].(float64)) * time.Millisecond)
+		return nil
+	})
+	l.AddCommand("StopEffect", func(params map[string]interface{}) interface{} {
+		l.StopEffect()
+		return nil
+	})
+
+	return l
+}
+
+func (l *RgbLedDriver) adaptor() Pwm {
+	return l.Adaptor().(Pwm)
+}
+
+func (l *RgbLedDriver) Start() bool { return true }
+func (l *RgbLedDriver) Init() bool  { return true }
+
+// Halt stops the running effect.
+func (l *RgbLedDriver) Halt() bool {
+	l.StopEffect()
+	return true
+}
+
+// SetRGB sets the colour of the LED.
+func (l *RgbLedDriver) SetRGB(r byte, g byte, b byte) {
+	l.effect.stop()
+	l.setRGB(r, g, b)
+}
+
+// SetHSV sets the colour of the LED from a hue in degrees, and a
+// saturation and value from 0 to 1.
+func (l *RgbLedDriver) SetHSV(h float64, s float64, v float64) {
+	l.SetRGB(HSVToRGB(h, s, v))
+}
+
+// SetHex sets the colour of the LED from a hex colour such as "#FF8000" or
+// "f80".
+func (l *RgbLedDriver) SetHex(color string) error {
+	r, g, b, err := ParseHexColor(color)
+	if err != nil {
+		return err
+	}
+	l.SetRGB(r, g, b)
+	return nil
+}
+
+// RGB returns the current colour of the LED.
+func (l *RgbLedDriver) RGB() (r byte, g byte, b byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.red, l.green, l.blue
+}
+
+func (l *RgbLedDriver) IsOn() bool {
+	r, g, b := l.RGB()
+	return r != 0 || g != 0 || b != 0
+}
+
+func (l *RgbLedDriver) IsOff() bool {
+	return !l.IsOn()
+}
+
+// On turns the LED on at the last colour set, white at first.
+func (l *RgbLedDriver) On() {
+	l.mutex.Lock()
+	c := l.color
+	l.mutex.Unlock()
+	l.SetRGB(c[0], c[1], c[2])
+}
+
+// Off turns the LED off. On turns it back on at the same colour.
+func (l *RgbLedDriver) Off() {
+	l.effect.stop()
+	l.write(0, 0, 0)
+}
+
+func (l *RgbLedDriver) Toggle() {
+	if l.IsOn() {
+		l.Off()
+	} else {
+		l.On()
+	}
+}
+
+// Blink turns the LED on at its current colour for duty, from 0 to 1, of
+// every period, and off for the rest, until cancelled.
+func (l *RgbLedDriver) Blink(period time.Duration, duty float64) {
+	l.mutex.Lock()
+	c := l.color
+	l.mutex.Unlock()
+	l.effect.start(l.Interval(), blinkStep(period, duty, func(on bool) {
+		if on {
+			l.write(c[0], c[1], c[2])
+		} else {
+			l.write(0, 0, 0)
+		}
+	}), l.effectComplete)
+}
+
+// FadeTo changes the colour gradually to r, g, b over duration, and
+// publishes an "effect_complete" event when it is done.
+func (l *RgbLedDriver) FadeTo(r byte, g byte, b byte, duration time.Duration) {
+	fr, fg, fb := l.RGB()
+	l.effect.start(l.Interval(), fadeStep(duration, func(t float64) {
+		l.setRGB(scaleLevel(fr, r, t), scaleLevel(fg, g, t), scaleLevel(fb, b, t))
+	}), l.effectComplete)
+}
+
+// Breathe fades the LED smoothly up to its current colour and back down to
+// off every period, until cancelled.
+func (l *RgbLedDriver) Breathe(period time.Duration) {
+	l.mutex.Lock()
+	c := l.color
+	l.mutex.Unlock()
+	l.effect.start(l.Interval(), func(elapsed time.Duration) bool {
+		t := breatheLevel(elapsed, period)
+		l.write(scaleLevel(0, c[0], t), scaleLevel(0, c[1], t), scaleLevel(0, c[2], t))
+		return true
+	}, l.effectComplete)
+}
+
+// ColorCycle turns the hue of the LED through the colour wheel once every
+// period, at full saturation, until cancelled.
+func (l *RgbLedDriver) ColorCycle(period time.Duration) {
+	l.effect.start(l.Interval(), func(elapsed time.Duration) bool {
+		hue := 0.0
+		if period > 0 {
+			hue = 360 * float64(elapsed%period) / float64(period)
+		}
+		l.setRGB(HSVToRGB(hue, 1, 1))
+		return true
+	}, l.effectComplete)
+}
+
+// StopEffect cancels the running effect, leaving the LED as it is.
+func (l *RgbLedDriver) StopEffect() {
+	l.effect.stop()
+}
+
+func (l *RgbLedDriver) effectComplete() {
+	gobot.Publish(l.Event("effect_complete"), l.Name())
+}
+
+// setRGB writes a colour and remembers it for On.
+func (l *RgbLedDriver) setRGB(r byte, g byte, b byte) {
+	l.write(r, g, b)
+	if r != 0 || g != 0 || b != 0 {
+		l.mutex.Lock()
+		l.color = [3]byte{r, g, b}
+		l.mutex.Unlock()
+	}
+}
+
+func (l *RgbLedDriver) write(r byte, g byte, b byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.red, l.green, l.blue = r, g, b
+	if l.CommonAnode {
+		r, g, b = 255-r, 255-g, 255-b
+	}
+	l.adaptor().PwmWrite(l.RedPin, r)
+	l.adaptor().PwmWrite(l.GreenPin, g)
+	l.adaptor().PwmWrite(l.BluePin, b)
+}
+
+// HSVToRGB converts a hue in degrees, and a saturation and value from 0 to
+// 1, to red, green and blue levels.
+func HSVToRGB(h float64, s float64, v float64) (r byte, g byte, b byte) {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	s = math.Max(0, math.Min(1, s))
+	v = math.Max(0, math.Min(1, v))
+
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := v - c
+	var rf, gf, bf float64
+	switch {
+	case h < 60:
+		rf, gf, bf = c, x, 0
+	case h < 120:
+		rf, gf, bf = x, c, 0
+	case h < 180:
+		rf, gf, bf = 0, c, x
+	case h < 240:
+		rf, gf, bf = 0, x, c
+	case h < 300:
+		rf, gf, bf = x, 0, c
+	default:
+		rf, gf, bf = c, 0, x
+	}
+	return byte((rf+m)*255 + 0.5), byte((gf+m)*255 + 0.5), byte((bf+m)*255 + 0.5)
+}
+
+// RGBToHSV converts red, green and blue levels to a hue in degrees, and a
+// saturation and value from 0 to 1.
+func RGBToHSV(r byte, g byte, b byte) (h float64, s float64, v float64) {
+	rf, gf, bf := float64(r)/255, float64(g)/255, float64(b)/255
+	max := math.Max(rf, math.Max(gf, bf))
+	min := math.Min(rf, math.Min(gf, bf))
+	delta := max - min
+
+	v = max
+	if max > 0 {
+		s = delta / max
+	}
+	switch {
+	case delta == 0:
+		h = 0
+	case max == rf:
+		h = 60 * math.Mod((gf-bf)/delta, 6)
+	case max == gf:
+		h = 60 * ((bf-rf)/delta + 2)
+	default:
+		h = 60 * ((rf-gf)/delta + 4)
+	}
+	if h < 0 {
+		h += 360
+	}
+	return h, s, v
+}
+
+// ParseHexColor parses a colour written as "#RRGGBB" or "#RGB", with or
+// without the "#".
+func ParseHexColor(color string) (r byte, g byte, b byte, err error) {
+	hex := strings.TrimPrefix(color, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return 0, 0, 0, fmt.Errorf("invalid hex color %q", color)
+	}
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid hex color %q", color)
+	}
+	return byte(value >> 16), byte(value >> 8), byte(value), nil
+}
@@ -0,0 +1,143 @@
+package gpio
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestRgbLedDriver() *RgbLedDriver {
+	return NewRgbLedDriver(newGpioTestAdaptor("adaptor"), "bot", "1", "2", "3")
+}
+
+func rgbPins(l *RgbLedDriver) []int {
+	a := l.adaptor().(*gpioTestAdaptor)
+	return []int{a.pinValue("1"), a.pinValue("2"), a.pinValue("3")}
+}
+
+func TestRgbLedDriverStart(t *testing.T) {
+	d := initTestRgbLedDriver()
+	gobot.Assert(t, d.Start(), true)
+}
+
+func TestRgbLedDriverHalt(t *testing.T) {
+	d := initTestRgbLedDriver()
+	gobot.Assert(t, d.Halt(), true)
+}
+
+func TestRgbLedDriverInit(t *testing.T) {
+	d := initTestRgbLedDriver()
+	gobot.Assert(t, d.Init(), true)
+}
+
+func TestRgbLedDriverSetRGB(t *testing.T) {
+	d := initTestRgbLedDriver()
+	d.SetRGB(255, 128, 0)
+	gobot.Assert(t, rgbPins(d), []int{255, 128, 0})
+	gobot.Assert(t, d.IsOn(), true)
+
+	d.CommonAnode = true
+	d.SetRGB(255, 128, 0)
+	gobot.Assert(t, rgbPins(d), []int{0, 127, 255})
+	r, g, b := d.RGB()
+	gobot.Assert(t, []byte{r, g, b}, []byte{255, 128, 0})
+}
+
+func TestRgbLedDriverOnOff(t *testing.T) {
+	d := initTestRgbLedDriver()
+	d.On()
+	gobot.Assert(t, rgbPins(d), []int{255, 255, 255})
+	d.SetRGB(10, 20, 30)
+	d.Off()
+	gobot.Assert(t, d.IsOff(), true)
+	gobot.Assert(t, rgbPins(d), []int{0, 0, 0})
+	d.Toggle()
+	gobot.Assert(t, rgbPins(d), []int{10, 20, 30})
+	d.Toggle()
+	gobot.Assert(t, d.IsOff(), true)
+}
+
+func TestRgbLedDriverSetHSVAndHex(t *testing.T) {
+	d := initTestRgbLedDriver()
+	d.SetHSV(120, 1, 1)
+	gobot.Assert(t, rgbPins(d), []int{0, 255, 0})
+	gobot.Assert(t, d.SetHex("#0080ff"), nil)
+	gobot.Assert(t, rgbPins(d), []int{0, 128, 255})
+	gobot.Assert(t, d.SetHex("f80"), nil)
+	gobot.Assert(t, rgbPins(d), []int{255, 136, 0})
+	gobot.Assert(t, d.SetHex("#12345"), errors.New("invalid hex color \"#12345\""))
+}
+
+func TestRgbLedDriverColorConversions(t *testing.T) {
+	r, g, b := HSVToRGB(0, 1, 1)
+	gobot.Assert(t, []byte{r, g, b}, []byte{255, 0, 0})
+	r, g, b = HSVToRGB(-60, 1, 1)
+	gobot.Assert(t, []byte{r, g, b}, []byte{255, 0, 255})
+	r, g, b = HSVToRGB(30, 0, 0.5)
+	gobot.Assert(t, []byte{r, g, b}, []byte{128, 128, 128})
+
+	h, s, v := RGBToHSV(0, 0, 255)
+	gobot.Assert(t, []float64{h, s, v}, []float64{240, 1, 1})
+	h, s, v = RGBToHSV(255, 0, 128)
+	r, g, b = HSVToRGB(h, s, v)
+	gobot.Assert(t, []byte{r, g, b}, []byte{255, 0, 128})
+	h, s, v = RGBToHSV(0, 0, 0)
+	gobot.Assert(t, []float64{h, s, v}, []float64{0, 0, 0})
+}
+
+func TestRgbLedDriverFadeTo(t *testing.T) {
+	d := initTestRgbLedDriver()
+	d.SetInterval(time.Millisecond)
+	done := make(chan bool, 1)
+	gobot.Once(d.Event("effect_complete"), func(data interface{}) {
+		done <- true
+	})
+	d.FadeTo(100, 0, 50, 20*time.Millisecond)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("effect_complete was not published")
+	}
+	gobot.Assert(t, rgbPins(d), []int{100, 0, 50})
+}
+
+func TestRgbLedDriverEffects(t *testing.T) {
+	d := initTestRgbLedDriver()
+	d.SetInterval(time.Millisecond)
+	d.SetRGB(0, 0, 200)
+	d.Blink(time.Hour, 0.5)
+	gobot.Assert(t, rgbPins(d), []int{0, 0, 200})
+	d.Breathe(time.Hour)
+	gobot.Assert(t, rgbPins(d), []int{0, 0, 0})
+	d.ColorCycle(time.Hour)
+	gobot.Assert(t, rgbPins(d), []int{255, 0, 0})
+	d.StopEffect()
+	d.SetRGB(1, 2, 3)
+	<-time.After(5 * time.Millisecond)
+	gobot.Assert(t, rgbPins(d), []int{1, 2, 3})
+}
+
+func TestRgbLedDriverCommands(t *testing.T) {
+	d := initTestRgbLedDriver()
+	d.Command("SetRGB")(map[string]interface{}{"r": 1.0, "g": 2.0, "b": 3.0})
+	gobot.Assert(t, d.Command("RGB")(map[string]interface{}{}),
+		map[string]byte{"r": 1, "g": 2, "b": 3})
+	d.Command("SetHSV")(map[string]interface{}{"h": 0.0, "s": 1.0, "v": 1.0})
+	gobot.Assert(t, rgbPins(d), []int{255, 0, 0})
+	gobot.Assert(t, d.Command("SetHex")(map[string]interface{}{"color": "#000102"}), nil)
+	gobot.Assert(t, d.Command("SetHex")(map[string]interface{}{"color": "nope"}),
+		"invalid hex color \"nope\"")
+	d.Command("FadeTo")(map[string]interface{}{"r": 9.0, "g": 8.0, "b": 7.0, "duration": 0.0})
+	gobot.Assert(t, rgbPins(d), []int{9, 8, 7})
+	d.Command("Off")(map[string]interface{}{})
+	gobot.Assert(t, d.IsOff(), true)
+	d.Command("On")(map[string]interface{}{})
+	d.Command("Toggle")(map[string]interface{}{})
+	gobot.Assert(t, d.IsOff(), true)
+	d.Command("Blink")(map[string]interface{}{"period": 1000.0, "duty": 0.5})
+	d.Command("Breathe")(map[string]interface{}{"period": 1000.0})
+	d.Command("ColorCycle")(map[string]interface{}{"period": 1000.0})
+	d.Command("StopEffect")(map[string]interface{}{})
+}
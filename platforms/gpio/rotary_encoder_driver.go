@@ -0,0 +1,181 @@
+package gpio
+
+import (
+	"sync"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+// quadratureSteps gives the step, -1, 0 or 1, for each change of the two
+// encoder pins, indexed by the previous and current pin states.
+var quadratureSteps = [16]int{
+	0, -1, 1, 0,
+	1, 0, 0, -1,
+	-1, 0, 0, 1,
+	0, 1, -1, 0,
+}
+
+// RotaryEncoderDriver counts the turns of a quadrature rotary encoder by
+// polling its A and B pins.
+type RotaryEncoderDriver struct {
+	gobot.Driver
+	PinA string
+	PinB string
+	// StepsPerDetent is the number of pin changes per click of the knob.
+	// It is 4 for most encoders.
+	StepsPerDetent int
+	mutex          sync.Mutex
+	state          int
+	steps          int
+	position       int
+	watching       bool
+	levels         [2]int
+	halt           chan bool
+}
+
+// NewRotaryEncoderDriver creates a driver for an encoder on pins A and B.
+// The position counts up when the knob is turned clockwise, for the usual
+// wiring.
+func NewRotaryEncoderDriver(a DigitalReader, name string, pinA string, pinB string) *RotaryEncoderDriver {
+	r := &RotaryEncoderDriver{
+		Driver: *gobot.NewDriver(
+			name,
+			"RotaryEncoderDriver",
+			a.(gobot.AdaptorInterface),
+			pinA,
+			// 200 polls a second keep up with a knob turned by hand
+			5*time.Millisecond,
+		),
+		PinA:           pinA,
+		PinB:           pinB,
+		StepsPerDetent: 4,
+		state:          -1,
+	}
+
+	r.AddEvent("position")
+
+	r.AddCommand("Position", func(params map[string]interface{}) interface{} {
+		return r.Position()
+	})
+	r.AddCommand("Reset", func(params map[string]interface{}) interface{} {
+		r.Reset()
+		return nil
+	})
+
+	return r
+}
+
+func (r *RotaryEncoderDriver) adaptor() DigitalReader {
+	return r.Adaptor().(DigitalReader)
+}
+
+// Start watches the encoder with the adaptor's DigitalWatcher when it has
+// one, and otherwise polls it every interval, publishing a "position" event
+// with the new position whenever it changes.
+func (r *RotaryEncoderDriver) Start() bool {
+	if w, ok := r.Adaptor().(DigitalWatcher); ok {
+		r.mutex.Lock()
+		r.watching = true
+		r.levels = [2]int{-1, -1}
+		r.mutex.Unlock()
+		if w.WatchDigital(r.PinA, EdgeBoth, func(level int) { r.change(0, level) }) == nil &&
+			w.WatchDigital(r.PinB, EdgeBoth, func(level int) { r.change(1, level) }) == nil {
+			return true
+		}
+		r.Halt()
+	}
+	halt := gobot.Poll(r.Interval(), r.poll)
+	r.mutex.Lock()
+	r.halt = halt
+	r.mutex.Unlock()
+	return true
+}
+func (r *RotaryEncoderDriver) Init() bool { return true }
+
+// Halt stops watching or polling the encoder.
+func (r *RotaryEncoderDriver) Halt() bool {
+	r.mutex.Lock()
+	watching := r.watching
+	r.watching = false
+	if r.halt != nil {
+		close(r.halt)
+		r.halt = nil
+	}
+	r.mutex.Unlock()
+	if watching {
+		w := r.Adaptor().(DigitalWatcher)
+		w.UnwatchDigital(r.PinA)
+		w.UnwatchDigital(r.PinB)
+	}
+	return true
+}
+
+// Position returns the number of detents turned since the driver was
+// created or reset, negative when turned anticlockwise.
+func (r *RotaryEncoderDriver) Position() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.position
+}
+
+// Reset makes the current position 0.
+func (r *RotaryEncoderDriver) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.steps = 0
+	r.position = 0
+}
+
+func (r *RotaryEncoderDriver) poll() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	a := r.adaptor().DigitalRead(r.PinA)
+	b := r.adaptor().DigitalRead(r.PinB)
+	if a == -1 || b == -1 {
+		return
+	}
+	r.update(a<<1 | b)
+}
+
+// change handles a change of pin A, 0, or B, 1, reported by the adaptor's
+// DigitalWatcher.
+func (r *RotaryEncoderDriver) change(pin int, level int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.watching {
+		return
+	}
+	r.levels[pin] = level
+	if r.levels[0] == -1 || r.levels[1] == -1 {
+		return
+	}
+	r.update(r.levels[0]<<1 | r.levels[1])
+}
+
+// update counts the step from the last pin state to state, and publishes
+// the position when a detent is passed.
+func (r *RotaryEncoderDriver) update(state int) {
+	if r.state == -1 {
+		r.state = state
+		return
+	}
+	r.steps += quadratureSteps[r.state<<2|state]
+	r.state = state
+
+	perDetent := r.StepsPerDetent
+	if perDetent < 1 {
+		perDetent = 1
+	}
+	// round towards minus infinity, so each detent is as wide either side
+	// of 0
+	position := r.steps / perDetent
+	if r.steps%perDetent < 0 {
+		position--
+	}
+	if position != r.position {
+		r.position = position
+		gobot.Publish(r.Event("position"), position)
+	}
+}
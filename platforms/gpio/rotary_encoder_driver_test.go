@@ -0,0 +1,89 @@
+package gpio
+
+import (
+	"testing"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestRotaryEncoderDriver() *RotaryEncoderDriver {
+	return NewRotaryEncoderDriver(newGpioTestAdaptor("adaptor"), "bot", "1", "2")
+}
+
+// turnEncoder steps the pins of the encoder through the quadrature states.
+func turnEncoder(d *RotaryEncoderDriver, states ...int) {
+	a := d.adaptor().(*gpioTestAdaptor)
+	for _, state := range states {
+		a.setInput("1", state>>1)
+		a.setInput("2", state&1)
+		d.poll()
+	}
+}
+
+func TestRotaryEncoderDriverStart(t *testing.T) {
+	d := initTestRotaryEncoderDriver()
+	gobot.Assert(t, d.Start(), true)
+}
+
+func TestRotaryEncoderDriverHalt(t *testing.T) {
+	d := initTestRotaryEncoderDriver()
+	gobot.Assert(t, d.Halt(), true)
+}
+
+func TestRotaryEncoderDriverInit(t *testing.T) {
+	d := initTestRotaryEncoderDriver()
+	gobot.Assert(t, d.Init(), true)
+}
+
+func TestRotaryEncoderDriverClockwise(t *testing.T) {
+	d := initTestRotaryEncoderDriver()
+	sem := make(chan interface{}, 1)
+	gobot.Once(d.Event("position"), func(data interface{}) {
+		sem <- data
+	})
+	turnEncoder(d, 0, 2, 3, 1)
+	gobot.Assert(t, d.Position(), 0)
+	turnEncoder(d, 0)
+	gobot.Assert(t, d.Position(), 1)
+	gobot.Assert(t, <-sem, 1)
+	turnEncoder(d, 2, 3, 1, 0)
+	gobot.Assert(t, d.Position(), 2)
+}
+
+func TestRotaryEncoderDriverAnticlockwise(t *testing.T) {
+	d := initTestRotaryEncoderDriver()
+	turnEncoder(d, 0, 1)
+	gobot.Assert(t, d.Position(), -1)
+	turnEncoder(d, 3, 2, 0)
+	gobot.Assert(t, d.Position(), -1)
+	// bouncing back and forth does not count
+	turnEncoder(d, 1, 0, 1, 0)
+	gobot.Assert(t, d.Position(), -1)
+}
+
+func TestRotaryEncoderDriverStepsPerDetent(t *testing.T) {
+	d := initTestRotaryEncoderDriver()
+	d.StepsPerDetent = 1
+	turnEncoder(d, 0, 2, 3)
+	gobot.Assert(t, d.Position(), 2)
+	d.Command("Reset")(map[string]interface{}{})
+	gobot.Assert(t, d.Command("Position")(map[string]interface{}{}), 0)
+}
+
+func TestRotaryEncoderDriverWatch(t *testing.T) {
+	a := newWatchTestAdaptor()
+	a.setInput("1", 0)
+	a.setInput("2", 0)
+	d := NewRotaryEncoderDriver(a, "bot", "1", "2")
+	gobot.Assert(t, d.Start(), true)
+	gobot.Assert(t, a.watching("1") && a.watching("2"), true)
+	// one detent clockwise, from 00 through 10, 11 and 01
+	a.change("1", 1)
+	a.change("2", 1)
+	a.change("1", 0)
+	gobot.Assert(t, d.Position(), 0)
+	a.change("2", 0)
+	gobot.Assert(t, d.Position(), 1)
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, a.watching("1") || a.watching("2"), false)
+}
@@ -1,12 +1,45 @@
 package gpio
 
 import (
+	"math"
+	"sync"
+	"time"
+
 	"github.com/edmontongo/gobot"
 )
 
+// Easing functions map the fraction of a move's duration that has passed,
+// from 0 to 1, to the fraction of the distance covered.
+var (
+	// EaseLinear moves at a constant speed.
+	EaseLinear = func(t float64) float64 { return t }
+	// EaseInOut speeds up at the start of a move and slows down at the end.
+	EaseInOut = func(t float64) float64 { return (1 - math.Cos(t*math.Pi)) / 2 }
+)
+
+// ServoDriver drives a hobby servo. MinPulse and MaxPulse are the pulse
+// widths that move it to MinAngle and MaxAngle, 544µs at 0 degrees and
+// 2400µs at 180 degrees by default, as in the Arduino Servo library.
 type ServoDriver struct {
 	gobot.Driver
 	CurrentAngle byte
+	MinPulse     time.Duration
+	MaxPulse     time.Duration
+	MinAngle     float64
+	MaxAngle     float64
+	// Easing shapes the motion of MoveTo and Sweep. It is EaseLinear by
+	// default.
+	Easing func(float64) float64
+	// StepInterval is how often the position is updated during a move.
+	StepInterval time.Duration
+	// Period is the time between pulses, 20ms by default, on adaptors with
+	// a PwmController that can set it.
+	Period   time.Duration
+	mutex    sync.Mutex
+	period   time.Duration
+	angle    float64
+	rotating bool
+	motion   int
 }
 
 func NewServoDriver(a Servo, name string, pin string) *ServoDriver {
@@ -18,8 +51,17 @@ func NewServoDriver(a Servo, name string, pin string) *ServoDriver {
 			pin,
 		),
 		CurrentAngle: 0,
+		MinPulse:     DefaultServoMinPulse,
+		MaxPulse:     DefaultServoMaxPulse,
+		MinAngle:     0,
+		MaxAngle:     180,
+		Easing:       EaseLinear,
+		StepInterval: 20 * time.Millisecond,
+		Period:       20 * time.Millisecond,
 	}
 
+	s.AddEvent("move_complete")
+
 	s.AddCommand("Move", func(params map[string]interface{}) interface{} {
 		angle := byte(params["angle"].(float64))
 		s.Move(angle)
@@ -37,43 +79,229 @@ func NewServoDriver(a Servo, name string, pin string) *ServoDriver {
 		s.Max()
 		return nil
 	})
+	s.AddCommand("MoveTo", func(params map[string]interface{}) interface{} {
+		s.MoveTo(params["angle"].(float64),
+			time.Duration(params["duration"].(float64))*time.Millisecond)
+		return nil
+	})
+	s.AddCommand("Sweep", func(params map[string]interface{}) interface{} {
+		s.Sweep(params["from"].(float64), params["to"].(float64),
+			time.Duration(params["period"].(float64))*time.Millisecond)
+		return nil
+	})
+	s.AddCommand("Rotate", func(params map[string]interface{}) interface{} {
+		s.Rotate(params["speed"].(float64))
+		return nil
+	})
+	s.AddCommand("Stop", func(params map[string]interface{}) interface{} {
+		s.Stop()
+		return nil
+	})
 
 	return s
 
 }
 
+// NewContinuousServoDriver creates a driver for a continuous rotation
+// servo, which is driven with Rotate and stops at the center pulse.
+func NewContinuousServoDriver(a Servo, name string, pin string) *ServoDriver {
+	s := NewServoDriver(a, name, pin)
+	s.MinPulse = 1000 * time.Microsecond
+	s.MaxPulse = 2000 * time.Microsecond
+	return s
+}
+
 func (s *ServoDriver) adaptor() Servo {
 	return s.Adaptor().(Servo)
 }
 
 func (s *ServoDriver) Start() bool { return true }
-func (s *ServoDriver) Halt() bool  { return true }
 func (s *ServoDriver) Init() bool  { return true }
 
+// Halt stops any motion, and a continuous rotation servo.
+func (s *ServoDriver) Halt() bool {
+	s.Stop()
+	return true
+}
+
 func (s *ServoDriver) InitServo() {
 	s.adaptor().InitServo()
 }
 
+// Move moves the servo to angle at once. Angles outside MinAngle and
+// MaxAngle are limited to the range.
 func (s *ServoDriver) Move(angle uint8) {
-	if !(angle >= 0 && angle <= 180) {
-		panic("Servo angle must be an integer between 0-180")
-	}
-	s.CurrentAngle = angle
-	s.adaptor().ServoWrite(s.Pin(), s.angleToSpan(angle))
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.motion++
+	s.move(float64(angle))
 }
 
 func (s *ServoDriver) Min() {
-	s.Move(0)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.motion++
+	s.move(s.MinAngle)
 }
 
 func (s *ServoDriver) Center() {
-	s.Move(90)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.motion++
+	s.move((s.MinAngle + s.MaxAngle) / 2)
 }
 
 func (s *ServoDriver) Max() {
-	s.Move(180)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.motion++
+	s.move(s.MaxAngle)
+}
+
+// Angle returns the current angle of the servo.
+func (s *ServoDriver) Angle() float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.angle
+}
+
+// MoveTo moves the servo to angle over duration, following Easing. A
+// "move_complete" event is published when it gets there. Any other move
+// cancels it.
+func (s *ServoDriver) MoveTo(angle float64, duration time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.motion++
+	motion := s.motion
+	from := s.angle
+	if duration <= 0 || s.StepInterval <= 0 {
+		s.move(angle)
+		gobot.Publish(s.Event("move_complete"), s.angle)
+		return
+	}
+	go func() {
+		if s.animate(motion, from, angle, duration) {
+			gobot.Publish(s.Event("move_complete"), s.Angle())
+		}
+	}()
+}
+
+// Sweep moves the servo back and forth between from and to, taking period
+// for each round trip, until another move or Stop.
+func (s *ServoDriver) Sweep(from float64, to float64, period time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.motion++
+	motion := s.motion
+	s.move(from)
+	if period <= 0 || s.StepInterval <= 0 {
+		return
+	}
+	go func() {
+		for s.animate(motion, from, to, period/2) &&
+			s.animate(motion, to, from, period/2) {
+		}
+	}()
+}
+
+// Rotate turns a continuous rotation servo at speed, from -1 to 1. The
+// servo stops at 0, the pulse half way between MinPulse and MaxPulse.
+func (s *ServoDriver) Rotate(speed float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.motion++
+	speed = math.Max(-1, math.Min(1, speed))
+	s.rotating = speed != 0
+	s.move(s.MinAngle + (speed+1)/2*(s.MaxAngle-s.MinAngle))
+}
+
+// Stop cancels MoveTo and Sweep, and stops a continuous rotation servo.
+func (s *ServoDriver) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.motion++
+	if s.rotating {
+		s.rotating = false
+		s.move((s.MinAngle + s.MaxAngle) / 2)
+	}
+}
+
+// animate moves from one angle to another over duration, and returns
+// false if it was cancelled by another motion.
+func (s *ServoDriver) animate(motion int, from float64, to float64, duration time.Duration) bool {
+	s.mutex.Lock()
+	interval := s.StepInterval
+	easing := s.Easing
+	s.mutex.Unlock()
+	if easing == nil {
+		easing = EaseLinear
+	}
+
+	began := time.Now()
+	for {
+		<-time.After(interval)
+		elapsed := time.Since(began)
+		t := math.Min(1, float64(elapsed)/float64(duration))
+
+		s.mutex.Lock()
+		if s.motion != motion {
+			s.mutex.Unlock()
+			return false
+		}
+		s.move(from + (to-from)*easing(t))
+		s.mutex.Unlock()
+
+		if t >= 1 {
+			return true
+		}
+	}
+}
+
+func (s *ServoDriver) move(angle float64) {
+	angle = math.Max(math.Min(s.MinAngle, s.MaxAngle),
+		math.Min(math.Max(s.MinAngle, s.MaxAngle), angle))
+	s.angle = angle
+	s.CurrentAngle = byte(math.Min(255, math.Max(0, angle)) + 0.5)
+
+	pulse := s.angleToPulse(angle)
+	if s.pwmWrite(pulse) {
+		return
+	}
+	if p, ok := s.Adaptor().(ServoPulseWriter); ok {
+		p.ServoPulseWrite(s.Pin(), pulse)
+	} else {
+		s.adaptor().ServoWrite(s.Pin(), s.angleToSpan(pulse))
+	}
+}
+
+// pwmWrite sends pulse with the adaptor's PwmController, and returns false
+// if it has none or cannot set Period on the pin.
+func (s *ServoDriver) pwmWrite(pulse time.Duration) bool {
+	p, ok := s.Adaptor().(PwmController)
+	if !ok || s.Period <= 0 {
+		return false
+	}
+	if s.period != s.Period {
+		if p.PwmSetPeriod(s.Pin(), s.Period) != nil {
+			return false
+		}
+		s.period = s.Period
+	}
+	return p.PwmSetDutyCycle(s.Pin(), float64(pulse)/float64(s.Period)) == nil
+}
+
+func (s *ServoDriver) angleToPulse(angle float64) time.Duration {
+	if s.MaxAngle == s.MinAngle {
+		return s.MinPulse
+	}
+	scale := (angle - s.MinAngle) / (s.MaxAngle - s.MinAngle)
+	return s.MinPulse + time.Duration(scale*float64(s.MaxPulse-s.MinPulse))
 }
 
-func (s *ServoDriver) angleToSpan(angle byte) byte {
-	return byte(angle * (255 / 180))
+// angleToSpan converts pulse to the angle from 0 to 180 that ServoWrite
+// expects, which adaptors map to the default pulse range.
+func (s *ServoDriver) angleToSpan(pulse time.Duration) byte {
+	span := float64(pulse-DefaultServoMinPulse) /
+		float64(DefaultServoMaxPulse-DefaultServoMinPulse) * 180
+	return byte(math.Max(0, math.Min(180, span)) + 0.5)
 }
@@ -1,8 +1,11 @@
 package gpio
 
 import (
-	"github.com/edmontongo/gobot"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
 )
 
 func initTestServoDriver() *ServoDriver {
@@ -30,6 +33,155 @@ func TestServoDriverMove(t *testing.T) {
 	gobot.Assert(t, d.CurrentAngle, uint8(100))
 }
 
+func TestServoDriverMoveOutOfRange(t *testing.T) {
+	d := initTestServoDriver()
+	d.Move(200)
+	gobot.Assert(t, d.CurrentAngle, uint8(180))
+	gobot.Assert(t, d.adaptor().(*gpioTestAdaptor).pinValue("1"), 180)
+
+	d.MinAngle = 20
+	d.MaxAngle = 160
+	d.Move(10)
+	gobot.Assert(t, d.Angle(), 20.0)
+}
+
+func TestServoDriverAngleToSpan(t *testing.T) {
+	d := initTestServoDriver()
+	for _, angle := range []uint8{0, 1, 45, 90, 179, 180} {
+		d.Move(angle)
+		gobot.Assert(t, d.adaptor().(*gpioTestAdaptor).pinValue("1"), int(angle))
+	}
+
+	d.MaxAngle = 90
+	d.Move(45)
+	gobot.Assert(t, d.adaptor().(*gpioTestAdaptor).pinValue("1"), 90)
+}
+
+func TestServoDriverPulseRange(t *testing.T) {
+	d := initTestServoDriver()
+	d.MinPulse = 1000 * time.Microsecond
+	d.MaxPulse = 2000 * time.Microsecond
+	d.Min()
+	gobot.Assert(t, d.adaptor().(*gpioTestAdaptor).pinValue("1"), 44)
+	d.Max()
+	gobot.Assert(t, d.adaptor().(*gpioTestAdaptor).pinValue("1"), 141)
+}
+
+func TestServoDriverPulseWriter(t *testing.T) {
+	a := newServoPulseTestAdaptor()
+	d := NewServoDriver(a, "bot", "1")
+	d.Center()
+	gobot.Assert(t, a.lastPulse(), 1472*time.Microsecond)
+	d.MinPulse = 1000 * time.Microsecond
+	d.MaxPulse = 2000 * time.Microsecond
+	d.Move(180)
+	gobot.Assert(t, a.lastPulse(), 2000*time.Microsecond)
+}
+
+func TestServoDriverPwmController(t *testing.T) {
+	a := newPwmTestAdaptor()
+	d := NewServoDriver(a, "bot", "1")
+	d.MinPulse = 1000 * time.Microsecond
+	d.MaxPulse = 2000 * time.Microsecond
+	d.Center()
+	gobot.Assert(t, a.period("1"), 20*time.Millisecond)
+	gobot.Assert(t, a.duty("1"), 0.075)
+	gobot.Assert(t, a.pinValue("1"), -1)
+
+	d.Period = 10 * time.Millisecond
+	d.Max()
+	gobot.Assert(t, a.period("1"), 10*time.Millisecond)
+	gobot.Assert(t, a.duty("1"), 0.2)
+}
+
+func TestServoDriverPwmControllerUnsupportedPeriod(t *testing.T) {
+	a := newPwmTestAdaptor()
+	a.maxPeriod = 10 * time.Millisecond
+	d := NewServoDriver(a, "bot", "1")
+	d.Max()
+	gobot.Assert(t, a.duty("1"), -1.0)
+	gobot.Assert(t, a.pinValue("1"), 180)
+}
+
+func TestServoDriverMoveTo(t *testing.T) {
+	d := initTestServoDriver()
+	d.StepInterval = time.Millisecond
+	done := make(chan interface{}, 1)
+	gobot.Once(d.Event("move_complete"), func(data interface{}) {
+		done <- data
+	})
+	d.MoveTo(120, 20*time.Millisecond)
+	select {
+	case angle := <-done:
+		gobot.Assert(t, angle, 120.0)
+	case <-time.After(time.Second):
+		t.Errorf("move_complete was not published")
+	}
+	gobot.Assert(t, d.CurrentAngle, uint8(120))
+}
+
+func TestServoDriverMoveToImmediate(t *testing.T) {
+	d := initTestServoDriver()
+	d.MoveTo(30, 0)
+	gobot.Assert(t, d.Angle(), 30.0)
+}
+
+func TestServoDriverEasing(t *testing.T) {
+	gobot.Assert(t, EaseLinear(0.25), 0.25)
+	gobot.Assert(t, EaseInOut(0), 0.0)
+	gobot.Assert(t, EaseInOut(0.5) > 0.49 && EaseInOut(0.5) < 0.51, true)
+	gobot.Assert(t, EaseInOut(1), 1.0)
+}
+
+func TestServoDriverSweepStop(t *testing.T) {
+	d := initTestServoDriver()
+	d.StepInterval = time.Millisecond
+	d.Sweep(0, 180, 40*time.Millisecond)
+	<-time.After(30 * time.Millisecond)
+	d.Stop()
+	angle := d.Angle()
+	<-time.After(20 * time.Millisecond)
+	gobot.Assert(t, d.Angle(), angle)
+}
+
+func TestServoDriverMoveCancelsMoveTo(t *testing.T) {
+	d := initTestServoDriver()
+	d.StepInterval = time.Millisecond
+	d.MoveTo(180, 50*time.Millisecond)
+	d.Move(10)
+	<-time.After(70 * time.Millisecond)
+	gobot.Assert(t, d.Angle(), 10.0)
+}
+
+func TestContinuousServoDriver(t *testing.T) {
+	a := newServoPulseTestAdaptor()
+	d := NewContinuousServoDriver(a, "bot", "1")
+	d.Rotate(1)
+	gobot.Assert(t, a.lastPulse(), 2000*time.Microsecond)
+	d.Rotate(-0.5)
+	gobot.Assert(t, a.lastPulse(), 1250*time.Microsecond)
+	d.Stop()
+	gobot.Assert(t, a.lastPulse(), 1500*time.Microsecond)
+	d.Rotate(2)
+	gobot.Assert(t, a.lastPulse(), 2000*time.Microsecond)
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, a.lastPulse(), 1500*time.Microsecond)
+}
+
+func TestServoDriverCommands(t *testing.T) {
+	d := initTestServoDriver()
+	d.Command("Move")(map[string]interface{}{"angle": 45.0})
+	gobot.Assert(t, d.CurrentAngle, uint8(45))
+	d.Command("Max")(map[string]interface{}{})
+	gobot.Assert(t, d.CurrentAngle, uint8(180))
+	d.Command("MoveTo")(map[string]interface{}{"angle": 60.0, "duration": 0.0})
+	gobot.Assert(t, d.CurrentAngle, uint8(60))
+	d.Command("Rotate")(map[string]interface{}{"speed": -1.0})
+	gobot.Assert(t, d.CurrentAngle, uint8(0))
+	d.Command("Stop")(map[string]interface{}{})
+	gobot.Assert(t, d.CurrentAngle, uint8(90))
+}
+
 func TestServoDriverMin(t *testing.T) {
 	d := initTestServoDriver()
 	d.Min()
@@ -52,3 +204,28 @@ func TestServoDriverInitServo(t *testing.T) {
 	d := initTestServoDriver()
 	d.InitServo()
 }
+
+type servoPulseTestAdaptor struct {
+	*gpioTestAdaptor
+	mutex  sync.Mutex
+	pulses []time.Duration
+}
+
+func newServoPulseTestAdaptor() *servoPulseTestAdaptor {
+	return &servoPulseTestAdaptor{gpioTestAdaptor: newGpioTestAdaptor("adaptor")}
+}
+
+func (t *servoPulseTestAdaptor) ServoPulseWrite(pin string, pulse time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.pulses = append(t.pulses, pulse)
+}
+
+func (t *servoPulseTestAdaptor) lastPulse() time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if len(t.pulses) == 0 {
+		return 0
+	}
+	return t.pulses[len(t.pulses)-1]
+}
@@ -0,0 +1,226 @@
+package gpio
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+// sevenSegmentFont gives the segments lit for each character, with bit 0
+// for segment a through bit 6 for segment g.
+var sevenSegmentFont = map[rune]byte{
+	'0': 0x3F, '1': 0x06, '2': 0x5B, '3': 0x4F, '4': 0x66,
+	'5': 0x6D, '6': 0x7D, '7': 0x07, '8': 0x7F, '9': 0x6F,
+	'A': 0x77, 'B': 0x7C, 'C': 0x39, 'D': 0x5E, 'E': 0x79,
+	'F': 0x71, 'G': 0x3D, 'H': 0x76, 'I': 0x30, 'J': 0x1E,
+	'L': 0x38, 'N': 0x54, 'O': 0x5C, 'P': 0x73, 'R': 0x50,
+	'S': 0x6D, 'T': 0x78, 'U': 0x3E, 'Y': 0x6E,
+	'-': 0x40, '_': 0x08, ' ': 0x00,
+}
+
+// sevenSegmentPoint is the decimal point segment.
+const sevenSegmentPoint byte = 0x80
+
+// SevenSegmentDriver drives a seven segment display of one or more digits,
+// with the segments a to g, and optionally the decimal point, on
+// SegmentPins. Displays of more than one digit share the segment pins and
+// select each digit with one of DigitPins, so the driver lights one digit
+// at a time, moving to the next every interval once started.
+type SevenSegmentDriver struct {
+	gobot.Driver
+	SegmentPins []string
+	DigitPins   []string
+	// CommonAnode is set for displays lit by pulling the segment pins low,
+	// and selected by pulling the digit pins high.
+	CommonAnode bool
+	mutex       sync.Mutex
+	digits      []byte
+	current     int
+	running     bool
+	halt        chan bool
+}
+
+// NewSevenSegmentDriver creates a driver for a common cathode display with
+// the segment pins a to g and, optionally, dp. The digit pins, leftmost
+// first, are only needed for multiplexed displays of more than one digit.
+func NewSevenSegmentDriver(a DigitalWriter, name string, segmentPins []string, digitPins ...string) *SevenSegmentDriver {
+	digits := len(digitPins)
+	if digits == 0 {
+		digits = 1
+	}
+	s := &SevenSegmentDriver{
+		Driver: *gobot.NewDriver(
+			name,
+			"SevenSegmentDriver",
+			a.(gobot.AdaptorInterface),
+			segmentPins[0],
+			// each of 4 digits lit 50 times a second
+			5*time.Millisecond,
+		),
+		SegmentPins: segmentPins,
+		DigitPins:   digitPins,
+		digits:      make([]byte, digits),
+		halt:        make(chan bool),
+	}
+
+	s.AddCommand("Display", func(params map[string]interface{}) interface{} {
+		s.Display(params["text"].(string))
+		return nil
+	})
+	s.AddCommand("DisplayNumber", func(params map[string]interface{}) interface{} {
+		s.DisplayNumber(params["value"].(float64), int(params["decimals"].(float64)))
+		return nil
+	})
+	s.AddCommand("Clear", func(params map[string]interface{}) interface{} {
+		s.Clear()
+		return nil
+	})
+
+	return s
+}
+
+func (s *SevenSegmentDriver) adaptor() DigitalWriter {
+	return s.Adaptor().(DigitalWriter)
+}
+
+// Start lights the digits in turn every interval, for displays with digit
+// pins.
+func (s *SevenSegmentDriver) Start() bool {
+	if len(s.DigitPins) == 0 {
+		return true
+	}
+	s.mutex.Lock()
+	s.running = true
+	s.mutex.Unlock()
+	go func() {
+		for {
+			select {
+			case <-s.halt:
+				return
+			case <-time.After(s.Interval()):
+				s.refresh()
+			}
+		}
+	}()
+	return true
+}
+func (s *SevenSegmentDriver) Init() bool { return true }
+
+// Halt stops lighting the digits.
+func (s *SevenSegmentDriver) Halt() bool {
+	s.mutex.Lock()
+	running := s.running
+	s.running = false
+	s.mutex.Unlock()
+	if running {
+		s.halt <- true
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i := range s.digits {
+		s.digits[i] = 0
+	}
+	s.writeSegments(0)
+	for i := range s.DigitPins {
+		s.selectDigit(i, false)
+	}
+	return true
+}
+
+// Display shows text, left aligned. A "." lights the decimal point of the
+// character before it. Characters the display cannot show are left blank,
+// and characters beyond the last digit are dropped.
+func (s *SevenSegmentDriver) Display(text string) {
+	digits := make([]byte, len(s.digits))
+	i := -1
+	for _, c := range strings.ToUpper(text) {
+		if c == '.' && i >= 0 && digits[i]&sevenSegmentPoint == 0 {
+			digits[i] |= sevenSegmentPoint
+			continue
+		}
+		i++
+		if i >= len(digits) {
+			break
+		}
+		if c == '.' {
+			digits[i] = sevenSegmentPoint
+		} else {
+			digits[i] = sevenSegmentFont[c]
+		}
+	}
+	s.SetSegments(digits...)
+}
+
+// DisplayNumber shows value with decimals digits after the point, right
+// aligned.
+func (s *SevenSegmentDriver) DisplayNumber(value float64, decimals int) {
+	text := strconv.FormatFloat(value, 'f', decimals, 64)
+	width := len(s.digits)
+	if strings.Contains(text, ".") {
+		width++
+	}
+	if len(text) < width {
+		text = strings.Repeat(" ", width-len(text)) + text
+	}
+	s.Display(text)
+}
+
+// SetSegments lights the segments of each digit from the left, given as
+// bit 0 for segment a through bit 6 for g and bit 7 for the decimal point.
+func (s *SevenSegmentDriver) SetSegments(digits ...byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i := range s.digits {
+		s.digits[i] = 0
+		if i < len(digits) {
+			s.digits[i] = digits[i]
+		}
+	}
+	if len(s.DigitPins) == 0 {
+		s.writeSegments(s.digits[0])
+	}
+}
+
+// Segments returns the segments lit on each digit.
+func (s *SevenSegmentDriver) Segments() []byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]byte{}, s.digits...)
+}
+
+// Clear turns off every segment.
+func (s *SevenSegmentDriver) Clear() {
+	s.SetSegments()
+}
+
+// refresh turns off the digit that is lit, and lights the next one.
+func (s *SevenSegmentDriver) refresh() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.selectDigit(s.current, false)
+	s.current = (s.current + 1) % len(s.DigitPins)
+	s.writeSegments(s.digits[s.current])
+	s.selectDigit(s.current, true)
+}
+
+func (s *SevenSegmentDriver) writeSegments(segments byte) {
+	for i, pin := range s.SegmentPins {
+		level := (segments >> uint(i)) & 1
+		if s.CommonAnode {
+			level ^= 1
+		}
+		s.adaptor().DigitalWrite(pin, level)
+	}
+}
+
+func (s *SevenSegmentDriver) selectDigit(digit int, on bool) {
+	level := byte(0)
+	if on == s.CommonAnode {
+		level = 1
+	}
+	s.adaptor().DigitalWrite(s.DigitPins[digit], level)
+}
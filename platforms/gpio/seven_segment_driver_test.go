@@ -0,0 +1,90 @@
+package gpio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestSevenSegmentDriver() *SevenSegmentDriver {
+	return NewSevenSegmentDriver(newGpioTestAdaptor("adaptor"), "bot",
+		[]string{"a", "b", "c", "d", "e", "f", "g", "dp"}, "d1", "d2", "d3", "d4")
+}
+
+func TestSevenSegmentDriverStart(t *testing.T) {
+	d := initTestSevenSegmentDriver()
+	gobot.Assert(t, d.Start(), true)
+	gobot.Assert(t, d.Halt(), true)
+}
+
+func TestSevenSegmentDriverHalt(t *testing.T) {
+	d := initTestSevenSegmentDriver()
+	gobot.Assert(t, d.Halt(), true)
+}
+
+func TestSevenSegmentDriverInit(t *testing.T) {
+	d := initTestSevenSegmentDriver()
+	gobot.Assert(t, d.Init(), true)
+}
+
+func TestSevenSegmentDriverDisplay(t *testing.T) {
+	d := initTestSevenSegmentDriver()
+	d.Display("1.2ab")
+	gobot.Assert(t, d.Segments(), []byte{0x86, 0x5B, 0x77, 0x7C})
+	d.Display("..!")
+	gobot.Assert(t, d.Segments(), []byte{0x80, 0x80, 0x00, 0x00})
+	d.Clear()
+	gobot.Assert(t, d.Segments(), []byte{0, 0, 0, 0})
+}
+
+func TestSevenSegmentDriverDisplayNumber(t *testing.T) {
+	d := initTestSevenSegmentDriver()
+	d.DisplayNumber(3.14159, 2)
+	gobot.Assert(t, d.Segments(), []byte{0x00, 0xCF, 0x06, 0x66})
+	d.DisplayNumber(-7, 0)
+	gobot.Assert(t, d.Segments(), []byte{0x00, 0x00, 0x40, 0x07})
+}
+
+func TestSevenSegmentDriverRefresh(t *testing.T) {
+	d := initTestSevenSegmentDriver()
+	a := d.adaptor().(*gpioTestAdaptor)
+	d.Display("8")
+	d.current = 3
+	d.refresh()
+	gobot.Assert(t, a.pinValue("d1"), 0)
+	gobot.Assert(t, a.pinValue("d4"), 1)
+	gobot.Assert(t, a.pinValue("g"), 1)
+	gobot.Assert(t, a.pinValue("dp"), 0)
+	d.refresh()
+	gobot.Assert(t, a.pinValue("d1"), 1)
+	gobot.Assert(t, a.pinValue("d2"), 0)
+	gobot.Assert(t, a.pinValue("g"), 0)
+
+	d.SetInterval(time.Millisecond)
+	d.Start()
+	<-time.After(10 * time.Millisecond)
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, a.pinValue("d1"), 1)
+	gobot.Assert(t, a.pinValue("d2"), 1)
+}
+
+func TestSevenSegmentDriverSingleDigit(t *testing.T) {
+	d := NewSevenSegmentDriver(newGpioTestAdaptor("adaptor"), "bot",
+		[]string{"a", "b", "c", "d", "e", "f", "g"})
+	d.CommonAnode = true
+	d.Display("7")
+	a := d.adaptor().(*gpioTestAdaptor)
+	gobot.Assert(t, a.pinValue("a"), 0)
+	gobot.Assert(t, a.pinValue("d"), 1)
+}
+
+func TestSevenSegmentDriverCommands(t *testing.T) {
+	d := initTestSevenSegmentDriver()
+	d.Command("Display")(map[string]interface{}{"text": "E"})
+	gobot.Assert(t, d.Segments()[0], byte(0x79))
+	d.Command("DisplayNumber")(map[string]interface{}{"value": 42.0, "decimals": 0.0})
+	gobot.Assert(t, d.Segments(), []byte{0x00, 0x00, 0x66, 0x5B})
+	d.Command("Clear")(map[string]interface{}{})
+	gobot.Assert(t, d.Segments(), []byte{0, 0, 0, 0})
+}
@@ -0,0 +1,148 @@
+package gpio
+
+import (
+	"sync"
+
+	"github.com/edmontongo/gobot"
+)
+
+// ShiftRegisterDriver drives a chain of 74HC595 style serial-in,
+// parallel-out shift registers. Outputs are numbered from 0, the Q0 output
+// of the register nearest the board, through 8 per register.
+type ShiftRegisterDriver struct {
+	gobot.Driver
+	DataPin  string
+	ClockPin string
+	LatchPin string
+	// Registers is the number of registers in the chain.
+	Registers int
+	mutex     sync.Mutex
+	outputs   []byte
+}
+
+// NewShiftRegisterDriver creates a driver for a single shift register with
+// its serial data, shift clock and storage latch inputs on the given pins.
+// Set Registers for a chain.
+func NewShiftRegisterDriver(a DigitalWriter, name string, dataPin string, clockPin string, latchPin string) *ShiftRegisterDriver {
+	s := &ShiftRegisterDriver{
+		Driver: *gobot.NewDriver(
+			name,
+			"ShiftRegisterDriver",
+			a.(gobot.AdaptorInterface),
+			dataPin,
+		),
+		DataPin:   dataPin,
+		ClockPin:  clockPin,
+		LatchPin:  latchPin,
+		Registers: 1,
+	}
+
+	s.AddCommand("Write", func(params map[string]interface{}) interface{} {
+		values := []byte{}
+		for _, v := range params["values"].([]interface{}) {
+			values = append(values, byte(v.(float64)))
+		}
+		s.Write(values...)
+		return nil
+	})
+	s.AddCommand("SetOutput", func(params map[string]interface{}) interface{} {
+		s.SetOutput(int(params["output"].(float64)), byte(params["level"].(float64)))
+		return nil
+	})
+	s.AddCommand("Output", func(params map[string]interface{}) interface{} {
+		return s.Output(int(params["output"].(float64)))
+	})
+	s.AddCommand("Clear", func(params map[string]interface{}) interface{} {
+		s.Clear()
+		return nil
+	})
+
+	return s
+}
+
+func (s *ShiftRegisterDriver) adaptor() DigitalWriter {
+	return s.Adaptor().(DigitalWriter)
+}
+
+func (s *ShiftRegisterDriver) Start() bool { return true }
+func (s *ShiftRegisterDriver) Init() bool  { return true }
+
+// Halt turns all the outputs off.
+func (s *ShiftRegisterDriver) Halt() bool {
+	s.Clear()
+	return true
+}
+
+// Write sets the outputs of each register in turn, starting with the one
+// nearest the board. Bit 0 of each value is its Q0 output. Registers
+// without a value are cleared.
+func (s *ShiftRegisterDriver) Write(values ...byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.resize()
+	for i := range s.outputs {
+		s.outputs[i] = 0
+		if i < len(values) {
+			s.outputs[i] = values[i]
+		}
+	}
+	s.latch()
+}
+
+// SetOutput sets a single output to level, 0 or 1, leaving the rest as
+// they are. Outputs beyond the chain are ignored.
+func (s *ShiftRegisterDriver) SetOutput(output int, level byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.resize()
+	if output < 0 || output >= 8*len(s.outputs) {
+		return
+	}
+	mask := byte(1) << uint(output%8)
+	if level != 0 {
+		s.outputs[output/8] |= mask
+	} else {
+		s.outputs[output/8] &^= mask
+	}
+	s.latch()
+}
+
+// Output returns the level of an output, or -1 if it is beyond the chain.
+func (s *ShiftRegisterDriver) Output(output int) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.resize()
+	if output < 0 || output >= 8*len(s.outputs) {
+		return -1
+	}
+	return int(s.outputs[output/8]>>uint(output%8)) & 1
+}
+
+// Clear turns all the outputs off.
+func (s *ShiftRegisterDriver) Clear() {
+	s.Write()
+}
+
+// resize matches the outputs kept to Registers.
+func (s *ShiftRegisterDriver) resize() {
+	for len(s.outputs) < s.Registers {
+		s.outputs = append(s.outputs, 0)
+	}
+	if s.Registers >= 0 && len(s.outputs) > s.Registers {
+		s.outputs = s.outputs[:s.Registers]
+	}
+}
+
+// latch shifts the outputs into the chain, furthest register and highest
+// output first, and then latches them onto the outputs.
+func (s *ShiftRegisterDriver) latch() {
+	s.adaptor().DigitalWrite(s.LatchPin, 0)
+	for i := len(s.outputs) - 1; i >= 0; i-- {
+		for bit := 7; bit >= 0; bit-- {
+			s.adaptor().DigitalWrite(s.DataPin, (s.outputs[i]>>uint(bit))&1)
+			s.adaptor().DigitalWrite(s.ClockPin, 1)
+			s.adaptor().DigitalWrite(s.ClockPin, 0)
+		}
+	}
+	s.adaptor().DigitalWrite(s.LatchPin, 1)
+}
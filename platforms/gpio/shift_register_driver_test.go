@@ -0,0 +1,86 @@
+package gpio
+
+import (
+	"testing"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestShiftRegisterDriver() *ShiftRegisterDriver {
+	return NewShiftRegisterDriver(newGpioTestAdaptor("adaptor"), "bot", "1", "2", "3")
+}
+
+// shiftedBits returns the data bits clocked into the register chain, and
+// whether they were latched at the end.
+func shiftedBits(writes []pinWrite) (bits []int, latched bool) {
+	data := 0
+	for _, w := range writes {
+		switch {
+		case w.pin == "1":
+			data = w.value
+		case w.pin == "2" && w.value == 1:
+			bits = append(bits, data)
+		case w.pin == "3":
+			latched = w.value == 1
+		}
+	}
+	return bits, latched
+}
+
+func TestShiftRegisterDriverStart(t *testing.T) {
+	d := initTestShiftRegisterDriver()
+	gobot.Assert(t, d.Start(), true)
+}
+
+func TestShiftRegisterDriverHalt(t *testing.T) {
+	d := initTestShiftRegisterDriver()
+	d.Write(0xFF)
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, d.Output(0), 0)
+}
+
+func TestShiftRegisterDriverInit(t *testing.T) {
+	d := initTestShiftRegisterDriver()
+	gobot.Assert(t, d.Init(), true)
+}
+
+func TestShiftRegisterDriverWrite(t *testing.T) {
+	d := initTestShiftRegisterDriver()
+	a := d.adaptor().(*gpioTestAdaptor)
+	d.Write(0x81)
+	bits, latched := shiftedBits(a.pinWrites())
+	gobot.Assert(t, bits, []int{1, 0, 0, 0, 0, 0, 0, 1})
+	gobot.Assert(t, latched, true)
+	gobot.Assert(t, d.Output(0), 1)
+	gobot.Assert(t, d.Output(1), 0)
+	gobot.Assert(t, d.Output(8), -1)
+}
+
+func TestShiftRegisterDriverChain(t *testing.T) {
+	d := initTestShiftRegisterDriver()
+	a := d.adaptor().(*gpioTestAdaptor)
+	d.Registers = 2
+	d.Write(0x01)
+	bits, _ := shiftedBits(a.pinWrites())
+	// the furthest register is shifted first
+	gobot.Assert(t, bits, []int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1})
+
+	d.SetOutput(15, 1)
+	bits, _ = shiftedBits(a.pinWrites())
+	gobot.Assert(t, bits, []int{1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1})
+	d.SetOutput(0, 0)
+	gobot.Assert(t, d.Output(0), 0)
+	gobot.Assert(t, d.Output(15), 1)
+	d.SetOutput(16, 1)
+	gobot.Assert(t, d.Output(16), -1)
+}
+
+func TestShiftRegisterDriverCommands(t *testing.T) {
+	d := initTestShiftRegisterDriver()
+	d.Command("Write")(map[string]interface{}{"values": []interface{}{3.0}})
+	gobot.Assert(t, d.Command("Output")(map[string]interface{}{"output": 1.0}), 1)
+	d.Command("SetOutput")(map[string]interface{}{"output": 1.0, "level": 0.0})
+	gobot.Assert(t, d.Output(1), 0)
+	d.Command("Clear")(map[string]interface{}{})
+	gobot.Assert(t, d.Output(0), 0)
+}
@@ -0,0 +1,336 @@
+package gpio
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+// Coil sequences, one row of pin levels per step.
+var (
+	stepperTwoWire = [][]byte{
+		{0, 1},
+		{1, 1},
+		{1, 0},
+		{0, 0},
+	}
+	stepperFourWire = [][]byte{
+		{1, 0, 1, 0},
+		{0, 1, 1, 0},
+		{0, 1, 0, 1},
+		{1, 0, 0, 1},
+	}
+	stepperFourWireHalfStep = [][]byte{
+		{1, 0, 0, 0},
+		{1, 0, 1, 0},
+		{0, 0, 1, 0},
+		{0, 1, 1, 0},
+		{0, 1, 0, 0},
+		{0, 1, 0, 1},
+		{0, 0, 0, 1},
+		{1, 0, 0, 1},
+	}
+)
+
+// StepperDriver drives a stepper motor, either through its coils on two or
+// four pins, or through a STEP/DIR driver board such as an A4988 or
+// DRV8825. Positions are counted in steps from where the motor was when
+// the driver was created, or last zeroed.
+type StepperDriver struct {
+	gobot.Driver
+	// Pins are the coil pins, or the step and direction pins of a STEP/DIR
+	// driver.
+	Pins          []string
+	StepDirection bool
+	// HalfStep doubles the resolution of a four wire motor by energizing
+	// one and two coils in turn. Steps are then half steps.
+	HalfStep           bool
+	StepsPerRevolution int
+	// Speed is the top speed in steps per second. It defaults to one
+	// revolution per second; zero steps as fast as the adaptor allows.
+	Speed float64
+	// Acceleration is how fast moves speed up to Speed and slow down
+	// again, in steps per second per second. Zero moves at Speed
+	// throughout.
+	Acceleration float64
+	mutex        sync.Mutex
+	position     int
+	target       int
+	phase        int
+	direction    int
+	move         int
+	moving       bool
+}
+
+// NewStepperDriver creates a driver for a motor with its coils on two or
+// four pins, which takes stepsPerRevolution full steps to turn once.
+func NewStepperDriver(a DigitalWriter, name string, stepsPerRevolution int, pins ...string) *StepperDriver {
+	return newStepperDriver(a, name, stepsPerRevolution, false, pins...)
+}
+
+// NewStepDirStepperDriver creates a driver for a motor on a STEP/DIR driver
+// board, which takes stepsPerRevolution steps to turn once at the
+// microstepping set on the board.
+func NewStepDirStepperDriver(a DigitalWriter, name string, stepsPerRevolution int, stepPin string, directionPin string) *StepperDriver {
+	return newStepperDriver(a, name, stepsPerRevolution, true, stepPin, directionPin)
+}
+
+func newStepperDriver(a DigitalWriter, name string, stepsPerRevolution int, stepDirection bool, pins ...string) *StepperDriver {
+	s := &StepperDriver{
+		Driver: *gobot.NewDriver(
+			name,
+			"StepperDriver",
+			a.(gobot.AdaptorInterface),
+			pins[0],
+		),
+		Pins:               pins,
+		StepDirection:      stepDirection,
+		StepsPerRevolution: stepsPerRevolution,
+		Speed:              float64(stepsPerRevolution),
+	}
+
+	s.AddEvent("move_complete")
+
+	s.AddCommand("Move", func(params map[string]interface{}) interface{} {
+		s.Move(int(params["steps"].(float64)))
+		return nil
+	})
+	s.AddCommand("MoveTo", func(params map[string]interface{}) interface{} {
+		s.MoveTo(int(params["position"].(float64)))
+		return nil
+	})
+	s.AddCommand("Stop", func(params map[string]interface{}) interface{} {
+		s.Stop()
+		return nil
+	})
+	s.AddCommand("Zero", func(params map[string]interface{}) interface{} {
+		s.Zero()
+		return nil
+	})
+	s.AddCommand("Release", func(params map[string]interface{}) interface{} {
+		s.Release()
+		return nil
+	})
+	s.AddCommand("SetSpeed", func(params map[string]interface{}) interface{} {
+		s.SetSpeed(params["speed"].(float64))
+		return nil
+	})
+	s.AddCommand("SetRPM", func(params map[string]interface{}) interface{} {
+		s.SetRPM(params["rpm"].(float64))
+		return nil
+	})
+	s.AddCommand("SetAcceleration", func(params map[string]interface{}) interface{} {
+		s.SetAcceleration(params["acceleration"].(float64))
+		return nil
+	})
+	s.AddCommand("Position", func(params map[string]interface{}) interface{} {
+		return s.Position()
+	})
+	s.AddCommand("IsMoving", func(params map[string]interface{}) interface{} {
+		return s.IsMoving()
+	})
+
+	return s
+}
+
+func (s *StepperDriver) adaptor() DigitalWriter {
+	return s.Adaptor().(DigitalWriter)
+}
+
+func (s *StepperDriver) Start() bool { return true }
+func (s *StepperDriver) Init() bool  { return true }
+
+// Halt stops the motor and releases its coils.
+func (s *StepperDriver) Halt() bool {
+	s.Stop()
+	s.Release()
+	return true
+}
+
+// SetSpeed sets the top speed in steps per second.
+func (s *StepperDriver) SetSpeed(speed float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Speed = speed
+}
+
+// SetRPM sets the top speed in revolutions per minute.
+func (s *StepperDriver) SetRPM(rpm float64) {
+	s.SetSpeed(rpm * float64(s.stepsPerRevolution()) / 60)
+}
+
+// SetAcceleration sets the acceleration in steps per second per second.
+func (s *StepperDriver) SetAcceleration(acceleration float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Acceleration = acceleration
+}
+
+// Position returns the current position in steps.
+func (s *StepperDriver) Position() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.position
+}
+
+// IsMoving returns true while a move is under way.
+func (s *StepperDriver) IsMoving() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.moving
+}
+
+// Zero stops the motor and makes its current position 0.
+func (s *StepperDriver) Zero() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.stop()
+	s.position = 0
+}
+
+// Move turns the motor by steps, backward if negative. See MoveTo.
+func (s *StepperDriver) Move(steps int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.moveTo(s.position + steps)
+}
+
+// MoveTo turns the motor to position and publishes a "move_complete" event
+// with the position when it gets there. It returns at once; another move
+// or Stop cancels it.
+func (s *StepperDriver) MoveTo(position int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.moveTo(position)
+}
+
+// Stop stops the motor where it is.
+func (s *StepperDriver) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.stop()
+}
+
+// Release turns off the coils, so the motor no longer holds its position.
+// It does nothing on a STEP/DIR driver.
+func (s *StepperDriver) Release() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.StepDirection {
+		return
+	}
+	for _, pin := range s.Pins {
+		s.adaptor().DigitalWrite(pin, 0)
+	}
+}
+
+func (s *StepperDriver) stepsPerRevolution() int {
+	if s.HalfStep && !s.StepDirection {
+		return 2 * s.StepsPerRevolution
+	}
+	return s.StepsPerRevolution
+}
+
+func (s *StepperDriver) stop() {
+	s.move++
+	s.moving = false
+}
+
+func (s *StepperDriver) moveTo(position int) {
+	s.move++
+	s.target = position
+	if position == s.position {
+		s.moving = false
+		gobot.Publish(s.Event("move_complete"), s.position)
+		return
+	}
+	s.moving = true
+	go s.run(s.move)
+}
+
+// run steps towards the target until it gets there or the move is
+// cancelled.
+func (s *StepperDriver) run(move int) {
+	taken := 0
+	for {
+		s.mutex.Lock()
+		if s.move != move {
+			s.mutex.Unlock()
+			return
+		}
+		remaining := s.target - s.position
+		if remaining == 0 {
+			s.moving = false
+			position := s.position
+			s.mutex.Unlock()
+			gobot.Publish(s.Event("move_complete"), position)
+			return
+		}
+		direction := 1
+		if remaining < 0 {
+			direction = -1
+			remaining = -remaining
+		}
+		s.step(direction)
+		taken++
+		delay := s.stepDelay(taken, remaining-1)
+		s.mutex.Unlock()
+
+		if delay > 0 {
+			<-time.After(delay)
+		}
+	}
+}
+
+// stepDelay returns how long to wait before the next step, having taken
+// steps since the move began with remaining still to go. The speed rises
+// and falls with Acceleration, and is limited to Speed.
+func (s *StepperDriver) stepDelay(taken int, remaining int) time.Duration {
+	if remaining == 0 {
+		return 0
+	}
+	speed := s.Speed
+	if s.Acceleration > 0 {
+		n := math.Min(float64(taken), float64(remaining))
+		speed = math.Min(speed, math.Sqrt(2*s.Acceleration*n))
+	}
+	if speed <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / speed)
+}
+
+// step moves the motor one step in direction, 1 or -1.
+func (s *StepperDriver) step(direction int) {
+	if s.StepDirection {
+		if direction != s.direction {
+			level := byte(0)
+			if direction > 0 {
+				level = 1
+			}
+			s.adaptor().DigitalWrite(s.Pins[1], level)
+			s.direction = direction
+		}
+		s.adaptor().DigitalWrite(s.Pins[0], 1)
+		s.adaptor().DigitalWrite(s.Pins[0], 0)
+	} else {
+		sequence := s.sequence()
+		s.phase = (s.phase + direction + len(sequence)) % len(sequence)
+		for i, level := range sequence[s.phase] {
+			s.adaptor().DigitalWrite(s.Pins[i], level)
+		}
+	}
+	s.position += direction
+}
+
+func (s *StepperDriver) sequence() [][]byte {
+	switch {
+	case len(s.Pins) == 2:
+		return stepperTwoWire
+	case s.HalfStep:
+		return stepperFourWireHalfStep
+	}
+	return stepperFourWire
+}
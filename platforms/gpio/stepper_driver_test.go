@@ -0,0 +1,166 @@
+package gpio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestStepperDriver() *StepperDriver {
+	return NewStepperDriver(newGpioTestAdaptor("adaptor"), "bot", 200, "1", "2", "3", "4")
+}
+
+func stepperPins(s *StepperDriver) []int {
+	values := []int{}
+	for _, pin := range s.Pins {
+		values = append(values, s.adaptor().(*gpioTestAdaptor).pinValue(pin))
+	}
+	return values
+}
+
+func waitForStepper(s *StepperDriver) bool {
+	timeout := time.After(time.Second)
+	for s.IsMoving() {
+		select {
+		case <-timeout:
+			return false
+		case <-time.After(time.Millisecond):
+		}
+	}
+	return true
+}
+
+func TestStepperDriverStart(t *testing.T) {
+	d := initTestStepperDriver()
+	gobot.Assert(t, d.Start(), true)
+}
+
+func TestStepperDriverHalt(t *testing.T) {
+	d := initTestStepperDriver()
+	d.step(1)
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, stepperPins(d), []int{0, 0, 0, 0})
+}
+
+func TestStepperDriverInit(t *testing.T) {
+	d := initTestStepperDriver()
+	gobot.Assert(t, d.Init(), true)
+}
+
+func TestStepperDriverFourWire(t *testing.T) {
+	d := initTestStepperDriver()
+	d.step(1)
+	gobot.Assert(t, stepperPins(d), []int{0, 1, 1, 0})
+	d.step(1)
+	gobot.Assert(t, stepperPins(d), []int{0, 1, 0, 1})
+	d.step(-1)
+	d.step(-1)
+	d.step(-1)
+	gobot.Assert(t, stepperPins(d), []int{1, 0, 0, 1})
+	gobot.Assert(t, d.Position(), -1)
+}
+
+func TestStepperDriverHalfStep(t *testing.T) {
+	d := initTestStepperDriver()
+	d.HalfStep = true
+	d.step(1)
+	gobot.Assert(t, stepperPins(d), []int{1, 0, 1, 0})
+	d.step(1)
+	gobot.Assert(t, stepperPins(d), []int{0, 0, 1, 0})
+	d.SetRPM(60)
+	gobot.Assert(t, d.Speed, 400.0)
+}
+
+func TestStepperDriverTwoWire(t *testing.T) {
+	d := NewStepperDriver(newGpioTestAdaptor("adaptor"), "bot", 48, "1", "2")
+	d.step(1)
+	gobot.Assert(t, stepperPins(d), []int{1, 1})
+	d.step(1)
+	gobot.Assert(t, stepperPins(d), []int{1, 0})
+}
+
+func TestStepperDriverStepDirection(t *testing.T) {
+	d := NewStepDirStepperDriver(newGpioTestAdaptor("adaptor"), "bot", 200, "1", "2")
+	d.step(1)
+	gobot.Assert(t, stepperPins(d), []int{0, 1})
+	d.step(-1)
+	gobot.Assert(t, stepperPins(d), []int{0, 0})
+	gobot.Assert(t, d.Position(), 0)
+	d.Release()
+	gobot.Assert(t, stepperPins(d), []int{0, 0})
+}
+
+func TestStepperDriverMove(t *testing.T) {
+	d := initTestStepperDriver()
+	d.Speed = 0
+	done := make(chan interface{}, 1)
+	gobot.Once(d.Event("move_complete"), func(data interface{}) {
+		done <- data
+	})
+	d.Move(10)
+	select {
+	case position := <-done:
+		gobot.Assert(t, position, 10)
+	case <-time.After(time.Second):
+		t.Errorf("move_complete was not published")
+	}
+	gobot.Assert(t, d.IsMoving(), false)
+
+	d.MoveTo(-5)
+	gobot.Assert(t, waitForStepper(d), true)
+	gobot.Assert(t, d.Position(), -5)
+	d.Move(3)
+	gobot.Assert(t, waitForStepper(d), true)
+	gobot.Assert(t, d.Position(), -2)
+}
+
+func TestStepperDriverStop(t *testing.T) {
+	d := initTestStepperDriver()
+	d.Speed = 500
+	d.Move(1000)
+	<-time.After(20 * time.Millisecond)
+	d.Stop()
+	gobot.Assert(t, d.IsMoving(), false)
+	position := d.Position()
+	gobot.Assert(t, position > 0 && position < 1000, true)
+	<-time.After(10 * time.Millisecond)
+	gobot.Assert(t, d.Position(), position)
+
+	d.Zero()
+	gobot.Assert(t, d.Position(), 0)
+}
+
+func TestStepperDriverStepDelay(t *testing.T) {
+	d := initTestStepperDriver()
+	d.Speed = 100
+	gobot.Assert(t, d.stepDelay(1, 50), 10*time.Millisecond)
+	gobot.Assert(t, d.stepDelay(1, 0), time.Duration(0))
+
+	d.Acceleration = 50
+	// sqrt(2 * 50 * 1) = 10 steps per second
+	gobot.Assert(t, d.stepDelay(1, 50), 100*time.Millisecond)
+	gobot.Assert(t, d.stepDelay(100, 100), 10*time.Millisecond)
+	// slowing down again at the end of the move
+	gobot.Assert(t, d.stepDelay(50, 1), 100*time.Millisecond)
+}
+
+func TestStepperDriverCommands(t *testing.T) {
+	d := initTestStepperDriver()
+	d.Command("SetSpeed")(map[string]interface{}{"speed": 0.0})
+	d.Command("SetAcceleration")(map[string]interface{}{"acceleration": 0.0})
+	d.Command("Move")(map[string]interface{}{"steps": 4.0})
+	gobot.Assert(t, waitForStepper(d), true)
+	gobot.Assert(t, d.Command("Position")(map[string]interface{}{}), 4)
+	d.Command("MoveTo")(map[string]interface{}{"position": 1.0})
+	gobot.Assert(t, waitForStepper(d), true)
+	gobot.Assert(t, d.Command("IsMoving")(map[string]interface{}{}), false)
+	gobot.Assert(t, d.Position(), 1)
+	d.Command("Zero")(map[string]interface{}{})
+	gobot.Assert(t, d.Position(), 0)
+	d.Command("SetRPM")(map[string]interface{}{"rpm": 30.0})
+	gobot.Assert(t, d.Speed, 100.0)
+	d.Command("Stop")(map[string]interface{}{})
+	d.Command("Release")(map[string]interface{}{})
+	gobot.Assert(t, stepperPins(d), []int{0, 0, 0, 0})
+}
@@ -1,30 +1,234 @@
 package gpio
 
-import "github.com/edmontongo/gobot"
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+type pinWrite struct {
+	pin   string
+	value int
+}
 
 type gpioTestAdaptor struct {
 	gobot.Adaptor
+	mutex   sync.Mutex
+	written map[string]int
+	writes  []pinWrite
+	inputs  map[string]int
+	pulse   time.Duration
+	tones   []float64
 }
 
-func (t *gpioTestAdaptor) AnalogWrite(string, byte)  {}
-func (t *gpioTestAdaptor) DigitalWrite(string, byte) {}
-func (t *gpioTestAdaptor) ServoWrite(string, byte)   {}
-func (t *gpioTestAdaptor) PwmWrite(string, byte)     {}
-func (t *gpioTestAdaptor) InitServo()                {}
+func (t *gpioTestAdaptor) AnalogWrite(pin string, level byte)  { t.write(pin, level) }
+func (t *gpioTestAdaptor) DigitalWrite(pin string, level byte) { t.write(pin, level) }
+func (t *gpioTestAdaptor) ServoWrite(pin string, angle byte)   { t.write(pin, angle) }
+func (t *gpioTestAdaptor) PwmWrite(pin string, level byte)     { t.write(pin, level) }
+func (t *gpioTestAdaptor) InitServo()                          {}
 func (t *gpioTestAdaptor) AnalogRead(string) int {
 	return 99
 }
-func (t *gpioTestAdaptor) DigitalRead(string) int {
+
+// DigitalRead returns the value set with setInput, or 1.
+func (t *gpioTestAdaptor) DigitalRead(pin string) int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if value, ok := t.inputs[pin]; ok {
+		return value
+	}
 	return 1
 }
+
+// PulseIn returns the pulse set with setPulse, or times out if it is 0.
+func (t *gpioTestAdaptor) PulseIn(pin string, level byte, timeout time.Duration) (time.Duration, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.pulse == 0 {
+		return 0, errors.New("timed out")
+	}
+	return t.pulse, nil
+}
+
+func (t *gpioTestAdaptor) ToneWrite(pin string, frequency float64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.tones = append(t.tones, frequency)
+}
 func (t *gpioTestAdaptor) Connect() bool  { return true }
 func (t *gpioTestAdaptor) Finalize() bool { return true }
 
+func (t *gpioTestAdaptor) write(pin string, value byte) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.written[pin] = int(value)
+	t.writes = append(t.writes, pinWrite{pin, int(value)})
+}
+
+// pinWrites returns the writes made so far, in order, and forgets them.
+func (t *gpioTestAdaptor) pinWrites() []pinWrite {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	writes := t.writes
+	t.writes = nil
+	return writes
+}
+
+func (t *gpioTestAdaptor) setInput(pin string, value int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.inputs[pin] = value
+}
+
+func (t *gpioTestAdaptor) setPulse(pulse time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.pulse = pulse
+}
+
+// playedTones returns the frequencies passed to ToneWrite.
+func (t *gpioTestAdaptor) playedTones() []float64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]float64{}, t.tones...)
+}
+
+// pinValue returns the last value written to pin, or -1 if none was.
+func (t *gpioTestAdaptor) pinValue(pin string) int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if value, ok := t.written[pin]; ok {
+		return value
+	}
+	return -1
+}
+
 func newGpioTestAdaptor(name string) *gpioTestAdaptor {
 	return &gpioTestAdaptor{
 		Adaptor: *gobot.NewAdaptor(
 			name,
 			"/dev/null",
 		),
+		written: make(map[string]int),
+		inputs:  make(map[string]int),
+	}
+}
+
+// pwmTestAdaptor adds a PwmController to the test adaptor. PwmSetPeriod
+// fails for periods longer than maxPeriod, when it is set.
+type pwmTestAdaptor struct {
+	*gpioTestAdaptor
+	maxPeriod time.Duration
+	periods   map[string]time.Duration
+	duties    map[string]float64
+	enabled   map[string]bool
+	inverted  map[string]bool
+}
+
+func newPwmTestAdaptor() *pwmTestAdaptor {
+	return &pwmTestAdaptor{
+		gpioTestAdaptor: newGpioTestAdaptor("adaptor"),
+		periods:         make(map[string]time.Duration),
+		duties:          make(map[string]float64),
+		enabled:         make(map[string]bool),
+		inverted:        make(map[string]bool),
 	}
 }
+
+func (t *pwmTestAdaptor) PwmSetPeriod(pin string, period time.Duration) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.maxPeriod > 0 && period > t.maxPeriod {
+		return errors.New("period too long")
+	}
+	t.periods[pin] = period
+	return nil
+}
+
+func (t *pwmTestAdaptor) PwmSetDutyCycle(pin string, duty float64) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.duties[pin] = duty
+	return nil
+}
+
+func (t *pwmTestAdaptor) PwmSetEnabled(pin string, enabled bool) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.enabled[pin] = enabled
+	return nil
+}
+
+func (t *pwmTestAdaptor) PwmSetPolarity(pin string, inverted bool) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.inverted[pin] = inverted
+	return nil
+}
+
+// period returns the period set on pin, or 0 if none was.
+func (t *pwmTestAdaptor) period(pin string) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.periods[pin]
+}
+
+// duty returns the duty cycle set on pin, or -1 if none was.
+func (t *pwmTestAdaptor) duty(pin string) float64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if duty, ok := t.duties[pin]; ok {
+		return duty
+	}
+	return -1
+}
+
+// watchTestAdaptor adds a DigitalWatcher to the test adaptor. Changes are
+// reported by calling change.
+type watchTestAdaptor struct {
+	*gpioTestAdaptor
+	handlers map[string]func(int)
+}
+
+func newWatchTestAdaptor() *watchTestAdaptor {
+	return &watchTestAdaptor{
+		gpioTestAdaptor: newGpioTestAdaptor("adaptor"),
+		handlers:        make(map[string]func(int)),
+	}
+}
+
+func (t *watchTestAdaptor) WatchDigital(pin string, edge string, handler func(int)) error {
+	t.mutex.Lock()
+	t.handlers[pin] = handler
+	t.mutex.Unlock()
+	handler(t.DigitalRead(pin))
+	return nil
+}
+
+func (t *watchTestAdaptor) UnwatchDigital(pin string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.handlers, pin)
+	return nil
+}
+
+// change sets the input of pin to level and reports it to the handler
+// watching pin, if there is one.
+func (t *watchTestAdaptor) change(pin string, level int) {
+	t.setInput(pin, level)
+	t.mutex.Lock()
+	handler := t.handlers[pin]
+	t.mutex.Unlock()
+	if handler != nil {
+		handler(level)
+	}
+}
+
+// watching returns true while pin is watched.
+func (t *watchTestAdaptor) watching(pin string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.handlers[pin] != nil
+}
@@ -0,0 +1,96 @@
+package gpio
+
+import (
+	"sync"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+// UltrasonicDriver measures distance with an HC-SR04 style ultrasonic
+// rangefinder, which sends out a burst of sound when its trigger pin is
+// pulsed and holds its echo pin high until the echo comes back.
+type UltrasonicDriver struct {
+	gobot.Driver
+	TriggerPin string
+	EchoPin    string
+	// Timeout is how long to wait for an echo. The default of 38ms is when
+	// an HC-SR04 gives up, at about 6.5m.
+	Timeout time.Duration
+	// SpeedOfSound in metres per second, 343 by default, for air at 20
+	// degrees Celsius.
+	SpeedOfSound float64
+	mutex        sync.Mutex
+}
+
+// NewUltrasonicDriver creates a driver for a rangefinder on the trigger and
+// echo pins. It measures every 100ms once started, leaving time for the
+// echoes of one measurement to die down before the next.
+func NewUltrasonicDriver(a DigitalPulseReader, name string, triggerPin string, echoPin string) *UltrasonicDriver {
+	u := &UltrasonicDriver{
+		Driver: *gobot.NewDriver(
+			name,
+			"UltrasonicDriver",
+			a.(gobot.AdaptorInterface),
+			triggerPin,
+			100*time.Millisecond,
+		),
+		TriggerPin:   triggerPin,
+		EchoPin:      echoPin,
+		Timeout:      38 * time.Millisecond,
+		SpeedOfSound: 343,
+	}
+
+	u.AddEvent("distance")
+	u.AddEvent("error")
+
+	u.AddCommand("Distance", func(params map[string]interface{}) interface{} {
+		distance, err := u.Distance()
+		if err != nil {
+			return err.Error()
+		}
+		return distance
+	})
+
+	return u
+}
+
+func (u *UltrasonicDriver) adaptor() DigitalPulseReader {
+	return u.Adaptor().(DigitalPulseReader)
+}
+
+// Start measures the distance every interval, publishing a "distance"
+// event in centimetres, or an "error" event when there is no echo.
+func (u *UltrasonicDriver) Start() bool {
+	gobot.Every(u.Interval(), func() {
+		distance, err := u.Distance()
+		if err != nil {
+			gobot.Publish(u.Event("error"), err)
+			return
+		}
+		gobot.Publish(u.Event("distance"), distance)
+	})
+	return true
+}
+func (u *UltrasonicDriver) Init() bool { return true }
+func (u *UltrasonicDriver) Halt() bool { return true }
+
+// Distance measures the distance to the nearest object in centimetres.
+func (u *UltrasonicDriver) Distance() (float64, error) {
+	// measurements overlapping would hear each other's echoes
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	u.adaptor().DigitalWrite(u.TriggerPin, 0)
+	u.adaptor().DigitalWrite(u.TriggerPin, 1)
+	<-time.After(10 * time.Microsecond)
+	u.adaptor().DigitalWrite(u.TriggerPin, 0)
+
+	echo, err := u.adaptor().PulseIn(u.EchoPin, 1, u.Timeout)
+	if err != nil {
+		return 0, err
+	}
+	// the echo, timed in nanoseconds, travels there and back at
+	// SpeedOfSound metres per second
+	return float64(echo) * u.SpeedOfSound / 2e7, nil
+}
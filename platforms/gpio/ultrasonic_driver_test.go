@@ -0,0 +1,69 @@
+package gpio
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestUltrasonicDriver() *UltrasonicDriver {
+	return NewUltrasonicDriver(newGpioTestAdaptor("adaptor"), "bot", "1", "2")
+}
+
+func TestUltrasonicDriverStart(t *testing.T) {
+	d := initTestUltrasonicDriver()
+	gobot.Assert(t, d.Start(), true)
+}
+
+func TestUltrasonicDriverHalt(t *testing.T) {
+	d := initTestUltrasonicDriver()
+	gobot.Assert(t, d.Halt(), true)
+}
+
+func TestUltrasonicDriverInit(t *testing.T) {
+	d := initTestUltrasonicDriver()
+	gobot.Assert(t, d.Init(), true)
+	gobot.Assert(t, d.Interval(), 100*time.Millisecond)
+}
+
+func TestUltrasonicDriverDistance(t *testing.T) {
+	d := initTestUltrasonicDriver()
+	a := d.adaptor().(*gpioTestAdaptor)
+	a.setPulse(1000 * time.Microsecond)
+	distance, err := d.Distance()
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, distance, 17.15)
+	// the trigger is left low
+	gobot.Assert(t, a.pinValue("1"), 0)
+
+	a.setPulse(0)
+	_, err = d.Distance()
+	gobot.Assert(t, err, errors.New("timed out"))
+}
+
+func TestUltrasonicDriverEvents(t *testing.T) {
+	d := initTestUltrasonicDriver()
+	d.SetInterval(time.Millisecond)
+	d.adaptor().(*gpioTestAdaptor).setPulse(2000 * time.Microsecond)
+	sem := make(chan interface{}, 1)
+	gobot.Once(d.Event("distance"), func(data interface{}) {
+		sem <- data
+	})
+	d.Start()
+	select {
+	case distance := <-sem:
+		gobot.Assert(t, distance, 34.3)
+	case <-time.After(time.Second):
+		t.Errorf("distance was not published")
+	}
+}
+
+func TestUltrasonicDriverCommands(t *testing.T) {
+	d := initTestUltrasonicDriver()
+	d.adaptor().(*gpioTestAdaptor).setPulse(1000 * time.Microsecond)
+	gobot.Assert(t, d.Command("Distance")(map[string]interface{}{}), 17.15)
+	d.adaptor().(*gpioTestAdaptor).setPulse(0)
+	gobot.Assert(t, d.Command("Distance")(map[string]interface{}{}), "timed out")
+}
@@ -1,5 +1,16 @@
 package gpio
 
+import (
+	"math"
+	"time"
+)
+
+// The pulse widths that ServoWrite maps angles of 0 and 180 degrees to.
+const (
+	DefaultServoMinPulse = 544 * time.Microsecond
+	DefaultServoMaxPulse = 2400 * time.Microsecond
+)
+
 type PwmDigitalWriter interface {
 	DigitalWriter
 	Pwm
@@ -15,10 +26,78 @@ type DirectPin interface {
 type Pwm interface {
 	PwmWrite(string, byte)
 }
+
+// PwmController is implemented by adaptors with finer control of PWM than
+// PwmWrite, which has 256 levels at a period fixed by the adaptor. The
+// duty cycle is the fraction of each period, from 0 to 1, that the output
+// is high, or low when the polarity is inverted. Adaptors return an error
+// for settings the pin does not support. LedDriver, MotorDriver and
+// ServoDriver use it when available.
+type PwmController interface {
+	PwmSetPeriod(pin string, period time.Duration) error
+	PwmSetDutyCycle(pin string, duty float64) error
+	PwmSetEnabled(pin string, enabled bool) error
+	PwmSetPolarity(pin string, inverted bool) error
+}
+
+// PwmFrequency returns the period of PWM at frequency hertz, for
+// PwmSetPeriod.
+func PwmFrequency(frequency float64) time.Duration {
+	return time.Duration(float64(time.Second)/frequency + 0.5)
+}
+
+// PwmDuty16 returns the duty cycle of a 16-bit PWM level, from 0 to 65535,
+// for PwmSetDutyCycle.
+func PwmDuty16(level uint16) float64 {
+	return float64(level) / 0xFFFF
+}
+
+// Servo is implemented by adaptors that drive servos. ServoWrite takes an
+// angle from 0 to 180 degrees, sent as a pulse from DefaultServoMinPulse
+// to DefaultServoMaxPulse.
 type Servo interface {
 	InitServo()
 	ServoWrite(string, byte)
 }
+
+// ServoPulseWriter is implemented by adaptors that can send a servo a
+// pulse of any width. ServoDriver uses it when available.
+type ServoPulseWriter interface {
+	ServoPulseWrite(string, time.Duration)
+}
+
+// PulseReader is implemented by adaptors that can time a pulse on a pin,
+// such as the echo of an ultrasonic rangefinder. PulseIn waits for pin to
+// reach level and returns how long it stays there, or an error if either
+// takes longer than timeout.
+type PulseReader interface {
+	PulseIn(pin string, level byte, timeout time.Duration) (time.Duration, error)
+}
+
+// DigitalPulseReader is implemented by adaptors that can trigger and time
+// a pulse, as an ultrasonic rangefinder needs.
+type DigitalPulseReader interface {
+	DigitalWriter
+	PulseReader
+}
+
+// ToneWriter is implemented by adaptors that can play a square wave on a
+// pin, such as for a piezo buzzer. A frequency of 0 stops it.
+type ToneWriter interface {
+	ToneWrite(pin string, frequency float64)
+}
+
+// pwmDutyWrite sets pin to duty, from 0 to 1, with the full resolution of
+// the adaptor's PwmController when it has one, and the nearest PwmWrite
+// level otherwise.
+func pwmDutyWrite(a Pwm, pin string, duty float64) {
+	duty = math.Max(0, math.Min(1, duty))
+	if p, ok := a.(PwmController); ok && p.PwmSetDutyCycle(pin, duty) == nil {
+		return
+	}
+	a.PwmWrite(pin, byte(duty*255+0.5))
+}
+
 type AnalogWriter interface {
 	AnalogWrite(string, byte)
 }
@@ -31,3 +110,21 @@ type DigitalWriter interface {
 type DigitalReader interface {
 	DigitalRead(string) int
 }
+
+// The edges of a digital input that a DigitalWatcher reports.
+const (
+	EdgeRising  = "rising"
+	EdgeFalling = "falling"
+	EdgeBoth    = "both"
+)
+
+// DigitalWatcher is implemented by adaptors that report changes of a
+// digital input as they happen, such as with interrupts, so that drivers
+// need not poll DigitalRead. WatchDigital calls handler with the level of
+// pin once watching starts, and again after each change on edge, until
+// UnwatchDigital is called. ButtonDriver, PIRMotionDriver and
+// RotaryEncoderDriver use it when available.
+type DigitalWatcher interface {
+	WatchDigital(pin string, edge string, handler func(level int)) error
+	UnwatchDigital(pin string) error
+}
@@ -0,0 +1,279 @@
+package i2c
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+// ADS1015 and ADS1115 registers, which are 16-bit, high byte first.
+const (
+	ads1x15Conversion byte = 0x00
+	ads1x15Config     byte = 0x01
+
+	// ads1x15Start starts a single conversion when written to the config
+	// register, and reads as set once it is done.
+	ads1x15Start uint16 = 0x8000
+	// ads1x15SingleShot powers the converter down between conversions.
+	ads1x15SingleShot uint16 = 0x0100
+	// ads1x15NoComparator disables the comparator and its ALERT pin.
+	ads1x15NoComparator uint16 = 0x0003
+)
+
+// errConversionTimeout is returned when an ADS1015 or ADS1115 does not
+// finish a conversion in time.
+var errConversionTimeout = errors.New("i2c: ADS1x15 conversion timed out")
+
+// ADS1x15Gain is the full scale of the programmable gain amplifier of an
+// ADS1015 or ADS1115, from ±6.144V to ±0.256V. Inputs must still stay
+// between ground and the supply.
+type ADS1x15Gain byte
+
+const (
+	ADS1x15Gain6_144 ADS1x15Gain = iota
+	ADS1x15Gain4_096
+	ADS1x15Gain2_048
+	ADS1x15Gain1_024
+	ADS1x15Gain0_512
+	ADS1x15Gain0_256
+)
+
+// ads1x15FullScales are the full scales of the gains, in volts.
+var ads1x15FullScales = []float64{6.144, 4.096, 2.048, 1.024, 0.512, 0.256}
+
+// ads1x15Differentials are the multiplexer settings of the differential
+// pins.
+var ads1x15Differentials = map[string]uint16{
+	"0-1": 0,
+	"0-3": 1,
+	"1-3": 2,
+	"2-3": 3,
+}
+
+// ADS1x15Driver reads the four inputs of an ADS1015, which converts to 12
+// bits, or an ADS1115, which converts to 16 bits more slowly. Like
+// PCA9685Driver it is a connection of the robot, added after the adaptor
+// of its bus, that gpio drivers use as their adaptor: it implements
+// gpio.AnalogReader, so AnalogSensorDriver works on its pins.
+//
+// Pins "0" to "3" are single-ended, measured from ground, and pins "0-1",
+// "0-3", "1-3" and "2-3" are differential, measured from the second input
+// to the first.
+type ADS1x15Driver struct {
+	gobot.Adaptor
+	// Address is 0x48, or 0x49 to 0x4B when the ADDR pin is tied to VDD,
+	// SDA or SCL.
+	Address byte
+	Gain    ADS1x15Gain
+	// DataRate is the samples per second converted, one of DataRates. It is
+	// 1600 for the ADS1015 and 128 for the ADS1115 by default.
+	DataRate int
+	// AnalogMillivolts makes AnalogRead return millivolts rather than the
+	// reading.
+	AnalogMillivolts bool
+	bus              I2cBus
+	device           I2cDevice
+	mutex            sync.Mutex
+	bits             uint
+	rates            []int
+}
+
+// NewADS1015Driver creates a driver for an ADS1015 at address 0x48 on the
+// bus of a, at a gain of ±2.048V.
+func NewADS1015Driver(a I2cBus, name string) *ADS1x15Driver {
+	return newADS1x15Driver(a, name, "ADS1015Driver", 12, 1600,
+		[]int{128, 250, 490, 920, 1600, 2400, 3300})
+}
+
+// NewADS1115Driver creates a driver for an ADS1115 at address 0x48 on the
+// bus of a, at a gain of ±2.048V.
+func NewADS1115Driver(a I2cBus, name string) *ADS1x15Driver {
+	return newADS1x15Driver(a, name, "ADS1115Driver", 16, 128,
+		[]int{8, 16, 32, 64, 128, 250, 475, 860})
+}
+
+func newADS1x15Driver(a I2cBus, name string, typ string, bits uint, rate int, rates []int) *ADS1x15Driver {
+	d := &ADS1x15Driver{
+		Adaptor: *gobot.NewAdaptor(
+			name,
+			typ,
+		),
+		Address:  0x48,
+		Gain:     ADS1x15Gain2_048,
+		DataRate: rate,
+		bus:      a,
+		bits:     bits,
+		rates:    rates,
+	}
+
+	d.AddCommand("AnalogRead", func(params map[string]interface{}) interface{} {
+		return d.AnalogRead(params["pin"].(string))
+	})
+	d.AddCommand("ReadVoltage", func(params map[string]interface{}) interface{} {
+		voltage, err := d.ReadVoltage(params["pin"].(string))
+		if err != nil {
+			return err.Error()
+		}
+		return voltage
+	})
+
+	return d
+}
+
+// DataRates returns the data rates the converter supports, in samples per
+// second.
+func (d *ADS1x15Driver) DataRates() []int {
+	return append([]int{}, d.rates...)
+}
+
+// Connect opens the converter, which converts only when read.
+func (d *ADS1x15Driver) Connect() bool {
+	device, err := d.bus.I2cOpen(d.Address)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	d.device = device
+	d.SetConnected(true)
+	return true
+}
+
+// Finalize closes the converter.
+func (d *ADS1x15Driver) Finalize() bool {
+	if d.device != nil {
+		d.device.Close()
+	}
+	d.SetConnected(false)
+	return true
+}
+
+// ToJSON describes the address, gain and data rate of the converter in
+// Details.
+func (d *ADS1x15Driver) ToJSON() *gobot.JSONConnection {
+	json := d.Adaptor.ToJSON()
+	json.Details = map[string]interface{}{
+		"address":  d.Address,
+		"gain":     d.Gain,
+		"dataRate": d.DataRate,
+	}
+	return json
+}
+
+// AnalogRead converts pin, returning the reading, of 11 bits for the
+// ADS1015 and 15 bits for the ADS1115, or millivolts with AnalogMillivolts.
+// It returns -1 if pin cannot be read. Negative readings, which single-ended
+// pins only give from noise at ground, are returned as 0 so as not to be
+// taken for errors: differential pins must use ReadVoltage to read below 0.
+func (d *ADS1x15Driver) AnalogRead(pin string) int {
+	reading, err := d.read(pin)
+	if err != nil {
+		log.Println(err)
+		return -1
+	}
+	if reading < 0 {
+		reading = 0
+	}
+	if d.AnalogMillivolts {
+		return int(math.Floor(d.voltage(reading)*1000 + 0.5))
+	}
+	return reading
+}
+
+// ReadVoltage converts pin, returning volts, which are negative when the
+// second input of a differential pin is higher than the first.
+func (d *ADS1x15Driver) ReadVoltage(pin string) (float64, error) {
+	reading, err := d.read(pin)
+	if err != nil {
+		return 0, err
+	}
+	return d.voltage(reading), nil
+}
+
+// voltage returns the volts of a reading, at the full scale of the gain.
+func (d *ADS1x15Driver) voltage(reading int) float64 {
+	return float64(reading) * ads1x15FullScales[d.Gain] / float64(int(1)<<(d.bits-1))
+}
+
+// read starts a single conversion of pin, waits for it and reads it.
+func (d *ADS1x15Driver) read(pin string) (int, error) {
+	config, err := d.config(pin)
+	if err != nil {
+		return 0, err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.device == nil {
+		return 0, errNotConnected
+	}
+	if err := d.writeRegister(ads1x15Config, config); err != nil {
+		return 0, err
+	}
+
+	conversion := time.Second / time.Duration(d.DataRate)
+	time.Sleep(conversion)
+	for deadline := time.Now().Add(conversion + 10*time.Millisecond); ; {
+		status, err := d.readRegister(ads1x15Config)
+		if err != nil {
+			return 0, err
+		}
+		if status&ads1x15Start != 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			return 0, errConversionTimeout
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	reading, err := d.readRegister(ads1x15Conversion)
+	if err != nil {
+		return 0, err
+	}
+	// the ADS1015 left justifies its 12 bits
+	return int(int16(reading) >> (16 - d.bits)), nil
+}
+
+// config returns the config register that starts a conversion of pin.
+func (d *ADS1x15Driver) config(pin string) (uint16, error) {
+	mux, ok := ads1x15Differentials[pin]
+	if !ok {
+		input, err := strconv.Atoi(pin)
+		if err != nil || input < 0 || input > 3 {
+			return 0, fmt.Errorf("i2c: no ADS1x15 pin %v", pin)
+		}
+		mux = 0x4 | uint16(input)
+	}
+	if int(d.Gain) >= len(ads1x15FullScales) {
+		return 0, fmt.Errorf("i2c: no ADS1x15 gain %v", d.Gain)
+	}
+	rate := -1
+	for i, r := range d.rates {
+		if r == d.DataRate {
+			rate = i
+		}
+	}
+	if rate < 0 {
+		return 0, fmt.Errorf("i2c: no %v data rate %v", d.Type(), d.DataRate)
+	}
+	return ads1x15Start | mux<<12 | uint16(d.Gain)<<9 | ads1x15SingleShot |
+		uint16(rate)<<5 | ads1x15NoComparator, nil
+}
+
+func (d *ADS1x15Driver) readRegister(reg byte) (uint16, error) {
+	data := make([]byte, 2)
+	if err := d.device.ReadBlockData(reg, data); err != nil {
+		return 0, err
+	}
+	return uint16(data[0])<<8 | uint16(data[1]), nil
+}
+
+func (d *ADS1x15Driver) writeRegister(reg byte, value uint16) error {
+	return d.device.WriteBlockData(reg, []byte{byte(value >> 8), byte(value)})
+}
@@ -0,0 +1,189 @@
+package i2c
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/gpio"
+)
+
+// ads1x15TestDevice is a fake ADS1015 or ADS1115, with 16-bit conversion
+// and config registers, whose conversions take busy reads of the config.
+type ads1x15TestDevice struct {
+	mutex     sync.Mutex
+	registers [2]uint16
+	pointer   byte
+	writes    [][]byte
+	busy      int
+	closed    bool
+}
+
+func (d *ads1x15TestDevice) Read(data []byte) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	value := d.registers[d.pointer]
+	if d.pointer == ads1x15Config && d.busy > 0 {
+		d.busy--
+		value &^= ads1x15Start
+	}
+	data[0], data[1] = byte(value>>8), byte(value)
+	return 2, nil
+}
+
+func (d *ads1x15TestDevice) Write(data []byte) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.writes = append(d.writes, append([]byte{}, data...))
+	d.pointer = data[0]
+	if len(data) == 3 {
+		d.registers[d.pointer] = uint16(data[1])<<8 | uint16(data[2])
+	}
+	return len(data), nil
+}
+
+func (d *ads1x15TestDevice) Close() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.closed = true
+	return nil
+}
+
+// set sets the reading of the next conversions.
+func (d *ads1x15TestDevice) set(reading uint16) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.registers[ads1x15Conversion] = reading
+}
+
+// written returns the writes made to the device, and forgets them.
+func (d *ads1x15TestDevice) written() [][]byte {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	writes := d.writes
+	d.writes = nil
+	return writes
+}
+
+func initTestADS1115Driver(t *testing.T) (*ADS1x15Driver, *ads1x15TestDevice) {
+	device := &ads1x15TestDevice{}
+	d := NewADS1115Driver(&i2cTestBus{device}, "ads1115")
+	gobot.Assert(t, d.Connect(), true)
+	return d, device
+}
+
+func TestADS1x15Driver(t *testing.T) {
+	var _ gpio.AnalogReader = (*ADS1x15Driver)(nil)
+	var _ gobot.Connection = (*ADS1x15Driver)(nil)
+
+	a := newI2cTestAdaptor("adaptor")
+	d := NewADS1015Driver(a, "ads1015")
+	gobot.Assert(t, d.Type(), "ADS1015Driver")
+	gobot.Assert(t, d.Address, byte(0x48))
+	gobot.Assert(t, d.Gain, ADS1x15Gain2_048)
+	gobot.Assert(t, d.DataRate, 1600)
+	gobot.Assert(t, d.DataRates(), []int{128, 250, 490, 920, 1600, 2400, 3300})
+	gobot.Refute(t, d.Command("AnalogRead"), nil)
+	gobot.Refute(t, d.Command("ReadVoltage"), nil)
+
+	d = NewADS1115Driver(a, "ads1115")
+	gobot.Assert(t, d.Type(), "ADS1115Driver")
+	gobot.Assert(t, d.DataRate, 128)
+	gobot.Assert(t, d.ToJSON().Details, map[string]interface{}{
+		"address":  byte(0x48),
+		"gain":     ADS1x15Gain2_048,
+		"dataRate": 128,
+	})
+}
+
+func TestADS1x15DriverNotConnected(t *testing.T) {
+	d := NewADS1115Driver(newI2cTestAdaptor("adaptor"), "ads1115")
+	gobot.Assert(t, d.AnalogRead("0"), -1)
+	_, err := d.ReadVoltage("0")
+	gobot.Assert(t, err, errNotConnected)
+}
+
+func TestADS1x15DriverAnalogRead(t *testing.T) {
+	d, device := initTestADS1115Driver(t)
+	device.set(0x4000)
+	gobot.Assert(t, d.AnalogRead("0"), 16384)
+	gobot.Assert(t, device.written(), [][]byte{{0x01, 0xC5, 0x83}, {0x01}, {0x00}})
+
+	device.set(0xFFF0)
+	// negative readings are not taken for errors
+	gobot.Assert(t, d.Command("AnalogRead")(map[string]interface{}{"pin": "3"}), 0)
+	gobot.Assert(t, device.written()[0], []byte{0x01, 0xF5, 0x83})
+
+	device.set(0x4000)
+	d.AnalogMillivolts = true
+	gobot.Assert(t, d.AnalogRead("0"), 1024)
+	gobot.Assert(t, d.AnalogRead("4"), -1)
+}
+
+func TestADS1x15DriverReadVoltage(t *testing.T) {
+	d, device := initTestADS1115Driver(t)
+	device.set(0x2000)
+	d.Gain = ADS1x15Gain6_144
+	d.DataRate = 860
+	voltage, err := d.ReadVoltage("0-1")
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, voltage, 1.536)
+	gobot.Assert(t, device.written()[0], []byte{0x01, 0x81, 0xE3})
+	gobot.Assert(t, d.Command("ReadVoltage")(map[string]interface{}{"pin": "1-3"}), 1.536)
+	gobot.Assert(t, device.written()[0], []byte{0x01, 0xA1, 0xE3})
+
+	d.DataRate = 100
+	_, err = d.ReadVoltage("0")
+	gobot.Assert(t, err, errors.New("i2c: no ADS1115Driver data rate 100"))
+	d.DataRate = 128
+	d.Gain = 6
+	_, err = d.ReadVoltage("0")
+	gobot.Assert(t, err, errors.New("i2c: no ADS1x15 gain 6"))
+	gobot.Assert(t, d.Command("ReadVoltage")(map[string]interface{}{"pin": "1-2"}), "i2c: no ADS1x15 pin 1-2")
+}
+
+func TestADS1x15DriverConversionTime(t *testing.T) {
+	d, device := initTestADS1115Driver(t)
+	d.DataRate = 860
+	device.set(0x0100)
+	device.busy = 3
+	gobot.Assert(t, d.AnalogRead("0"), 256)
+	gobot.Assert(t, len(device.written()), 6)
+
+	device.busy = 1000
+	_, err := d.ReadVoltage("0")
+	gobot.Assert(t, err, errConversionTimeout)
+}
+
+func TestADS1015DriverAnalogRead(t *testing.T) {
+	device := &ads1x15TestDevice{}
+	d := NewADS1015Driver(&i2cTestBus{device}, "ads1015")
+	gobot.Assert(t, d.Connect(), true)
+
+	// 12 bits, left justified
+	device.set(0x7FF0)
+	gobot.Assert(t, d.AnalogRead("2-3"), 2047)
+	gobot.Assert(t, device.written()[0], []byte{0x01, 0xB5, 0x83})
+	voltage, err := d.ReadVoltage("2-3")
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, voltage, 2.047)
+
+	device.set(0x8000)
+	gobot.Assert(t, d.AnalogRead("2-3"), 0)
+	voltage, err = d.ReadVoltage("2-3")
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, voltage, -2.048)
+
+	gobot.Assert(t, d.Finalize(), true)
+	gobot.Assert(t, device.closed, true)
+}
+
+func TestADS1x15DriverAnalogSensorDriver(t *testing.T) {
+	d, device := initTestADS1115Driver(t)
+	device.set(0x1234)
+	sensor := gpio.NewAnalogSensorDriver(d, "sensor", "1")
+	gobot.Assert(t, sensor.Read(), 0x1234)
+	gobot.Assert(t, device.written()[0], []byte{0x01, 0xD5, 0x83})
+	gobot.Assert(t, sensor.ToJSON().Connection, "ads1115")
+}
@@ -2,15 +2,20 @@ package i2c
 
 import (
 	"fmt"
+	"log"
 
 	"github.com/edmontongo/gobot"
 )
 
+// blinkmAddress is the default address of a BlinkM.
+const blinkmAddress = 0x09
+
 type BlinkMDriver struct {
 	gobot.Driver
+	device I2cDevice
 }
 
-func NewBlinkMDriver(a I2cInterface, name string) *BlinkMDriver {
+func NewBlinkMDriver(a I2cBus, name string) *BlinkMDriver {
 	b := &BlinkMDriver{
 		Driver: *gobot.NewDriver(
 			name,
@@ -20,66 +25,115 @@ func NewBlinkMDriver(a I2cInterface, name string) *BlinkMDriver {
 	}
 
 	b.AddCommand("FirmwareVersion", func(params map[string]interface{}) interface{} {
-		return b.FirmwareVersion()
+		version, err := b.FirmwareVersion()
+		if err != nil {
+			return err.Error()
+		}
+		return version
 	})
 	b.AddCommand("Color", func(params map[string]interface{}) interface{} {
-		return b.Color()
+		color, err := b.Color()
+		if err != nil {
+			return err.Error()
+		}
+		return color
 	})
 	b.AddCommand("Rgb", func(params map[string]interface{}) interface{} {
 		red := byte(params["red"].(float64))
 		green := byte(params["green"].(float64))
 		blue := byte(params["blue"].(float64))
-		b.Rgb(red, green, blue)
-		return nil
+		return errorString(b.Rgb(red, green, blue))
 	})
 	b.AddCommand("Fade", func(params map[string]interface{}) interface{} {
 		red := byte(params["red"].(float64))
 		green := byte(params["green"].(float64))
 		blue := byte(params["blue"].(float64))
-		b.Fade(red, green, blue)
-		return nil
+		return errorString(b.Fade(red, green, blue))
 	})
 
 	return b
 }
 
-func (b *BlinkMDriver) adaptor() I2cInterface {
-	return b.Adaptor().(I2cInterface)
+func (b *BlinkMDriver) adaptor() I2cBus {
+	return b.Adaptor().(I2cBus)
 }
 
+// Start stops the script the BlinkM plays at power on, and turns it off.
 func (b *BlinkMDriver) Start() bool {
-	b.adaptor().I2cStart(0x09)
-	b.adaptor().I2cWrite([]byte("o"))
-	b.Rgb(0, 0, 0)
+	device, err := b.adaptor().I2cOpen(blinkmAddress)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	b.device = device
+	if _, err := b.device.Write([]byte("o")); err != nil {
+		log.Println(err)
+		return false
+	}
+	if err := b.Rgb(0, 0, 0); err != nil {
+		log.Println(err)
+		return false
+	}
 	return true
 }
 func (b *BlinkMDriver) Init() bool { return true }
-func (b *BlinkMDriver) Halt() bool { return true }
 
-func (b *BlinkMDriver) Rgb(red byte, green byte, blue byte) {
-	b.adaptor().I2cWrite([]byte("n"))
-	b.adaptor().I2cWrite([]byte{red, green, blue})
+// Halt closes the device.
+func (b *BlinkMDriver) Halt() bool {
+	if b.device != nil {
+		b.device.Close()
+	}
+	return true
+}
+
+// Rgb sets the color at once.
+func (b *BlinkMDriver) Rgb(red byte, green byte, blue byte) error {
+	return b.command('n', red, green, blue)
 }
 
-func (b *BlinkMDriver) Fade(red byte, green byte, blue byte) {
-	b.adaptor().I2cWrite([]byte("c"))
-	b.adaptor().I2cWrite([]byte{red, green, blue})
+// Fade fades to the color.
+func (b *BlinkMDriver) Fade(red byte, green byte, blue byte) error {
+	return b.command('c', red, green, blue)
 }
 
-func (b *BlinkMDriver) FirmwareVersion() string {
-	b.adaptor().I2cWrite([]byte("Z"))
-	data := b.adaptor().I2cRead(2)
-	if len(data) != 2 {
-		return ""
+// FirmwareVersion returns the version of the BlinkM firmware, such as
+// "97.98".
+func (b *BlinkMDriver) FirmwareVersion() (string, error) {
+	data, err := b.query('Z', 2)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v.%v", data[0], data[1]), nil
+}
+
+// Color returns the red, green and blue of the current color.
+func (b *BlinkMDriver) Color() ([]byte, error) {
+	return b.query('g', 3)
+}
+
+func (b *BlinkMDriver) command(command byte, args ...byte) error {
+	if _, err := b.device.Write([]byte{command}); err != nil {
+		return err
+	}
+	_, err := b.device.Write(args)
+	return err
+}
+
+func (b *BlinkMDriver) query(command byte, size int) ([]byte, error) {
+	if _, err := b.device.Write([]byte{command}); err != nil {
+		return nil, err
+	}
+	data := make([]byte, size)
+	if _, err := b.device.Read(data); err != nil {
+		return nil, err
 	}
-	return fmt.Sprintf("%v.%v", data[0], data[1])
+	return data, nil
 }
 
-func (b *BlinkMDriver) Color() []byte {
-	b.adaptor().I2cWrite([]byte("g"))
-	data := b.adaptor().I2cRead(3)
-	if len(data) != 3 {
-		return make([]byte, 0)
+// errorString returns the message of err for an API command, or nil.
+func errorString(err error) interface{} {
+	if err != nil {
+		return err.Error()
 	}
-	return []byte{data[0], data[1], data[2]}
+	return nil
 }
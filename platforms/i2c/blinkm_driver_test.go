@@ -1,15 +1,51 @@
 package i2c
 
 import (
-	"github.com/edmontongo/gobot"
 	"testing"
+
+	"github.com/edmontongo/gobot"
 )
 
-func initTestBlinkMDriver() *BlinkMDriver {
-	return NewBlinkMDriver(newI2cTestAdaptor("adaptor"), "bot")
+func initTestBlinkMDriver() (*BlinkMDriver, *i2cTestDevice) {
+	a := newI2cTestAdaptor("adaptor")
+	return NewBlinkMDriver(a, "bot"), a.addDevice(0x09)
 }
 
 func TestBlinkMDriverStart(t *testing.T) {
-	d := initTestBlinkMDriver()
+	d, device := initTestBlinkMDriver()
 	gobot.Assert(t, d.Start(), true)
+	gobot.Assert(t, device.written(), [][]byte{[]byte("o"), []byte("n"), {0, 0, 0}})
+}
+
+func TestBlinkMDriverStartNoDevice(t *testing.T) {
+	d := NewBlinkMDriver(newI2cTestAdaptor("adaptor"), "bot")
+	gobot.Assert(t, d.Start(), false)
+}
+
+func TestBlinkMDriverRgb(t *testing.T) {
+	d, device := initTestBlinkMDriver()
+	d.Start()
+	device.written()
+	gobot.Assert(t, d.Rgb(1, 2, 3), nil)
+	gobot.Assert(t, d.Fade(4, 5, 6), nil)
+	gobot.Assert(t, device.written(), [][]byte{[]byte("n"), {1, 2, 3}, []byte("c"), {4, 5, 6}})
+}
+
+func TestBlinkMDriverFirmwareVersion(t *testing.T) {
+	d, device := initTestBlinkMDriver()
+	d.Start()
+	device.set('Z', 'a', 'b')
+	version, err := d.FirmwareVersion()
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, version, "97.98")
+	gobot.Assert(t, d.Command("FirmwareVersion")(map[string]interface{}{}), "97.98")
+}
+
+func TestBlinkMDriverColor(t *testing.T) {
+	d, device := initTestBlinkMDriver()
+	d.Start()
+	device.set('g', 10, 20, 30)
+	color, err := d.Color()
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, color, []byte{10, 20, 30})
 }
@@ -0,0 +1,242 @@
+package i2c
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+// BMP180 registers.
+const (
+	bmp180Address byte = 0x77
+
+	bmp180Calibration byte = 0xAA
+	bmp180ID          byte = 0xD0
+	bmp180Control     byte = 0xF4
+	bmp180Data        byte = 0xF6
+
+	bmp180MeasureTemperature byte = 0x2E
+	bmp180MeasurePressure    byte = 0x34
+)
+
+// BMP180Oversampling is the number of samples the BMP180 averages for a
+// pressure, from 1 at ultra low power to 8 at ultra high resolution.
+type BMP180Oversampling byte
+
+const (
+	BMP180UltraLowPower BMP180Oversampling = iota
+	BMP180Standard
+	BMP180HighResolution
+	BMP180UltraHighResolution
+)
+
+// bmp180Coefficients are the coefficients each BMP180 is programmed with.
+type bmp180Coefficients struct {
+	ac1, ac2, ac3      int64
+	ac4, ac5, ac6      int64
+	b1, b2, mb, mc, md int64
+}
+
+// BMP180Driver reads the pressure and temperature of a BMP180.
+type BMP180Driver struct {
+	gobot.Driver
+	Oversampling BMP180Oversampling
+	device       I2cDevice
+	mutex        sync.Mutex
+	calibration  bmp180Coefficients
+}
+
+// NewBMP180Driver creates a driver for a BMP180 at standard resolution,
+// read every second.
+func NewBMP180Driver(a I2cBus, name string) *BMP180Driver {
+	b := &BMP180Driver{
+		Driver: *gobot.NewDriver(
+			name,
+			"BMP180Driver",
+			a.(gobot.AdaptorInterface),
+			time.Second,
+		),
+		Oversampling: BMP180Standard,
+	}
+
+	b.AddEvent("pressure")
+	b.AddEvent("temperature")
+	b.AddEvent("error")
+
+	b.AddCommand("Pressure", func(params map[string]interface{}) interface{} {
+		pressure, err := b.Pressure()
+		if err != nil {
+			return err.Error()
+		}
+		return pressure
+	})
+	b.AddCommand("Temperature", func(params map[string]interface{}) interface{} {
+		temperature, err := b.Temperature()
+		if err != nil {
+			return err.Error()
+		}
+		return temperature
+	})
+
+	return b
+}
+
+func (b *BMP180Driver) adaptor() I2cBus {
+	return b.Adaptor().(I2cBus)
+}
+
+// Start reads the calibration of the BMP180, then measures every interval,
+// publishing "pressure" in pascals and "temperature" in degrees Celsius, or
+// "error".
+func (b *BMP180Driver) Start() bool {
+	if err := b.init(); err != nil {
+		log.Println(err)
+		return false
+	}
+	gobot.Every(b.Interval(), func() {
+		temperature, pressure, err := b.measure()
+		if err != nil {
+			gobot.Publish(b.Event("error"), err)
+			return
+		}
+		gobot.Publish(b.Event("pressure"), pressure)
+		gobot.Publish(b.Event("temperature"), temperature)
+	})
+	return true
+}
+func (b *BMP180Driver) Init() bool { return true }
+
+// Halt closes the device.
+func (b *BMP180Driver) Halt() bool {
+	if b.device != nil {
+		b.device.Close()
+	}
+	return true
+}
+
+// Pressure measures the pressure, in pascals.
+func (b *BMP180Driver) Pressure() (float64, error) {
+	_, pressure, err := b.measure()
+	return pressure, err
+}
+
+// Temperature measures the temperature, in degrees Celsius.
+func (b *BMP180Driver) Temperature() (float64, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	ut, err := b.rawTemperature()
+	if err != nil {
+		return 0, err
+	}
+	return float64(b.temperature(ut)) / 10, nil
+}
+
+func (b *BMP180Driver) init() error {
+	device, err := b.adaptor().I2cOpen(bmp180Address)
+	if err != nil {
+		return err
+	}
+	b.device = device
+
+	data := make([]byte, 22)
+	if err := b.device.ReadBlockData(bmp180Calibration, data); err != nil {
+		return err
+	}
+	// eleven 16-bit words, high byte first, of which AC4 to AC6 are
+	// unsigned
+	words := make([]int64, 11)
+	for i := range words {
+		word := uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		if i >= 3 && i <= 5 {
+			words[i] = int64(word)
+		} else {
+			words[i] = int64(int16(word))
+		}
+	}
+	b.calibration = bmp180Coefficients{
+		ac1: words[0], ac2: words[1], ac3: words[2],
+		ac4: words[3], ac5: words[4], ac6: words[5],
+		b1: words[6], b2: words[7], mb: words[8], mc: words[9], md: words[10],
+	}
+	return nil
+}
+
+// measure measures the temperature, which the pressure is compensated
+// with, then the pressure.
+func (b *BMP180Driver) measure() (float64, float64, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	ut, err := b.rawTemperature()
+	if err != nil {
+		return 0, 0, err
+	}
+	up, err := b.rawPressure()
+	if err != nil {
+		return 0, 0, err
+	}
+	return float64(b.temperature(ut)) / 10, float64(b.pressure(ut, up)), nil
+}
+
+func (b *BMP180Driver) rawTemperature() (int64, error) {
+	if err := b.device.WriteByteData(bmp180Control, bmp180MeasureTemperature); err != nil {
+		return 0, err
+	}
+	time.Sleep(4500 * time.Microsecond)
+	data := make([]byte, 2)
+	if err := b.device.ReadBlockData(bmp180Data, data); err != nil {
+		return 0, err
+	}
+	return int64(data[0])<<8 | int64(data[1]), nil
+}
+
+func (b *BMP180Driver) rawPressure() (int64, error) {
+	oss := uint(b.Oversampling & 0x03)
+	if err := b.device.WriteByteData(bmp180Control, bmp180MeasurePressure|byte(oss<<6)); err != nil {
+		return 0, err
+	}
+	// 4.5ms at ultra low power to 25.5ms at ultra high resolution
+	time.Sleep(time.Duration(1500+3000<<oss) * time.Microsecond)
+	data := make([]byte, 3)
+	if err := b.device.ReadBlockData(bmp180Data, data); err != nil {
+		return 0, err
+	}
+	return (int64(data[0])<<16 | int64(data[1])<<8 | int64(data[2])) >> (8 - oss), nil
+}
+
+// b5 is the temperature term that both compensations use.
+func (b *BMP180Driver) b5(ut int64) int64 {
+	c := b.calibration
+	x1 := (ut - c.ac6) * c.ac5 >> 15
+	x2 := (c.mc << 11) / (x1 + c.md)
+	return x1 + x2
+}
+
+// temperature compensates ut, giving tenths of a degree Celsius, as in the
+// datasheet.
+func (b *BMP180Driver) temperature(ut int64) int64 {
+	return (b.b5(ut) + 8) >> 4
+}
+
+// pressure compensates up, measured at temperature ut, giving pascals, as
+// in the datasheet.
+func (b *BMP180Driver) pressure(ut int64, up int64) int64 {
+	c := b.calibration
+	oss := uint(b.Oversampling & 0x03)
+	b6 := b.b5(ut) - 4000
+	x1 := (c.b2 * (b6 * b6 >> 12)) >> 11
+	x2 := c.ac2 * b6 >> 11
+	x3 := x1 + x2
+	b3 := ((c.ac1*4+x3)<<oss + 2) / 4
+	x1 = c.ac3 * b6 >> 13
+	x2 = (c.b1 * (b6 * b6 >> 12)) >> 16
+	x3 = (x1 + x2 + 2) >> 2
+	b4 := c.ac4 * (x3 + 32768) >> 15
+	b7 := (up - b3) * (50000 >> oss)
+	p := b7 * 2 / b4
+	x1 = (p >> 8) * (p >> 8)
+	x1 = (x1 * 3038) >> 16
+	x2 = (-7357 * p) >> 16
+	return p + (x1+x2+3791)>>4
+}
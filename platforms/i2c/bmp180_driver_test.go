@@ -0,0 +1,75 @@
+package i2c
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+// bmp180Example is the calibration of the example in the datasheet.
+var bmp180Example = []byte{
+	0x01, 0x98, 0xff, 0xb8, 0xc7, 0xd1, 0x7f, 0xe5, 0x7f, 0xf5, 0x5a, 0x71,
+	0x18, 0x2e, 0x00, 0x04, 0x80, 0x00, 0xdd, 0xf9, 0x0b, 0x34,
+}
+
+func initTestBMP180Driver() (*BMP180Driver, *i2cTestDevice) {
+	a := newI2cTestAdaptor("adaptor")
+	device := a.addDevice(0x77)
+	device.set(0xAA, bmp180Example...)
+	return NewBMP180Driver(a, "bot"), device
+}
+
+func TestBMP180Driver(t *testing.T) {
+	d, _ := initTestBMP180Driver()
+	gobot.Assert(t, d.Oversampling, BMP180Standard)
+	gobot.Assert(t, d.Interval(), time.Second)
+	gobot.Refute(t, d.Command("Pressure"), nil)
+	gobot.Refute(t, d.Command("Temperature"), nil)
+}
+
+func TestBMP180DriverStart(t *testing.T) {
+	d, device := initTestBMP180Driver()
+	d.SetInterval(time.Hour)
+	gobot.Assert(t, d.Start(), true)
+	gobot.Assert(t, d.calibration, bmp180Coefficients{
+		ac1: 408, ac2: -72, ac3: -14383, ac4: 32741, ac5: 32757, ac6: 23153,
+		b1: 6190, b2: 4, mb: -32768, mc: -8711, md: 2868,
+	})
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, device.closed, true)
+}
+
+func TestBMP180DriverCompensation(t *testing.T) {
+	d, _ := initTestBMP180Driver()
+	d.Oversampling = BMP180UltraLowPower
+	gobot.Assert(t, d.init(), nil)
+	gobot.Assert(t, d.temperature(27898), int64(150))
+	gobot.Assert(t, d.pressure(27898, 23843), int64(69964))
+}
+
+func TestBMP180DriverMeasure(t *testing.T) {
+	d, device := initTestBMP180Driver()
+	d.Oversampling = BMP180UltraHighResolution
+	gobot.Assert(t, d.init(), nil)
+	device.written()
+	device.set(0xF6, 0x6c, 0xfa, 0x00)
+	temperature, err := d.Temperature()
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, temperature, 15.0)
+	gobot.Assert(t, device.written(), [][]byte{{0xF4, 0x2E}, {0xF6}})
+
+	_, err = d.Pressure()
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, device.written(), [][]byte{{0xF4, 0x2E}, {0xF6}, {0xF4, 0xF4}, {0xF6}})
+	gobot.Assert(t, d.Command("Temperature")(map[string]interface{}{}), 15.0)
+}
+
+func TestBMP180DriverMeasureError(t *testing.T) {
+	d, device := initTestBMP180Driver()
+	gobot.Assert(t, d.init(), nil)
+	device.absent = true
+	_, err := d.Pressure()
+	gobot.Assert(t, err, errNoDevice)
+	gobot.Assert(t, d.Command("Pressure")(map[string]interface{}{}), errNoDevice.Error())
+}
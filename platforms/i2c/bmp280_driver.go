@@ -0,0 +1,230 @@
+package i2c
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+// BMP280 registers.
+const (
+	bmp280Calibration byte = 0x88
+	bmp280ID          byte = 0xD0
+	bmp280CtrlMeas    byte = 0xF4
+	bmp280Config      byte = 0xF5
+	bmp280Data        byte = 0xF7
+
+	bmp280NormalMode byte = 0x03
+	bmp280SleepMode  byte = 0x00
+)
+
+// BMP280Oversampling is the number of samples the BMP280 averages for a
+// measurement, from 1 to 16, or none to skip it.
+type BMP280Oversampling byte
+
+const (
+	BMP280Skipped BMP280Oversampling = iota
+	BMP280Oversampling1
+	BMP280Oversampling2
+	BMP280Oversampling4
+	BMP280Oversampling8
+	BMP280Oversampling16
+)
+
+// BMP280Filter is the coefficient of the IIR filter the BMP280 smooths
+// pressures with, from off to 16.
+type BMP280Filter byte
+
+const (
+	BMP280FilterOff BMP280Filter = iota
+	BMP280Filter2
+	BMP280Filter4
+	BMP280Filter8
+	BMP280Filter16
+)
+
+// bmp280Coefficients are the coefficients each BMP280 is programmed with.
+type bmp280Coefficients struct {
+	t1, t2, t3                         int64
+	p1, p2, p3, p4, p5, p6, p7, p8, p9 int64
+}
+
+// BMP280Driver reads the pressure and temperature of a BMP280, which
+// measures continuously.
+type BMP280Driver struct {
+	gobot.Driver
+	// Address is 0x77, or 0x76 when the SDO pin is low.
+	Address                 byte
+	PressureOversampling    BMP280Oversampling
+	TemperatureOversampling BMP280Oversampling
+	Filter                  BMP280Filter
+	device                  I2cDevice
+	mutex                   sync.Mutex
+	calibration             bmp280Coefficients
+}
+
+// NewBMP280Driver creates a driver for a BMP280 at address 0x77, sampling
+// pressures 4 times and temperatures once, read every second.
+func NewBMP280Driver(a I2cBus, name string) *BMP280Driver {
+	b := &BMP280Driver{
+		Driver: *gobot.NewDriver(
+			name,
+			"BMP280Driver",
+			a.(gobot.AdaptorInterface),
+			time.Second,
+		),
+		Address:                 0x77,
+		PressureOversampling:    BMP280Oversampling4,
+		TemperatureOversampling: BMP280Oversampling1,
+		Filter:                  BMP280FilterOff,
+	}
+
+	b.AddEvent("pressure")
+	b.AddEvent("temperature")
+	b.AddEvent("error")
+
+	b.AddCommand("Pressure", func(params map[string]interface{}) interface{} {
+		pressure, err := b.Pressure()
+		if err != nil {
+			return err.Error()
+		}
+		return pressure
+	})
+	b.AddCommand("Temperature", func(params map[string]interface{}) interface{} {
+		temperature, err := b.Temperature()
+		if err != nil {
+			return err.Error()
+		}
+		return temperature
+	})
+
+	return b
+}
+
+func (b *BMP280Driver) adaptor() I2cBus {
+	return b.Adaptor().(I2cBus)
+}
+
+// Start reads the calibration of the BMP280 and sets it measuring, then
+// reads it every interval, publishing "pressure" in pascals and
+// "temperature" in degrees Celsius, or "error".
+func (b *BMP280Driver) Start() bool {
+	if err := b.init(); err != nil {
+		log.Println(err)
+		return false
+	}
+	gobot.Every(b.Interval(), func() {
+		temperature, pressure, err := b.read()
+		if err != nil {
+			gobot.Publish(b.Event("error"), err)
+			return
+		}
+		gobot.Publish(b.Event("pressure"), pressure)
+		gobot.Publish(b.Event("temperature"), temperature)
+	})
+	return true
+}
+func (b *BMP280Driver) Init() bool { return true }
+
+// Halt puts the BMP280 to sleep.
+func (b *BMP280Driver) Halt() bool {
+	if b.device != nil {
+		b.device.WriteByteData(bmp280CtrlMeas, bmp280SleepMode)
+		b.device.Close()
+	}
+	return true
+}
+
+// Pressure reads the latest pressure, in pascals.
+func (b *BMP280Driver) Pressure() (float64, error) {
+	_, pressure, err := b.read()
+	return pressure, err
+}
+
+// Temperature reads the latest temperature, in degrees Celsius.
+func (b *BMP280Driver) Temperature() (float64, error) {
+	temperature, _, err := b.read()
+	return temperature, err
+}
+
+func (b *BMP280Driver) init() error {
+	device, err := b.adaptor().I2cOpen(b.Address)
+	if err != nil {
+		return err
+	}
+	b.device = device
+
+	data := make([]byte, 24)
+	if err := b.device.ReadBlockData(bmp280Calibration, data); err != nil {
+		return err
+	}
+	// twelve 16-bit words, low byte first, of which T1 and P1 are unsigned
+	words := make([]int64, 12)
+	for i := range words {
+		word := uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		if i == 0 || i == 3 {
+			words[i] = int64(word)
+		} else {
+			words[i] = int64(int16(word))
+		}
+	}
+	b.calibration = bmp280Coefficients{
+		t1: words[0], t2: words[1], t3: words[2],
+		p1: words[3], p2: words[4], p3: words[5], p4: words[6], p5: words[7],
+		p6: words[8], p7: words[9], p8: words[10], p9: words[11],
+	}
+
+	// the filter must be set while the BMP280 sleeps
+	if err := b.device.WriteByteData(bmp280Config, byte(b.Filter&0x07)<<2); err != nil {
+		return err
+	}
+	ctrl := byte(b.TemperatureOversampling&0x07)<<5 | byte(b.PressureOversampling&0x07)<<2 | bmp280NormalMode
+	return b.device.WriteByteData(bmp280CtrlMeas, ctrl)
+}
+
+// read reads the pressure and temperature, which are 20-bit registers high
+// byte first, and compensates them.
+func (b *BMP280Driver) read() (float64, float64, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	data := make([]byte, 6)
+	if err := b.device.ReadBlockData(bmp280Data, data); err != nil {
+		return 0, 0, err
+	}
+	up := int64(data[0])<<12 | int64(data[1])<<4 | int64(data[2])>>4
+	ut := int64(data[3])<<12 | int64(data[4])<<4 | int64(data[5])>>4
+	temperature, fine := b.temperature(ut)
+	return float64(temperature) / 100, float64(b.pressure(up, fine)) / 256, nil
+}
+
+// temperature compensates ut, giving hundredths of a degree Celsius, and
+// the fine temperature the pressure is compensated with, as in the
+// datasheet.
+func (b *BMP280Driver) temperature(ut int64) (int64, int64) {
+	c := b.calibration
+	var1 := ((ut >> 3) - (c.t1 << 1)) * c.t2 >> 11
+	var2 := (((ut >> 4) - c.t1) * ((ut >> 4) - c.t1) >> 12) * c.t3 >> 14
+	fine := var1 + var2
+	return (fine*5 + 128) >> 8, fine
+}
+
+// pressure compensates up, giving 256ths of a pascal, as in the datasheet.
+func (b *BMP280Driver) pressure(up int64, fine int64) int64 {
+	c := b.calibration
+	var1 := fine - 128000
+	var2 := var1 * var1 * c.p6
+	var2 += (var1 * c.p5) << 17
+	var2 += c.p4 << 35
+	var1 = (var1 * var1 * c.p3 >> 8) + (var1 * c.p2 << 12)
+	var1 = ((int64(1) << 47) + var1) * c.p1 >> 33
+	if var1 == 0 {
+		return 0
+	}
+	p := 1048576 - up
+	p = ((p<<31 - var2) * 3125) / var1
+	var1 = (c.p9 * (p >> 13) * (p >> 13)) >> 25
+	var2 = (c.p8 * p) >> 19
+	return ((p + var1 + var2) >> 8) + (c.p7 << 4)
+}
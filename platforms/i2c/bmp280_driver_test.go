@@ -0,0 +1,71 @@
+package i2c
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+// bmp280Example is the calibration of the example in the datasheet.
+var bmp280Example = []byte{
+	0x70, 0x6b, 0x43, 0x67, 0x18, 0xfc, 0x7d, 0x8e, 0x43, 0xd6, 0xd0, 0x0b,
+	0x27, 0x0b, 0x8c, 0x00, 0xf9, 0xff, 0x8c, 0x3c, 0xf8, 0xc6, 0x70, 0x17,
+}
+
+func initTestBMP280Driver() (*BMP280Driver, *i2cTestDevice) {
+	a := newI2cTestAdaptor("adaptor")
+	device := a.addDevice(0x77)
+	device.set(0x88, bmp280Example...)
+	return NewBMP280Driver(a, "bot"), device
+}
+
+func TestBMP280Driver(t *testing.T) {
+	d, _ := initTestBMP280Driver()
+	gobot.Assert(t, d.Address, byte(0x77))
+	gobot.Assert(t, d.Interval(), time.Second)
+	gobot.Refute(t, d.Command("Pressure"), nil)
+	gobot.Refute(t, d.Command("Temperature"), nil)
+}
+
+func TestBMP280DriverStart(t *testing.T) {
+	d, device := initTestBMP280Driver()
+	d.SetInterval(time.Hour)
+	d.PressureOversampling = BMP280Oversampling16
+	d.TemperatureOversampling = BMP280Oversampling2
+	d.Filter = BMP280Filter4
+	gobot.Assert(t, d.Start(), true)
+	gobot.Assert(t, d.calibration, bmp280Coefficients{
+		t1: 27504, t2: 26435, t3: -1000,
+		p1: 36477, p2: -10685, p3: 3024, p4: 2855, p5: 140,
+		p6: -7, p7: 15500, p8: -14600, p9: 6000,
+	})
+	gobot.Assert(t, device.written(), [][]byte{{0x88}, {0xF5, 0x08}, {0xF4, 0x57}})
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, device.written(), [][]byte{{0xF4, 0x00}})
+	gobot.Assert(t, device.closed, true)
+}
+
+func TestBMP280DriverRead(t *testing.T) {
+	d, device := initTestBMP280Driver()
+	gobot.Assert(t, d.init(), nil)
+	// the readings of the example in the datasheet
+	device.set(0xF7, 0x65, 0x5a, 0xc0, 0x7e, 0xed, 0x00)
+	temperature, err := d.Temperature()
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, temperature, 25.08)
+	pressure, err := d.Pressure()
+	gobot.Assert(t, err, nil)
+	// the datasheet gives 100653.27 from its floating point compensation
+	gobot.Assert(t, pressure, 100653.25390625)
+	gobot.Assert(t, d.Command("Pressure")(map[string]interface{}{}), pressure)
+}
+
+func TestBMP280DriverReadError(t *testing.T) {
+	d, device := initTestBMP280Driver()
+	gobot.Assert(t, d.init(), nil)
+	device.absent = true
+	_, err := d.Temperature()
+	gobot.Assert(t, err, errNoDevice)
+	gobot.Assert(t, d.Command("Temperature")(map[string]interface{}{}), errNoDevice.Error())
+}
@@ -0,0 +1,106 @@
+package i2c
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// magneticOverflow is read from an axis of an HMC5883L or LSM303
+// magnetometer when the field is beyond its range.
+const magneticOverflow = -4096
+
+// errMagneticOverflow is returned when the field is beyond the range of a
+// magnetometer.
+var errMagneticOverflow = errors.New("i2c: magnetic field out of range")
+
+// ThreeDData is a reading along the X, Y and Z axes of a sensor.
+type ThreeDData struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+func (d ThreeDData) sub(o ThreeDData) ThreeDData {
+	return ThreeDData{d.X - o.X, d.Y - o.Y, d.Z - o.Z}
+}
+
+func (d ThreeDData) cross(o ThreeDData) ThreeDData {
+	return ThreeDData{
+		d.Y*o.Z - d.Z*o.Y,
+		d.Z*o.X - d.X*o.Z,
+		d.X*o.Y - d.Y*o.X,
+	}
+}
+
+// Heading returns the compass heading of the X axis of a magnetometer held
+// level, in degrees clockwise from magnetic north, from 0 to 360. The Y
+// axis is to the left of X and Z is up, as printed on most breakouts.
+func Heading(magnetic ThreeDData) float64 {
+	return degrees360(math.Atan2(magnetic.Y, magnetic.X))
+}
+
+// TiltCompensatedHeading returns the compass heading of the X axis of a
+// magnetometer however it is tilted, using the acceleration of an
+// accelerometer with the same axes, held still, to find which way is up.
+func TiltCompensatedHeading(magnetic ThreeDData, acceleration ThreeDData) float64 {
+	// an accelerometer at rest reads 1g upwards, so east is across the
+	// magnetic field and up, and north is across up and east
+	east := magnetic.cross(acceleration)
+	north := acceleration.cross(east)
+	return degrees360(math.Atan2(east.X, north.X))
+}
+
+// withDeclination adds declination, in degrees east, to a magnetic heading
+// to give it from true north.
+func withDeclination(heading float64, declination float64) float64 {
+	return math.Mod(heading+declination+360, 360)
+}
+
+// degrees360 converts radians to degrees from 0 to 360.
+func degrees360(radians float64) float64 {
+	degrees := radians * 180 / math.Pi
+	if degrees < 0 {
+		degrees += 360
+	}
+	return degrees
+}
+
+// readMagnetic reads the X, Z and Y axes of an HMC5883L or LSM303
+// magnetometer, in that order from reg, which are 16-bit registers high
+// byte first, and converts them to gauss.
+func readMagnetic(device I2cDevice, reg byte, xyGain float64, zGain float64) (ThreeDData, error) {
+	data := make([]byte, 6)
+	if err := device.ReadBlockData(reg, data); err != nil {
+		return ThreeDData{}, err
+	}
+	x := int16(uint16(data[0])<<8 | uint16(data[1]))
+	z := int16(uint16(data[2])<<8 | uint16(data[3]))
+	y := int16(uint16(data[4])<<8 | uint16(data[5]))
+	if x == magneticOverflow || y == magneticOverflow || z == magneticOverflow {
+		return ThreeDData{}, errMagneticOverflow
+	}
+	return ThreeDData{float64(x) / xyGain, float64(y) / xyGain, float64(z) / zGain}, nil
+}
+
+// calibrateHardIron calls read every interval for duration, and returns
+// the middle of the smallest and largest readings on each axis: the field
+// of magnets and iron that turn with the magnetometer.
+func calibrateHardIron(duration time.Duration, interval time.Duration, read func() (ThreeDData, error)) (ThreeDData, error) {
+	min := ThreeDData{math.Inf(1), math.Inf(1), math.Inf(1)}
+	max := ThreeDData{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	end := time.Now().Add(duration)
+	for {
+		reading, err := read()
+		if err != nil {
+			return ThreeDData{}, err
+		}
+		min = ThreeDData{math.Min(min.X, reading.X), math.Min(min.Y, reading.Y), math.Min(min.Z, reading.Z)}
+		max = ThreeDData{math.Max(max.X, reading.X), math.Max(max.Y, reading.Y), math.Max(max.Z, reading.Z)}
+		if !time.Now().Before(end) {
+			break
+		}
+		time.Sleep(interval)
+	}
+	return ThreeDData{(min.X + max.X) / 2, (min.Y + max.Y) / 2, (min.Z + max.Z) / 2}, nil
+}
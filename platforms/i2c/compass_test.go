@@ -0,0 +1,79 @@
+package i2c
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+func round(value float64) float64 {
+	return math.Floor(value*1000+0.5) / 1000
+}
+
+func dot(a ThreeDData, b ThreeDData) float64 {
+	return a.X*b.X + a.Y*b.Y + a.Z*b.Z
+}
+
+func scale(a ThreeDData, s float64) ThreeDData {
+	return ThreeDData{a.X * s, a.Y * s, a.Z * s}
+}
+
+func add(a ThreeDData, b ThreeDData) ThreeDData {
+	return ThreeDData{a.X + b.X, a.Y + b.Y, a.Z + b.Z}
+}
+
+// tilted returns what a sensor heading, pitched nose up and rolled right
+// by the angles, in degrees, reads of a vector given in east, north and up
+// components.
+func tilted(v ThreeDData, heading float64, pitch float64, roll float64) ThreeDData {
+	h, p, r := heading*math.Pi/180, pitch*math.Pi/180, roll*math.Pi/180
+	forward := ThreeDData{math.Sin(h), math.Cos(h), 0}
+	left := ThreeDData{-math.Cos(h), math.Sin(h), 0}
+	up := ThreeDData{0, 0, 1}
+	forward, up = add(scale(forward, math.Cos(p)), scale(up, math.Sin(p))),
+		add(scale(up, math.Cos(p)), scale(forward, -math.Sin(p)))
+	left, up = add(scale(left, math.Cos(r)), scale(up, math.Sin(r))),
+		add(scale(up, math.Cos(r)), scale(left, -math.Sin(r)))
+	return ThreeDData{dot(v, forward), dot(v, left), dot(v, up)}
+}
+
+func TestHeading(t *testing.T) {
+	gobot.Assert(t, Heading(ThreeDData{X: 0.2, Y: 0, Z: -0.4}), 0.0)
+	gobot.Assert(t, Heading(ThreeDData{X: 0, Y: 0.2, Z: -0.4}), 90.0)
+	gobot.Assert(t, Heading(ThreeDData{X: -0.2, Y: 0, Z: -0.4}), 180.0)
+	gobot.Assert(t, Heading(ThreeDData{X: 0, Y: -0.2, Z: -0.4}), 270.0)
+
+	field := ThreeDData{0, 0.25, -0.43}
+	gobot.Assert(t, round(Heading(tilted(field, 135, 0, 0))), 135.0)
+}
+
+func TestTiltCompensatedHeading(t *testing.T) {
+	// a field dipping 60 degrees, as in northern Europe
+	field := ThreeDData{0, 0.25, -0.43}
+	gravity := ThreeDData{0, 0, 1}
+	for _, heading := range []float64{0, 45, 135, 300} {
+		magnetic := tilted(field, heading, 20, -15)
+		acceleration := tilted(gravity, heading, 20, -15)
+		gobot.Assert(t, round(TiltCompensatedHeading(magnetic, acceleration)), heading)
+		// which is needed once tilted
+		gobot.Refute(t, round(Heading(magnetic)), heading)
+	}
+}
+
+func TestCalibrateHardIron(t *testing.T) {
+	readings := []ThreeDData{{1, 2, 3}, {-3, 4, 1}, {2, 0, 5}}
+	i := 0
+	offset, err := calibrateHardIron(20*time.Millisecond, time.Millisecond, func() (ThreeDData, error) {
+		i++
+		return readings[i%len(readings)], nil
+	})
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, offset, ThreeDData{-0.5, 2, 3})
+}
+
+func TestWithDeclination(t *testing.T) {
+	gobot.Assert(t, withDeclination(350, 15), 5.0)
+	gobot.Assert(t, withDeclination(10, -15), 355.0)
+}
@@ -0,0 +1,145 @@
+package i2c
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/edmontongo/gobot/platforms/gpio"
+)
+
+// errNoInterrupt is returned by WatchDigital of an expander whose
+// interrupt output is not wired, so that drivers poll instead.
+var errNoInterrupt = errors.New("i2c: expander has no interrupt pin")
+
+// expanderWatch is the handler of changes of an expander pin.
+type expanderWatch struct {
+	edge    string
+	handler func(level int)
+}
+
+// expanderInterrupts reports changes of the inputs of a GPIO expander to
+// the handlers of its WatchDigital. The interrupt output of the expander,
+// which falls when an input changes, is wired to a pin of another adaptor
+// that watches it, and each fall reads the inputs and compares them with
+// the last.
+type expanderInterrupts struct {
+	mutex   sync.Mutex
+	watcher gpio.DigitalWatcher
+	pin     string
+	read    func() (uint16, error)
+	levels  uint16
+	watches map[int]expanderWatch
+}
+
+// start watches pin of watcher for interrupts, reading the inputs with
+// read. Without a pin, it does nothing.
+func (e *expanderInterrupts) start(watcher gpio.DigitalWatcher, pin string, read func() (uint16, error)) error {
+	if watcher == nil || pin == "" {
+		return nil
+	}
+	levels, err := read()
+	if err != nil {
+		return err
+	}
+	e.mutex.Lock()
+	e.watcher = watcher
+	e.pin = pin
+	e.read = read
+	e.levels = levels
+	if e.watches == nil {
+		e.watches = make(map[int]expanderWatch)
+	}
+	e.mutex.Unlock()
+	return watcher.WatchDigital(pin, gpio.EdgeFalling, e.interrupt)
+}
+
+// stop stops watching for interrupts.
+func (e *expanderInterrupts) stop() {
+	e.mutex.Lock()
+	watcher, pin := e.watcher, e.pin
+	e.watcher = nil
+	e.read = nil
+	e.mutex.Unlock()
+	if watcher != nil {
+		watcher.UnwatchDigital(pin)
+	}
+}
+
+// watch calls handler with the level of pin, and again after each change
+// on edge.
+func (e *expanderInterrupts) watch(pin int, edge string, handler func(int)) error {
+	e.mutex.Lock()
+	read := e.read
+	e.mutex.Unlock()
+	if read == nil {
+		return errNoInterrupt
+	}
+	levels, err := read()
+	if err != nil {
+		return err
+	}
+
+	// the read clears any interrupt, so changes of other pins are
+	// reported now
+	e.mutex.Lock()
+	calls := e.changes(levels)
+	e.watches[pin] = expanderWatch{edge: edge, handler: handler}
+	e.mutex.Unlock()
+	for _, call := range calls {
+		call()
+	}
+	handler(int(levels >> uint(pin) & 1))
+	return nil
+}
+
+// unwatch stops calling the handler of pin.
+func (e *expanderInterrupts) unwatch(pin int) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	delete(e.watches, pin)
+}
+
+// interrupt reads the inputs, which also clears the interrupt, and calls
+// the handlers of the pins that changed on their edges. The level of the
+// interrupt pin does not matter, as the watcher also reports it when
+// watching starts.
+func (e *expanderInterrupts) interrupt(level int) {
+	e.mutex.Lock()
+	read := e.read
+	e.mutex.Unlock()
+	if read == nil {
+		return
+	}
+	levels, err := read()
+	if err != nil {
+		logError(err)
+		return
+	}
+
+	e.mutex.Lock()
+	calls := e.changes(levels)
+	e.mutex.Unlock()
+	for _, call := range calls {
+		call()
+	}
+}
+
+// changes records levels, and returns the calls of the handlers of the
+// pins that changed on their edges.
+func (e *expanderInterrupts) changes(levels uint16) []func() {
+	changed := levels ^ e.levels
+	e.levels = levels
+	calls := []func(){}
+	for pin, w := range e.watches {
+		if changed>>uint(pin)&1 == 0 {
+			continue
+		}
+		level := int(levels >> uint(pin) & 1)
+		if w.edge == gpio.EdgeRising && level == 0 || w.edge == gpio.EdgeFalling && level == 1 {
+			continue
+		}
+		handler := w.handler
+		calls = append(calls, func() { handler(level) })
+	}
+	return calls
+}
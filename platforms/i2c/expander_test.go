@@ -0,0 +1,142 @@
+package i2c
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/gpio"
+)
+
+// testDigitalWatcher is a fake adaptor pin wired to the interrupt output
+// of an expander.
+type testDigitalWatcher struct {
+	mutex    sync.Mutex
+	handlers map[string]func(int)
+	edges    map[string]string
+}
+
+func newTestDigitalWatcher() *testDigitalWatcher {
+	return &testDigitalWatcher{
+		handlers: make(map[string]func(int)),
+		edges:    make(map[string]string),
+	}
+}
+
+func (w *testDigitalWatcher) WatchDigital(pin string, edge string, handler func(int)) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.handlers[pin] = handler
+	w.edges[pin] = edge
+	return nil
+}
+
+func (w *testDigitalWatcher) UnwatchDigital(pin string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	delete(w.handlers, pin)
+	return nil
+}
+
+// fire calls the handler of pin, as when the interrupt output falls.
+func (w *testDigitalWatcher) fire(pin string) {
+	w.mutex.Lock()
+	handler := w.handlers[pin]
+	w.mutex.Unlock()
+	if handler != nil {
+		handler(0)
+	}
+}
+
+func (w *testDigitalWatcher) watching(pin string) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.handlers[pin] != nil
+}
+
+// testInputs are the levels of fake expander inputs.
+type testInputs struct {
+	mutex  sync.Mutex
+	levels uint16
+	err    error
+}
+
+func (i *testInputs) set(levels uint16) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.levels = levels
+}
+
+func (i *testInputs) read() (uint16, error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return i.levels, i.err
+}
+
+func TestExpanderInterruptsWithoutPin(t *testing.T) {
+	var e expanderInterrupts
+	inputs := &testInputs{}
+	gobot.Assert(t, e.start(newTestDigitalWatcher(), "", inputs.read), nil)
+	gobot.Assert(t, e.start(nil, "7", inputs.read), nil)
+	gobot.Assert(t, e.watch(0, gpio.EdgeBoth, func(int) {}), errNoInterrupt)
+	e.stop()
+}
+
+func TestExpanderInterruptsStart(t *testing.T) {
+	var e expanderInterrupts
+	watcher := newTestDigitalWatcher()
+	inputs := &testInputs{err: errNoDevice}
+	gobot.Assert(t, e.start(watcher, "7", inputs.read), errNoDevice)
+	gobot.Assert(t, watcher.watching("7"), false)
+
+	inputs.err = nil
+	gobot.Assert(t, e.start(watcher, "7", inputs.read), nil)
+	gobot.Assert(t, watcher.edges["7"], gpio.EdgeFalling)
+	e.stop()
+	gobot.Assert(t, watcher.watching("7"), false)
+	gobot.Assert(t, e.watch(0, gpio.EdgeBoth, func(int) {}), errNoInterrupt)
+}
+
+func TestExpanderInterruptsWatch(t *testing.T) {
+	var e expanderInterrupts
+	watcher := newTestDigitalWatcher()
+	inputs := &testInputs{levels: 0x0002}
+	gobot.Assert(t, e.start(watcher, "7", inputs.read), nil)
+
+	var both, rising, falling []int
+	gobot.Assert(t, e.watch(1, gpio.EdgeBoth, func(level int) { both = append(both, level) }), nil)
+	gobot.Assert(t, e.watch(2, gpio.EdgeRising, func(level int) { rising = append(rising, level) }), nil)
+	gobot.Assert(t, e.watch(9, gpio.EdgeFalling, func(level int) { falling = append(falling, level) }), nil)
+	gobot.Assert(t, both, []int{1})
+	gobot.Assert(t, rising, []int{0})
+	gobot.Assert(t, falling, []int{0})
+
+	for _, levels := range []uint16{0x0204, 0x0000, 0x0006, 0x0006, 0x0200} {
+		inputs.set(levels)
+		watcher.fire("7")
+	}
+	gobot.Assert(t, both, []int{1, 0, 1, 0})
+	gobot.Assert(t, rising, []int{0, 1, 1})
+	gobot.Assert(t, falling, []int{0, 0})
+
+	e.unwatch(1)
+	inputs.set(0x0002)
+	watcher.fire("7")
+	gobot.Assert(t, both, []int{1, 0, 1, 0})
+	gobot.Assert(t, falling, []int{0, 0, 0})
+}
+
+func TestExpanderInterruptsReadError(t *testing.T) {
+	var e expanderInterrupts
+	watcher := newTestDigitalWatcher()
+	inputs := &testInputs{}
+	gobot.Assert(t, e.start(watcher, "7", inputs.read), nil)
+	calls := 0
+	gobot.Assert(t, e.watch(0, gpio.EdgeBoth, func(int) { calls++ }), nil)
+
+	inputs.err = errors.New("read failed")
+	watcher.fire("7")
+	gobot.Assert(t, e.watch(1, gpio.EdgeBoth, func(int) {}), inputs.err)
+	gobot.Assert(t, calls, 1)
+}
@@ -0,0 +1,220 @@
+package i2c
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+// HMC5883L registers.
+const (
+	hmc5883lAddress byte = 0x1E
+
+	hmc5883lConfigA byte = 0x00
+	hmc5883lConfigB byte = 0x01
+	hmc5883lMode    byte = 0x02
+	hmc5883lDataX   byte = 0x03
+	hmc5883lIDA     byte = 0x0A
+
+	hmc5883lContinuous byte = 0x00
+	hmc5883lIdle       byte = 0x03
+)
+
+// HMC5883LGain is the range of the magnetometer, from ±0.88 to ±8.1
+// gauss.
+type HMC5883LGain byte
+
+const (
+	HMC5883LGain0_88 HMC5883LGain = iota
+	HMC5883LGain1_3
+	HMC5883LGain1_9
+	HMC5883LGain2_5
+	HMC5883LGain4_0
+	HMC5883LGain4_7
+	HMC5883LGain5_6
+	HMC5883LGain8_1
+)
+
+// hmc5883lGains are the readings per gauss of each gain.
+var hmc5883lGains = []float64{1370, 1090, 820, 660, 440, 390, 330, 230}
+
+// HMC5883LRate is how many times a second the magnetometer measures, from
+// 0.75 to 75.
+type HMC5883LRate byte
+
+const (
+	HMC5883LRate0_75 HMC5883LRate = iota
+	HMC5883LRate1_5
+	HMC5883LRate3
+	HMC5883LRate7_5
+	HMC5883LRate15
+	HMC5883LRate30
+	HMC5883LRate75
+)
+
+// HMC5883LDriver reads the magnetic field of an HMC5883L magnetometer, and
+// the compass heading it gives.
+type HMC5883LDriver struct {
+	gobot.Driver
+	Gain HMC5883LGain
+	Rate HMC5883LRate
+	// Samples is the number of measurements averaged for each reading, 1,
+	// 2, 4 or 8.
+	Samples int
+	// Declination, in degrees east, is added to headings to give them from
+	// true north.
+	Declination float64
+	// Offset is subtracted from each reading, to cancel the field of
+	// magnets and iron near the magnetometer. CalibrateHardIron measures
+	// it.
+	Offset   ThreeDData
+	device   I2cDevice
+	mutex    sync.Mutex
+	magnetic ThreeDData
+}
+
+// NewHMC5883LDriver creates a driver for an HMC5883L with a range of ±1.3
+// gauss, measuring 15 times a second.
+func NewHMC5883LDriver(a I2cBus, name string) *HMC5883LDriver {
+	h := &HMC5883LDriver{
+		Driver: *gobot.NewDriver(
+			name,
+			"HMC5883LDriver",
+			a.(gobot.AdaptorInterface),
+			// the default rate
+			67*time.Millisecond,
+		),
+		Gain:    HMC5883LGain1_3,
+		Rate:    HMC5883LRate15,
+		Samples: 1,
+	}
+
+	h.AddEvent("magnetic")
+	h.AddEvent("heading")
+	h.AddEvent("error")
+
+	h.AddCommand("Magnetic", func(params map[string]interface{}) interface{} {
+		return h.Magnetic()
+	})
+	h.AddCommand("Heading", func(params map[string]interface{}) interface{} {
+		return h.Heading()
+	})
+	h.AddCommand("CalibrateHardIron", func(params map[string]interface{}) interface{} {
+		offset, err := h.CalibrateHardIron(time.Duration(params["seconds"].(float64) * float64(time.Second)))
+		if err != nil {
+			return err.Error()
+		}
+		return offset
+	})
+
+	return h
+}
+
+func (h *HMC5883LDriver) adaptor() I2cBus {
+	return h.Adaptor().(I2cBus)
+}
+
+// Start sets the range, rate and averaging of the HMC5883L and has it
+// measure continuously. It is read every interval, publishing "magnetic"
+// in gauss and "heading" in degrees, or "error".
+func (h *HMC5883LDriver) Start() bool {
+	if err := h.init(); err != nil {
+		log.Println(err)
+		return false
+	}
+	gobot.Every(h.Interval(), func() {
+		if err := h.update(); err != nil {
+			gobot.Publish(h.Event("error"), err)
+			return
+		}
+		gobot.Publish(h.Event("magnetic"), h.Magnetic())
+		gobot.Publish(h.Event("heading"), h.Heading())
+	})
+	return true
+}
+func (h *HMC5883LDriver) Init() bool { return true }
+
+// Halt stops the HMC5883L measuring.
+func (h *HMC5883LDriver) Halt() bool {
+	if h.device != nil {
+		h.device.WriteByteData(hmc5883lMode, hmc5883lIdle)
+		h.device.Close()
+	}
+	return true
+}
+
+// Magnetic returns the last field read, less Offset, in gauss.
+func (h *HMC5883LDriver) Magnetic() ThreeDData {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.magnetic
+}
+
+// Heading returns the heading of the last field read, with the HMC5883L
+// held level, in degrees from 0 to 360.
+func (h *HMC5883LDriver) Heading() float64 {
+	return withDeclination(Heading(h.Magnetic()), h.Declination)
+}
+
+// TiltCompensatedHeading returns the heading of the last field read,
+// however the HMC5883L is tilted, given the acceleration of an
+// accelerometer with the same axes.
+func (h *HMC5883LDriver) TiltCompensatedHeading(acceleration ThreeDData) float64 {
+	return withDeclination(TiltCompensatedHeading(h.Magnetic(), acceleration), h.Declination)
+}
+
+// CalibrateHardIron reads the field for duration, while the HMC5883L is
+// turned in every direction, and makes the middle of the readings on each
+// axis the Offset.
+func (h *HMC5883LDriver) CalibrateHardIron(duration time.Duration) (ThreeDData, error) {
+	offset, err := calibrateHardIron(duration, h.Interval(), h.read)
+	if err != nil {
+		return ThreeDData{}, err
+	}
+	h.mutex.Lock()
+	h.Offset = offset
+	h.mutex.Unlock()
+	return offset, nil
+}
+
+func (h *HMC5883LDriver) init() error {
+	device, err := h.adaptor().I2cOpen(hmc5883lAddress)
+	if err != nil {
+		return err
+	}
+	h.device = device
+
+	samples := byte(0)
+	for s := h.Samples; s > 1 && samples < 3; s >>= 1 {
+		samples++
+	}
+	writes := [][2]byte{
+		{hmc5883lConfigA, samples<<5 | byte(h.Rate&0x07)<<2},
+		{hmc5883lConfigB, byte(h.Gain&0x07) << 5},
+		{hmc5883lMode, hmc5883lContinuous},
+	}
+	for _, w := range writes {
+		if err := h.device.WriteByteData(w[0], w[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *HMC5883LDriver) update() error {
+	magnetic, err := h.read()
+	if err != nil {
+		return err
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.magnetic = magnetic.sub(h.Offset)
+	return nil
+}
+
+func (h *HMC5883LDriver) read() (ThreeDData, error) {
+	gain := hmc5883lGains[h.Gain&0x07]
+	return readMagnetic(h.device, hmc5883lDataX, gain, gain)
+}
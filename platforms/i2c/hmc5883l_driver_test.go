@@ -0,0 +1,74 @@
+package i2c
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestHMC5883LDriver() (*HMC5883LDriver, *i2cTestDevice) {
+	a := newI2cTestAdaptor("adaptor")
+	return NewHMC5883LDriver(a, "bot"), a.addDevice(0x1E)
+}
+
+func TestHMC5883LDriver(t *testing.T) {
+	d, _ := initTestHMC5883LDriver()
+	gobot.Assert(t, d.Gain, HMC5883LGain1_3)
+	gobot.Assert(t, d.Rate, HMC5883LRate15)
+	gobot.Refute(t, d.Command("Magnetic"), nil)
+	gobot.Refute(t, d.Command("Heading"), nil)
+	gobot.Refute(t, d.Command("CalibrateHardIron"), nil)
+}
+
+func TestHMC5883LDriverStart(t *testing.T) {
+	d, device := initTestHMC5883LDriver()
+	d.SetInterval(time.Hour)
+	d.Samples = 8
+	d.Gain = HMC5883LGain8_1
+	gobot.Assert(t, d.Start(), true)
+	gobot.Assert(t, device.written(), [][]byte{
+		{0x00, 0x70},
+		{0x01, 0xe0},
+		{0x02, 0x00},
+	})
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, device.written(), [][]byte{{0x02, 0x03}})
+	gobot.Assert(t, device.closed, true)
+}
+
+func TestHMC5883LDriverUpdate(t *testing.T) {
+	d, device := initTestHMC5883LDriver()
+	gobot.Assert(t, d.init(), nil)
+	// X 1 gauss, Z -0.5 gauss, Y 1 gauss
+	device.set(0x03, 0x04, 0x42, 0xfd, 0xdf, 0x04, 0x42)
+	gobot.Assert(t, d.update(), nil)
+	gobot.Assert(t, d.Magnetic(), ThreeDData{1, 1, -0.5})
+	gobot.Assert(t, d.Heading(), 45.0)
+	d.Declination = -50
+	gobot.Assert(t, d.Heading(), 355.0)
+	gobot.Assert(t, d.TiltCompensatedHeading(ThreeDData{0, 0, 1}), 355.0)
+
+	d.Offset = ThreeDData{1, 0, 0}
+	gobot.Assert(t, d.update(), nil)
+	gobot.Assert(t, d.Magnetic(), ThreeDData{0, 1, -0.5})
+}
+
+func TestHMC5883LDriverOverflow(t *testing.T) {
+	d, device := initTestHMC5883LDriver()
+	gobot.Assert(t, d.init(), nil)
+	device.set(0x03, 0x00, 0x00, 0xf0, 0x00, 0x00, 0x00)
+	gobot.Assert(t, d.update(), errMagneticOverflow)
+}
+
+func TestHMC5883LDriverCalibrateHardIron(t *testing.T) {
+	d, device := initTestHMC5883LDriver()
+	gobot.Assert(t, d.init(), nil)
+	// X 1 gauss, Z -0.5 gauss, Y 1 gauss
+	device.set(0x03, 0x04, 0x42, 0xfd, 0xdf, 0x04, 0x42)
+	offset, err := d.CalibrateHardIron(0)
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, offset, ThreeDData{1, 1, -0.5})
+	gobot.Assert(t, d.Offset, offset)
+	gobot.Assert(t, d.Command("CalibrateHardIron")(map[string]interface{}{"seconds": 0.0}), offset)
+}
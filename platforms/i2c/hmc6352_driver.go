@@ -1,40 +1,94 @@
 package i2c
 
 import (
+	"log"
+	"sync"
+
 	"github.com/edmontongo/gobot"
 )
 
+// hmc6352Address is the address of an HMC6352.
+const hmc6352Address = 0x21
+
 type HMC6352Driver struct {
 	gobot.Driver
 	Heading uint16
+	device  I2cDevice
+	mutex   sync.Mutex
+	halt    chan bool
 }
 
-func NewHMC6352Driver(a I2cInterface, name string) *HMC6352Driver {
-	return &HMC6352Driver{
+func NewHMC6352Driver(a I2cBus, name string) *HMC6352Driver {
+	h := &HMC6352Driver{
 		Driver: *gobot.NewDriver(
 			name,
 			"HMC6352Driver",
 			a.(gobot.AdaptorInterface),
 		),
 	}
+
+	h.AddEvent("heading")
+	h.AddEvent("error")
+
+	return h
 }
 
-func (h *HMC6352Driver) adaptor() I2cInterface {
-	return h.Adaptor().(I2cInterface)
+func (h *HMC6352Driver) adaptor() I2cBus {
+	return h.Adaptor().(I2cBus)
 }
 
+// Start reads the heading every interval into Heading, in degrees, and
+// publishes it as "heading", or publishes "error".
 func (h *HMC6352Driver) Start() bool {
-	h.adaptor().I2cStart(0x21)
-	h.adaptor().I2cWrite([]byte("A"))
-
-	gobot.Every(h.Interval(), func() {
-		h.adaptor().I2cWrite([]byte("A"))
-		ret := h.adaptor().I2cRead(2)
-		if len(ret) == 2 {
-			h.Heading = (uint16(ret[1]) + uint16(ret[0])*256) / 10
+	device, err := h.adaptor().I2cOpen(hmc6352Address)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	h.device = device
+	if err := h.device.WriteByte('A'); err != nil {
+		log.Println(err)
+		return false
+	}
+
+	h.halt = gobot.Poll(h.Interval(), func() {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+		heading, err := h.readHeading()
+		if err != nil {
+			gobot.Publish(h.Event("error"), err)
+			return
 		}
+		h.Heading = heading
+		gobot.Publish(h.Event("heading"), heading)
 	})
 	return true
 }
 func (h *HMC6352Driver) Init() bool { return true }
-func (h *HMC6352Driver) Halt() bool { return true }
+
+// Halt stops reading the heading and closes the device.
+func (h *HMC6352Driver) Halt() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.halt != nil {
+		close(h.halt)
+		h.halt = nil
+	}
+	if h.device != nil {
+		h.device.Close()
+	}
+	return true
+}
+
+// readHeading asks for a heading, which comes in tenths of a degree, high
+// byte first.
+func (h *HMC6352Driver) readHeading() (uint16, error) {
+	if err := h.device.WriteByte('A'); err != nil {
+		return 0, err
+	}
+	data := make([]byte, 2)
+	if _, err := h.device.Read(data); err != nil {
+		return 0, err
+	}
+	return (uint16(data[1]) + uint16(data[0])*256) / 10, nil
+}
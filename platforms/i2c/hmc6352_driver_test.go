@@ -1,16 +1,49 @@
 package i2c
 
 import (
-	"github.com/edmontongo/gobot"
 	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
 )
 
-func initTestHMC6352Driver() *HMC6352Driver {
-	return NewHMC6352Driver(newI2cTestAdaptor("adaptor"), "bot")
+func initTestHMC6352Driver() (*HMC6352Driver, *i2cTestDevice) {
+	a := newI2cTestAdaptor("adaptor")
+	return NewHMC6352Driver(a, "bot"), a.addDevice(0x21)
 }
 
 func TestHMC6352DriverStart(t *testing.T) {
-	t.SkipNow()
-	d := initTestHMC6352Driver()
+	d, device := initTestHMC6352Driver()
+	d.SetInterval(time.Hour)
+	gobot.Assert(t, d.Start(), true)
+	gobot.Assert(t, device.written(), [][]byte{[]byte("A")})
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, device.closed, true)
+}
+
+func TestHMC6352DriverReadHeading(t *testing.T) {
+	d, device := initTestHMC6352Driver()
+	d.device, _ = d.adaptor().I2cOpen(0x21)
+	// 359.5 degrees
+	device.set('A', 0x0e, 0x0b)
+	heading, err := d.readHeading()
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, heading, uint16(359))
+}
+
+func TestHMC6352DriverHeadingEvent(t *testing.T) {
+	d, device := initTestHMC6352Driver()
+	d.SetInterval(time.Millisecond)
+	device.set('A', 0x03, 0x84)
+	headings := make(chan interface{}, 1)
+	gobot.Once(d.Event("heading"), func(data interface{}) {
+		headings <- data
+	})
 	gobot.Assert(t, d.Start(), true)
+	select {
+	case heading := <-headings:
+		gobot.Assert(t, heading, uint16(90))
+	case <-time.After(time.Second):
+		t.Error("heading event was not published")
+	}
 }
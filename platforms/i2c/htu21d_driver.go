@@ -0,0 +1,209 @@
+package i2c
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+// HTU21D commands.
+const (
+	htu21dAddress byte = 0x40
+
+	htu21dMeasureTemperature byte = 0xF3
+	htu21dMeasureHumidity    byte = 0xF5
+	htu21dWriteUser          byte = 0xE6
+	htu21dReadUser           byte = 0xE7
+	htu21dReset              byte = 0xFE
+
+	htu21dResolutionBits byte = 0x81
+)
+
+// errChecksum is returned when a reading does not match its checksum.
+var errChecksum = errors.New("i2c: checksum mismatch")
+
+// HTU21DResolution is the resolution, in bits, of humidities and
+// temperatures. Higher resolutions take longer to measure.
+type HTU21DResolution byte
+
+const (
+	HTU21DResolution12_14 HTU21DResolution = 0x00
+	HTU21DResolution8_12  HTU21DResolution = 0x01
+	HTU21DResolution10_13 HTU21DResolution = 0x80
+	HTU21DResolution11_11 HTU21DResolution = 0x81
+)
+
+// htu21dDurations are the longest a humidity and a temperature take to
+// measure at each resolution.
+var htu21dDurations = map[HTU21DResolution][2]time.Duration{
+	HTU21DResolution12_14: {16 * time.Millisecond, 50 * time.Millisecond},
+	HTU21DResolution8_12:  {3 * time.Millisecond, 13 * time.Millisecond},
+	HTU21DResolution10_13: {5 * time.Millisecond, 25 * time.Millisecond},
+	HTU21DResolution11_11: {8 * time.Millisecond, 7 * time.Millisecond},
+}
+
+// HTU21DDriver reads the humidity and temperature of an HTU21D, or of an
+// SHT21, which it is compatible with.
+type HTU21DDriver struct {
+	gobot.Driver
+	Resolution HTU21DResolution
+	device     I2cDevice
+	mutex      sync.Mutex
+}
+
+// NewHTU21DDriver creates a driver for an HTU21D measuring humidities to
+// 12 bits and temperatures to 14, read every second.
+func NewHTU21DDriver(a I2cBus, name string) *HTU21DDriver {
+	h := &HTU21DDriver{
+		Driver: *gobot.NewDriver(
+			name,
+			"HTU21DDriver",
+			a.(gobot.AdaptorInterface),
+			time.Second,
+		),
+		Resolution: HTU21DResolution12_14,
+	}
+
+	h.AddEvent("humidity")
+	h.AddEvent("temperature")
+	h.AddEvent("error")
+
+	h.AddCommand("Humidity", func(params map[string]interface{}) interface{} {
+		humidity, err := h.Humidity()
+		if err != nil {
+			return err.Error()
+		}
+		return humidity
+	})
+	h.AddCommand("Temperature", func(params map[string]interface{}) interface{} {
+		temperature, err := h.Temperature()
+		if err != nil {
+			return err.Error()
+		}
+		return temperature
+	})
+
+	return h
+}
+
+func (h *HTU21DDriver) adaptor() I2cBus {
+	return h.Adaptor().(I2cBus)
+}
+
+// Start resets the HTU21D and sets its resolution, then measures every
+// interval, publishing "humidity" in percent relative humidity and
+// "temperature" in degrees Celsius, or "error".
+func (h *HTU21DDriver) Start() bool {
+	if err := h.init(); err != nil {
+		log.Println(err)
+		return false
+	}
+	gobot.Every(h.Interval(), func() {
+		humidity, err := h.Humidity()
+		if err != nil {
+			gobot.Publish(h.Event("error"), err)
+			return
+		}
+		temperature, err := h.Temperature()
+		if err != nil {
+			gobot.Publish(h.Event("error"), err)
+			return
+		}
+		gobot.Publish(h.Event("humidity"), humidity)
+		gobot.Publish(h.Event("temperature"), temperature)
+	})
+	return true
+}
+func (h *HTU21DDriver) Init() bool { return true }
+
+// Halt closes the device.
+func (h *HTU21DDriver) Halt() bool {
+	if h.device != nil {
+		h.device.Close()
+	}
+	return true
+}
+
+// Humidity measures the relative humidity, in percent.
+func (h *HTU21DDriver) Humidity() (float64, error) {
+	value, err := h.measure(htu21dMeasureHumidity, htu21dDurations[h.resolution()][0])
+	if err != nil {
+		return 0, err
+	}
+	return -6 + 125*float64(value)/65536, nil
+}
+
+// Temperature measures the temperature, in degrees Celsius.
+func (h *HTU21DDriver) Temperature() (float64, error) {
+	value, err := h.measure(htu21dMeasureTemperature, htu21dDurations[h.resolution()][1])
+	if err != nil {
+		return 0, err
+	}
+	return -46.85 + 175.72*float64(value)/65536, nil
+}
+
+func (h *HTU21DDriver) resolution() HTU21DResolution {
+	return h.Resolution & HTU21DResolution(htu21dResolutionBits)
+}
+
+func (h *HTU21DDriver) init() error {
+	device, err := h.adaptor().I2cOpen(htu21dAddress)
+	if err != nil {
+		return err
+	}
+	h.device = device
+
+	if err := h.device.WriteByte(htu21dReset); err != nil {
+		return err
+	}
+	time.Sleep(15 * time.Millisecond)
+	// the other bits of the user register must be kept
+	user, err := h.device.ReadByteData(htu21dReadUser)
+	if err != nil {
+		return err
+	}
+	user = user&^htu21dResolutionBits | byte(h.resolution())
+	return h.device.WriteByteData(htu21dWriteUser, user)
+}
+
+// measure sends command, waits duration for the measurement, and reads it,
+// which is 16 bits high byte first, with two status bits, and a checksum.
+func (h *HTU21DDriver) measure(command byte, duration time.Duration) (uint16, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if err := h.device.WriteByte(command); err != nil {
+		return 0, err
+	}
+	time.Sleep(duration)
+	data := make([]byte, 3)
+	n, err := h.device.Read(data)
+	if err != nil {
+		return 0, err
+	}
+	if n < len(data) {
+		return 0, ErrShortRead
+	}
+	if htu21dChecksum(data[:2]) != data[2] {
+		return 0, errChecksum
+	}
+	return (uint16(data[0])<<8 | uint16(data[1])) &^ 0x03, nil
+}
+
+// htu21dChecksum is the CRC-8 of data, with polynomial x^8 + x^5 + x^4 + 1.
+func htu21dChecksum(data []byte) byte {
+	crc := byte(0)
+	for _, b := range data {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x31
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
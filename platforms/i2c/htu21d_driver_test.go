@@ -0,0 +1,63 @@
+package i2c
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestHTU21DDriver() (*HTU21DDriver, *i2cTestDevice) {
+	a := newI2cTestAdaptor("adaptor")
+	device := a.addDevice(0x40)
+	// the default user register
+	device.set(0xE7, 0x02)
+	return NewHTU21DDriver(a, "bot"), device
+}
+
+func TestHTU21DDriver(t *testing.T) {
+	d, _ := initTestHTU21DDriver()
+	gobot.Assert(t, d.Resolution, HTU21DResolution12_14)
+	gobot.Refute(t, d.Command("Humidity"), nil)
+	gobot.Refute(t, d.Command("Temperature"), nil)
+}
+
+func TestHTU21DDriverStart(t *testing.T) {
+	d, device := initTestHTU21DDriver()
+	d.SetInterval(time.Hour)
+	d.Resolution = HTU21DResolution10_13
+	gobot.Assert(t, d.Start(), true)
+	gobot.Assert(t, device.written(), [][]byte{{0xFE}, {0xE7}, {0xE6, 0x82}})
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, device.closed, true)
+}
+
+func TestHTU21DDriverMeasure(t *testing.T) {
+	d, device := initTestHTU21DDriver()
+	d.Resolution = HTU21DResolution8_12
+	gobot.Assert(t, d.init(), nil)
+	device.written()
+	// the example in the datasheet, with its checksum
+	device.set(0xF5, 0x68, 0x3a, 0x7c)
+	humidity, err := d.Humidity()
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, round(humidity), 44.888)
+	gobot.Assert(t, device.written(), [][]byte{{0xF5}})
+
+	device.set(0xF3, 0x68, 0x3a, 0x7c)
+	temperature, err := d.Temperature()
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, round(temperature), 24.686)
+	gobot.Assert(t, d.Command("Temperature")(map[string]interface{}{}), temperature)
+}
+
+func TestHTU21DDriverChecksum(t *testing.T) {
+	d, device := initTestHTU21DDriver()
+	d.Resolution = HTU21DResolution8_12
+	gobot.Assert(t, d.init(), nil)
+	device.set(0xF5, 0x68, 0x3a, 0x7d)
+	_, err := d.Humidity()
+	gobot.Assert(t, err, errChecksum)
+	gobot.Assert(t, d.Command("Humidity")(map[string]interface{}{}), errChecksum.Error())
+	gobot.Assert(t, htu21dChecksum([]byte{0x4e, 0x85}), byte(0x6b))
+}
@@ -1,7 +1,133 @@
 package i2c
 
-type I2cInterface interface {
-	I2cStart(byte)
-	I2cRead(uint) []byte
-	I2cWrite([]byte)
+import (
+	"errors"
+	"io"
+	"log"
+	"sync"
+)
+
+// ErrShortRead is returned when a device sends fewer bytes than were asked
+// for.
+var ErrShortRead = errors.New("i2c: short read")
+
+// errNotConnected is returned for transfers of drivers that are connections,
+// such as PCA9685Driver, before Connect.
+var errNotConnected = errors.New("i2c: not connected")
+
+// I2cBus is implemented by adaptors with an I2C bus. I2cOpen returns a
+// handle on the device at the 7-bit address, so several drivers can talk
+// to their own devices on the same bus without interfering.
+type I2cBus interface {
+	I2cOpen(address byte) (I2cDevice, error)
+}
+
+// I2cDevice is a device on an I2C bus. Read and Write are plain transfers
+// of whole buffers. The register operations write the register number and
+// then read or write its value, as most devices expect. Words are sent low
+// byte first, as in SMBus. Block operations transfer len(data) bytes from
+// consecutive registers, starting at reg, for devices that increment the
+// register after each byte.
+type I2cDevice interface {
+	io.ReadWriteCloser
+	ReadByte() (byte, error)
+	WriteByte(value byte) error
+	ReadByteData(reg byte) (byte, error)
+	WriteByteData(reg byte, value byte) error
+	ReadWordData(reg byte) (uint16, error)
+	WriteWordData(reg byte, value uint16) error
+	ReadBlockData(reg byte, data []byte) error
+	WriteBlockData(reg byte, data []byte) error
+}
+
+// NewI2cDevice builds an I2cDevice on the plain transfers of rw, for
+// adaptors that only have those. A register read is a write and a read,
+// which the device is kept to by a mutex.
+func NewI2cDevice(rw io.ReadWriteCloser) I2cDevice {
+	return &i2cDevice{rw: rw}
+}
+
+type i2cDevice struct {
+	rw    io.ReadWriteCloser
+	mutex sync.Mutex
+}
+
+func (d *i2cDevice) Read(data []byte) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.read(data)
+}
+
+func (d *i2cDevice) Write(data []byte) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.rw.Write(data)
+}
+
+func (d *i2cDevice) Close() error {
+	return d.rw.Close()
+}
+
+func (d *i2cDevice) ReadByte() (byte, error) {
+	data := make([]byte, 1)
+	_, err := d.Read(data)
+	return data[0], err
+}
+
+func (d *i2cDevice) WriteByte(value byte) error {
+	_, err := d.Write([]byte{value})
+	return err
+}
+
+func (d *i2cDevice) ReadByteData(reg byte) (byte, error) {
+	data := make([]byte, 1)
+	err := d.ReadBlockData(reg, data)
+	return data[0], err
+}
+
+func (d *i2cDevice) WriteByteData(reg byte, value byte) error {
+	_, err := d.Write([]byte{reg, value})
+	return err
+}
+
+func (d *i2cDevice) ReadWordData(reg byte) (uint16, error) {
+	data := make([]byte, 2)
+	err := d.ReadBlockData(reg, data)
+	return uint16(data[0]) | uint16(data[1])<<8, err
+}
+
+func (d *i2cDevice) WriteWordData(reg byte, value uint16) error {
+	_, err := d.Write([]byte{reg, byte(value), byte(value >> 8)})
+	return err
+}
+
+func (d *i2cDevice) ReadBlockData(reg byte, data []byte) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if _, err := d.rw.Write([]byte{reg}); err != nil {
+		return err
+	}
+	_, err := d.read(data)
+	return err
+}
+
+func (d *i2cDevice) WriteBlockData(reg byte, data []byte) error {
+	_, err := d.Write(append([]byte{reg}, data...))
+	return err
+}
+
+// read fills data, or returns ErrShortRead.
+func (d *i2cDevice) read(data []byte) (int, error) {
+	n, err := d.rw.Read(data)
+	if err == nil && n < len(data) {
+		err = ErrShortRead
+	}
+	return n, err
+}
+
+// logError logs err, if any, for methods of interfaces without errors.
+func logError(err error) {
+	if err != nil {
+		log.Println(err)
+	}
 }
@@ -0,0 +1,71 @@
+package i2c
+
+import (
+	"testing"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestI2cDevice() (I2cDevice, *i2cTestDevice) {
+	a := newI2cTestAdaptor("adaptor")
+	fake := a.addDevice(0x10)
+	device, _ := a.I2cOpen(0x10)
+	return device, fake
+}
+
+func TestI2cDeviceByteData(t *testing.T) {
+	device, fake := initTestI2cDevice()
+	gobot.Assert(t, device.WriteByteData(0x20, 0x7f), nil)
+	gobot.Assert(t, fake.written(), [][]byte{{0x20, 0x7f}})
+	value, err := device.ReadByteData(0x20)
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, value, byte(0x7f))
+	gobot.Assert(t, fake.written(), [][]byte{{0x20}})
+}
+
+func TestI2cDeviceWordData(t *testing.T) {
+	device, fake := initTestI2cDevice()
+	gobot.Assert(t, device.WriteWordData(0x30, 0x1234), nil)
+	gobot.Assert(t, fake.written(), [][]byte{{0x30, 0x34, 0x12}})
+	value, err := device.ReadWordData(0x30)
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, value, uint16(0x1234))
+}
+
+func TestI2cDeviceBlockData(t *testing.T) {
+	device, fake := initTestI2cDevice()
+	gobot.Assert(t, device.WriteBlockData(0x40, []byte{1, 2, 3}), nil)
+	gobot.Assert(t, fake.written(), [][]byte{{0x40, 1, 2, 3}})
+	data := make([]byte, 2)
+	gobot.Assert(t, device.ReadBlockData(0x41, data), nil)
+	gobot.Assert(t, data, []byte{2, 3})
+}
+
+func TestI2cDeviceByte(t *testing.T) {
+	device, fake := initTestI2cDevice()
+	fake.set(0x05, 0x99)
+	gobot.Assert(t, device.WriteByte(0x05), nil)
+	value, err := device.ReadByte()
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, value, byte(0x99))
+	gobot.Assert(t, device.Close(), nil)
+	gobot.Assert(t, fake.closed, true)
+}
+
+type shortReader struct{ i2cTestDevice }
+
+func (s *shortReader) Read(data []byte) (int, error) { return 1, nil }
+
+func TestI2cDeviceShortRead(t *testing.T) {
+	device := NewI2cDevice(&shortReader{})
+	_, err := device.ReadWordData(0x01)
+	gobot.Assert(t, err, ErrShortRead)
+}
+
+func TestI2cMissingDevice(t *testing.T) {
+	a := newI2cTestAdaptor("adaptor")
+	device, err := a.I2cOpen(0x10)
+	gobot.Assert(t, err, nil)
+	_, err = device.ReadByteData(0x01)
+	gobot.Assert(t, err, errNoDevice)
+}
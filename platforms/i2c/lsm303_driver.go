@@ -0,0 +1,303 @@
+package i2c
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+// LSM303DLHC registers. The accelerometer and magnetometer are separate
+// devices on the bus.
+const (
+	lsm303AccelAddress byte = 0x19
+	lsm303MagAddress   byte = 0x1E
+
+	lsm303CtrlReg1A byte = 0x20
+	lsm303CtrlReg4A byte = 0x23
+	lsm303OutXLA    byte = 0x28
+	lsm303CraRegM   byte = 0x00
+	lsm303CrbRegM   byte = 0x01
+	lsm303MrRegM    byte = 0x02
+	lsm303OutXHM    byte = 0x03
+	lsm303IraRegM   byte = 0x0A
+
+	// lsm303AutoIncrement makes accelerometer reads continue to the
+	// following registers.
+	lsm303AutoIncrement byte = 0x80
+	lsm303AxesEnable    byte = 0x07
+	lsm303PowerDown     byte = 0x00
+	lsm303HighRes       byte = 0x08
+	lsm303Continuous    byte = 0x00
+	lsm303Sleep         byte = 0x03
+)
+
+// LSM303AccelRange is the full scale range of the accelerometer.
+type LSM303AccelRange byte
+
+const (
+	LSM303Accel2G LSM303AccelRange = iota
+	LSM303Accel4G
+	LSM303Accel8G
+	LSM303Accel16G
+)
+
+// lsm303AccelSensitivities are the milli-g per reading of each range.
+var lsm303AccelSensitivities = []float64{1, 2, 4, 12}
+
+// LSM303AccelRate is how many times a second the accelerometer measures.
+type LSM303AccelRate byte
+
+const (
+	LSM303Accel1Hz LSM303AccelRate = iota + 1
+	LSM303Accel10Hz
+	LSM303Accel25Hz
+	LSM303Accel50Hz
+	LSM303Accel100Hz
+	LSM303Accel200Hz
+	LSM303Accel400Hz
+)
+
+// LSM303MagGain is the range of the magnetometer, from ±1.3 to ±8.1 gauss.
+type LSM303MagGain byte
+
+const (
+	LSM303Gain1_3 LSM303MagGain = iota + 1
+	LSM303Gain1_9
+	LSM303Gain2_5
+	LSM303Gain4_0
+	LSM303Gain4_7
+	LSM303Gain5_6
+	LSM303Gain8_1
+)
+
+// The readings per gauss of each gain, which differ for the Z axis.
+var (
+	lsm303XYGains = []float64{1100, 855, 670, 450, 400, 330, 230}
+	lsm303ZGains  = []float64{980, 760, 600, 400, 355, 295, 205}
+)
+
+// LSM303MagRate is how many times a second the magnetometer measures, from
+// 0.75 to 220.
+type LSM303MagRate byte
+
+const (
+	LSM303MagRate0_75 LSM303MagRate = iota
+	LSM303MagRate1_5
+	LSM303MagRate3
+	LSM303MagRate7_5
+	LSM303MagRate15
+	LSM303MagRate30
+	LSM303MagRate75
+	LSM303MagRate220
+)
+
+// LSM303Driver reads the accelerometer and magnetometer of an LSM303DLHC,
+// and the tilt compensated compass heading they give.
+type LSM303Driver struct {
+	gobot.Driver
+	AccelRange LSM303AccelRange
+	AccelRate  LSM303AccelRate
+	MagGain    LSM303MagGain
+	MagRate    LSM303MagRate
+	// Declination, in degrees east, is added to headings to give them from
+	// true north.
+	Declination float64
+	// Offset is subtracted from each magnetometer reading, to cancel the
+	// field of magnets and iron near it. CalibrateHardIron measures it.
+	Offset       ThreeDData
+	accel        I2cDevice
+	mag          I2cDevice
+	mutex        sync.Mutex
+	acceleration ThreeDData
+	magnetic     ThreeDData
+}
+
+// NewLSM303Driver creates a driver for an LSM303DLHC with ranges of ±2g
+// and ±1.3 gauss, measuring 100 and 15 times a second.
+func NewLSM303Driver(a I2cBus, name string) *LSM303Driver {
+	l := &LSM303Driver{
+		Driver: *gobot.NewDriver(
+			name,
+			"LSM303Driver",
+			a.(gobot.AdaptorInterface),
+			// the default magnetometer rate
+			67*time.Millisecond,
+		),
+		AccelRange: LSM303Accel2G,
+		AccelRate:  LSM303Accel100Hz,
+		MagGain:    LSM303Gain1_3,
+		MagRate:    LSM303MagRate15,
+	}
+
+	l.AddEvent("acceleration")
+	l.AddEvent("magnetic")
+	l.AddEvent("heading")
+	l.AddEvent("error")
+
+	l.AddCommand("Acceleration", func(params map[string]interface{}) interface{} {
+		return l.Acceleration()
+	})
+	l.AddCommand("Magnetic", func(params map[string]interface{}) interface{} {
+		return l.Magnetic()
+	})
+	l.AddCommand("Heading", func(params map[string]interface{}) interface{} {
+		return l.Heading()
+	})
+	l.AddCommand("CalibrateHardIron", func(params map[string]interface{}) interface{} {
+		offset, err := l.CalibrateHardIron(time.Duration(params["seconds"].(float64) * float64(time.Second)))
+		if err != nil {
+			return err.Error()
+		}
+		return offset
+	})
+
+	return l
+}
+
+func (l *LSM303Driver) adaptor() I2cBus {
+	return l.Adaptor().(I2cBus)
+}
+
+// Start sets the ranges and rates of the accelerometer and magnetometer,
+// then reads them every interval, publishing "acceleration" in g,
+// "magnetic" in gauss and the tilt compensated "heading" in degrees, or
+// "error".
+func (l *LSM303Driver) Start() bool {
+	if err := l.init(); err != nil {
+		log.Println(err)
+		return false
+	}
+	gobot.Every(l.Interval(), func() {
+		if err := l.update(); err != nil {
+			gobot.Publish(l.Event("error"), err)
+			return
+		}
+		gobot.Publish(l.Event("acceleration"), l.Acceleration())
+		gobot.Publish(l.Event("magnetic"), l.Magnetic())
+		gobot.Publish(l.Event("heading"), l.Heading())
+	})
+	return true
+}
+func (l *LSM303Driver) Init() bool { return true }
+
+// Halt powers the accelerometer down and puts the magnetometer to sleep.
+func (l *LSM303Driver) Halt() bool {
+	if l.accel != nil {
+		l.accel.WriteByteData(lsm303CtrlReg1A, lsm303PowerDown)
+		l.accel.Close()
+	}
+	if l.mag != nil {
+		l.mag.WriteByteData(lsm303MrRegM, lsm303Sleep)
+		l.mag.Close()
+	}
+	return true
+}
+
+// Acceleration returns the last acceleration read, in g.
+func (l *LSM303Driver) Acceleration() ThreeDData {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.acceleration
+}
+
+// Magnetic returns the last field read, less Offset, in gauss.
+func (l *LSM303Driver) Magnetic() ThreeDData {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.magnetic
+}
+
+// Heading returns the heading of the last readings, however the LSM303 is
+// tilted, in degrees from 0 to 360.
+func (l *LSM303Driver) Heading() float64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return withDeclination(TiltCompensatedHeading(l.magnetic, l.acceleration), l.Declination)
+}
+
+// CalibrateHardIron reads the field for duration, while the LSM303 is
+// turned in every direction, and makes the middle of the readings on each
+// axis the Offset.
+func (l *LSM303Driver) CalibrateHardIron(duration time.Duration) (ThreeDData, error) {
+	offset, err := calibrateHardIron(duration, l.Interval(), l.readMagnetic)
+	if err != nil {
+		return ThreeDData{}, err
+	}
+	l.mutex.Lock()
+	l.Offset = offset
+	l.mutex.Unlock()
+	return offset, nil
+}
+
+func (l *LSM303Driver) init() (err error) {
+	if l.accel, err = l.adaptor().I2cOpen(lsm303AccelAddress); err != nil {
+		return err
+	}
+	if l.mag, err = l.adaptor().I2cOpen(lsm303MagAddress); err != nil {
+		return err
+	}
+
+	if err := l.accel.WriteByteData(lsm303CtrlReg1A, byte(l.AccelRate&0x0f)<<4|lsm303AxesEnable); err != nil {
+		return err
+	}
+	if err := l.accel.WriteByteData(lsm303CtrlReg4A, byte(l.AccelRange&0x03)<<4|lsm303HighRes); err != nil {
+		return err
+	}
+	writes := [][2]byte{
+		{lsm303CraRegM, byte(l.MagRate&0x07) << 2},
+		{lsm303CrbRegM, byte(l.magGain()) << 5},
+		{lsm303MrRegM, lsm303Continuous},
+	}
+	for _, w := range writes {
+		if err := l.mag.WriteByteData(w[0], w[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// magGain returns MagGain, or the lowest gain when it is unset.
+func (l *LSM303Driver) magGain() LSM303MagGain {
+	if l.MagGain < LSM303Gain1_3 || l.MagGain > LSM303Gain8_1 {
+		return LSM303Gain1_3
+	}
+	return l.MagGain
+}
+
+func (l *LSM303Driver) update() error {
+	acceleration, err := l.readAcceleration()
+	if err != nil {
+		return err
+	}
+	magnetic, err := l.readMagnetic()
+	if err != nil {
+		return err
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.acceleration = acceleration
+	l.magnetic = magnetic.sub(l.Offset)
+	return nil
+}
+
+// readAcceleration reads the X, Y and Z axes, 12-bit readings left
+// justified in 16-bit registers, low byte first.
+func (l *LSM303Driver) readAcceleration() (ThreeDData, error) {
+	data := make([]byte, 6)
+	if err := l.accel.ReadBlockData(lsm303OutXLA|lsm303AutoIncrement, data); err != nil {
+		return ThreeDData{}, err
+	}
+	values := make([]float64, 3)
+	for i := range values {
+		values[i] = float64(int16(uint16(data[2*i+1])<<8|uint16(data[2*i])) >> 4)
+	}
+	g := lsm303AccelSensitivities[l.AccelRange&0x03] / 1000
+	return ThreeDData{values[0] * g, values[1] * g, values[2] * g}, nil
+}
+
+func (l *LSM303Driver) readMagnetic() (ThreeDData, error) {
+	gain := l.magGain() - LSM303Gain1_3
+	return readMagnetic(l.mag, lsm303OutXHM, lsm303XYGains[gain], lsm303ZGains[gain])
+}
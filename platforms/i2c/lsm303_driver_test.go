@@ -0,0 +1,65 @@
+package i2c
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestLSM303Driver() (*LSM303Driver, *i2cTestDevice, *i2cTestDevice) {
+	a := newI2cTestAdaptor("adaptor")
+	return NewLSM303Driver(a, "bot"), a.addDevice(0x19), a.addDevice(0x1E)
+}
+
+func TestLSM303Driver(t *testing.T) {
+	d, _, _ := initTestLSM303Driver()
+	gobot.Assert(t, d.AccelRange, LSM303Accel2G)
+	gobot.Assert(t, d.MagGain, LSM303Gain1_3)
+	gobot.Refute(t, d.Command("Acceleration"), nil)
+	gobot.Refute(t, d.Command("Magnetic"), nil)
+	gobot.Refute(t, d.Command("Heading"), nil)
+	gobot.Refute(t, d.Command("CalibrateHardIron"), nil)
+}
+
+func TestLSM303DriverStart(t *testing.T) {
+	d, accel, mag := initTestLSM303Driver()
+	d.SetInterval(time.Hour)
+	d.AccelRange = LSM303Accel8G
+	d.MagGain = LSM303Gain4_0
+	gobot.Assert(t, d.Start(), true)
+	gobot.Assert(t, accel.written(), [][]byte{{0x20, 0x57}, {0x23, 0x28}})
+	gobot.Assert(t, mag.written(), [][]byte{{0x00, 0x10}, {0x01, 0x80}, {0x02, 0x00}})
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, accel.written(), [][]byte{{0x20, 0x00}})
+	gobot.Assert(t, mag.written(), [][]byte{{0x02, 0x03}})
+	gobot.Assert(t, accel.closed, true)
+	gobot.Assert(t, mag.closed, true)
+}
+
+func TestLSM303DriverUpdate(t *testing.T) {
+	d, accel, mag := initTestLSM303Driver()
+	gobot.Assert(t, d.init(), nil)
+	// 0.5g, -0.5g, 1g, read with the auto increment bit set
+	accel.set(0xa8, 0x40, 0x1f, 0xc0, 0xe0, 0x80, 0x3e)
+	// X 0 gauss, Z -1 gauss, Y -1 gauss
+	mag.set(0x03, 0x00, 0x00, 0xfc, 0x2c, 0xfb, 0xb4)
+	gobot.Assert(t, d.update(), nil)
+	gobot.Assert(t, d.Acceleration(), ThreeDData{0.5, -0.5, 1})
+	gobot.Assert(t, d.Magnetic(), ThreeDData{0, -1, -1})
+
+	accel.set(0xa8, 0x00, 0x00, 0x00, 0x00, 0x80, 0x3e)
+	gobot.Assert(t, d.update(), nil)
+	gobot.Assert(t, d.Heading(), 270.0)
+}
+
+func TestLSM303DriverCalibrateHardIron(t *testing.T) {
+	d, _, mag := initTestLSM303Driver()
+	gobot.Assert(t, d.init(), nil)
+	mag.set(0x03, 0x00, 0x00, 0xfc, 0x2c, 0xfb, 0xb4)
+	offset, err := d.CalibrateHardIron(0)
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, offset, ThreeDData{0, -1, -1})
+	gobot.Assert(t, d.update(), nil)
+	gobot.Assert(t, d.Magnetic(), ThreeDData{})
+}
@@ -0,0 +1,279 @@
+package i2c
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+
+	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/gpio"
+)
+
+// MCP23017 registers, with the A and B ports of each at consecutive
+// addresses, so they are read and written as words, A first.
+const (
+	mcp23017IODir   byte = 0x00
+	mcp23017GPIntEn byte = 0x04
+	mcp23017IOCon   byte = 0x0A
+	mcp23017GPPU    byte = 0x0C
+	mcp23017GPIO    byte = 0x12
+	mcp23017OLat    byte = 0x14
+
+	// mcp23017Mirror joins the INTA and INTB outputs, so one pin reports
+	// changes of both ports.
+	mcp23017Mirror byte = 0x40
+)
+
+// MCP23017Driver drives the 16 pins of an MCP23017 GPIO expander, named
+// "A0" to "A7" and "B0" to "B7", or "0" to "15". Like PCA9685Driver it is
+// a connection of the robot, added after the adaptor of its bus, that gpio
+// drivers use as their adaptor: it implements gpio.DigitalReader,
+// gpio.DigitalWriter, gpio.Pwm and gpio.DigitalWatcher, so ButtonDriver and
+// LedDriver work on its pins. Pins are inputs until written.
+type MCP23017Driver struct {
+	gobot.Adaptor
+	// Address is 0x20, plus the levels of the address pins.
+	Address byte
+	// InterruptPin is the pin of InterruptWatcher wired to the INTA or INTB
+	// output, for WatchDigital. InterruptWatcher is the adaptor of the bus
+	// by default, when it is a gpio.DigitalWatcher.
+	InterruptPin     string
+	InterruptWatcher gpio.DigitalWatcher
+	bus              I2cBus
+	device           I2cDevice
+	mutex            sync.Mutex
+	direction        uint16
+	latch            uint16
+	pullUps          uint16
+	interruptEnable  uint16
+	interrupts       expanderInterrupts
+}
+
+// NewMCP23017Driver creates a driver for an MCP23017 at address 0x20 on the
+// bus of a.
+func NewMCP23017Driver(a I2cBus, name string) *MCP23017Driver {
+	m := &MCP23017Driver{
+		Adaptor: *gobot.NewAdaptor(
+			name,
+			"MCP23017Driver",
+		),
+		Address:   0x20,
+		bus:       a,
+		direction: 0xFFFF,
+	}
+	m.InterruptWatcher, _ = a.(gpio.DigitalWatcher)
+
+	m.AddCommand("DigitalRead", func(params map[string]interface{}) interface{} {
+		return m.DigitalRead(params["pin"].(string))
+	})
+	m.AddCommand("DigitalWrite", func(params map[string]interface{}) interface{} {
+		m.DigitalWrite(params["pin"].(string), byte(params["level"].(float64)))
+		return nil
+	})
+	m.AddCommand("SetPullUp", func(params map[string]interface{}) interface{} {
+		return errorString(m.SetPullUp(params["pin"].(string), params["enabled"].(bool)))
+	})
+
+	return m
+}
+
+// Connect sets the directions, levels and pull-ups of the pins, and
+// watches InterruptPin, if set.
+func (m *MCP23017Driver) Connect() bool {
+	if err := m.init(); err != nil {
+		log.Println(err)
+		return false
+	}
+	m.SetConnected(true)
+	return true
+}
+
+// Finalize stops watching InterruptPin. The pins keep their levels.
+func (m *MCP23017Driver) Finalize() bool {
+	m.interrupts.stop()
+	if m.device != nil {
+		m.device.Close()
+	}
+	m.SetConnected(false)
+	return true
+}
+
+// ToJSON describes the address and interrupt pin of the MCP23017 in
+// Details.
+func (m *MCP23017Driver) ToJSON() *gobot.JSONConnection {
+	json := m.Adaptor.ToJSON()
+	json.Details = map[string]interface{}{
+		"address":   m.Address,
+		"interrupt": m.InterruptPin,
+	}
+	return json
+}
+
+// DigitalRead makes pin an input and returns its level, or -1 if it cannot
+// be read.
+func (m *MCP23017Driver) DigitalRead(pin string) int {
+	bit, err := m.pin(pin)
+	if err == nil {
+		err = m.setDirection(bit, true)
+	}
+	var levels uint16
+	if err == nil {
+		levels, err = m.readInputs()
+	}
+	if err != nil {
+		log.Println(err)
+		return -1
+	}
+	return int(levels >> bit & 1)
+}
+
+// DigitalWrite makes pin an output at level.
+func (m *MCP23017Driver) DigitalWrite(pin string, level byte) {
+	bit, err := m.pin(pin)
+	if err == nil {
+		m.mutex.Lock()
+		m.latch = setBit(m.latch, bit, level != 0)
+		err = m.writeWord(mcp23017OLat, m.latch)
+		m.mutex.Unlock()
+	}
+	if err == nil {
+		err = m.setDirection(bit, false)
+	}
+	logError(err)
+}
+
+// PwmWrite turns pin on for levels from 128, as the MCP23017 has no PWM,
+// so that LedDriver can turn LEDs on and off.
+func (m *MCP23017Driver) PwmWrite(pin string, level byte) {
+	m.DigitalWrite(pin, level>>7)
+}
+
+// SetPullUp enables or disables the 100kΩ pull-up of pin, for buttons and
+// switches to ground. It may be called before Connect.
+func (m *MCP23017Driver) SetPullUp(pin string, enabled bool) error {
+	bit, err := m.pin(pin)
+	if err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.pullUps = setBit(m.pullUps, bit, enabled)
+	if m.device == nil {
+		return nil
+	}
+	return m.writeWord(mcp23017GPPU, m.pullUps)
+}
+
+// WatchDigital makes pin an input and calls handler with its level, and
+// again after each change on edge, using the interrupt output of the
+// MCP23017. It returns an error without an InterruptPin.
+func (m *MCP23017Driver) WatchDigital(pin string, edge string, handler func(int)) error {
+	bit, err := m.pin(pin)
+	if err != nil {
+		return err
+	}
+	if err := m.setDirection(bit, true); err != nil {
+		return err
+	}
+	if err := m.interrupts.watch(int(bit), edge, handler); err != nil {
+		return err
+	}
+	return m.setInterrupt(bit, true)
+}
+
+// UnwatchDigital stops calling the handler given to WatchDigital for pin.
+func (m *MCP23017Driver) UnwatchDigital(pin string) error {
+	bit, err := m.pin(pin)
+	if err != nil {
+		return err
+	}
+	m.interrupts.unwatch(int(bit))
+	return m.setInterrupt(bit, false)
+}
+
+func (m *MCP23017Driver) init() error {
+	device, err := m.bus.I2cOpen(m.Address)
+	if err != nil {
+		return err
+	}
+	m.device = device
+
+	m.mutex.Lock()
+	writes := []struct {
+		reg   byte
+		value uint16
+	}{
+		{mcp23017IOCon, uint16(mcp23017Mirror)<<8 | uint16(mcp23017Mirror)},
+		{mcp23017OLat, m.latch},
+		{mcp23017IODir, m.direction},
+		{mcp23017GPPU, m.pullUps},
+		{mcp23017GPIntEn, m.interruptEnable},
+	}
+	for _, w := range writes {
+		if err := m.writeWord(w.reg, w.value); err != nil {
+			m.mutex.Unlock()
+			return err
+		}
+	}
+	m.mutex.Unlock()
+	return m.interrupts.start(m.InterruptWatcher, m.InterruptPin, m.readInputs)
+}
+
+// readInputs reads the levels of all pins, which clears the interrupt.
+func (m *MCP23017Driver) readInputs() (uint16, error) {
+	if m.device == nil {
+		return 0, errNotConnected
+	}
+	return m.device.ReadWordData(mcp23017GPIO)
+}
+
+func (m *MCP23017Driver) setDirection(bit uint, input bool) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	direction := setBit(m.direction, bit, input)
+	if direction == m.direction {
+		return nil
+	}
+	m.direction = direction
+	return m.writeWord(mcp23017IODir, direction)
+}
+
+func (m *MCP23017Driver) setInterrupt(bit uint, enabled bool) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.interruptEnable = setBit(m.interruptEnable, bit, enabled)
+	return m.writeWord(mcp23017GPIntEn, m.interruptEnable)
+}
+
+func (m *MCP23017Driver) writeWord(reg byte, value uint16) error {
+	if m.device == nil {
+		return errNotConnected
+	}
+	return m.device.WriteWordData(reg, value)
+}
+
+// pin returns the bit of a pin name, from 0 for A0 to 15 for B7.
+func (m *MCP23017Driver) pin(pin string) (uint, error) {
+	bit := -1
+	if len(pin) == 2 && (pin[0] == 'A' || pin[0] == 'B') && pin[1] >= '0' && pin[1] <= '7' {
+		bit = int(pin[1] - '0')
+		if pin[0] == 'B' {
+			bit += 8
+		}
+	} else if n, err := strconv.Atoi(pin); err == nil {
+		bit = n
+	}
+	if bit < 0 || bit > 15 {
+		return 0, fmt.Errorf("i2c: no MCP23017 pin %v", pin)
+	}
+	return uint(bit), nil
+}
+
+// setBit returns value with bit set or cleared.
+func setBit(value uint16, bit uint, set bool) uint16 {
+	if set {
+		return value | 1<<bit
+	}
+	return value &^ (1 << bit)
+}
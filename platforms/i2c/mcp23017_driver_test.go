@@ -0,0 +1,208 @@
+package i2c
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/gpio"
+)
+
+func initTestMCP23017Driver() (*MCP23017Driver, *i2cTestDevice) {
+	a := newI2cTestAdaptor("adaptor")
+	device := a.addDevice(0x20)
+	d := NewMCP23017Driver(a, "mcp23017")
+	return d, device
+}
+
+func initConnectedMCP23017Driver(t *testing.T) (*MCP23017Driver, *i2cTestDevice) {
+	d, device := initTestMCP23017Driver()
+	gobot.Assert(t, d.Connect(), true)
+	device.written()
+	return d, device
+}
+
+func TestMCP23017Driver(t *testing.T) {
+	var _ gpio.DigitalReader = (*MCP23017Driver)(nil)
+	var _ gpio.PwmDigitalWriter = (*MCP23017Driver)(nil)
+	var _ gpio.DigitalWatcher = (*MCP23017Driver)(nil)
+	var _ gobot.Connection = (*MCP23017Driver)(nil)
+
+	d, _ := initTestMCP23017Driver()
+	gobot.Assert(t, d.Address, byte(0x20))
+	gobot.Assert(t, d.InterruptWatcher, nil)
+	gobot.Refute(t, d.Command("DigitalRead"), nil)
+	gobot.Refute(t, d.Command("DigitalWrite"), nil)
+	gobot.Refute(t, d.Command("SetPullUp"), nil)
+}
+
+func TestMCP23017DriverConnect(t *testing.T) {
+	d, device := initTestMCP23017Driver()
+	gobot.Assert(t, d.SetPullUp("B1", true), nil)
+	gobot.Assert(t, len(device.written()), 0)
+	gobot.Assert(t, d.Connect(), true)
+	gobot.Assert(t, d.Connected(), true)
+	gobot.Assert(t, device.written(), [][]byte{
+		{0x0A, 0x40, 0x40},
+		{0x14, 0x00, 0x00},
+		{0x00, 0xFF, 0xFF},
+		{0x0C, 0x00, 0x02},
+		{0x04, 0x00, 0x00},
+	})
+	gobot.Assert(t, d.ToJSON().Details, map[string]interface{}{
+		"address":   byte(0x20),
+		"interrupt": "",
+	})
+
+	gobot.Assert(t, d.Finalize(), true)
+	gobot.Assert(t, device.closed, true)
+}
+
+func TestMCP23017DriverNotConnected(t *testing.T) {
+	d, _ := initTestMCP23017Driver()
+	gobot.Assert(t, d.DigitalRead("A0"), -1)
+	gobot.Assert(t, d.WatchDigital("A0", gpio.EdgeBoth, func(int) {}), errNoInterrupt)
+
+	a := newI2cTestAdaptor("adaptor")
+	d = NewMCP23017Driver(a, "mcp23017")
+	gobot.Assert(t, d.Connect(), false)
+}
+
+func TestMCP23017DriverPins(t *testing.T) {
+	d, _ := initTestMCP23017Driver()
+	for name, bit := range map[string]uint{"A0": 0, "A7": 7, "B0": 8, "B7": 15, "0": 0, "15": 15} {
+		b, err := d.pin(name)
+		gobot.Assert(t, err, nil)
+		gobot.Assert(t, b, bit)
+	}
+	for _, name := range []string{"A8", "C0", "16", "-1", ""} {
+		_, err := d.pin(name)
+		gobot.Assert(t, err, errors.New("i2c: no MCP23017 pin "+name))
+	}
+}
+
+func TestMCP23017DriverDigitalWrite(t *testing.T) {
+	d, device := initConnectedMCP23017Driver(t)
+	d.DigitalWrite("B2", 1)
+	d.DigitalWrite("A0", 1)
+	d.DigitalWrite("B2", 0)
+	gobot.Assert(t, device.written(), [][]byte{
+		{0x14, 0x00, 0x04},
+		{0x00, 0xFF, 0xFB},
+		{0x14, 0x01, 0x04},
+		{0x00, 0xFE, 0xFB},
+		{0x14, 0x01, 0x00},
+	})
+
+	d.PwmWrite("A0", 127)
+	d.PwmWrite("A0", 128)
+	gobot.Assert(t, device.written(), [][]byte{{0x14, 0x00, 0x00}, {0x14, 0x01, 0x00}})
+	gobot.Assert(t, d.Command("DigitalWrite")(map[string]interface{}{"pin": "15", "level": 1.0}), nil)
+	gobot.Assert(t, device.written(), [][]byte{{0x14, 0x01, 0x80}, {0x00, 0xFE, 0x7B}})
+}
+
+func TestMCP23017DriverDigitalRead(t *testing.T) {
+	d, device := initConnectedMCP23017Driver(t)
+	d.DigitalWrite("B4", 1)
+	device.written()
+	device.set(0x12, 0x00, 0x10)
+	gobot.Assert(t, d.DigitalRead("B4"), 1)
+	gobot.Assert(t, d.DigitalRead("A4"), 0)
+	// B4 is made an input again
+	gobot.Assert(t, device.written(), [][]byte{{0x00, 0xFF, 0xFF}, {0x12}, {0x12}})
+	gobot.Assert(t, d.Command("DigitalRead")(map[string]interface{}{"pin": "12"}), 1)
+	gobot.Assert(t, d.DigitalRead("X"), -1)
+}
+
+func TestMCP23017DriverSetPullUp(t *testing.T) {
+	d, device := initConnectedMCP23017Driver(t)
+	gobot.Assert(t, d.SetPullUp("A3", true), nil)
+	gobot.Assert(t, d.Command("SetPullUp")(map[string]interface{}{"pin": "B0", "enabled": true}), nil)
+	gobot.Assert(t, d.SetPullUp("A3", false), nil)
+	gobot.Assert(t, device.written(), [][]byte{
+		{0x0C, 0x08, 0x00},
+		{0x0C, 0x08, 0x01},
+		{0x0C, 0x00, 0x01},
+	})
+	gobot.Assert(t, d.Command("SetPullUp")(map[string]interface{}{"pin": "B9", "enabled": true}),
+		"i2c: no MCP23017 pin B9")
+}
+
+func TestMCP23017DriverWatchDigital(t *testing.T) {
+	d, device := initTestMCP23017Driver()
+	watcher := newTestDigitalWatcher()
+	d.InterruptWatcher = watcher
+	d.InterruptPin = "P8_7"
+	device.set(0x12, 0x00, 0x01)
+	gobot.Assert(t, d.Connect(), true)
+	gobot.Assert(t, watcher.edges["P8_7"], gpio.EdgeFalling)
+	gobot.Assert(t, d.ToJSON().Details["interrupt"], "P8_7")
+	device.written()
+
+	var levels []int
+	gobot.Assert(t, d.WatchDigital("B0", gpio.EdgeBoth, func(level int) {
+		levels = append(levels, level)
+	}), nil)
+	gobot.Assert(t, device.written(), [][]byte{{0x12}, {0x04, 0x00, 0x01}})
+
+	device.set(0x12, 0x00, 0x00)
+	watcher.fire("P8_7")
+	device.set(0x12, 0x01, 0x00)
+	watcher.fire("P8_7")
+	gobot.Assert(t, levels, []int{1, 0})
+
+	gobot.Assert(t, d.UnwatchDigital("B0"), nil)
+	gobot.Assert(t, device.written(), [][]byte{{0x12}, {0x12}, {0x04, 0x00, 0x00}})
+	device.set(0x12, 0x00, 0x01)
+	watcher.fire("P8_7")
+	gobot.Assert(t, levels, []int{1, 0})
+
+	gobot.Assert(t, d.Finalize(), true)
+	gobot.Assert(t, watcher.watching("P8_7"), false)
+}
+
+func TestMCP23017DriverWatchDigitalWithoutInterrupt(t *testing.T) {
+	d, _ := initConnectedMCP23017Driver(t)
+	gobot.Assert(t, d.WatchDigital("A0", gpio.EdgeBoth, func(int) {}), errNoInterrupt)
+	gobot.Refute(t, d.WatchDigital("Z", gpio.EdgeBoth, func(int) {}), nil)
+}
+
+func TestMCP23017DriverButtonDriver(t *testing.T) {
+	d, device := initTestMCP23017Driver()
+	watcher := newTestDigitalWatcher()
+	d.InterruptWatcher = watcher
+	d.InterruptPin = "P8_7"
+	gobot.Assert(t, d.Connect(), true)
+
+	button := gpio.NewButtonDriver(d, "button", "A5")
+	button.DebounceTime = 0
+	pushes := make(chan interface{}, 1)
+	gobot.Once(button.Event("push"), func(data interface{}) {
+		pushes <- data
+	})
+	gobot.Assert(t, button.Start(), true)
+	gobot.Assert(t, button.ToJSON().Connection, "mcp23017")
+
+	device.set(0x12, 0x20, 0x00)
+	watcher.fire("P8_7")
+	select {
+	case data := <-pushes:
+		gobot.Assert(t, data, 1)
+	case <-time.After(time.Second):
+		t.Error("no push event")
+	}
+	gobot.Assert(t, button.Halt(), true)
+}
+
+func TestMCP23017DriverLedDriver(t *testing.T) {
+	d, device := initConnectedMCP23017Driver(t)
+	led := gpio.NewLedDriver(d, "led", "B7")
+	gobot.Assert(t, led.On(), true)
+	gobot.Assert(t, led.Off(), true)
+	gobot.Assert(t, device.written(), [][]byte{
+		{0x14, 0x00, 0x80},
+		{0x00, 0xFF, 0x7F},
+		{0x14, 0x00, 0x00},
+	})
+}
@@ -0,0 +1,190 @@
+package i2c
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+// MPL115A2 registers.
+const (
+	mpl115a2Address byte = 0x60
+
+	mpl115a2Padc    byte = 0x00
+	mpl115a2A0      byte = 0x04
+	mpl115a2Convert byte = 0x12
+)
+
+// mpl115a2Coefficients are the coefficients each MPL115A2 is programmed
+// with.
+type mpl115a2Coefficients struct {
+	a0, b1, b2, c12 float64
+}
+
+// MPL115A2Driver reads the pressure and temperature of an MPL115A2.
+type MPL115A2Driver struct {
+	gobot.Driver
+	// Samples is the number of conversions averaged for each reading. It
+	// is 1 by default.
+	Samples     int
+	device      I2cDevice
+	mutex       sync.Mutex
+	calibration mpl115a2Coefficients
+}
+
+// NewMPL115A2Driver creates a driver for an MPL115A2, read every second.
+func NewMPL115A2Driver(a I2cBus, name string) *MPL115A2Driver {
+	m := &MPL115A2Driver{
+		Driver: *gobot.NewDriver(
+			name,
+			"MPL115A2Driver",
+			a.(gobot.AdaptorInterface),
+			time.Second,
+		),
+		Samples: 1,
+	}
+
+	m.AddEvent("pressure")
+	m.AddEvent("temperature")
+	m.AddEvent("error")
+
+	m.AddCommand("Pressure", func(params map[string]interface{}) interface{} {
+		pressure, err := m.Pressure()
+		if err != nil {
+			return err.Error()
+		}
+		return pressure
+	})
+	m.AddCommand("Temperature", func(params map[string]interface{}) interface{} {
+		temperature, err := m.Temperature()
+		if err != nil {
+			return err.Error()
+		}
+		return temperature
+	})
+
+	return m
+}
+
+func (m *MPL115A2Driver) adaptor() I2cBus {
+	return m.Adaptor().(I2cBus)
+}
+
+// Start reads the coefficients of the MPL115A2, then measures every
+// interval, publishing "pressure" in pascals and "temperature" in degrees
+// Celsius, or "error".
+func (m *MPL115A2Driver) Start() bool {
+	if err := m.init(); err != nil {
+		log.Println(err)
+		return false
+	}
+	gobot.Every(m.Interval(), func() {
+		pressure, temperature, err := m.measure()
+		if err != nil {
+			gobot.Publish(m.Event("error"), err)
+			return
+		}
+		gobot.Publish(m.Event("pressure"), pressure)
+		gobot.Publish(m.Event("temperature"), temperature)
+	})
+	return true
+}
+func (m *MPL115A2Driver) Init() bool { return true }
+
+// Halt closes the device.
+func (m *MPL115A2Driver) Halt() bool {
+	if m.device != nil {
+		m.device.Close()
+	}
+	return true
+}
+
+// Pressure measures the pressure, in pascals.
+func (m *MPL115A2Driver) Pressure() (float64, error) {
+	pressure, _, err := m.measure()
+	return pressure, err
+}
+
+// Temperature measures the temperature, in degrees Celsius. It is only
+// accurate to a few degrees, being meant to compensate the pressure.
+func (m *MPL115A2Driver) Temperature() (float64, error) {
+	_, temperature, err := m.measure()
+	return temperature, err
+}
+
+func (m *MPL115A2Driver) init() error {
+	device, err := m.adaptor().I2cOpen(mpl115a2Address)
+	if err != nil {
+		return err
+	}
+	m.device = device
+
+	data := make([]byte, 8)
+	if err := m.device.ReadBlockData(mpl115a2A0, data); err != nil {
+		return err
+	}
+	word := func(i int) int16 {
+		return int16(uint16(data[2*i])<<8 | uint16(data[2*i+1]))
+	}
+	// fixed point, with 3, 13, 14 and, after the two unused bits, 22
+	// fractional bits
+	m.calibration = mpl115a2Coefficients{
+		a0:  float64(word(0)) / (1 << 3),
+		b1:  float64(word(1)) / (1 << 13),
+		b2:  float64(word(2)) / (1 << 14),
+		c12: float64(word(3)>>2) / (1 << 22),
+	}
+	return nil
+}
+
+// measure averages Samples conversions of the pressure and temperature.
+func (m *MPL115A2Driver) measure() (float64, float64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	samples := m.Samples
+	if samples < 1 {
+		samples = 1
+	}
+	var pressure, temperature float64
+	for i := 0; i < samples; i++ {
+		padc, tadc, err := m.convert()
+		if err != nil {
+			return 0, 0, err
+		}
+		p, t := m.compensate(padc, tadc)
+		pressure += p
+		temperature += t
+	}
+	return pressure / float64(samples), temperature / float64(samples), nil
+}
+
+// convert has the MPL115A2 convert the pressure and temperature, and reads
+// them, which are 10-bit readings left justified in 16-bit registers, high
+// byte first.
+func (m *MPL115A2Driver) convert() (float64, float64, error) {
+	if err := m.device.WriteByteData(mpl115a2Convert, 0x00); err != nil {
+		return 0, 0, err
+	}
+	time.Sleep(3 * time.Millisecond)
+	data := make([]byte, 4)
+	if err := m.device.ReadBlockData(mpl115a2Padc, data); err != nil {
+		return 0, 0, err
+	}
+	padc := (uint16(data[0])<<8 | uint16(data[1])) >> 6
+	tadc := (uint16(data[2])<<8 | uint16(data[3])) >> 6
+	return float64(padc), float64(tadc), nil
+}
+
+// compensate returns the pressure, in pascals, and temperature, in degrees
+// Celsius, of a conversion, as in the datasheet.
+func (m *MPL115A2Driver) compensate(padc float64, tadc float64) (float64, float64) {
+	c := m.calibration
+	pcomp := c.a0 + (c.b1+c.c12*tadc)*padc + c.b2*tadc
+	// pcomp spans 50 to 115 kPa
+	pressure := (pcomp*(115-50)/1023 + 50) * 1000
+	// the temperature reading falls 5.35 a degree from 498 at 25 degrees
+	temperature := (tadc-498)/-5.35 + 25
+	return pressure, temperature
+}
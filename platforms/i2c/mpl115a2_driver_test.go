@@ -0,0 +1,56 @@
+package i2c
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestMPL115A2Driver() (*MPL115A2Driver, *i2cTestDevice) {
+	a := newI2cTestAdaptor("adaptor")
+	device := a.addDevice(0x60)
+	// the coefficients and readings of the example in the application note
+	device.set(0x00, 0x66, 0x80, 0x7e, 0xc0, 0x3e, 0xce, 0xb3, 0xf9, 0xc5, 0x17, 0x33, 0xc8)
+	return NewMPL115A2Driver(a, "bot"), device
+}
+
+func TestMPL115A2Driver(t *testing.T) {
+	d, _ := initTestMPL115A2Driver()
+	gobot.Assert(t, d.Samples, 1)
+	gobot.Refute(t, d.Command("Pressure"), nil)
+	gobot.Refute(t, d.Command("Temperature"), nil)
+}
+
+func TestMPL115A2DriverStart(t *testing.T) {
+	d, device := initTestMPL115A2Driver()
+	d.SetInterval(time.Hour)
+	gobot.Assert(t, d.Start(), true)
+	gobot.Assert(t, d.calibration.a0, 2009.75)
+	gobot.Assert(t, round(d.calibration.b1), -2.376)
+	gobot.Assert(t, round(d.calibration.b2), -0.92)
+	gobot.Assert(t, d.calibration.c12, 3314.0/(1<<22))
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, device.closed, true)
+}
+
+func TestMPL115A2DriverMeasure(t *testing.T) {
+	d, device := initTestMPL115A2Driver()
+	d.Samples = 2
+	gobot.Assert(t, d.init(), nil)
+	device.written()
+	pressure, err := d.Pressure()
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, round(pressure), 96587.326)
+	gobot.Assert(t, device.written(), [][]byte{{0x12, 0x00}, {0x00}, {0x12, 0x00}, {0x00}})
+	temperature, err := d.Temperature()
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, round(temperature), 23.318)
+}
+
+func TestMPL115A2DriverMeasureError(t *testing.T) {
+	d, device := initTestMPL115A2Driver()
+	gobot.Assert(t, d.init(), nil)
+	device.absent = true
+	gobot.Assert(t, d.Command("Pressure")(map[string]interface{}{}), errNoDevice.Error())
+}
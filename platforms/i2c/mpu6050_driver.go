@@ -0,0 +1,259 @@
+package i2c
+
+import (
+	"log"
+	"sync"
+
+	"github.com/edmontongo/gobot"
+)
+
+// MPU6050 registers.
+const (
+	mpu6050SampleRateDivider byte = 0x19
+	mpu6050Config            byte = 0x1A
+	mpu6050GyroConfig        byte = 0x1B
+	mpu6050AccelConfig       byte = 0x1C
+	mpu6050AccelXOut         byte = 0x3B
+	mpu6050PowerManagement1  byte = 0x6B
+	mpu6050WhoAmI            byte = 0x75
+
+	// mpu6050ClockGyroX clocks the MPU6050 from its X gyro, which is more
+	// stable than its internal oscillator.
+	mpu6050ClockGyroX byte = 0x01
+	mpu6050Sleep      byte = 0x40
+)
+
+// MPU6050AccelRange is the full scale range of the accelerometer.
+type MPU6050AccelRange byte
+
+const (
+	MPU6050Accel2G MPU6050AccelRange = iota
+	MPU6050Accel4G
+	MPU6050Accel8G
+	MPU6050Accel16G
+)
+
+// MPU6050GyroRange is the full scale range of the gyroscope.
+type MPU6050GyroRange byte
+
+const (
+	MPU6050Gyro250 MPU6050GyroRange = iota
+	MPU6050Gyro500
+	MPU6050Gyro1000
+	MPU6050Gyro2000
+)
+
+// MPU6050Driver reads the accelerometer, gyroscope and temperature sensor
+// of an MPU6050.
+type MPU6050Driver struct {
+	gobot.Driver
+	// Address is 0x68, or 0x69 when the AD0 pin is high.
+	Address    byte
+	AccelRange MPU6050AccelRange
+	GyroRange  MPU6050GyroRange
+	// SampleRate is how many times a second the MPU6050 samples, from 4 to
+	// 1000. It is 100 by default.
+	SampleRate int
+	// LowPassFilter sets the bandwidth of the digital low pass filter, from
+	// 1, 188Hz, to 6, 5Hz. It is 3, 44Hz, by default.
+	LowPassFilter byte
+	// GyroBias is subtracted from each rotation. CalibrateGyro measures
+	// it.
+	GyroBias     ThreeDData
+	device       I2cDevice
+	mutex        sync.Mutex
+	acceleration ThreeDData
+	rotation     ThreeDData
+	temperature  float64
+}
+
+// NewMPU6050Driver creates a driver for an MPU6050 at address 0x68, with
+// ranges of ±2g and ±250°/s.
+func NewMPU6050Driver(a I2cBus, name string) *MPU6050Driver {
+	m := &MPU6050Driver{
+		Driver: *gobot.NewDriver(
+			name,
+			"MPU6050Driver",
+			a.(gobot.AdaptorInterface),
+		),
+		Address:       0x68,
+		AccelRange:    MPU6050Accel2G,
+		GyroRange:     MPU6050Gyro250,
+		SampleRate:    100,
+		LowPassFilter: 3,
+	}
+
+	m.AddEvent("acceleration")
+	m.AddEvent("rotation")
+	m.AddEvent("temperature")
+	m.AddEvent("error")
+
+	m.AddCommand("Acceleration", func(params map[string]interface{}) interface{} {
+		return m.Acceleration()
+	})
+	m.AddCommand("Rotation", func(params map[string]interface{}) interface{} {
+		return m.Rotation()
+	})
+	m.AddCommand("Temperature", func(params map[string]interface{}) interface{} {
+		return m.Temperature()
+	})
+	m.AddCommand("CalibrateGyro", func(params map[string]interface{}) interface{} {
+		samples := 100
+		if s, ok := params["samples"]; ok {
+			samples = int(s.(float64))
+		}
+		bias, err := m.CalibrateGyro(samples)
+		if err != nil {
+			return err.Error()
+		}
+		return bias
+	})
+
+	return m
+}
+
+func (m *MPU6050Driver) adaptor() I2cBus {
+	return m.Adaptor().(I2cBus)
+}
+
+// Start wakes the MPU6050 and sets its ranges and sample rate, then reads
+// it every interval, publishing "acceleration" in g, "rotation" in degrees
+// per second and "temperature" in degrees Celsius, or "error".
+func (m *MPU6050Driver) Start() bool {
+	if err := m.init(); err != nil {
+		log.Println(err)
+		return false
+	}
+	gobot.Every(m.Interval(), func() {
+		if err := m.update(); err != nil {
+			gobot.Publish(m.Event("error"), err)
+			return
+		}
+		gobot.Publish(m.Event("acceleration"), m.Acceleration())
+		gobot.Publish(m.Event("rotation"), m.Rotation())
+		gobot.Publish(m.Event("temperature"), m.Temperature())
+	})
+	return true
+}
+func (m *MPU6050Driver) Init() bool { return true }
+
+// Halt puts the MPU6050 to sleep.
+func (m *MPU6050Driver) Halt() bool {
+	if m.device != nil {
+		m.device.WriteByteData(mpu6050PowerManagement1, mpu6050Sleep)
+		m.device.Close()
+	}
+	return true
+}
+
+// Acceleration returns the last acceleration read, in g.
+func (m *MPU6050Driver) Acceleration() ThreeDData {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.acceleration
+}
+
+// Rotation returns the last rotation read, less GyroBias, in degrees per
+// second.
+func (m *MPU6050Driver) Rotation() ThreeDData {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.rotation
+}
+
+// Temperature returns the last temperature read, in degrees Celsius.
+func (m *MPU6050Driver) Temperature() float64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.temperature
+}
+
+// CalibrateGyro averages samples rotations, which must be read with the
+// MPU6050 kept still, and makes the average the GyroBias.
+func (m *MPU6050Driver) CalibrateGyro(samples int) (ThreeDData, error) {
+	if samples < 1 {
+		samples = 1
+	}
+	sum := ThreeDData{}
+	for i := 0; i < samples; i++ {
+		_, rotation, _, err := m.read()
+		if err != nil {
+			return ThreeDData{}, err
+		}
+		sum = ThreeDData{sum.X + rotation.X, sum.Y + rotation.Y, sum.Z + rotation.Z}
+	}
+	bias := ThreeDData{sum.X / float64(samples), sum.Y / float64(samples), sum.Z / float64(samples)}
+	m.mutex.Lock()
+	m.GyroBias = bias
+	m.mutex.Unlock()
+	return bias, nil
+}
+
+func (m *MPU6050Driver) init() error {
+	device, err := m.adaptor().I2cOpen(m.Address)
+	if err != nil {
+		return err
+	}
+	m.device = device
+
+	rate := m.SampleRate
+	if rate < 4 {
+		rate = 4
+	} else if rate > 1000 {
+		rate = 1000
+	}
+	filter := m.LowPassFilter
+	if filter < 1 {
+		filter = 1
+	} else if filter > 6 {
+		filter = 6
+	}
+	// with the low pass filter on, the gyro runs at 1kHz, which the
+	// divider slows down
+	writes := [][2]byte{
+		{mpu6050PowerManagement1, mpu6050ClockGyroX},
+		{mpu6050Config, filter},
+		{mpu6050SampleRateDivider, byte(1000/rate - 1)},
+		{mpu6050GyroConfig, byte(m.GyroRange&0x03) << 3},
+		{mpu6050AccelConfig, byte(m.AccelRange&0x03) << 3},
+	}
+	for _, w := range writes {
+		if err := m.device.WriteByteData(w[0], w[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MPU6050Driver) update() error {
+	acceleration, rotation, temperature, err := m.read()
+	if err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.acceleration = acceleration
+	m.rotation = rotation.sub(m.GyroBias)
+	m.temperature = temperature
+	return nil
+}
+
+// read reads the accelerometer, temperature and gyroscope, which are
+// consecutive 16-bit registers, high byte first.
+func (m *MPU6050Driver) read() (ThreeDData, ThreeDData, float64, error) {
+	data := make([]byte, 14)
+	if err := m.device.ReadBlockData(mpu6050AccelXOut, data); err != nil {
+		return ThreeDData{}, ThreeDData{}, 0, err
+	}
+	values := make([]float64, 7)
+	for i := range values {
+		values[i] = float64(int16(uint16(data[2*i])<<8 | uint16(data[2*i+1])))
+	}
+	// ±2g is 16384 per g, halving with each range
+	accelScale := float64(int(16384) >> (m.AccelRange & 0x03))
+	// ±250°/s is 131 per degree per second, halving with each range
+	gyroScale := 131 / float64(uint(1)<<(m.GyroRange&0x03))
+	acceleration := ThreeDData{values[0] / accelScale, values[1] / accelScale, values[2] / accelScale}
+	rotation := ThreeDData{values[4] / gyroScale, values[5] / gyroScale, values[6] / gyroScale}
+	return acceleration, rotation, values[3]/340 + 36.53, nil
+}
@@ -0,0 +1,100 @@
+package i2c
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestMPU6050Driver() (*MPU6050Driver, *i2cTestDevice) {
+	a := newI2cTestAdaptor("adaptor")
+	return NewMPU6050Driver(a, "bot"), a.addDevice(0x68)
+}
+
+func TestMPU6050Driver(t *testing.T) {
+	d, _ := initTestMPU6050Driver()
+	gobot.Assert(t, d.Address, byte(0x68))
+	gobot.Refute(t, d.Command("Acceleration"), nil)
+	gobot.Refute(t, d.Command("Rotation"), nil)
+	gobot.Refute(t, d.Command("Temperature"), nil)
+	gobot.Refute(t, d.Command("CalibrateGyro"), nil)
+}
+
+func TestMPU6050DriverStart(t *testing.T) {
+	d, device := initTestMPU6050Driver()
+	d.SetInterval(time.Hour)
+	d.AccelRange = MPU6050Accel8G
+	d.GyroRange = MPU6050Gyro500
+	d.SampleRate = 200
+	gobot.Assert(t, d.Start(), true)
+	gobot.Assert(t, device.written(), [][]byte{
+		{0x6B, 0x01},
+		{0x1A, 0x03},
+		{0x19, 0x04},
+		{0x1B, 0x08},
+		{0x1C, 0x10},
+	})
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, device.written(), [][]byte{{0x6B, 0x40}})
+	gobot.Assert(t, device.closed, true)
+}
+
+func TestMPU6050DriverUpdate(t *testing.T) {
+	d, device := initTestMPU6050Driver()
+	d.AccelRange = MPU6050Accel4G
+	gobot.Assert(t, d.init(), nil)
+	device.set(0x3B,
+		// acceleration 0.5g, -1g, 2g
+		0x10, 0x00, 0xe0, 0x00, 0x40, 0x00,
+		// 36.53 + 340/340 degrees
+		0x01, 0x54,
+		// rotation 2, -1, 0 degrees per second
+		0x01, 0x06, 0xff, 0x7d, 0x00, 0x00,
+	)
+	d.GyroBias = ThreeDData{1, -1, 0}
+	gobot.Assert(t, d.update(), nil)
+	gobot.Assert(t, d.Acceleration(), ThreeDData{0.5, -1, 2})
+	gobot.Assert(t, round(d.Temperature()), 37.53)
+	gobot.Assert(t, d.Rotation(), ThreeDData{1, 0, 0})
+}
+
+func TestMPU6050DriverCalibrateGyro(t *testing.T) {
+	d, device := initTestMPU6050Driver()
+	gobot.Assert(t, d.init(), nil)
+	// 1 and -2 degrees per second
+	device.set(0x43, 0x00, 0x83, 0xfe, 0xfa, 0x00, 0x00)
+	bias, err := d.CalibrateGyro(10)
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, bias, ThreeDData{1, -2, 0})
+	gobot.Assert(t, d.GyroBias, bias)
+	gobot.Assert(t, d.update(), nil)
+	gobot.Assert(t, d.Rotation(), ThreeDData{})
+}
+
+func TestMPU6050DriverUpdateError(t *testing.T) {
+	a := newI2cTestAdaptor("adaptor")
+	d := NewMPU6050Driver(a, "bot")
+	d.Address = 0x69
+	d.device, _ = a.I2cOpen(0x69)
+	gobot.Refute(t, d.update(), nil)
+	gobot.Assert(t, d.Command("CalibrateGyro")(map[string]interface{}{"samples": 1.0}), errNoDevice.Error())
+}
+
+func TestMPU6050DriverEvents(t *testing.T) {
+	d, device := initTestMPU6050Driver()
+	d.SetInterval(time.Millisecond)
+	// 1g on Z
+	device.set(0x3F, 0x40, 0x00)
+	accelerations := make(chan interface{}, 1)
+	gobot.Once(d.Event("acceleration"), func(data interface{}) {
+		accelerations <- data
+	})
+	gobot.Assert(t, d.Start(), true)
+	select {
+	case acceleration := <-accelerations:
+		gobot.Assert(t, acceleration, ThreeDData{0, 0, 1})
+	case <-time.After(time.Second):
+		t.Error("acceleration event was not published")
+	}
+}
@@ -0,0 +1,362 @@
+package i2c
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/gpio"
+)
+
+// PCA9685 registers.
+const (
+	pca9685Mode1    byte = 0x00
+	pca9685Mode2    byte = 0x01
+	pca9685LED0On   byte = 0x06
+	pca9685AllLEDOn byte = 0xFA
+	pca9685Prescale byte = 0xFE
+	pca9685Channels      = 16
+
+	pca9685Restart       byte = 0x80
+	pca9685AutoIncrement byte = 0x20
+	pca9685Sleep         byte = 0x10
+	pca9685AllCall       byte = 0x01
+	pca9685TotemPole     byte = 0x04
+
+	// pca9685Full is the bit of the on or off ticks of a channel that
+	// turns it fully on or off.
+	pca9685Full uint16 = 0x1000
+	// pca9685Ticks is the number of ticks in each period.
+	pca9685Ticks = 4096
+	// pca9685Oscillator is the frequency of the internal oscillator.
+	pca9685Oscillator = 25000000
+)
+
+// PCA9685Driver drives the 16 PWM channels of a PCA9685, named "0" to "15".
+// It is a connection of the robot rather than a device, added after the
+// adaptor of its bus, so that gpio drivers can use it as their adaptor: it
+// implements gpio.Servo, gpio.ServoPulseWriter, gpio.Pwm,
+// gpio.PwmController and gpio.DigitalWriter, and ServoDriver, LedDriver
+// and MotorDriver work on its channels as on any PWM pin. All channels
+// share one frequency.
+type PCA9685Driver struct {
+	gobot.Adaptor
+	// Address is 0x40, plus the levels of the address pins.
+	Address byte
+	// Frequency is the PWM frequency in hertz, from 24 to 1526. It is 50
+	// by default, for servos.
+	Frequency float64
+	bus       I2cBus
+	device    I2cDevice
+	mutex     sync.Mutex
+	prescale  byte
+	ticks     [pca9685Channels][2]uint16
+	disabled  [pca9685Channels]bool
+}
+
+// NewPCA9685Driver creates a driver for a PCA9685 at address 0x40 on the
+// bus of a, at 50Hz.
+func NewPCA9685Driver(a I2cBus, name string) *PCA9685Driver {
+	p := &PCA9685Driver{
+		Adaptor: *gobot.NewAdaptor(
+			name,
+			"PCA9685Driver",
+		),
+		Address:   0x40,
+		Frequency: 50,
+		bus:       a,
+	}
+
+	p.AddCommand("SetFrequency", func(params map[string]interface{}) interface{} {
+		return errorString(p.SetFrequency(params["frequency"].(float64)))
+	})
+	p.AddCommand("SetPwm", func(params map[string]interface{}) interface{} {
+		return errorString(p.SetPwm(int(params["channel"].(float64)),
+			uint16(params["on"].(float64)), uint16(params["off"].(float64))))
+	})
+	p.AddCommand("AllOff", func(params map[string]interface{}) interface{} {
+		return errorString(p.AllOff())
+	})
+
+	return p
+}
+
+// Connect wakes the PCA9685, sets its frequency and turns all channels
+// off.
+func (p *PCA9685Driver) Connect() bool {
+	if err := p.init(); err != nil {
+		log.Println(err)
+		return false
+	}
+	p.SetConnected(true)
+	return true
+}
+
+// Finalize turns all channels off and puts the PCA9685 to sleep.
+func (p *PCA9685Driver) Finalize() bool {
+	if p.device != nil {
+		logError(p.AllOff())
+		p.device.WriteByteData(pca9685Mode1, pca9685AutoIncrement|pca9685Sleep|pca9685AllCall)
+		p.device.Close()
+	}
+	p.SetConnected(false)
+	return true
+}
+
+// ToJSON describes the address and frequency of the PCA9685 in Details.
+func (p *PCA9685Driver) ToJSON() *gobot.JSONConnection {
+	json := p.Adaptor.ToJSON()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	json.Details = map[string]interface{}{
+		"address":   p.Address,
+		"frequency": p.Frequency,
+	}
+	return json
+}
+
+// SetFrequency sets the PWM frequency of all channels, in hertz, from 24
+// to 1526. Channels keep their ticks, so their duty cycles are kept and
+// their pulse widths change.
+func (p *PCA9685Driver) SetFrequency(frequency float64) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.setFrequency(frequency)
+}
+
+// SetPwm sets the tick, from 0 to 4095, at which channel turns on in each
+// period, and the tick at which it turns off. Adding 4096 to on or off
+// turns the channel fully on or off. A disabled channel stays off until it
+// is enabled.
+func (p *PCA9685Driver) SetPwm(channel int, on uint16, off uint16) error {
+	if channel < 0 || channel >= pca9685Channels {
+		return fmt.Errorf("i2c: no PCA9685 channel %v", channel)
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.ticks[channel] = [2]uint16{on, off}
+	if p.disabled[channel] {
+		return nil
+	}
+	return p.writeTicks(pca9685LED0On+4*byte(channel), on, off)
+}
+
+// AllOff turns every channel fully off at once, such as to stop all
+// servos and motors in an emergency.
+func (p *PCA9685Driver) AllOff() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for i := range p.ticks {
+		p.ticks[i] = [2]uint16{0, pca9685Full}
+	}
+	return p.writeTicks(pca9685AllLEDOn, 0, pca9685Full)
+}
+
+// PwmWrite sets the duty cycle of pin to level, from 0 to 255.
+func (p *PCA9685Driver) PwmWrite(pin string, level byte) {
+	logError(p.PwmSetDutyCycle(pin, float64(level)/255))
+}
+
+// DigitalWrite turns pin fully on for level 1 and off for 0.
+func (p *PCA9685Driver) DigitalWrite(pin string, level byte) {
+	channel, err := p.channel(pin)
+	if err == nil {
+		if level == 0 {
+			err = p.SetPwm(channel, 0, pca9685Full)
+		} else {
+			err = p.SetPwm(channel, pca9685Full, 0)
+		}
+	}
+	logError(err)
+}
+
+func (p *PCA9685Driver) InitServo() {}
+
+// ServoWrite moves the servo on pin to angle, from 0 to 180 degrees, using
+// the default pulse range of gpio.ServoDriver.
+func (p *PCA9685Driver) ServoWrite(pin string, angle byte) {
+	scale := gobot.FromScale(math.Min(float64(angle), 180), 0, 180)
+	p.ServoPulseWrite(pin, gpio.DefaultServoMinPulse+
+		time.Duration(scale*float64(gpio.DefaultServoMaxPulse-gpio.DefaultServoMinPulse)))
+}
+
+// ServoPulseWrite sends pulses of the given width to the servo on pin, at
+// the frequency of the PCA9685.
+func (p *PCA9685Driver) ServoPulseWrite(pin string, pulse time.Duration) {
+	p.mutex.Lock()
+	period := p.period()
+	p.mutex.Unlock()
+	logError(p.PwmSetDutyCycle(pin, float64(pulse)/float64(period)))
+}
+
+// PwmSetPeriod sets the frequency of all channels, as only one is
+// supported, to that of period. It returns an error rather than change
+// the period under another channel that is pulsing, which SetFrequency
+// can still do.
+func (p *PCA9685Driver) PwmSetPeriod(pin string, period time.Duration) error {
+	channel, err := p.channel(pin)
+	if err != nil {
+		return err
+	}
+	if period <= 0 {
+		return fmt.Errorf("i2c: invalid PCA9685 period %v", period)
+	}
+	frequency := float64(time.Second) / float64(period)
+	prescale, err := pca9685PrescaleFor(frequency)
+	if err != nil {
+		return err
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if prescale != p.prescale {
+		for i, ticks := range p.ticks {
+			if i != channel && !p.disabled[i] && ticks[0]&pca9685Full == 0 && ticks[1]&pca9685Full == 0 {
+				return fmt.Errorf("i2c: PCA9685 pin %v needs period %v, not %v", i, p.period(), period)
+			}
+		}
+	}
+	return p.setFrequency(frequency)
+}
+
+// PwmSetDutyCycle sets the fraction of each period, from 0 to 1, that pin
+// is on. Channels turn on at the start of each period.
+func (p *PCA9685Driver) PwmSetDutyCycle(pin string, duty float64) error {
+	channel, err := p.channel(pin)
+	if err != nil {
+		return err
+	}
+	duty = math.Max(0, math.Min(1, duty))
+	switch {
+	case duty == 0:
+		return p.SetPwm(channel, 0, pca9685Full)
+	case duty == 1:
+		return p.SetPwm(channel, pca9685Full, 0)
+	}
+	// 4096 would turn the channel fully off
+	return p.SetPwm(channel, 0, uint16(math.Min(duty*pca9685Ticks+0.5, pca9685Ticks-1)))
+}
+
+// PwmSetEnabled turns pin fully off while disabled, and back to its ticks
+// when enabled.
+func (p *PCA9685Driver) PwmSetEnabled(pin string, enabled bool) error {
+	channel, err := p.channel(pin)
+	if err != nil {
+		return err
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.disabled[channel] = !enabled
+	reg := pca9685LED0On + 4*byte(channel)
+	if !enabled {
+		return p.writeTicks(reg, 0, pca9685Full)
+	}
+	return p.writeTicks(reg, p.ticks[channel][0], p.ticks[channel][1])
+}
+
+// PwmSetPolarity returns an error for inverted channels, as the PCA9685
+// can only invert them all.
+func (p *PCA9685Driver) PwmSetPolarity(pin string, inverted bool) error {
+	if _, err := p.channel(pin); err != nil {
+		return err
+	}
+	if inverted {
+		return fmt.Errorf("i2c: PCA9685 pin %v cannot be inverted alone", pin)
+	}
+	return nil
+}
+
+func (p *PCA9685Driver) init() error {
+	device, err := p.bus.I2cOpen(p.Address)
+	if err != nil {
+		return err
+	}
+	p.device = device
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if err := p.device.WriteByteData(pca9685Mode2, pca9685TotemPole); err != nil {
+		return err
+	}
+	// the prescale of a new PCA9685 is unknown
+	p.prescale = 0
+	if err := p.setFrequency(p.Frequency); err != nil {
+		return err
+	}
+	for i := range p.ticks {
+		p.ticks[i] = [2]uint16{0, pca9685Full}
+	}
+	return p.writeTicks(pca9685AllLEDOn, 0, pca9685Full)
+}
+
+// setFrequency sets the prescaler, which can only be written while the
+// PCA9685 sleeps, then wakes and restarts it, so channels carry on.
+func (p *PCA9685Driver) setFrequency(frequency float64) error {
+	prescale, err := pca9685PrescaleFor(frequency)
+	if err != nil {
+		return err
+	}
+	if prescale == p.prescale {
+		p.Frequency = frequency
+		return nil
+	}
+	writes := [][2]byte{
+		{pca9685Mode1, pca9685AutoIncrement | pca9685Sleep | pca9685AllCall},
+		{pca9685Prescale, prescale},
+		{pca9685Mode1, pca9685AutoIncrement | pca9685AllCall},
+	}
+	for _, w := range writes {
+		if err := p.device.WriteByteData(w[0], w[1]); err != nil {
+			return err
+		}
+	}
+	// the oscillator takes 500µs to start
+	time.Sleep(500 * time.Microsecond)
+	if err := p.device.WriteByteData(pca9685Mode1, pca9685Restart|pca9685AutoIncrement|pca9685AllCall); err != nil {
+		return err
+	}
+	p.prescale = prescale
+	p.Frequency = frequency
+	return nil
+}
+
+// pca9685PrescaleFor returns the prescaler value of frequency.
+func pca9685PrescaleFor(frequency float64) (byte, error) {
+	if frequency <= 0 {
+		return 0, fmt.Errorf("i2c: invalid PCA9685 frequency %v", frequency)
+	}
+	prescale := math.Floor(pca9685Oscillator/(pca9685Ticks*frequency)+0.5) - 1
+	if prescale < 3 || prescale > 255 {
+		return 0, fmt.Errorf("i2c: PCA9685 frequency %v out of range", frequency)
+	}
+	return byte(prescale), nil
+}
+
+// period returns the actual period, which the prescaler rounds.
+func (p *PCA9685Driver) period() time.Duration {
+	prescale := float64(p.prescale)
+	if prescale == 0 {
+		prescale = math.Floor(pca9685Oscillator/(pca9685Ticks*p.Frequency)+0.5) - 1
+	}
+	return time.Duration(float64(time.Second) * pca9685Ticks * (prescale + 1) / pca9685Oscillator)
+}
+
+// writeTicks writes the on and off ticks of the channel at reg, which are
+// 13-bit registers, low byte first.
+func (p *PCA9685Driver) writeTicks(reg byte, on uint16, off uint16) error {
+	if p.device == nil {
+		return errNotConnected
+	}
+	return p.device.WriteBlockData(reg, []byte{byte(on), byte(on >> 8), byte(off), byte(off >> 8)})
+}
+
+func (p *PCA9685Driver) channel(pin string) (int, error) {
+	channel, err := strconv.Atoi(pin)
+	if err != nil || channel < 0 || channel >= pca9685Channels {
+		return 0, fmt.Errorf("i2c: no PCA9685 pin %v", pin)
+	}
+	return channel, nil
+}
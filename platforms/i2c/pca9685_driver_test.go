@@ -0,0 +1,177 @@
+package i2c
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/gpio"
+)
+
+func initTestPCA9685Driver() (*PCA9685Driver, *i2cTestDevice) {
+	a := newI2cTestAdaptor("adaptor")
+	device := a.addDevice(0x40)
+	d := NewPCA9685Driver(a, "pca9685")
+	return d, device
+}
+
+func initConnectedPCA9685Driver(t *testing.T) (*PCA9685Driver, *i2cTestDevice) {
+	d, device := initTestPCA9685Driver()
+	gobot.Assert(t, d.Connect(), true)
+	device.written()
+	return d, device
+}
+
+func TestPCA9685Driver(t *testing.T) {
+	var _ gpio.Servo = (*PCA9685Driver)(nil)
+	var _ gpio.ServoPulseWriter = (*PCA9685Driver)(nil)
+	var _ gpio.PwmDigitalWriter = (*PCA9685Driver)(nil)
+	var _ gpio.PwmController = (*PCA9685Driver)(nil)
+	var _ gobot.Connection = (*PCA9685Driver)(nil)
+
+	d, _ := initTestPCA9685Driver()
+	gobot.Assert(t, d.Address, byte(0x40))
+	gobot.Assert(t, d.Frequency, 50.0)
+	gobot.Refute(t, d.Command("SetFrequency"), nil)
+	gobot.Refute(t, d.Command("SetPwm"), nil)
+	gobot.Refute(t, d.Command("AllOff"), nil)
+}
+
+func TestPCA9685DriverConnect(t *testing.T) {
+	d, device := initTestPCA9685Driver()
+	gobot.Assert(t, d.Connect(), true)
+	gobot.Assert(t, d.Connected(), true)
+	gobot.Assert(t, device.written(), [][]byte{
+		{0x01, 0x04},
+		{0x00, 0x31},
+		{0xFE, 121},
+		{0x00, 0x21},
+		{0x00, 0xA1},
+		{0xFA, 0x00, 0x00, 0x00, 0x10},
+	})
+	gobot.Assert(t, d.ToJSON().Details, map[string]interface{}{
+		"address":   byte(0x40),
+		"frequency": 50.0,
+	})
+
+	gobot.Assert(t, d.Finalize(), true)
+	gobot.Assert(t, device.written(), [][]byte{
+		{0xFA, 0x00, 0x00, 0x00, 0x10},
+		{0x00, 0x31},
+	})
+	gobot.Assert(t, device.closed, true)
+}
+
+func TestPCA9685DriverNotConnected(t *testing.T) {
+	d, _ := initTestPCA9685Driver()
+	gobot.Assert(t, d.SetPwm(0, 0, 100), errNotConnected)
+	gobot.Assert(t, d.Command("AllOff")(map[string]interface{}{}), errNotConnected.Error())
+}
+
+func TestPCA9685DriverSetPwm(t *testing.T) {
+	d, device := initConnectedPCA9685Driver(t)
+	gobot.Assert(t, d.SetPwm(2, 0x100, 0x3ff), nil)
+	gobot.Assert(t, device.written(), [][]byte{{0x0E, 0x00, 0x01, 0xff, 0x03}})
+	gobot.Assert(t, d.SetPwm(16, 0, 0), errors.New("i2c: no PCA9685 channel 16"))
+	gobot.Assert(t, d.Command("SetPwm")(map[string]interface{}{"channel": 1.0, "on": 0.0, "off": 2048.0}), nil)
+	gobot.Assert(t, device.written(), [][]byte{{0x0A, 0x00, 0x00, 0x00, 0x08}})
+}
+
+func TestPCA9685DriverSetFrequency(t *testing.T) {
+	d, device := initConnectedPCA9685Driver(t)
+	gobot.Assert(t, d.SetFrequency(1000), nil)
+	gobot.Assert(t, d.Frequency, 1000.0)
+	gobot.Assert(t, device.written(), [][]byte{{0x00, 0x31}, {0xFE, 5}, {0x00, 0x21}, {0x00, 0xA1}})
+	// the same prescale
+	gobot.Assert(t, d.SetFrequency(1001), nil)
+	gobot.Assert(t, len(device.written()), 0)
+	gobot.Assert(t, d.SetFrequency(2000), errors.New("i2c: PCA9685 frequency 2000 out of range"))
+	gobot.Assert(t, d.Command("SetFrequency")(map[string]interface{}{"frequency": 0.0}),
+		"i2c: invalid PCA9685 frequency 0")
+}
+
+func TestPCA9685DriverAllOff(t *testing.T) {
+	d, device := initConnectedPCA9685Driver(t)
+	d.SetPwm(0, 0, 100)
+	device.written()
+	gobot.Assert(t, d.Command("AllOff")(map[string]interface{}{}), nil)
+	gobot.Assert(t, device.written(), [][]byte{{0xFA, 0x00, 0x00, 0x00, 0x10}})
+	gobot.Assert(t, d.ticks[0], [2]uint16{0, 0x1000})
+}
+
+func TestPCA9685DriverPwm(t *testing.T) {
+	d, device := initConnectedPCA9685Driver(t)
+	d.PwmWrite("4", 0)
+	d.PwmWrite("4", 255)
+	d.PwmWrite("4", 128)
+	gobot.Assert(t, d.PwmSetDutyCycle("4", 0.9999), nil)
+	d.DigitalWrite("5", 1)
+	d.DigitalWrite("5", 0)
+	gobot.Assert(t, device.written(), [][]byte{
+		{0x16, 0x00, 0x00, 0x00, 0x10},
+		{0x16, 0x00, 0x10, 0x00, 0x00},
+		{0x16, 0x00, 0x00, 0x08, 0x08},
+		{0x16, 0x00, 0x00, 0xff, 0x0f},
+		{0x1A, 0x00, 0x10, 0x00, 0x00},
+		{0x1A, 0x00, 0x00, 0x00, 0x10},
+	})
+	gobot.Assert(t, d.PwmSetDutyCycle("x", 0.5), errors.New("i2c: no PCA9685 pin x"))
+}
+
+func TestPCA9685DriverPwmSetEnabled(t *testing.T) {
+	d, device := initConnectedPCA9685Driver(t)
+	gobot.Assert(t, d.PwmSetEnabled("0", false), nil)
+	gobot.Assert(t, d.PwmSetDutyCycle("0", 0.5), nil)
+	gobot.Assert(t, device.written(), [][]byte{{0x06, 0x00, 0x00, 0x00, 0x10}})
+	gobot.Assert(t, d.PwmSetEnabled("0", true), nil)
+	gobot.Assert(t, device.written(), [][]byte{{0x06, 0x00, 0x00, 0x00, 0x08}})
+}
+
+func TestPCA9685DriverPwmSetPeriod(t *testing.T) {
+	d, device := initConnectedPCA9685Driver(t)
+	gobot.Assert(t, d.PwmSetPeriod("0", time.Millisecond), nil)
+	gobot.Assert(t, d.Frequency, 1000.0)
+	gobot.Assert(t, len(device.written()), 4)
+	gobot.Refute(t, d.PwmSetPeriod("0", 0), nil)
+
+	// the period of a pulsing channel is kept
+	gobot.Assert(t, d.PwmSetDutyCycle("1", 0.5), nil)
+	gobot.Assert(t, d.PwmSetDutyCycle("2", 1), nil)
+	device.written()
+	gobot.Assert(t, d.PwmSetPeriod("0", 2*time.Millisecond),
+		errors.New("i2c: PCA9685 pin 1 needs period 983.04µs, not 2ms"))
+	gobot.Assert(t, len(device.written()), 0)
+	gobot.Assert(t, d.PwmSetPeriod("1", 2*time.Millisecond), nil)
+	gobot.Assert(t, d.Frequency, 500.0)
+	gobot.Assert(t, d.PwmSetPeriod("0", 2*time.Millisecond), nil)
+	gobot.Assert(t, d.PwmSetEnabled("1", false), nil)
+	gobot.Assert(t, d.PwmSetPeriod("0", time.Millisecond), nil)
+
+	gobot.Assert(t, d.PwmSetPolarity("0", false), nil)
+	gobot.Assert(t, d.PwmSetPolarity("0", true), errors.New("i2c: PCA9685 pin 0 cannot be inverted alone"))
+}
+
+func TestPCA9685DriverServoDriver(t *testing.T) {
+	d, device := initConnectedPCA9685Driver(t)
+	servo := gpio.NewServoDriver(d, "servo", "3")
+	servo.Move(90)
+	// 1472µs of 20ms
+	gobot.Assert(t, device.written(), [][]byte{{0x12, 0x00, 0x00, 0x2d, 0x01}})
+	gobot.Assert(t, servo.ToJSON().Connection, "pca9685")
+
+	d.ServoWrite("3", 180)
+	// 2400µs of the 19.988ms period of prescale 121
+	gobot.Assert(t, device.written(), [][]byte{{0x12, 0x00, 0x00, 0xec, 0x01}})
+}
+
+func TestPCA9685DriverLedDriver(t *testing.T) {
+	d, device := initConnectedPCA9685Driver(t)
+	led := gpio.NewLedDriver(d, "led", "15")
+	gobot.Assert(t, led.On(), true)
+	led.Brightness(0)
+	gobot.Assert(t, device.written(), [][]byte{
+		{0x42, 0x00, 0x10, 0x00, 0x00},
+		{0x42, 0x00, 0x00, 0x00, 0x10},
+	})
+}
@@ -0,0 +1,203 @@
+package i2c
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/gpio"
+)
+
+// PCF8574Driver drives the 8 pins of a PCF8574 GPIO expander, named "0" to
+// "7" or "P0" to "P7". Like MCP23017Driver it is a connection of the robot,
+// added after the adaptor of its bus, that gpio drivers use as their
+// adaptor.
+//
+// The pins are quasi-bidirectional: a pin written low sinks current, and a
+// pin written high is pulled up weakly and may be read, so reading a pin
+// writes it high first. All pins are high after Connect.
+type PCF8574Driver struct {
+	gobot.Adaptor
+	// Address is 0x20, or 0x38 for the PCF8574A, plus the levels of the
+	// address pins.
+	Address byte
+	// InterruptPin is the pin of InterruptWatcher wired to the INT output,
+	// for WatchDigital. InterruptWatcher is the adaptor of the bus by
+	// default, when it is a gpio.DigitalWatcher.
+	InterruptPin     string
+	InterruptWatcher gpio.DigitalWatcher
+	bus              I2cBus
+	device           I2cDevice
+	mutex            sync.Mutex
+	output           byte
+	interrupts       expanderInterrupts
+}
+
+// NewPCF8574Driver creates a driver for a PCF8574 at address 0x20 on the
+// bus of a.
+func NewPCF8574Driver(a I2cBus, name string) *PCF8574Driver {
+	p := &PCF8574Driver{
+		Adaptor: *gobot.NewAdaptor(
+			name,
+			"PCF8574Driver",
+		),
+		Address: 0x20,
+		bus:     a,
+		output:  0xFF,
+	}
+	p.InterruptWatcher, _ = a.(gpio.DigitalWatcher)
+
+	p.AddCommand("DigitalRead", func(params map[string]interface{}) interface{} {
+		return p.DigitalRead(params["pin"].(string))
+	})
+	p.AddCommand("DigitalWrite", func(params map[string]interface{}) interface{} {
+		p.DigitalWrite(params["pin"].(string), byte(params["level"].(float64)))
+		return nil
+	})
+
+	return p
+}
+
+// Connect writes all pins high, and watches InterruptPin, if set.
+func (p *PCF8574Driver) Connect() bool {
+	if err := p.init(); err != nil {
+		log.Println(err)
+		return false
+	}
+	p.SetConnected(true)
+	return true
+}
+
+// Finalize stops watching InterruptPin. The pins keep their levels.
+func (p *PCF8574Driver) Finalize() bool {
+	p.interrupts.stop()
+	if p.device != nil {
+		p.device.Close()
+	}
+	p.SetConnected(false)
+	return true
+}
+
+// ToJSON describes the address and interrupt pin of the PCF8574 in
+// Details.
+func (p *PCF8574Driver) ToJSON() *gobot.JSONConnection {
+	json := p.Adaptor.ToJSON()
+	json.Details = map[string]interface{}{
+		"address":   p.Address,
+		"interrupt": p.InterruptPin,
+	}
+	return json
+}
+
+// DigitalRead writes pin high and returns its level, or -1 if it cannot
+// be read.
+func (p *PCF8574Driver) DigitalRead(pin string) int {
+	bit, err := p.pin(pin)
+	if err == nil {
+		err = p.write(bit, true)
+	}
+	var levels uint16
+	if err == nil {
+		levels, err = p.readInputs()
+	}
+	if err != nil {
+		log.Println(err)
+		return -1
+	}
+	return int(levels >> bit & 1)
+}
+
+// DigitalWrite writes pin at level.
+func (p *PCF8574Driver) DigitalWrite(pin string, level byte) {
+	bit, err := p.pin(pin)
+	if err == nil {
+		err = p.write(bit, level != 0)
+	}
+	logError(err)
+}
+
+// PwmWrite turns pin on for levels from 128, as the PCF8574 has no PWM,
+// so that LedDriver can turn LEDs on and off.
+func (p *PCF8574Driver) PwmWrite(pin string, level byte) {
+	p.DigitalWrite(pin, level>>7)
+}
+
+// WatchDigital writes pin high and calls handler with its level, and again
+// after each change on edge, using the interrupt output of the PCF8574,
+// which falls on a change of any pin. It returns an error without an
+// InterruptPin.
+func (p *PCF8574Driver) WatchDigital(pin string, edge string, handler func(int)) error {
+	bit, err := p.pin(pin)
+	if err != nil {
+		return err
+	}
+	if err := p.write(bit, true); err != nil {
+		return err
+	}
+	return p.interrupts.watch(int(bit), edge, handler)
+}
+
+// UnwatchDigital stops calling the handler given to WatchDigital for pin.
+func (p *PCF8574Driver) UnwatchDigital(pin string) error {
+	bit, err := p.pin(pin)
+	if err != nil {
+		return err
+	}
+	p.interrupts.unwatch(int(bit))
+	return nil
+}
+
+func (p *PCF8574Driver) init() error {
+	device, err := p.bus.I2cOpen(p.Address)
+	if err != nil {
+		return err
+	}
+	p.device = device
+
+	p.mutex.Lock()
+	err = p.device.WriteByte(p.output)
+	p.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	return p.interrupts.start(p.InterruptWatcher, p.InterruptPin, p.readInputs)
+}
+
+// readInputs reads the levels of the pins, which clears the interrupt.
+func (p *PCF8574Driver) readInputs() (uint16, error) {
+	if p.device == nil {
+		return 0, errNotConnected
+	}
+	levels, err := p.device.ReadByte()
+	return uint16(levels), err
+}
+
+// write writes the pin at bit high or low, unless it already is.
+func (p *PCF8574Driver) write(bit uint, high bool) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.device == nil {
+		return errNotConnected
+	}
+	output := byte(setBit(uint16(p.output), bit, high))
+	if output == p.output {
+		return nil
+	}
+	if err := p.device.WriteByte(output); err != nil {
+		return err
+	}
+	p.output = output
+	return nil
+}
+
+// pin returns the bit of a pin name.
+func (p *PCF8574Driver) pin(pin string) (uint, error) {
+	bit, err := strconv.Atoi(strings.TrimPrefix(pin, "P"))
+	if err != nil || bit < 0 || bit > 7 {
+		return 0, fmt.Errorf("i2c: no PCF8574 pin %v", pin)
+	}
+	return uint(bit), nil
+}
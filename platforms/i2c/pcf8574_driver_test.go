@@ -0,0 +1,213 @@
+package i2c
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/gpio"
+)
+
+// pcf8574TestDevice is a fake PCF8574, which has no registers: writes set
+// the pins and reads return their levels, with pins written low reading
+// low.
+type pcf8574TestDevice struct {
+	mutex  sync.Mutex
+	inputs byte
+	writes []byte
+	output byte
+	absent bool
+}
+
+func (d *pcf8574TestDevice) Read(data []byte) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.absent {
+		return 0, errNoDevice
+	}
+	for i := range data {
+		data[i] = d.inputs & d.output
+	}
+	return len(data), nil
+}
+
+func (d *pcf8574TestDevice) Write(data []byte) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.absent {
+		return 0, errNoDevice
+	}
+	for _, value := range data {
+		d.writes = append(d.writes, value)
+		d.output = value
+	}
+	return len(data), nil
+}
+
+func (d *pcf8574TestDevice) Close() error { return nil }
+
+func (d *pcf8574TestDevice) set(inputs byte) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.inputs = inputs
+}
+
+func (d *pcf8574TestDevice) written() []byte {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	writes := d.writes
+	d.writes = nil
+	return writes
+}
+
+func initTestPCF8574Driver() (*PCF8574Driver, *pcf8574TestDevice) {
+	device := &pcf8574TestDevice{inputs: 0xFF}
+	d := NewPCF8574Driver(&i2cTestBus{device}, "pcf8574")
+	return d, device
+}
+
+func initConnectedPCF8574Driver(t *testing.T) (*PCF8574Driver, *pcf8574TestDevice) {
+	d, device := initTestPCF8574Driver()
+	gobot.Assert(t, d.Connect(), true)
+	device.written()
+	return d, device
+}
+
+func TestPCF8574Driver(t *testing.T) {
+	var _ gpio.DigitalReader = (*PCF8574Driver)(nil)
+	var _ gpio.PwmDigitalWriter = (*PCF8574Driver)(nil)
+	var _ gpio.DigitalWatcher = (*PCF8574Driver)(nil)
+	var _ gobot.Connection = (*PCF8574Driver)(nil)
+
+	d, _ := initTestPCF8574Driver()
+	gobot.Assert(t, d.Address, byte(0x20))
+	gobot.Refute(t, d.Command("DigitalRead"), nil)
+	gobot.Refute(t, d.Command("DigitalWrite"), nil)
+}
+
+func TestPCF8574DriverConnect(t *testing.T) {
+	d, device := initTestPCF8574Driver()
+	gobot.Assert(t, d.Connect(), true)
+	gobot.Assert(t, d.Connected(), true)
+	gobot.Assert(t, device.written(), []byte{0xFF})
+	gobot.Assert(t, d.ToJSON().Details, map[string]interface{}{
+		"address":   byte(0x20),
+		"interrupt": "",
+	})
+	gobot.Assert(t, d.Finalize(), true)
+
+	d, device = initTestPCF8574Driver()
+	device.absent = true
+	gobot.Assert(t, d.Connect(), false)
+}
+
+func TestPCF8574DriverNotConnected(t *testing.T) {
+	d, _ := initTestPCF8574Driver()
+	gobot.Assert(t, d.DigitalRead("0"), -1)
+	gobot.Assert(t, d.WatchDigital("0", gpio.EdgeBoth, func(int) {}), errNotConnected)
+}
+
+func TestPCF8574DriverPins(t *testing.T) {
+	d, _ := initTestPCF8574Driver()
+	for name, bit := range map[string]uint{"0": 0, "7": 7, "P0": 0, "P7": 7} {
+		b, err := d.pin(name)
+		gobot.Assert(t, err, nil)
+		gobot.Assert(t, b, bit)
+	}
+	for _, name := range []string{"8", "P8", "A0", ""} {
+		_, err := d.pin(name)
+		gobot.Assert(t, err, errors.New("i2c: no PCF8574 pin "+name))
+	}
+}
+
+func TestPCF8574DriverDigitalWrite(t *testing.T) {
+	d, device := initConnectedPCF8574Driver(t)
+	d.DigitalWrite("3", 0)
+	d.DigitalWrite("P5", 0)
+	d.DigitalWrite("5", 0)
+	d.DigitalWrite("3", 1)
+	d.PwmWrite("0", 100)
+	gobot.Assert(t, d.Command("DigitalWrite")(map[string]interface{}{"pin": "0", "level": 1.0}), nil)
+	gobot.Assert(t, device.written(), []byte{0xF7, 0xD7, 0xDF, 0xDE, 0xDF})
+}
+
+func TestPCF8574DriverDigitalRead(t *testing.T) {
+	d, device := initConnectedPCF8574Driver(t)
+	d.DigitalWrite("2", 0)
+	device.set(0xFB)
+	gobot.Assert(t, d.DigitalRead("1"), 1)
+	// pin 2 is written high to be read
+	gobot.Assert(t, d.DigitalRead("2"), 0)
+	gobot.Assert(t, device.written(), []byte{0xFB, 0xFF})
+	device.set(0xFF)
+	gobot.Assert(t, d.Command("DigitalRead")(map[string]interface{}{"pin": "2"}), 1)
+	gobot.Assert(t, d.DigitalRead("9"), -1)
+}
+
+func TestPCF8574DriverWatchDigital(t *testing.T) {
+	d, device := initTestPCF8574Driver()
+	watcher := newTestDigitalWatcher()
+	d.InterruptWatcher = watcher
+	d.InterruptPin = "P8_9"
+	gobot.Assert(t, d.Connect(), true)
+	gobot.Assert(t, watcher.edges["P8_9"], gpio.EdgeFalling)
+	d.DigitalWrite("6", 0)
+
+	var levels []int
+	gobot.Assert(t, d.WatchDigital("6", gpio.EdgeFalling, func(level int) {
+		levels = append(levels, level)
+	}), nil)
+	device.set(0xBF)
+	watcher.fire("P8_9")
+	device.set(0xFF)
+	watcher.fire("P8_9")
+	device.set(0xBF)
+	watcher.fire("P8_9")
+	gobot.Assert(t, levels, []int{1, 0, 0})
+
+	gobot.Assert(t, d.UnwatchDigital("6"), nil)
+	device.set(0xFF)
+	watcher.fire("P8_9")
+	gobot.Assert(t, d.UnwatchDigital("x"), errors.New("i2c: no PCF8574 pin x"))
+	gobot.Assert(t, levels, []int{1, 0, 0})
+
+	gobot.Assert(t, d.Finalize(), true)
+	gobot.Assert(t, watcher.watching("P8_9"), false)
+}
+
+func TestPCF8574DriverButtonDriver(t *testing.T) {
+	d, device := initTestPCF8574Driver()
+	watcher := newTestDigitalWatcher()
+	d.InterruptWatcher = watcher
+	d.InterruptPin = "P8_9"
+	gobot.Assert(t, d.Connect(), true)
+
+	button := gpio.NewMakeyButtonDriver(d, "button", "4")
+	button.DebounceTime = 0
+	pushes := make(chan interface{}, 1)
+	gobot.Once(button.Event("push"), func(data interface{}) {
+		pushes <- data
+	})
+	gobot.Assert(t, button.Start(), true)
+
+	device.set(0xEF)
+	watcher.fire("P8_9")
+	select {
+	case data := <-pushes:
+		gobot.Assert(t, data, 0)
+	case <-time.After(time.Second):
+		t.Error("no push event")
+	}
+	gobot.Assert(t, button.Halt(), true)
+}
+
+func TestPCF8574DriverLedDriver(t *testing.T) {
+	d, device := initConnectedPCF8574Driver(t)
+	// pins are high after Connect, so only Off is written
+	led := gpio.NewLedDriver(d, "led", "7")
+	gobot.Assert(t, led.On(), true)
+	gobot.Assert(t, led.Off(), true)
+	gobot.Assert(t, device.written(), []byte{0x7F})
+}
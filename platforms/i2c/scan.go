@@ -0,0 +1,143 @@
+package i2c
+
+import "fmt"
+
+// The addresses Scan probes. The others are reserved by the I2C
+// specification.
+const (
+	scanFirst = 0x03
+	scanLast  = 0x77
+)
+
+// knownDevice describes a device the package has a driver for. Devices
+// with a mask have an identification register, reg, and are only
+// suggested when it reads value after masking. Others are suggested by
+// address alone.
+type knownDevice struct {
+	driver    string
+	addresses []byte
+	reg       byte
+	mask      byte
+	value     byte
+}
+
+// knownDevices are the devices of the drivers in this package.
+var knownDevices = []knownDevice{
+	{driver: "ADS1015Driver", addresses: []byte{0x48, 0x49, 0x4a, 0x4b}},
+	{driver: "ADS1115Driver", addresses: []byte{0x48, 0x49, 0x4a, 0x4b}},
+	{driver: "BlinkMDriver", addresses: []byte{0x09}},
+	{driver: "BMP180Driver", addresses: []byte{bmp180Address}, reg: bmp180ID, mask: 0xff, value: 0x55},
+	{driver: "BMP280Driver", addresses: []byte{0x76, 0x77}, reg: bmp280ID, mask: 0xff, value: 0x58},
+	{driver: "HMC5883LDriver", addresses: []byte{hmc5883lAddress}, reg: hmc5883lIDA, mask: 0xff, value: 'H'},
+	{driver: "HMC6352Driver", addresses: []byte{0x21}},
+	{driver: "HTU21DDriver", addresses: []byte{htu21dAddress}},
+	{driver: "LSM303Driver", addresses: []byte{lsm303AccelAddress}},
+	{driver: "LSM303Driver", addresses: []byte{lsm303MagAddress}, reg: lsm303IraRegM, mask: 0xff, value: 'H'},
+	{driver: "MCP23017Driver", addresses: []byte{0x20, 0x21, 0x22, 0x23, 0x24, 0x25, 0x26, 0x27}},
+	{driver: "MPL115A2Driver", addresses: []byte{mpl115a2Address}},
+	{driver: "MPU6050Driver", addresses: []byte{0x68, 0x69}, reg: mpu6050WhoAmI, mask: 0x7e, value: 0x68},
+	// boards usually set the lower four address bits of the PCA9685
+	{driver: "PCA9685Driver", addresses: []byte{
+		0x40, 0x41, 0x42, 0x43, 0x44, 0x45, 0x46, 0x47,
+		0x48, 0x49, 0x4a, 0x4b, 0x4c, 0x4d, 0x4e, 0x4f,
+	}},
+	// the PCF8574A answers at 0x38 to 0x3F
+	{driver: "PCF8574Driver", addresses: []byte{
+		0x20, 0x21, 0x22, 0x23, 0x24, 0x25, 0x26, 0x27,
+		0x38, 0x39, 0x3a, 0x3b, 0x3c, 0x3d, 0x3e, 0x3f,
+	}},
+	// the part number in the ID of a TSL2561 has bit 4 set and bit 5 clear
+	{driver: "TSL2561Driver", addresses: []byte{0x29, 0x39, 0x49}, reg: tsl2561Command | tsl2561ID, mask: 0x30, value: 0x10},
+	{driver: "WiichuckDriver", addresses: []byte{0x52}},
+}
+
+// I2cProber is implemented by buses that wait for the reply of each read,
+// such as those of Firmata boards. Scan opens devices with I2cProbe rather
+// than I2cOpen, for reads that give up sooner on absent devices.
+type I2cProber interface {
+	I2cProbe(address byte) (I2cDevice, error)
+}
+
+// ScanResult is a device that answered a Scan, and the drivers it may
+// need.
+type ScanResult struct {
+	Address byte     `json:"address"`
+	Drivers []string `json:"drivers"`
+}
+
+func (r ScanResult) String() string {
+	return fmt.Sprintf("%#02x %v", r.Address, r.Drivers)
+}
+
+// Scan probes addresses 0x03 to 0x77 of bus by reading a byte from each,
+// and returns the devices that answer. Drivers are suggested for devices
+// at the address of one of the package's, whose identification register,
+// if they have one, matches. Addresses that cannot be opened, such as
+// those of devices in use by the kernel, are skipped. It returns an error
+// if none can be.
+func Scan(bus I2cBus) ([]ScanResult, error) {
+	open := bus.I2cOpen
+	if prober, ok := bus.(I2cProber); ok {
+		open = prober.I2cProbe
+	}
+	results := []ScanResult{}
+	var openErr error
+	opened := false
+	for address := byte(scanFirst); address <= scanLast; address++ {
+		device, err := open(address)
+		if err != nil {
+			openErr = err
+			continue
+		}
+		opened = true
+		if _, err := device.ReadByte(); err == nil {
+			results = append(results, ScanResult{
+				Address: address,
+				Drivers: suggestDrivers(device, address),
+			})
+		}
+		device.Close()
+	}
+	if !opened {
+		return results, openErr
+	}
+	return results, nil
+}
+
+// ScanCommand returns an API command that scans bus, for adaptors to add
+// as "I2cScan". Its result is the devices found, or the error message.
+func ScanCommand(bus I2cBus) func(map[string]interface{}) interface{} {
+	return func(params map[string]interface{}) interface{} {
+		results, err := Scan(bus)
+		if err != nil {
+			return err.Error()
+		}
+		return results
+	}
+}
+
+func suggestDrivers(device I2cDevice, address byte) []string {
+	drivers := []string{}
+	for _, known := range knownDevices {
+		if !hasAddress(known.addresses, address) {
+			continue
+		}
+		if known.mask != 0 {
+			id, err := device.ReadByteData(known.reg)
+			if err != nil || id&known.mask != known.value {
+				continue
+			}
+		}
+		drivers = append(drivers, known.driver)
+	}
+	return drivers
+}
+
+func hasAddress(addresses []byte, address byte) bool {
+	for _, a := range addresses {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package i2c
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/edmontongo/gobot"
+)
+
+// failingBus is a bus that cannot be opened, except at address when it is
+// not 0.
+type failingBus struct {
+	address byte
+}
+
+func (f failingBus) I2cOpen(address byte) (I2cDevice, error) {
+	if address != 0 && address == f.address {
+		return NewI2cDevice(&i2cTestDevice{}), nil
+	}
+	return nil, errors.New("no bus")
+}
+
+func TestScan(t *testing.T) {
+	a := newI2cTestAdaptor("adaptor")
+	a.addDevice(0x09)
+	a.addDevice(0x30)
+	results, err := Scan(a)
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, results, []ScanResult{
+		{Address: 0x09, Drivers: []string{"BlinkMDriver"}},
+		{Address: 0x30, Drivers: []string{}},
+	})
+	gobot.Assert(t, a.devices[0x09].closed, true)
+	gobot.Assert(t, results[0].String(), "0x09 [BlinkMDriver]")
+}
+
+func TestScanIdentification(t *testing.T) {
+	defer func(devices []knownDevice) { knownDevices = devices }(knownDevices)
+	knownDevices = []knownDevice{
+		{driver: "ADriver", addresses: []byte{0x40}, reg: 0x0f, mask: 0xf0, value: 0x50},
+		{driver: "BDriver", addresses: []byte{0x40}, reg: 0x0f, mask: 0xff, value: 0x33},
+		{driver: "CDriver", addresses: []byte{0x40, 0x41}},
+	}
+	a := newI2cTestAdaptor("adaptor")
+	a.addDevice(0x40).set(0x0f, 0x5a)
+	results, err := Scan(a)
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, results, []ScanResult{
+		{Address: 0x40, Drivers: []string{"ADriver", "CDriver"}},
+	})
+}
+
+func TestScanFailingBus(t *testing.T) {
+	results, err := Scan(failingBus{})
+	gobot.Assert(t, results, []ScanResult{})
+	gobot.Assert(t, err, errors.New("no bus"))
+}
+
+func TestScanBusyAddresses(t *testing.T) {
+	results, err := Scan(failingBus{address: 0x52})
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, results, []ScanResult{{Address: 0x52, Drivers: []string{"WiichuckDriver"}}})
+}
+
+// probingBus is a failingBus whose device can only be opened with
+// I2cProbe.
+type probingBus struct {
+	failingBus
+}
+
+func (p probingBus) I2cOpen(address byte) (I2cDevice, error) {
+	return nil, errors.New("no bus")
+}
+
+func (p probingBus) I2cProbe(address byte) (I2cDevice, error) {
+	return p.failingBus.I2cOpen(address)
+}
+
+func TestScanProbingBus(t *testing.T) {
+	results, err := Scan(probingBus{failingBus{address: 0x09}})
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, results, []ScanResult{{Address: 0x09, Drivers: []string{"BlinkMDriver"}}})
+}
+
+func TestScanCommand(t *testing.T) {
+	a := newI2cTestAdaptor("adaptor")
+	a.addDevice(0x52)
+	gobot.Assert(t, ScanCommand(a)(map[string]interface{}{}),
+		[]ScanResult{{Address: 0x52, Drivers: []string{"WiichuckDriver"}}})
+	gobot.Assert(t, ScanCommand(failingBus{})(map[string]interface{}{}), "no bus")
+}
+
+func TestScanMotionSensors(t *testing.T) {
+	a := newI2cTestAdaptor("adaptor")
+	a.addDevice(0x19)
+	a.addDevice(0x1E).set(0x0A, 'H', '4', '3')
+	a.addDevice(0x68).set(0x75, 0x68)
+	a.addDevice(0x69)
+	results, err := Scan(a)
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, results, []ScanResult{
+		{Address: 0x19, Drivers: []string{"LSM303Driver"}},
+		{Address: 0x1E, Drivers: []string{"HMC5883LDriver", "LSM303Driver"}},
+		{Address: 0x68, Drivers: []string{"MPU6050Driver"}},
+		{Address: 0x69, Drivers: []string{}},
+	})
+}
+
+func TestScanEnvironmentalSensors(t *testing.T) {
+	a := newI2cTestAdaptor("adaptor")
+	a.addDevice(0x39).set(0x8A, 0x50)
+	a.addDevice(0x40)
+	a.addDevice(0x60)
+	a.addDevice(0x76).set(0xD0, 0x58)
+	a.addDevice(0x77).set(0xD0, 0x55)
+	results, err := Scan(a)
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, results, []ScanResult{
+		{Address: 0x39, Drivers: []string{"PCF8574Driver", "TSL2561Driver"}},
+		{Address: 0x40, Drivers: []string{"HTU21DDriver", "PCA9685Driver"}},
+		{Address: 0x60, Drivers: []string{"MPL115A2Driver"}},
+		{Address: 0x76, Drivers: []string{"BMP280Driver"}},
+		{Address: 0x77, Drivers: []string{"BMP180Driver"}},
+	})
+}
+
+func TestScanExpanders(t *testing.T) {
+	a := newI2cTestAdaptor("adaptor")
+	a.addDevice(0x20)
+	a.addDevice(0x3f)
+	a.addDevice(0x4b)
+	results, err := Scan(a)
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, results, []ScanResult{
+		{Address: 0x20, Drivers: []string{"MCP23017Driver", "PCF8574Driver"}},
+		{Address: 0x3f, Drivers: []string{"PCF8574Driver"}},
+		{Address: 0x4b, Drivers: []string{"ADS1015Driver", "ADS1115Driver", "PCA9685Driver"}},
+	})
+}
@@ -1,18 +1,41 @@
 package i2c
 
-import "github.com/edmontongo/gobot"
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/edmontongo/gobot"
+)
 
 type i2cTestAdaptor struct {
 	gobot.Adaptor
+	mutex   sync.Mutex
+	devices map[byte]*i2cTestDevice
+}
+
+// I2cOpen returns the fake device at address, or one that fails to read
+// and write for addresses without one, as a real bus does.
+func (t *i2cTestAdaptor) I2cOpen(address byte) (I2cDevice, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	device, ok := t.devices[address]
+	if !ok {
+		device = &i2cTestDevice{absent: true}
+	}
+	return NewI2cDevice(device), nil
 }
+func (t *i2cTestAdaptor) Connect() bool  { return true }
+func (t *i2cTestAdaptor) Finalize() bool { return true }
 
-func (t *i2cTestAdaptor) I2cStart(byte) {}
-func (t *i2cTestAdaptor) I2cRead(uint) []byte {
-	return []byte{99, 1}
+// addDevice attaches a fake device at address.
+func (t *i2cTestAdaptor) addDevice(address byte) *i2cTestDevice {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	device := &i2cTestDevice{}
+	t.devices[address] = device
+	return device
 }
-func (t *i2cTestAdaptor) I2cWrite([]byte) {}
-func (t *i2cTestAdaptor) Connect() bool   { return true }
-func (t *i2cTestAdaptor) Finalize() bool  { return true }
 
 func newI2cTestAdaptor(name string) *i2cTestAdaptor {
 	return &i2cTestAdaptor{
@@ -20,5 +43,86 @@ func newI2cTestAdaptor(name string) *i2cTestAdaptor {
 			name,
 			"I2cTestAdaptor",
 		),
+		devices: make(map[byte]*i2cTestDevice),
+	}
+}
+
+// i2cTestBus is a bus with a single fake device at every address, for
+// devices unlike i2cTestDevice.
+type i2cTestBus struct {
+	device io.ReadWriteCloser
+}
+
+func (b *i2cTestBus) I2cOpen(address byte) (I2cDevice, error) {
+	return NewI2cDevice(b.device), nil
+}
+
+// i2cTestDevice is a fake device with 256 registers. Like most devices,
+// the first byte of a write selects a register, the rest are written to it
+// and the following registers, and reads start at the selected register.
+type i2cTestDevice struct {
+	mutex     sync.Mutex
+	registers [256]byte
+	register  byte
+	writes    [][]byte
+	closed    bool
+	absent    bool
+}
+
+var errNoDevice = errors.New("no device")
+
+func (d *i2cTestDevice) Read(data []byte) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.absent {
+		return 0, errNoDevice
+	}
+	for i := range data {
+		data[i] = d.registers[d.register]
+		d.register++
 	}
+	return len(data), nil
+}
+
+func (d *i2cTestDevice) Write(data []byte) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.absent {
+		return 0, errNoDevice
+	}
+	d.writes = append(d.writes, append([]byte{}, data...))
+	if len(data) > 0 {
+		d.register = data[0]
+		for _, value := range data[1:] {
+			d.registers[d.register] = value
+			d.register++
+		}
+	}
+	return len(data), nil
+}
+
+func (d *i2cTestDevice) Close() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.closed = true
+	return nil
+}
+
+// set sets registers from reg on.
+func (d *i2cTestDevice) set(reg byte, values ...byte) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for _, value := range values {
+		d.registers[reg] = value
+		reg++
+	}
+}
+
+// written returns the writes made to the device, and forgets them.
+func (d *i2cTestDevice) written() [][]byte {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	writes := d.writes
+	d.writes = nil
+	return writes
 }
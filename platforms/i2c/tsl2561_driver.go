@@ -0,0 +1,188 @@
+package i2c
+
+import (
+	"errors"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+// TSL2561 registers, which are addressed through the command register.
+const (
+	tsl2561Control byte = 0x00
+	tsl2561Timing  byte = 0x01
+	tsl2561ID      byte = 0x0A
+	tsl2561Data0   byte = 0x0C
+	tsl2561Data1   byte = 0x0E
+
+	tsl2561Command  byte = 0x80
+	tsl2561Word     byte = 0x20
+	tsl2561PowerOn  byte = 0x03
+	tsl2561PowerOff byte = 0x00
+	tsl2561Gain16   byte = 0x10
+)
+
+// errSaturated is returned when a light sensor has more light than it can
+// measure.
+var errSaturated = errors.New("i2c: light sensor saturated")
+
+// TSL2561Integration is how long the TSL2561 integrates light for each
+// reading. Longer integrations measure dimmer light.
+type TSL2561Integration byte
+
+const (
+	TSL2561Integration13ms TSL2561Integration = iota
+	TSL2561Integration101ms
+	TSL2561Integration402ms
+)
+
+var (
+	// tsl2561Scales scale readings of each integration to those of 402ms.
+	tsl2561Scales = []float64{402.0 / 13.7, 402.0 / 101, 1}
+	// tsl2561Saturations are the readings of each integration at which the
+	// TSL2561 saturates.
+	tsl2561Saturations = []uint16{5047, 37177, 65535}
+)
+
+// TSL2561Driver reads the illuminance of a TSL2561 light sensor.
+type TSL2561Driver struct {
+	gobot.Driver
+	// Address is 0x39, or 0x29 or 0x49 when the ADDR pin is low or high.
+	Address     byte
+	Integration TSL2561Integration
+	// HighGain amplifies readings 16 times, for dim light.
+	HighGain bool
+	device   I2cDevice
+	mutex    sync.Mutex
+}
+
+// NewTSL2561Driver creates a driver for a TSL2561 at address 0x39,
+// integrating for 402ms without gain, read every second.
+func NewTSL2561Driver(a I2cBus, name string) *TSL2561Driver {
+	t := &TSL2561Driver{
+		Driver: *gobot.NewDriver(
+			name,
+			"TSL2561Driver",
+			a.(gobot.AdaptorInterface),
+			time.Second,
+		),
+		Address:     0x39,
+		Integration: TSL2561Integration402ms,
+	}
+
+	t.AddEvent("illuminance")
+	t.AddEvent("error")
+
+	t.AddCommand("Illuminance", func(params map[string]interface{}) interface{} {
+		lux, err := t.Illuminance()
+		if err != nil {
+			return err.Error()
+		}
+		return lux
+	})
+
+	return t
+}
+
+func (t *TSL2561Driver) adaptor() I2cBus {
+	return t.Adaptor().(I2cBus)
+}
+
+// Start powers the TSL2561 up and sets its integration and gain, then
+// reads it every interval, publishing "illuminance" in lux, or "error".
+func (t *TSL2561Driver) Start() bool {
+	if err := t.init(); err != nil {
+		log.Println(err)
+		return false
+	}
+	gobot.Every(t.Interval(), func() {
+		lux, err := t.Illuminance()
+		if err != nil {
+			gobot.Publish(t.Event("error"), err)
+			return
+		}
+		gobot.Publish(t.Event("illuminance"), lux)
+	})
+	return true
+}
+func (t *TSL2561Driver) Init() bool { return true }
+
+// Halt powers the TSL2561 down.
+func (t *TSL2561Driver) Halt() bool {
+	if t.device != nil {
+		t.device.WriteByteData(tsl2561Command|tsl2561Control, tsl2561PowerOff)
+		t.device.Close()
+	}
+	return true
+}
+
+// Illuminance reads the latest illuminance, in lux. It returns an error
+// when the light saturates the TSL2561, which a shorter integration or no
+// gain may avoid.
+func (t *TSL2561Driver) Illuminance() (float64, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	broadband, err := t.device.ReadWordData(tsl2561Command | tsl2561Word | tsl2561Data0)
+	if err != nil {
+		return 0, err
+	}
+	infrared, err := t.device.ReadWordData(tsl2561Command | tsl2561Word | tsl2561Data1)
+	if err != nil {
+		return 0, err
+	}
+	integration := t.Integration
+	if integration > TSL2561Integration402ms {
+		integration = TSL2561Integration402ms
+	}
+	saturation := tsl2561Saturations[integration]
+	if broadband >= saturation || infrared >= saturation {
+		return 0, errSaturated
+	}
+	scale := tsl2561Scales[integration]
+	if !t.HighGain {
+		scale *= 16
+	}
+	return tsl2561Lux(float64(broadband)*scale, float64(infrared)*scale), nil
+}
+
+func (t *TSL2561Driver) init() error {
+	device, err := t.adaptor().I2cOpen(t.Address)
+	if err != nil {
+		return err
+	}
+	t.device = device
+
+	if err := t.device.WriteByteData(tsl2561Command|tsl2561Control, tsl2561PowerOn); err != nil {
+		return err
+	}
+	timing := byte(t.Integration & 0x03)
+	if t.HighGain {
+		timing |= tsl2561Gain16
+	}
+	return t.device.WriteByteData(tsl2561Command|tsl2561Timing, timing)
+}
+
+// tsl2561Lux returns the illuminance of readings of the broadband and
+// infrared channels, integrated for 402ms with 16 times gain, using the
+// approximation for the T, FN and CL packages in the datasheet.
+func tsl2561Lux(broadband float64, infrared float64) float64 {
+	if broadband == 0 {
+		return 0
+	}
+	ratio := infrared / broadband
+	var lux float64
+	switch {
+	case ratio <= 0.50:
+		lux = 0.0304*broadband - 0.062*broadband*math.Pow(ratio, 1.4)
+	case ratio <= 0.61:
+		lux = 0.0224*broadband - 0.031*infrared
+	case ratio <= 0.80:
+		lux = 0.0128*broadband - 0.0153*infrared
+	case ratio <= 1.30:
+		lux = 0.00146*broadband - 0.00112*infrared
+	}
+	return lux
+}
@@ -0,0 +1,62 @@
+package i2c
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+func initTestTSL2561Driver() (*TSL2561Driver, *i2cTestDevice) {
+	a := newI2cTestAdaptor("adaptor")
+	return NewTSL2561Driver(a, "bot"), a.addDevice(0x39)
+}
+
+func TestTSL2561Driver(t *testing.T) {
+	d, _ := initTestTSL2561Driver()
+	gobot.Assert(t, d.Address, byte(0x39))
+	gobot.Assert(t, d.Integration, TSL2561Integration402ms)
+	gobot.Refute(t, d.Command("Illuminance"), nil)
+}
+
+func TestTSL2561DriverStart(t *testing.T) {
+	d, device := initTestTSL2561Driver()
+	d.SetInterval(time.Hour)
+	d.Integration = TSL2561Integration101ms
+	d.HighGain = true
+	gobot.Assert(t, d.Start(), true)
+	gobot.Assert(t, device.written(), [][]byte{{0x80, 0x03}, {0x81, 0x11}})
+	gobot.Assert(t, d.Halt(), true)
+	gobot.Assert(t, device.written(), [][]byte{{0x80, 0x00}})
+	gobot.Assert(t, device.closed, true)
+}
+
+func TestTSL2561DriverIlluminance(t *testing.T) {
+	d, device := initTestTSL2561Driver()
+	gobot.Assert(t, d.init(), nil)
+	// broadband 1000, infrared 250, read as words through the command
+	// register
+	device.set(0xAC, 0xe8, 0x03, 0xfa, 0x00)
+	lux, err := d.Illuminance()
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, round(lux), 343.961)
+
+	d.Integration = TSL2561Integration13ms
+	d.HighGain = true
+	device.set(0xAC, 0x64, 0x00, 0x3c, 0x00)
+	lux, err = d.Illuminance()
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, round(lux), 11.15)
+
+	device.set(0xAC, 0xb7, 0x13)
+	_, err = d.Illuminance()
+	gobot.Assert(t, err, errSaturated)
+	gobot.Assert(t, d.Command("Illuminance")(map[string]interface{}{}), errSaturated.Error())
+}
+
+func TestTSL2561Lux(t *testing.T) {
+	gobot.Assert(t, tsl2561Lux(0, 0), 0.0)
+	gobot.Assert(t, round(tsl2561Lux(1000, 700)), 2.09)
+	gobot.Assert(t, round(tsl2561Lux(1000, 1000)), 0.34)
+	gobot.Assert(t, tsl2561Lux(1000, 1400), 0.0)
+}
@@ -2,17 +2,25 @@ package i2c
 
 import (
 	"fmt"
+	"log"
+	"sync"
 
 	"github.com/edmontongo/gobot"
 )
 
+// wiichuckAddress is the address of a Wii Nunchuck.
+const wiichuckAddress = 0x52
+
 type WiichuckDriver struct {
 	gobot.Driver
+	device   I2cDevice
 	joystick map[string]float64
 	data     map[string]float64
+	mutex    sync.Mutex
+	halt     chan bool
 }
 
-func NewWiichuckDriver(a I2cInterface, name string) *WiichuckDriver {
+func NewWiichuckDriver(a I2cBus, name string) *WiichuckDriver {
 	w := &WiichuckDriver{
 		Driver: *gobot.NewDriver(
 			name,
@@ -37,24 +45,56 @@ func NewWiichuckDriver(a I2cInterface, name string) *WiichuckDriver {
 	return w
 }
 
-func (w *WiichuckDriver) adaptor() I2cInterface {
-	return w.Adaptor().(I2cInterface)
+func (w *WiichuckDriver) adaptor() I2cBus {
+	return w.Adaptor().(I2cBus)
 }
 
+// Start polls the nunchuck every interval, publishing its buttons and
+// joystick.
 func (w *WiichuckDriver) Start() bool {
-	w.adaptor().I2cStart(0x52)
-	gobot.Every(w.Interval(), func() {
-		w.adaptor().I2cWrite([]byte{0x40, 0x00})
-		w.adaptor().I2cWrite([]byte{0x00})
-		newValue := w.adaptor().I2cRead(6)
-		if len(newValue) == 6 {
-			w.update(newValue)
+	device, err := w.adaptor().I2cOpen(wiichuckAddress)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	w.device = device
+	w.halt = gobot.Poll(w.Interval(), func() {
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		if value, err := w.read(); err == nil {
+			w.update(value)
 		}
 	})
 	return true
 }
 func (w *WiichuckDriver) Init() bool { return true }
-func (w *WiichuckDriver) Halt() bool { return true }
+
+// Halt stops polling the nunchuck and closes the device.
+func (w *WiichuckDriver) Halt() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.halt != nil {
+		close(w.halt)
+		w.halt = nil
+	}
+	if w.device != nil {
+		w.device.Close()
+	}
+	return true
+}
+
+// read initializes the nunchuck and reads its 6 bytes of state.
+func (w *WiichuckDriver) read() ([]byte, error) {
+	if err := w.device.WriteByteData(0x40, 0x00); err != nil {
+		return nil, err
+	}
+	if err := w.device.WriteByte(0x00); err != nil {
+		return nil, err
+	}
+	value := make([]byte, 6)
+	_, err := w.device.Read(value)
+	return value, err
+}
 
 func (w *WiichuckDriver) update(value []byte) {
 	if w.isEncrypted(value) {
@@ -1,16 +1,30 @@
 package i2c
 
 import (
-	"github.com/edmontongo/gobot"
 	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
 )
 
-func initTestWiichuckDriver() *WiichuckDriver {
-	return NewWiichuckDriver(newI2cTestAdaptor("adaptor"), "bot")
+func initTestWiichuckDriver() (*WiichuckDriver, *i2cTestDevice) {
+	a := newI2cTestAdaptor("adaptor")
+	return NewWiichuckDriver(a, "bot"), a.addDevice(0x52)
 }
 
 func TestWiichuckDriverStart(t *testing.T) {
-	t.SkipNow()
-	d := initTestWiichuckDriver()
+	d, _ := initTestWiichuckDriver()
+	d.SetInterval(time.Hour)
 	gobot.Assert(t, d.Start(), true)
+	gobot.Assert(t, d.Halt(), true)
+}
+
+func TestWiichuckDriverRead(t *testing.T) {
+	d, device := initTestWiichuckDriver()
+	d.device, _ = d.adaptor().I2cOpen(0x52)
+	device.set(0x00, 0x97, 0x98, 0x00, 0x00, 0x00, 0x17)
+	value, err := d.read()
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, value, []byte{0x97, 0x98, 0x00, 0x00, 0x00, 0x17})
+	gobot.Assert(t, device.written(), [][]byte{{0x40, 0x00}, {0x00}})
 }
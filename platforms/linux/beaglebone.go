@@ -0,0 +1,186 @@
+package linux
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The files of the Beaglebone cape manager and pinmux helpers, relative to
+// the Root of the adaptor. 3.8 kernels keep them under numbered
+// directories, and later ones under platform.
+const (
+	boneSlots       = "sys/devices/bone_capemgr.*"
+	boneOcp         = "sys/devices/ocp.*"
+	boneSlotsModern = "sys/devices/platform/bone_capemgr/slots"
+	boneOcpModern   = "sys/devices/platform/ocp"
+	boneIio         = "sys/bus/iio/devices/iio:device0"
+)
+
+// bonePwmOverlays are the device tree overlays enabling the PWM devices
+// of the Beaglebone, on kernels whose bootloader has not.
+var bonePwmOverlays = map[string]string{
+	"48300200.pwm": "BB-PWM0",
+	"48302200.pwm": "BB-PWM1",
+	"48304200.pwm": "BB-PWM2",
+}
+
+// beagleboneCape sets up the capes, overlays and pinmux of the Beaglebone
+// for its adaptor, on both 3.8 and later kernels.
+type beagleboneCape struct {
+	plainCape
+	// legacy is true for 3.8 kernels, with pwm_test devices and the
+	// cape-bone-iio helper.
+	legacy bool
+	// slots is the cape manager slots file, or "" when there is none, as
+	// on kernels that load overlays from the bootloader.
+	slots string
+}
+
+func newBeagleboneCape(l *LinuxAdaptor) cape {
+	return &beagleboneCape{plainCape: plainCape{l}}
+}
+
+// connect finds which interfaces the kernel has, and loads the capes of
+// 3.8 kernels, or the ADC overlay on later ones when the bootloader has
+// not. Later kernels load the PWM overlays as the pins are used.
+func (c *beagleboneCape) connect() error {
+	fs := c.l.fs
+	c.legacy, c.slots = false, ""
+	if _, err := glob(fs, c.l.path(boneOcp)); err == nil {
+		c.legacy = true
+	}
+	if slots, err := glob(fs, c.l.path(boneSlots)); err == nil {
+		c.slots = slots + "/slots"
+	} else if _, err := fs.Stat(c.l.path(boneSlotsModern)); err == nil {
+		c.slots = c.l.path(boneSlotsModern)
+	}
+
+	if c.legacy {
+		if err := c.loadOverlay("cape-bone-iio"); err != nil {
+			return err
+		}
+		return c.loadOverlay("am33xx_pwm")
+	}
+	if _, err := fs.Stat(c.l.path(boneIio)); err != nil && c.slots != "" {
+		return c.loadOverlay("BB-ADC")
+	}
+	return nil
+}
+
+// configure sets the mode of pin, such as "gpio" or "pwm", with the pinmux
+// helpers of the cape universal overlay, as its config-pin tool does. Pins
+// are left as they are on boards without the overlay.
+func (c *beagleboneCape) configure(pin string, mode string) error {
+	fs := c.l.fs
+	patterns := []string{
+		c.l.path(boneOcpModern, fmt.Sprintf("ocp:%v_pinmux/state", pin)),
+		c.l.path(boneOcp, fmt.Sprintf("%v_pinmux.*/state", pin)),
+	}
+	for _, pattern := range patterns {
+		if state, err := glob(fs, pattern); err == nil {
+			current, err := readFile(fs, state)
+			if err == nil && strings.TrimSpace(current) == mode {
+				return nil
+			}
+			return writeFile(fs, state, mode)
+		}
+	}
+	return nil
+}
+
+// pwmOutput starts pin with a pwm_test device on 3.8 kernels, and through
+// /sys/class/pwm on later ones, loading the overlay of its chip if need
+// be.
+func (c *beagleboneCape) pwmOutput(pin string, p PwmPin) (pwmOutput, error) {
+	if c.legacy {
+		return newBonePwmPin(c, pin)
+	}
+	if err := c.configure(pin, "pwm"); err != nil {
+		return nil, err
+	}
+	chip, err := c.l.pwmChip(p)
+	if overlay, ok := bonePwmOverlays[p.Device]; err != nil && ok && c.slots != "" {
+		if err = c.loadOverlay(overlay); err == nil {
+			err = waitFor(func() error {
+				chip, err = c.l.pwmChip(p)
+				return err
+			})
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newPwmPin(c.l.fs, chip, p.Channel)
+}
+
+// analogRead reads the cape-bone-iio helper, which gives millivolts, on
+// 3.8 kernels and the IIO device on later ones.
+func (c *beagleboneCape) analogRead(channel string) (int, float64, error) {
+	if !c.legacy {
+		return c.plainCape.analogRead(channel)
+	}
+	fs := c.l.fs
+	ocp, err := glob(fs, c.l.path(boneOcp))
+	if err != nil {
+		return -1, 0, err
+	}
+	helper, err := glob(fs, ocp+"/helper.*")
+	if err != nil {
+		return -1, 0, err
+	}
+	// in_voltageN_raw is AINN of the helper
+	ain := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(channel), "in_voltage"), "_raw")
+	millivolts, err := readInt(fs, helper+"/AIN"+ain)
+	if err != nil {
+		return -1, 0, err
+	}
+	board := c.l.Board
+	return int(float64(millivolts*board.AnalogMax)/float64(board.AnalogFullScale) + 0.5),
+		float64(millivolts), nil
+}
+
+// i2cBus returns the bus of the P9_19 and P9_20 pins, which 3.8 kernels
+// number 1.
+func (c *beagleboneCape) i2cBus() int {
+	if c.legacy {
+		return 1
+	}
+	return c.plainCape.i2cBus()
+}
+
+// loadOverlay loads the cape or device tree overlay item, unless it is
+// already loaded, when the kernel has a cape manager.
+func (c *beagleboneCape) loadOverlay(item string) error {
+	if c.slots == "" {
+		return fmt.Errorf("linux: no cape manager to load %v", item)
+	}
+	return ensureSlot(c.l.fs, c.slots, item)
+}
+
+// ensureSlot loads the cape item with the cape manager slots file, unless
+// it is already loaded.
+func ensureSlot(fs filesystem, slots string, item string) error {
+	fi, err := fs.OpenFile(slots, os.O_RDWR|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	defer fi.Close()
+
+	// ensure the slot is not already written into the capemanager
+	// (from: https://github.com/mrmorphic/hwio/blob/master/module_bb_pwm.go#L190)
+	scanner := bufio.NewScanner(fi)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Index(line, item) > 0 {
+			return nil
+		}
+	}
+
+	if _, err := fi.WriteString(item); err != nil {
+		return err
+	}
+	return fi.Sync()
+}
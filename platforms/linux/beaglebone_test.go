@@ -0,0 +1,399 @@
+package linux
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/edmontongo/gobot"
+)
+
+const (
+	testSlots       = "/sys/devices/bone_capemgr.9/slots"
+	testOcp         = "/sys/devices/ocp.3"
+	testSlotsModern = "/sys/devices/platform/bone_capemgr/slots"
+	testOcpModern   = "/sys/devices/platform/ocp"
+	testIio         = "/sys/bus/iio/devices/iio:device0"
+)
+
+// initTestLegacyBeaglebone returns a Beaglebone adaptor on a fake sysfs of
+// a 3.8 kernel, which makes the files of GPIOs when they are exported and
+// of pwm_test devices when their capes are loaded.
+func initTestLegacyBeaglebone() (*LinuxAdaptor, *fakeFilesystem) {
+	fs := newFakeFilesystem(
+		testSlots,
+		testOcp+"/helper.15/AIN1",
+		"/sys/class/gpio/export",
+		"/sys/class/gpio/unexport",
+		"/dev/i2c-1",
+	)
+	fs.onWrite = func(path string, data string) {
+		switch {
+		case path == "/sys/class/gpio/export":
+			for _, name := range []string{"direction", "value", "edge"} {
+				fs.add("/sys/class/gpio/gpio"+data+"/"+name, "")
+			}
+		case path == testSlots && strings.HasPrefix(data, "bone_pwm_"):
+			device := testOcp + "/pwm_test_" + strings.TrimPrefix(data, "bone_pwm_") + ".15"
+			for _, name := range []string{"run", "period", "duty", "polarity"} {
+				fs.add(device+"/"+name, "")
+			}
+		}
+	}
+	a := NewLinuxAdaptor("bot", Beaglebone)
+	a.fs = fs
+	return a, fs
+}
+
+// initTestModernBeaglebone returns a Beaglebone adaptor on a fake sysfs of
+// a 4.x kernel, with the cape universal pinmux helpers, PWM chip 2 and the
+// character devices of the GPIOs. Writing to export makes the files of a
+// PWM channel, and loading BB-PWM0 makes PWM chip 0.
+func initTestModernBeaglebone() (*LinuxAdaptor, *fakeFilesystem) {
+	fs := newFakeFilesystem(
+		testSlotsModern,
+		testOcpModern+"/ocp:P9_12_pinmux/state",
+		testOcpModern+"/ocp:P9_14_pinmux/state",
+		"/sys/bus/platform/devices/48302200.pwm/pwm/pwmchip2/npwm",
+		"/sys/class/pwm/pwmchip2/export",
+		"/sys/class/pwm/pwmchip2/unexport",
+		"/sys/class/pwm/pwmchip0/export",
+		testIio+"/in_voltage1_raw",
+		"/dev/gpiochip0",
+		"/dev/gpiochip1",
+		"/dev/i2c-2",
+	)
+	fs.add(testOcpModern+"/ocp:P9_12_pinmux/state", "default\n")
+	fs.add(testOcpModern+"/ocp:P9_14_pinmux/state", "default\n")
+	fs.onWrite = func(path string, data string) {
+		switch {
+		case strings.HasPrefix(path, "/sys/class/pwm/") && strings.HasSuffix(path, "/export"):
+			chip := strings.TrimSuffix(path, "/export")
+			dir := chip + "/pwm-" + strings.TrimPrefix(chip, "/sys/class/pwm/pwmchip") + ":" + data
+			for _, name := range []string{"period", "duty_cycle", "enable", "polarity"} {
+				fs.add(dir+"/"+name, "")
+			}
+		case path == testSlotsModern && data == "BB-PWM0":
+			fs.add("/sys/bus/platform/devices/48300200.pwm/pwm/pwmchip0/npwm", "2")
+		}
+	}
+	a := NewLinuxAdaptor("bot", Beaglebone)
+	a.fs = fs
+	return a, fs
+}
+
+func TestBeagleboneConnect(t *testing.T) {
+	a, fs := initTestLegacyBeaglebone()
+	gobot.Assert(t, a.Connect(), true)
+	gobot.Assert(t, a.cape, cape(&beagleboneCape{plainCape: plainCape{a}, legacy: true, slots: testSlots}))
+	gobot.Assert(t, fs.written(testSlots), "cape-bone-iioam33xx_pwm")
+
+	a, fs = initTestModernBeaglebone()
+	gobot.Assert(t, a.Connect(), true)
+	gobot.Assert(t, a.cape, cape(&beagleboneCape{plainCape: plainCape{a}, slots: testSlotsModern}))
+	// the ADC is already enabled
+	gobot.Assert(t, fs.written(testSlotsModern), "")
+
+	fs = newFakeFilesystem(testSlotsModern)
+	a.fs = fs
+	gobot.Assert(t, a.Connect(), true)
+	gobot.Assert(t, fs.written(testSlotsModern), "BB-ADC")
+
+	// overlays loaded by the bootloader
+	a.fs = newFakeFilesystem()
+	gobot.Assert(t, a.Connect(), true)
+	gobot.Assert(t, a.cape, cape(&beagleboneCape{plainCape: plainCape{a}}))
+}
+
+func TestBeagleboneConnectLoaded(t *testing.T) {
+	a, fs := initTestLegacyBeaglebone()
+	fs.add(testSlots, " 7: ff:P-O-L Override Board Name,00A0,Override Manuf,cape-bone-iio\n"+
+		" 8: ff:P-O-L Override Board Name,00A0,Override Manuf,am33xx_pwm\n")
+	gobot.Assert(t, a.Connect(), true)
+	gobot.Assert(t, fs.written(testSlots), "")
+}
+
+func TestBeagleboneConnectNoCapeManager(t *testing.T) {
+	a, _ := initTestLegacyBeaglebone()
+	a.fs = newFakeFilesystem(testOcp + "/helper.15/AIN1")
+	gobot.Assert(t, a.Connect(), false)
+}
+
+func TestBeagleboneConfigure(t *testing.T) {
+	a, fs := initTestModernBeaglebone()
+	state := testOcpModern + "/ocp:P9_14_pinmux/state"
+	gobot.Assert(t, a.cape.configure("P9_14", "pwm"), nil)
+	gobot.Assert(t, fs.written(state), "pwm")
+	// already set
+	gobot.Assert(t, a.cape.configure("P9_14", "pwm"), nil)
+	gobot.Assert(t, fs.written(state), "pwm")
+	// no pinmux helper
+	gobot.Assert(t, a.cape.configure("P8_13", "pwm"), nil)
+}
+
+func TestBeagleboneLegacyDigital(t *testing.T) {
+	a, fs := initTestLegacyBeaglebone()
+	a.Connect()
+	a.DigitalWrite("P9_12", 1)
+	gobot.Assert(t, fs.written("/sys/class/gpio/export"), "60")
+	gobot.Assert(t, fs.contents("/sys/class/gpio/gpio60/direction"), "out")
+	gobot.Assert(t, fs.contents("/sys/class/gpio/gpio60/value"), "1")
+
+	fs.add("/sys/class/gpio/gpio60/value", "1\n")
+	gobot.Assert(t, a.DigitalRead("P9_12"), 1)
+	gobot.Assert(t, fs.written("/sys/class/gpio/gpio60/direction"), "outin")
+	// exported once
+	gobot.Assert(t, fs.written("/sys/class/gpio/export"), "60")
+}
+
+func TestBeagleboneConcurrentPins(t *testing.T) {
+	a, fs := initTestLegacyBeaglebone()
+	a.Connect()
+	done := make(chan bool)
+	for _, pin := range []string{"P9_12", "P9_12", "P9_15", "P8_7"} {
+		go func(pin string) {
+			a.DigitalWrite(pin, 1)
+			done <- true
+		}(pin)
+	}
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+	gobot.Assert(t, len(fs.written("/sys/class/gpio/export")), len("604866"))
+}
+
+func TestBeaglebonePulseIn(t *testing.T) {
+	a, fs := initTestLegacyBeaglebone()
+	a.Connect()
+	a.DigitalRead("P9_12")
+	fs.add("/sys/class/gpio/gpio60/value", "0")
+	_, err := a.PulseIn("P9_12", 1, time.Millisecond)
+	gobot.Assert(t, err.Error(), "linux: timed out waiting for pulse")
+	_, err = a.PulseIn("P9_1", 1, time.Millisecond)
+	gobot.Assert(t, err.Error(), "linux: Beaglebone has no digital pin P9_1")
+}
+
+func TestBeagleboneWatchDigital(t *testing.T) {
+	a, fs := initTestLegacyBeaglebone()
+	a.Connect()
+	// epoll cannot watch the fake value file
+	gobot.Refute(t, a.WatchDigital("P9_12", "both", func(int) {}), nil)
+	gobot.Assert(t, fs.written("/sys/class/gpio/gpio60/edge"), "bothnone")
+	gobot.Assert(t, a.UnwatchDigital("P9_12"), nil)
+	gobot.Assert(t, a.UnwatchDigital("P9_1").Error(), "linux: Beaglebone has no digital pin P9_1")
+}
+
+func TestBeagleboneLegacyAnalogRead(t *testing.T) {
+	a, fs := initTestLegacyBeaglebone()
+	a.Connect()
+	// the cape-bone-iio helper reads in millivolts
+	fs.add(testOcp+"/helper.15/AIN1", "1234\n")
+	gobot.Assert(t, a.AnalogRead("P9_40"), 2807)
+	a.AnalogMillivolts = true
+	gobot.Assert(t, a.AnalogRead("P9_40"), 1234)
+	fs.add(testOcp+"/helper.15/AIN1", "")
+	gobot.Assert(t, a.AnalogRead("P9_40"), -1)
+}
+
+func TestBeagleboneAnalogRead(t *testing.T) {
+	a, fs := initTestModernBeaglebone()
+	a.Connect()
+	fs.add(testIio+"/in_voltage1_raw", "2048\n")
+	gobot.Assert(t, a.AnalogRead("P9_40"), 2048)
+	gobot.Assert(t, a.AnalogRead("P9_39"), -1)
+
+	a.AnalogMillivolts = true
+	gobot.Assert(t, a.AnalogRead("P9_40"), 900)
+}
+
+func TestBeagleboneAnalogSamples(t *testing.T) {
+	a, fs := initTestModernBeaglebone()
+	a.Connect()
+	path := testIio + "/in_voltage1_raw"
+	readings := []string{"1000\n", "1001\n", "1003\n", "1004\n"}
+	fs.add(path, readings[0])
+	// each read moves on to the next reading
+	next := 0
+	fs.onRead = func(p string) {
+		if p == path {
+			next++
+			fs.add(path, readings[next%len(readings)])
+		}
+	}
+	a.AnalogSamples = 4
+	gobot.Assert(t, a.AnalogRead("P9_40"), 1002)
+	gobot.Assert(t, next, 4)
+}
+
+func TestBeaglebonePinTables(t *testing.T) {
+	for pin, p := range Beaglebone.PwmPins {
+		_, ok := Beaglebone.DigitalPins[pin]
+		gobot.Assert(t, ok, true)
+		if _, ok := bonePwmOverlays[p.Device]; !ok {
+			gobot.Assert(t, p.Device, "48300100.ecap")
+		}
+	}
+	numbers := map[int]string{}
+	for pin, number := range Beaglebone.DigitalPins {
+		if other, ok := numbers[number]; ok {
+			t.Errorf("%v and %v are both GPIO %v", pin, other, number)
+		}
+		numbers[number] = pin
+	}
+}
+
+func TestBeagleboneUsrLeds(t *testing.T) {
+	a, fs := initTestLegacyBeaglebone()
+	led := "/sys/class/leds/beaglebone:green:usr0"
+	fs.add(led+"/trigger", "none nand-disk mmc0 timer oneshot [heartbeat] backlight gpio cpu0 default-on")
+	fs.add(led+"/brightness", "0")
+	a.Connect()
+
+	a.DigitalWrite("USR0", 1)
+	gobot.Assert(t, fs.contents(led+"/trigger"), "none")
+	gobot.Assert(t, fs.contents(led+"/brightness"), "1")
+	gobot.Assert(t, a.DigitalRead("USR0"), 1)
+	fs.add(led+"/brightness", "0\n")
+	gobot.Assert(t, a.DigitalRead("USR0"), 0)
+	// taken from its trigger once
+	gobot.Assert(t, fs.written(led+"/trigger"), "none")
+
+	gobot.Assert(t, a.Finalize(), true)
+	gobot.Assert(t, fs.contents(led+"/trigger"), "heartbeat")
+
+	// no such LED
+	gobot.Assert(t, a.DigitalRead("USR1"), -1)
+}
+
+func TestBeagleboneLegacyPwm(t *testing.T) {
+	a, fs := initTestLegacyBeaglebone()
+	a.Connect()
+	a.PwmWrite("P9_14", 255)
+	device := testOcp + "/pwm_test_P9_14.15"
+	gobot.Assert(t, fs.written(testSlots), "cape-bone-iioam33xx_pwmbone_pwm_P9_14")
+	gobot.Assert(t, fs.written(device+"/run"), "1")
+	gobot.Assert(t, fs.contents(device+"/period"), "500000")
+	// pwm_test counts the time the output is low
+	gobot.Assert(t, fs.contents(device+"/duty"), "0")
+
+	a.ServoPulseWrite("P9_14", 1500*time.Microsecond)
+	gobot.Assert(t, fs.contents(device+"/period"), "20000000")
+	gobot.Assert(t, fs.contents(device+"/duty"), "18500000")
+
+	gobot.Assert(t, a.Finalize(), true)
+	gobot.Assert(t, fs.written(device+"/run"), "10")
+}
+
+func TestBeagleboneLegacyPwmTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { fileTimeout = timeout }(fileTimeout)
+	fileTimeout = 50 * time.Millisecond
+	a, fs := initTestLegacyBeaglebone()
+	a.Connect()
+	fs.onWrite = nil
+	gobot.Refute(t, a.PwmSetDutyCycle("P9_14", 0.5), nil)
+}
+
+func TestBeaglebonePwm(t *testing.T) {
+	a, fs := initTestModernBeaglebone()
+	a.Connect()
+	dir := "/sys/class/pwm/pwmchip2/pwm-2:0"
+	gobot.Assert(t, a.PwmSetDutyCycle("P9_14", 0.25), nil)
+	gobot.Assert(t, fs.contents(testOcpModern+"/ocp:P9_14_pinmux/state"), "pwm")
+	gobot.Assert(t, fs.written("/sys/class/pwm/pwmchip2/export"), "0")
+	gobot.Assert(t, fs.contents(dir+"/period"), "500000")
+	gobot.Assert(t, fs.written(dir+"/duty_cycle"), "00125000")
+	gobot.Assert(t, fs.contents(dir+"/enable"), "1")
+
+	gobot.Assert(t, a.PwmSetPeriod("P9_14", 100*time.Microsecond), nil)
+	// the duty cycle goes first when the period gets shorter
+	gobot.Assert(t, fs.written(dir+"/duty_cycle"), "0012500025000")
+	gobot.Assert(t, fs.written(dir+"/period"), "500000100000")
+
+	gobot.Assert(t, a.Finalize(), true)
+	gobot.Assert(t, fs.contents(dir+"/enable"), "0")
+	gobot.Assert(t, fs.written("/sys/class/pwm/pwmchip2/unexport"), "0")
+}
+
+func TestBeaglebonePwmOverlay(t *testing.T) {
+	a, fs := initTestModernBeaglebone()
+	a.Connect()
+	gobot.Assert(t, a.PwmSetDutyCycle("P9_22", 0.5), nil)
+	gobot.Assert(t, fs.written(testSlotsModern), "BB-PWM0")
+	gobot.Assert(t, fs.contents("/sys/class/pwm/pwmchip0/pwm-0:0/duty_cycle"), "250000")
+
+	// no overlay for the eCAP
+	gobot.Assert(t, a.PwmSetDutyCycle("P9_42", 0.5).Error(), "linux: no PWM chip for device 48300100.ecap")
+}
+
+// fakeGpioLines fakes the ioctls of the GPIO character devices, returning
+// the lines requested and the values written to them.
+func fakeGpioLines(t *testing.T, value *uint8) (*[]gpioHandleRequest, *[]uint8, func()) {
+	oldIoctl, oldCloseFd := gpioIoctl, closeFd
+	requests := []gpioHandleRequest{}
+	written := []uint8{}
+	gpioIoctl = func(fd uintptr, request uintptr, arg unsafe.Pointer) error {
+		switch request {
+		case gpioGetLineHandle:
+			req := (*gpioHandleRequest)(arg)
+			req.fd = int32(100 + len(requests))
+			requests = append(requests, *req)
+		case gpioHandleGetLineValues:
+			(*gpioHandleData)(arg).values[0] = *value
+		case gpioHandleSetLineValues:
+			written = append(written, (*gpioHandleData)(arg).values[0])
+		default:
+			t.Errorf("unexpected ioctl %x", request)
+		}
+		return nil
+	}
+	closeFd = func(fd int) error { return nil }
+	return &requests, &written, func() { gpioIoctl, closeFd = oldIoctl, oldCloseFd }
+}
+
+func TestBeagleboneDigital(t *testing.T) {
+	value := uint8(1)
+	requests, written, restore := fakeGpioLines(t, &value)
+	defer restore()
+	a, fs := initTestModernBeaglebone()
+	a.Connect()
+
+	a.DigitalWrite("P9_12", 1)
+	gobot.Assert(t, fs.contents(testOcpModern+"/ocp:P9_12_pinmux/state"), "gpio")
+	gobot.Assert(t, len(*requests), 1)
+	// GPIO 60 is line 28 of gpiochip1
+	gobot.Assert(t, (*requests)[0].lineOffsets[0], uint32(28))
+	gobot.Assert(t, (*requests)[0].flags, uint32(gpioHandleRequestOutput))
+	gobot.Assert(t, string((*requests)[0].consumerLabel[:5]), "gobot")
+	a.DigitalWrite("P9_12", 0)
+	gobot.Assert(t, *written, []uint8{1, 0})
+
+	gobot.Assert(t, a.DigitalRead("P9_12"), 1)
+	gobot.Assert(t, len(*requests), 2)
+	gobot.Assert(t, (*requests)[1].flags, uint32(gpioHandleRequestInput))
+	value = 0
+	gobot.Assert(t, a.DigitalRead("P9_12"), 0)
+
+	gobot.Assert(t, a.Finalize(), true)
+	// nothing to unexport
+	gobot.Assert(t, fs.written("/sys/class/gpio/unexport"), "")
+}
+
+func TestBeagleboneI2c(t *testing.T) {
+	defer func(f func(uintptr, uintptr, uintptr) error) { ioctl = f }(ioctl)
+	ioctl = func(fd uintptr, request uintptr, arg uintptr) error { return nil }
+	a, fs := initTestModernBeaglebone()
+	a.Connect()
+	device, err := a.I2cOpen(0x52)
+	gobot.Assert(t, err, nil)
+	device.WriteByte(0x40)
+	gobot.Assert(t, fs.written("/dev/i2c-2"), "\x40")
+
+	// 3.8 kernels number the bus 1
+	a, fs = initTestLegacyBeaglebone()
+	a.Connect()
+	device, err = a.I2cOpen(0x52)
+	gobot.Assert(t, err, nil)
+	device.WriteByte(0x40)
+	gobot.Assert(t, fs.written("/dev/i2c-1"), "\x40")
+}
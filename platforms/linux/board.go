@@ -0,0 +1,275 @@
+package linux
+
+// Board describes the pins of a Linux board, so that one LinuxAdaptor can
+// drive any board given its Board.
+type Board struct {
+	Name string
+	// DigitalPins maps pin names to sysfs GPIO numbers.
+	DigitalPins map[string]int
+	// PwmPins maps pin names to sysfs PWM channels.
+	PwmPins map[string]PwmPin
+	// AnalogPins maps pin names to IIO channel files, relative to
+	// /sys/bus/iio/devices.
+	AnalogPins map[string]string
+	// AnalogMax is the largest reading of the analog pins, and
+	// AnalogFullScale the voltage it reads, in millivolts.
+	AnalogMax       int
+	AnalogFullScale int
+	// LedPins maps pin names to LEDs in /sys/class/leds, which are digital
+	// outputs taken from their kernel triggers while used.
+	LedPins map[string]string
+	// LinesPerChip, when set, is the number of lines of each /dev/gpiochipN,
+	// so that GPIO N is line N%LinesPerChip of chip N/LinesPerChip. Where the
+	// character devices exist they are used instead of /sys/class/gpio.
+	LinesPerChip int
+	// I2cBus is the number of the /dev/i2c-N bus used for I2C.
+	I2cBus int
+	// newCape, when set, makes what sets up the parts of the board that
+	// sysfs cannot describe.
+	newCape func(l *LinuxAdaptor) cape
+}
+
+// PwmPin is a channel of a PWM chip in /sys/class/pwm. The chip is found
+// by the name of its device when Device is set, since chip numbers can
+// change between kernels, and is pwmchip<Chip> otherwise.
+type PwmPin struct {
+	Chip    int
+	Device  string
+	Channel int
+}
+
+// Beaglebone is the Beaglebone Black. On 3.8 kernels it loads the capes
+// of the analog and PWM pins, and on later ones the device tree overlays
+// that the bootloader has not, and sets the pinmux of the cape universal
+// overlay. The USR0 to USR3 pins are the on-board LEDs.
+var Beaglebone = Board{
+	Name: "Beaglebone",
+	DigitalPins: map[string]int{
+		"P8_3":  38,
+		"P8_4":  39,
+		"P8_5":  34,
+		"P8_6":  35,
+		"P8_7":  66,
+		"P8_8":  67,
+		"P8_9":  69,
+		"P8_10": 68,
+		"P8_11": 45,
+		"P8_12": 44,
+		"P8_13": 23,
+		"P8_14": 26,
+		"P8_15": 47,
+		"P8_16": 46,
+		"P8_17": 27,
+		"P8_18": 65,
+		"P8_19": 22,
+		"P8_20": 63,
+		"P8_21": 62,
+		"P8_22": 37,
+		"P8_23": 36,
+		"P8_24": 33,
+		"P8_25": 32,
+		"P8_26": 61,
+		"P8_27": 86,
+		"P8_28": 88,
+		"P8_29": 87,
+		"P8_30": 89,
+		"P8_31": 10,
+		"P8_32": 11,
+		"P8_33": 9,
+		"P8_34": 81,
+		"P8_35": 8,
+		"P8_36": 80,
+		"P8_37": 78,
+		"P8_38": 79,
+		"P8_39": 76,
+		"P8_40": 77,
+		"P8_41": 74,
+		"P8_42": 75,
+		"P8_43": 72,
+		"P8_44": 73,
+		"P8_45": 70,
+		"P8_46": 71,
+		"P9_11": 30,
+		"P9_12": 60,
+		"P9_13": 31,
+		"P9_14": 50,
+		"P9_15": 48,
+		"P9_16": 51,
+		"P9_17": 5,
+		"P9_18": 4,
+		"P9_19": 13,
+		"P9_20": 12,
+		"P9_21": 3,
+		"P9_22": 2,
+		"P9_23": 49,
+		"P9_24": 15,
+		"P9_25": 117,
+		"P9_26": 14,
+		"P9_27": 115,
+		"P9_28": 113,
+		"P9_29": 111,
+		"P9_30": 112,
+		"P9_31": 110,
+		"P9_41": 20,
+		"P9_42": 7,
+	},
+	PwmPins: map[string]PwmPin{
+		"P9_22": {Device: "48300200.pwm", Channel: 0},
+		"P9_21": {Device: "48300200.pwm", Channel: 1},
+		"P9_29": {Device: "48300200.pwm", Channel: 1},
+		"P9_14": {Device: "48302200.pwm", Channel: 0},
+		"P9_16": {Device: "48302200.pwm", Channel: 1},
+		"P8_34": {Device: "48302200.pwm", Channel: 1},
+		"P8_19": {Device: "48304200.pwm", Channel: 0},
+		"P8_13": {Device: "48304200.pwm", Channel: 1},
+		"P8_45": {Device: "48304200.pwm", Channel: 0},
+		"P8_46": {Device: "48304200.pwm", Channel: 1},
+		"P9_42": {Device: "48300100.ecap", Channel: 0},
+	},
+	AnalogPins: map[string]string{
+		"P9_39": "iio:device0/in_voltage0_raw",
+		"P9_40": "iio:device0/in_voltage1_raw",
+		"P9_37": "iio:device0/in_voltage2_raw",
+		"P9_38": "iio:device0/in_voltage3_raw",
+		"P9_33": "iio:device0/in_voltage4_raw",
+		"P8_36": "iio:device0/in_voltage5_raw",
+		"P8_35": "iio:device0/in_voltage6_raw",
+	},
+	AnalogMax:       4095,
+	AnalogFullScale: 1800,
+	LedPins: map[string]string{
+		"USR0": "beaglebone:green:usr0",
+		"USR1": "beaglebone:green:usr1",
+		"USR2": "beaglebone:green:usr2",
+		"USR3": "beaglebone:green:usr3",
+	},
+	LinesPerChip: 32,
+	I2cBus:       2,
+	newCape:      newBeagleboneCape,
+}
+
+// RaspberryPi is a Raspberry Pi with the 40 pin header, named by the
+// numbers of the header pins. PWM needs the pwm overlay, which puts
+// channel 0 on pin 12 and channel 1 on pin 33 by default.
+var RaspberryPi = Board{
+	Name: "RaspberryPi",
+	DigitalPins: map[string]int{
+		"3":  2,
+		"5":  3,
+		"7":  4,
+		"8":  14,
+		"10": 15,
+		"11": 17,
+		"12": 18,
+		"13": 27,
+		"15": 22,
+		"16": 23,
+		"18": 24,
+		"19": 10,
+		"21": 9,
+		"22": 25,
+		"23": 11,
+		"24": 8,
+		"26": 7,
+		"29": 5,
+		"31": 6,
+		"32": 12,
+		"33": 13,
+		"35": 19,
+		"36": 16,
+		"37": 26,
+		"38": 20,
+		"40": 21,
+	},
+	PwmPins: map[string]PwmPin{
+		"12": {Chip: 0, Channel: 0},
+		"32": {Chip: 0, Channel: 0},
+		"33": {Chip: 0, Channel: 1},
+		"35": {Chip: 0, Channel: 1},
+	},
+	I2cBus: 1,
+}
+
+// Edison is an Intel Edison on the mini breakout board, with the pins
+// named by their GP numbers. PWM needs the pins set to mode 1 in debugfs.
+var Edison = Board{
+	Name: "Edison",
+	DigitalPins: map[string]int{
+		"GP12":  12,
+		"GP13":  13,
+		"GP14":  14,
+		"GP15":  15,
+		"GP19":  19,
+		"GP20":  20,
+		"GP27":  27,
+		"GP28":  28,
+		"GP40":  40,
+		"GP41":  41,
+		"GP42":  42,
+		"GP43":  43,
+		"GP44":  44,
+		"GP45":  45,
+		"GP46":  46,
+		"GP47":  47,
+		"GP48":  48,
+		"GP49":  49,
+		"GP77":  77,
+		"GP78":  78,
+		"GP79":  79,
+		"GP80":  80,
+		"GP81":  81,
+		"GP82":  82,
+		"GP83":  83,
+		"GP84":  84,
+		"GP109": 109,
+		"GP110": 110,
+		"GP111": 111,
+		"GP114": 114,
+		"GP115": 115,
+		"GP128": 128,
+		"GP129": 129,
+		"GP130": 130,
+		"GP131": 131,
+		"GP134": 134,
+		"GP135": 135,
+		"GP165": 165,
+		"GP182": 182,
+		"GP183": 183,
+	},
+	PwmPins: map[string]PwmPin{
+		"GP12":  {Chip: 0, Channel: 0},
+		"GP13":  {Chip: 0, Channel: 1},
+		"GP182": {Chip: 0, Channel: 2},
+		"GP183": {Chip: 0, Channel: 3},
+	},
+	I2cBus: 1,
+}
+
+// Chip is the Next Thing Co. C.H.I.P., running its 4.4 kernel, with the
+// pins named as on its headers. The XIO pins are on the I/O expander,
+// whose GPIO numbers start at 1013 on that kernel.
+var Chip = Board{
+	Name: "Chip",
+	DigitalPins: map[string]int{
+		"XIO-P0": 1013,
+		"XIO-P1": 1014,
+		"XIO-P2": 1015,
+		"XIO-P3": 1016,
+		"XIO-P4": 1017,
+		"XIO-P5": 1018,
+		"XIO-P6": 1019,
+		"XIO-P7": 1020,
+		"CSID0":  132,
+		"CSID1":  133,
+		"CSID2":  134,
+		"CSID3":  135,
+		"CSID4":  136,
+		"CSID5":  137,
+		"CSID6":  138,
+		"CSID7":  139,
+	},
+	PwmPins: map[string]PwmPin{
+		"PWM0": {Chip: 0, Channel: 0},
+	},
+	I2cBus: 2,
+}
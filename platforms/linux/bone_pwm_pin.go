@@ -0,0 +1,124 @@
+package linux
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// bonePwmPin is a PWM pin of a Beaglebone on a 3.8 kernel, driven by the
+// pwm_test device of its bone_pwm cape.
+type bonePwmPin struct {
+	pinNum    string
+	pwmDevice string
+	fs        filesystem
+	// period is in nanoseconds, and written the period last written
+	period   int64
+	written  int64
+	duty     float64
+	inverted bool
+}
+
+// newBonePwmPin loads the bone_pwm cape of pinNum and starts its pwm_test
+// device.
+func newBonePwmPin(c *beagleboneCape, pinNum string) (*bonePwmPin, error) {
+	fs := c.l.fs
+	d := &bonePwmPin{
+		pinNum: strings.ToUpper(pinNum),
+		period: defaultPwmPeriod,
+		fs:     fs,
+	}
+
+	if err := c.loadOverlay(fmt.Sprintf("bone_pwm_%v", d.pinNum)); err != nil {
+		return nil, err
+	}
+	ocp, err := glob(fs, c.l.path(boneOcp))
+	if err != nil {
+		return nil, err
+	}
+	// the device appears once the cape has loaded
+	pattern := fmt.Sprintf("%v/pwm_test_%v.*", ocp, d.pinNum)
+	err = waitFor(func() error {
+		d.pwmDevice, err = glob(fs, pattern)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"run", "period", "duty"} {
+		if err := waitForFile(fs, fmt.Sprintf("%v/%v", d.pwmDevice, name)); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.setEnabled(true); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// setPeriod sets the period, keeping the duty cycle.
+func (p *bonePwmPin) setPeriod(period int64) error {
+	if period <= 0 {
+		return fmt.Errorf("linux: invalid PWM period %vns", period)
+	}
+	p.period = period
+	return p.update()
+}
+
+// setDuty sets the fraction of the period, from 0 to 1, that the output is
+// high, or low when inverted.
+func (p *bonePwmPin) setDuty(duty float64) error {
+	p.duty = math.Max(0, math.Min(1, duty))
+	return p.update()
+}
+
+func (p *bonePwmPin) setPolarity(inverted bool) error {
+	p.inverted = inverted
+	return p.update()
+}
+
+func (p *bonePwmPin) setEnabled(enabled bool) error {
+	if enabled {
+		return p.writeFile("run", "1")
+	}
+	return p.writeFile("run", "0")
+}
+
+// update writes the period and duty. The pwm_test duty is the time the
+// output is low, and can never be longer than the period, so it is written
+// first when the period gets shorter.
+func (p *bonePwmPin) update() error {
+	low := p.duty
+	if !p.inverted {
+		low = 1 - low
+	}
+	duty := strconv.FormatInt(int64(low*float64(p.period)+0.5), 10)
+	period := strconv.FormatInt(p.period, 10)
+
+	if p.period < p.written {
+		if err := p.writeFile("duty", duty); err != nil {
+			return err
+		}
+		if err := p.writeFile("period", period); err != nil {
+			return err
+		}
+	} else {
+		if err := p.writeFile("period", period); err != nil {
+			return err
+		}
+		if err := p.writeFile("duty", duty); err != nil {
+			return err
+		}
+	}
+	p.written = p.period
+	return nil
+}
+
+func (p *bonePwmPin) writeFile(name string, value string) error {
+	return writeFile(p.fs, fmt.Sprintf("%v/%v", p.pwmDevice, name), value)
+}
+
+func (p *bonePwmPin) unexport() error {
+	return p.setEnabled(false)
+}
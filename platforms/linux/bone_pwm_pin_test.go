@@ -0,0 +1,54 @@
+package linux
+
+import (
+	"testing"
+
+	"github.com/edmontongo/gobot"
+)
+
+// initTestBonePwmPin returns a pin writing to pwm_test files on a fake sysfs,
+// and a function returning every value written to one of them.
+func initTestBonePwmPin(t *testing.T) (*bonePwmPin, func(string) string, func()) {
+	dir := testOcp + "/pwm_test_P9_14.15"
+	fs := newFakeFilesystem(dir+"/period", dir+"/duty", dir+"/run")
+	read := func(name string) string {
+		return fs.written(dir + "/" + name)
+	}
+	p := &bonePwmPin{pinNum: "P9_14", pwmDevice: dir, period: defaultPwmPeriod, fs: fs}
+	return p, read, func() {}
+}
+
+func TestBonePwmPinDuty(t *testing.T) {
+	p, read, cleanup := initTestBonePwmPin(t)
+	defer cleanup()
+	gobot.Assert(t, p.setDuty(0.25), nil)
+	gobot.Assert(t, read("period"), "500000")
+	// pwm_test counts the time the output is low
+	gobot.Assert(t, read("duty"), "375000")
+}
+
+func TestBonePwmPinPolarity(t *testing.T) {
+	p, read, cleanup := initTestBonePwmPin(t)
+	defer cleanup()
+	gobot.Assert(t, p.setPolarity(true), nil)
+	gobot.Assert(t, p.setDuty(0.25), nil)
+	gobot.Assert(t, read("duty"), "0125000")
+}
+
+func TestBonePwmPinPeriod(t *testing.T) {
+	p, read, cleanup := initTestBonePwmPin(t)
+	defer cleanup()
+	gobot.Assert(t, p.setDuty(0.5), nil)
+	gobot.Assert(t, p.setPeriod(1000), nil)
+	gobot.Assert(t, read("duty"), "250000500")
+	gobot.Assert(t, read("period"), "5000001000")
+	gobot.Refute(t, p.setPeriod(0), nil)
+}
+
+func TestBonePwmPinEnabled(t *testing.T) {
+	p, read, cleanup := initTestBonePwmPin(t)
+	defer cleanup()
+	gobot.Assert(t, p.setEnabled(true), nil)
+	gobot.Assert(t, p.setEnabled(false), nil)
+	gobot.Assert(t, read("run"), "10")
+}
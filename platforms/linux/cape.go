@@ -0,0 +1,50 @@
+package linux
+
+// cape sets up what the sysfs interfaces of a board cannot, such as the
+// capes, device tree overlays and pinmux of the Beaglebone. A Board makes
+// one for each adaptor with its newCape, and boards without one use
+// plainCape.
+type cape interface {
+	// connect is called by Connect, to load what the pins need.
+	connect() error
+	// configure readies pin for its first use as a "gpio" or "pwm" pin.
+	configure(pin string, mode string) error
+	// pwmOutput starts the PWM output of pin, whose channel is p.
+	pwmOutput(pin string, p PwmPin) (pwmOutput, error)
+	// analogRead returns the reading of the IIO channel file, from 0 to
+	// the AnalogMax of the board, and the same in millivolts.
+	analogRead(channel string) (int, float64, error)
+	// i2cBus returns the number of the /dev/i2c-N bus used for I2C.
+	i2cBus() int
+}
+
+// plainCape drives a board through sysfs alone.
+type plainCape struct {
+	l *LinuxAdaptor
+}
+
+func (c plainCape) connect() error { return nil }
+
+func (c plainCape) configure(pin string, mode string) error { return nil }
+
+func (c plainCape) pwmOutput(pin string, p PwmPin) (pwmOutput, error) {
+	chip, err := c.l.pwmChip(p)
+	if err != nil {
+		return nil, err
+	}
+	return newPwmPin(c.l.fs, chip, p.Channel)
+}
+
+func (c plainCape) analogRead(channel string) (int, float64, error) {
+	value, err := readInt(c.l.fs, c.l.path("sys/bus/iio/devices", channel))
+	if err != nil {
+		return -1, 0, err
+	}
+	board := c.l.Board
+	if board.AnalogMax <= 0 {
+		return value, 0, nil
+	}
+	return value, float64(value*board.AnalogFullScale) / float64(board.AnalogMax), nil
+}
+
+func (c plainCape) i2cBus() int { return c.l.Board.I2cBus }
@@ -0,0 +1,197 @@
+package linux
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/edmontongo/gobot/platforms/gpio"
+)
+
+// gpioPin is a GPIO, exported through /sys/class/gpio or requested from
+// its /dev/gpiochipN character device.
+type gpioPin interface {
+	read() (int, error)
+	write(level byte) error
+	watch(edge string, handler func(int)) error
+	unwatch() error
+	unexport() error
+}
+
+// digitalPin is a GPIO exported through /sys/class/gpio.
+type digitalPin struct {
+	fs        filesystem
+	gpioPath  string
+	number    int
+	direction string
+	value     file
+	watcher   *edgeWatcher
+}
+
+func newDigitalPin(fs filesystem, gpioPath string, number int) (*digitalPin, error) {
+	d := &digitalPin{fs: fs, gpioPath: gpioPath, number: number}
+	if err := export(fs, gpioPath+"/export", number, d.path("value")); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *digitalPin) path(file string) string {
+	return fmt.Sprintf("%v/gpio%v/%v", d.gpioPath, d.number, file)
+}
+
+// setDirection makes the pin an input, "in", or an output, "out", and
+// opens its value file to match.
+func (d *digitalPin) setDirection(direction string) error {
+	if d.direction == direction {
+		return nil
+	}
+	if err := writeFile(d.fs, d.path("direction"), direction); err != nil {
+		return err
+	}
+	d.direction = direction
+	if d.value != nil {
+		d.value.Close()
+	}
+
+	flag := os.O_RDONLY
+	if direction == "out" {
+		flag = os.O_WRONLY
+	}
+	var err error
+	if d.value, err = d.fs.OpenFile(d.path("value"), flag, 0644); err != nil {
+		d.direction = ""
+		d.value = nil
+	}
+	return err
+}
+
+func (d *digitalPin) write(level byte) error {
+	if err := d.setDirection("out"); err != nil {
+		return err
+	}
+	_, err := d.value.WriteString(strconv.Itoa(int(level)))
+	return err
+}
+
+func (d *digitalPin) read() (int, error) {
+	if err := d.setDirection("in"); err != nil {
+		return -1, err
+	}
+	buf := make([]byte, 1)
+	if _, err := d.value.ReadAt(buf, 0); err != nil {
+		return -1, err
+	}
+	return int(buf[0] - '0'), nil
+}
+
+// watch calls handler with the level of the pin, and again on each edge,
+// "rising", "falling" or "both", until unwatch is called. It waits for the
+// interrupts sysfs raises on the value file rather than polling it.
+func (d *digitalPin) watch(edge string, handler func(int)) error {
+	if err := d.setDirection("in"); err != nil {
+		return err
+	}
+	d.unwatch()
+	if err := d.writeEdge(edge); err != nil {
+		return err
+	}
+	fi, err := d.fs.OpenFile(d.path("value"), os.O_RDONLY, 0644)
+	if err != nil {
+		d.writeEdge("none")
+		return err
+	}
+	w, err := newEdgeWatcher(fi)
+	if err != nil {
+		d.writeEdge("none")
+		return err
+	}
+	d.watcher = w
+
+	go func() {
+		defer w.close()
+		// reading clears the interrupt sysfs raises on opening the file
+		if level, err := w.read(); err == nil {
+			handler(level)
+		}
+		for {
+			ok, err := w.wait()
+			if !ok || err != nil {
+				logError(err)
+				return
+			}
+			// a file that cannot be read would wake wait again at once
+			level, err := w.read()
+			if err != nil {
+				logError(err)
+				return
+			}
+			// a short pulse may be over before it is read
+			switch edge {
+			case gpio.EdgeRising:
+				level = 1
+			case gpio.EdgeFalling:
+				level = 0
+			}
+			handler(level)
+		}
+	}()
+	return nil
+}
+
+// unwatch stops the watch started by watch, if any.
+func (d *digitalPin) unwatch() error {
+	if d.watcher == nil {
+		return nil
+	}
+	d.watcher.stop()
+	d.watcher = nil
+	return d.writeEdge("none")
+}
+
+func (d *digitalPin) writeEdge(edge string) error {
+	return writeFile(d.fs, d.path("edge"), edge)
+}
+
+// unexport stops any watch and gives the pin back to the kernel.
+func (d *digitalPin) unexport() error {
+	d.unwatch()
+	if d.value != nil {
+		d.value.Close()
+	}
+	return writeFile(d.fs, d.gpioPath+"/unexport", strconv.Itoa(d.number))
+}
+
+// pulseIn polls read until it returns level, and returns how long it
+// stays there.
+func pulseIn(read func() (int, error), level int, timeout time.Duration) (time.Duration, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		value, err := read()
+		if err != nil {
+			return 0, err
+		}
+		if value == level {
+			break
+		}
+		if time.Now().After(deadline) {
+			return 0, errors.New("linux: timed out waiting for pulse")
+		}
+	}
+	began := time.Now()
+	for {
+		value, err := read()
+		if err != nil {
+			return 0, err
+		}
+		if value != level {
+			break
+		}
+		if time.Now().After(deadline) {
+			return 0, errors.New("linux: timed out waiting for end of pulse")
+		}
+	}
+	return time.Since(began), nil
+}
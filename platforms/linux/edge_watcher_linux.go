@@ -0,0 +1,110 @@
+package linux
+
+import (
+	"sync"
+	"syscall"
+)
+
+// edgeWatcher waits for the interrupts of a sysfs GPIO value file, or the
+// events of a GPIO line, with epoll, along with a pipe that wakes it up to
+// stop. It closes the file when closed.
+type edgeWatcher struct {
+	file   file
+	epfd   int
+	pipe   [2]int
+	mutex  sync.Mutex
+	closed bool
+}
+
+func newEdgeWatcher(f file) (*edgeWatcher, error) {
+	// sysfs signals an interrupt as priority data
+	return newEpollWatcher(f, syscall.EPOLLPRI|syscall.EPOLLERR)
+}
+
+// newLineEventWatcher waits for the events of a GPIO character device
+// line, which are read from f.
+func newLineEventWatcher(f file) (*edgeWatcher, error) {
+	return newEpollWatcher(f, syscall.EPOLLIN)
+}
+
+func newEpollWatcher(f file, events uint32) (*edgeWatcher, error) {
+	var err error
+	w := &edgeWatcher{file: f, epfd: -1, pipe: [2]int{-1, -1}}
+	if w.epfd, err = syscall.EpollCreate1(0); err != nil {
+		w.close()
+		return nil, err
+	}
+	if err = syscall.Pipe(w.pipe[:]); err != nil {
+		w.close()
+		return nil, err
+	}
+	fd := int(f.Fd())
+	err = syscall.EpollCtl(w.epfd, syscall.EPOLL_CTL_ADD, fd,
+		&syscall.EpollEvent{Events: events, Fd: int32(fd)})
+	if err == nil {
+		err = syscall.EpollCtl(w.epfd, syscall.EPOLL_CTL_ADD, w.pipe[0],
+			&syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(w.pipe[0])})
+	}
+	if err != nil {
+		w.close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// wait blocks until the next interrupt, and returns false once stop has
+// been called.
+func (w *edgeWatcher) wait() (bool, error) {
+	events := make([]syscall.EpollEvent, 2)
+	for {
+		n, err := syscall.EpollWait(w.epfd, events, -1)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		for _, e := range events[:n] {
+			if int(e.Fd) == w.pipe[0] {
+				return false, nil
+			}
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+}
+
+// read returns the level of the pin, which also clears the interrupt.
+func (w *edgeWatcher) read() (int, error) {
+	buf := make([]byte, 1)
+	if _, err := w.file.Seek(0, 0); err != nil {
+		return -1, err
+	}
+	if _, err := w.file.Read(buf); err != nil {
+		return -1, err
+	}
+	return int(buf[0] - '0'), nil
+}
+
+// stop makes wait return false. The watcher must then be closed, unless
+// it already is.
+func (w *edgeWatcher) stop() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if !w.closed {
+		syscall.Write(w.pipe[1], []byte{0})
+	}
+}
+
+func (w *edgeWatcher) close() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.closed = true
+	for _, fd := range []int{w.epfd, w.pipe[0], w.pipe[1]} {
+		if fd != -1 {
+			syscall.Close(fd)
+		}
+	}
+	w.file.Close()
+}
@@ -0,0 +1,156 @@
+package linux
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/edmontongo/gobot"
+)
+
+func TestEdgeWatcherStop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "edge_watcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// a fifo stands in for the value file, as epoll cannot watch a
+	// regular file
+	path := filepath.Join(dir, "value")
+	gobot.Assert(t, syscall.Mkfifo(path, 0644), nil)
+	writer, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := newEdgeWatcher(f)
+	gobot.Assert(t, err, nil)
+	done := make(chan bool, 1)
+	go func() {
+		ok, _ := w.wait()
+		done <- ok
+	}()
+	w.stop()
+	select {
+	case ok := <-done:
+		gobot.Assert(t, ok, false)
+	case <-time.After(time.Second):
+		t.Errorf("wait did not return")
+	}
+	w.close()
+	// the descriptors may already belong to other files
+	w.stop()
+}
+
+func TestBeagleboneWatchDigitalLine(t *testing.T) {
+	// the sizes of the structs are part of the ioctls
+	gobot.Assert(t, unsafe.Sizeof(gpioEventRequest{}), uintptr(0x30))
+	gobot.Assert(t, unsafe.Sizeof(gpioEventData{}), uintptr(16))
+
+	value := uint8(1)
+	_, _, restore := fakeGpioLines(t, &value)
+	defer restore()
+	events, requests := fakeGpioLineEvents(t)
+	defer syscall.Close(events)
+	a, _ := initTestModernBeaglebone()
+	a.Connect()
+
+	levels := make(chan int, 10)
+	next := func() int {
+		select {
+		case level := <-levels:
+			return level
+		case <-time.After(time.Second):
+			t.Error("no level")
+			return -1
+		}
+	}
+	gobot.Refute(t, a.WatchDigital("P9_12", "up", func(int) {}), nil)
+	gobot.Assert(t, a.WatchDigital("P9_12", "both", func(level int) { levels <- level }), nil)
+	gobot.Assert(t, len(*requests), 1)
+	gobot.Assert(t, (*requests)[0].lineOffset, uint32(28))
+	gobot.Assert(t, (*requests)[0].handleFlags, uint32(gpioHandleRequestInput))
+	gobot.Assert(t, (*requests)[0].eventFlags, uint32(gpioEventRequestRisingEdge|gpioEventRequestFallingEdge))
+	gobot.Assert(t, next(), 1)
+
+	for _, id := range []uint32{2, 1} {
+		data := gpioEventData{id: id}
+		syscall.Write(events, (*[unsafe.Sizeof(data)]byte)(unsafe.Pointer(&data))[:])
+	}
+	gobot.Assert(t, next(), 0)
+	gobot.Assert(t, next(), 1)
+	gobot.Assert(t, a.DigitalRead("P9_12"), 1)
+
+	gobot.Assert(t, a.UnwatchDigital("P9_12"), nil)
+	gobot.Assert(t, a.Finalize(), true)
+}
+
+func TestBeagleboneWatchDigitalLineHandler(t *testing.T) {
+	value := uint8(1)
+	lines, _, restore := fakeGpioLines(t, &value)
+	defer restore()
+	events, _ := fakeGpioLineEvents(t)
+	defer syscall.Close(events)
+	a, _ := initTestModernBeaglebone()
+	a.Connect()
+
+	entered := make(chan bool)
+	proceed := make(chan bool)
+	handled := make(chan int)
+	gobot.Assert(t, a.WatchDigital("P9_12", "both", func(int) {
+		entered <- true
+		<-proceed
+		handled <- a.DigitalRead("P9_12")
+	}), nil)
+	<-entered
+
+	// the line is given up while the handler waits for the adaptor
+	written := make(chan bool)
+	go func() {
+		a.DigitalWrite("P9_12", 0)
+		written <- true
+	}()
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		t.Fatal("DigitalWrite waited for the handler")
+	}
+	gobot.Assert(t, len(*lines), 1)
+	gobot.Assert(t, (*lines)[0].flags, uint32(gpioHandleRequestOutput))
+
+	close(proceed)
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Error("the handler could not use the adaptor")
+	}
+	gobot.Assert(t, a.Finalize(), true)
+}
+
+// fakeGpioLineEvents makes gpioIoctl, as faked by fakeGpioLines, give a
+// pipe for the events of the lines, returning the end to write them to.
+func fakeGpioLineEvents(t *testing.T) (int, *[]gpioEventRequest) {
+	events := [2]int{}
+	gobot.Assert(t, syscall.Pipe(events[:]), nil)
+	requests := []gpioEventRequest{}
+	handleIoctl := gpioIoctl
+	gpioIoctl = func(fd uintptr, request uintptr, arg unsafe.Pointer) error {
+		if request != gpioGetLineEvent {
+			return handleIoctl(fd, request, arg)
+		}
+		req := (*gpioEventRequest)(arg)
+		req.fd = int32(events[0])
+		requests = append(requests, *req)
+		return nil
+	}
+	return events[1], &requests
+}
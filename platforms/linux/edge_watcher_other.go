@@ -0,0 +1,27 @@
+//go:build !linux
+// +build !linux
+
+package linux
+
+import (
+	"errors"
+)
+
+// edgeWatcher needs epoll, so on other systems digital inputs are polled.
+type edgeWatcher struct {
+	file file
+}
+
+func newEdgeWatcher(f file) (*edgeWatcher, error) {
+	f.Close()
+	return nil, errors.New("linux: edge detection needs Linux")
+}
+
+func newLineEventWatcher(f file) (*edgeWatcher, error) {
+	return newEdgeWatcher(f)
+}
+
+func (w *edgeWatcher) wait() (bool, error) { return false, nil }
+func (w *edgeWatcher) read() (int, error)  { return -1, nil }
+func (w *edgeWatcher) stop()               {}
+func (w *edgeWatcher) close()              {}
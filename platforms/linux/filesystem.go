@@ -0,0 +1,109 @@
+package linux
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileTimeout is how long to wait for the files sysfs makes when a GPIO or
+// PWM channel is exported.
+var fileTimeout = time.Second
+
+// file is the part of *os.File used to read and write sysfs and devfs.
+type file interface {
+	io.ReadWriteCloser
+	io.ReaderAt
+	io.Seeker
+	WriteString(s string) (int, error)
+	Sync() error
+	Fd() uintptr
+}
+
+// filesystem is how the adaptor reaches sysfs and devfs, so that tests can
+// replace them with a fake.
+type filesystem interface {
+	OpenFile(name string, flag int, perm os.FileMode) (file, error)
+	Stat(name string) (os.FileInfo, error)
+	Glob(pattern string) ([]string, error)
+}
+
+// nativeFilesystem is the real filesystem.
+type nativeFilesystem struct{}
+
+func (nativeFilesystem) OpenFile(name string, flag int, perm os.FileMode) (file, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+func (nativeFilesystem) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (nativeFilesystem) Glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
+// writeFile writes value to the file at path.
+func writeFile(fs filesystem, path string, value string) error {
+	fi, err := fs.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	defer fi.Close()
+	_, err = fi.WriteString(value)
+	return err
+}
+
+// readFile reads up to 1024 bytes from the file at path.
+func readFile(fs filesystem, path string) (string, error) {
+	fi, err := fs.OpenFile(path, os.O_RDONLY, 0666)
+	if err != nil {
+		return "", err
+	}
+	defer fi.Close()
+	buf := make([]byte, 1024)
+	n, err := fi.Read(buf)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return string(buf[:n]), nil
+}
+
+// glob returns the first path matching pattern.
+func glob(fs filesystem, pattern string) (string, error) {
+	matches, err := fs.Glob(pattern)
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("linux: nothing matches %v", pattern)
+	}
+	return matches[0], nil
+}
+
+// waitForFile waits up to fileTimeout for path to appear.
+func waitForFile(fs filesystem, path string) error {
+	err := waitFor(func() error {
+		_, err := fs.Stat(path)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("linux: timed out waiting for %v", path)
+	}
+	return nil
+}
+
+// waitFor calls f every 10ms until it succeeds, for up to fileTimeout, and
+// returns its last error.
+func waitFor(f func() error) error {
+	deadline := time.Now().Add(fileTimeout)
+	for {
+		err := f()
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		<-time.After(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,207 @@
+package linux
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+)
+
+// fakeFilesystem is an in-memory sysfs. As in sysfs, each write replaces
+// the contents of a file. The writes are also kept, so that tests can
+// check what was written and in which order.
+type fakeFilesystem struct {
+	mutex sync.Mutex
+	files map[string]*fakeFileData
+	// onWrite plays the part of the kernel, making the files that writing
+	// to an export or cape manager slots file would.
+	onWrite func(path string, data string)
+	// onRead is called after each read, to change what the next returns.
+	onRead func(path string)
+}
+
+type fakeFileData struct {
+	contents string
+	writes   []string
+}
+
+func newFakeFilesystem(paths ...string) *fakeFilesystem {
+	fs := &fakeFilesystem{files: make(map[string]*fakeFileData)}
+	for _, path := range paths {
+		fs.add(path, "")
+	}
+	return fs
+}
+
+// add makes a file, or replaces the contents of one, as the kernel would
+// when a value changes.
+func (fs *fakeFilesystem) add(path string, contents string) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	if data, ok := fs.files[path]; ok {
+		data.contents = contents
+		return
+	}
+	fs.files[path] = &fakeFileData{contents: contents}
+}
+
+// written returns every value written to path, joined together.
+func (fs *fakeFilesystem) written(path string) string {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	if data, ok := fs.files[path]; ok {
+		return strings.Join(data.writes, "")
+	}
+	return ""
+}
+
+// contents returns the contents of path.
+func (fs *fakeFilesystem) contents(path string) string {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	if data, ok := fs.files[path]; ok {
+		return data.contents
+	}
+	return ""
+}
+
+func (fs *fakeFilesystem) OpenFile(name string, flag int, perm os.FileMode) (file, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	data, ok := fs.files[name]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return &fakeFile{fs: fs, path: name, data: data}, nil
+}
+
+func (fs *fakeFilesystem) Stat(name string) (os.FileInfo, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	for path := range fs.paths() {
+		if path == name {
+			return fakeFileInfo(filepath.Base(name)), nil
+		}
+	}
+	return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+}
+
+func (fs *fakeFilesystem) Glob(pattern string) ([]string, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	matches := []string{}
+	for path := range fs.paths() {
+		matched, err := filepath.Match(pattern, path)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			matches = append(matches, path)
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// paths returns the files and the directories holding them.
+func (fs *fakeFilesystem) paths() map[string]bool {
+	paths := make(map[string]bool)
+	for path := range fs.files {
+		for ; path != "/" && path != "."; path = filepath.Dir(path) {
+			paths[path] = true
+		}
+	}
+	return paths
+}
+
+type fakeFile struct {
+	fs     *fakeFilesystem
+	path   string
+	data   *fakeFileData
+	offset int64
+}
+
+func (f *fakeFile) Read(b []byte) (int, error) {
+	n, err := f.ReadAt(b, f.offset)
+	f.offset += int64(n)
+	f.fs.mutex.Lock()
+	onRead := f.fs.onRead
+	f.fs.mutex.Unlock()
+	if onRead != nil {
+		onRead(f.path)
+	}
+	return n, err
+}
+
+func (f *fakeFile) ReadAt(b []byte, off int64) (int, error) {
+	f.fs.mutex.Lock()
+	defer f.fs.mutex.Unlock()
+	if off >= int64(len(f.data.contents)) {
+		return 0, io.EOF
+	}
+	return copy(b, f.data.contents[off:]), nil
+}
+
+func (f *fakeFile) Seek(offset int64, whence int) (int64, error) {
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *fakeFile) Write(b []byte) (int, error) {
+	f.fs.mutex.Lock()
+	f.data.contents = string(b)
+	f.data.writes = append(f.data.writes, string(b))
+	onWrite := f.fs.onWrite
+	f.fs.mutex.Unlock()
+	if onWrite != nil {
+		onWrite(f.path, string(b))
+	}
+	return len(b), nil
+}
+
+func (f *fakeFile) WriteString(s string) (int, error) { return f.Write([]byte(s)) }
+func (f *fakeFile) Sync() error                       { return nil }
+func (f *fakeFile) Close() error                      { return nil }
+
+// Fd returns an invalid descriptor, so that epoll and ioctl fail on fake
+// files.
+func (f *fakeFile) Fd() uintptr { return ^uintptr(0) }
+
+type fakeFileInfo string
+
+func (fi fakeFileInfo) Name() string       { return string(fi) }
+func (fi fakeFileInfo) Size() int64        { return 0 }
+func (fi fakeFileInfo) Mode() os.FileMode  { return 0644 }
+func (fi fakeFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi fakeFileInfo) IsDir() bool        { return false }
+func (fi fakeFileInfo) Sys() interface{}   { return nil }
+
+func TestWaitForFile(t *testing.T) {
+	defer func(timeout time.Duration) { fileTimeout = timeout }(fileTimeout)
+	fileTimeout = 50 * time.Millisecond
+	fs := newFakeFilesystem("/sys/class/gpio/gpio60/value")
+
+	gobot.Assert(t, waitForFile(fs, "/sys/class/gpio/gpio60"), nil)
+	gobot.Refute(t, waitForFile(fs, "/sys/class/gpio/gpio61/value"), nil)
+
+	go func() {
+		<-time.After(10 * time.Millisecond)
+		fs.add("/sys/class/gpio/gpio61/value", "")
+	}()
+	gobot.Assert(t, waitForFile(fs, "/sys/class/gpio/gpio61/value"), nil)
+}
+
+func TestGlob(t *testing.T) {
+	fs := newFakeFilesystem("/sys/bus/platform/devices/48302200.pwm/pwm/pwmchip3/npwm")
+	path, err := glob(fs, "/sys/bus/platform/devices/48302200.pwm/pwm/pwmchip*")
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, path, "/sys/bus/platform/devices/48302200.pwm/pwm/pwmchip3")
+	_, err = glob(fs, "/sys/bus/platform/devices/48300200.pwm/pwm/pwmchip*")
+	gobot.Refute(t, err, nil)
+}
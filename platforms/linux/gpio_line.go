@@ -0,0 +1,243 @@
+package linux
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"github.com/edmontongo/gobot/platforms/gpio"
+)
+
+// The ioctls of the GPIO character device, from linux/gpio.h.
+const (
+	gpioGetLineHandle       = 0xc16cb403
+	gpioGetLineEvent        = 0xc030b404
+	gpioHandleGetLineValues = 0xc040b408
+	gpioHandleSetLineValues = 0xc040b409
+
+	gpioHandleRequestInput  = 1 << 0
+	gpioHandleRequestOutput = 1 << 1
+
+	gpioEventRequestRisingEdge  = 1 << 0
+	gpioEventRequestFallingEdge = 1 << 1
+	gpioEventRisingEdge         = 0x01
+)
+
+// gpioEventFlags are the event request flags of the edges of gpio.
+var gpioEventFlags = map[string]uint32{
+	gpio.EdgeRising:  gpioEventRequestRisingEdge,
+	gpio.EdgeFalling: gpioEventRequestFallingEdge,
+	gpio.EdgeBoth:    gpioEventRequestRisingEdge | gpioEventRequestFallingEdge,
+}
+
+// gpioHandleRequest is struct gpiohandle_request.
+type gpioHandleRequest struct {
+	lineOffsets   [64]uint32
+	flags         uint32
+	defaultValues [64]uint8
+	consumerLabel [32]byte
+	lines         uint32
+	fd            int32
+}
+
+// gpioEventRequest is struct gpioevent_request.
+type gpioEventRequest struct {
+	lineOffset    uint32
+	handleFlags   uint32
+	eventFlags    uint32
+	consumerLabel [32]byte
+	fd            int32
+}
+
+// gpioEventData is struct gpioevent_data.
+type gpioEventData struct {
+	timestamp uint64
+	id        uint32
+	_         uint32
+}
+
+// gpioHandleData is struct gpiohandle_data.
+type gpioHandleData struct {
+	values [64]uint8
+}
+
+// gpioIoctl makes an ioctl whose argument is a struct, and closeFd closes
+// a line handle. They are variables so that tests can fake them.
+var (
+	gpioIoctl = func(fd uintptr, request uintptr, arg unsafe.Pointer) error {
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(arg)); errno != 0 {
+			return errno
+		}
+		return nil
+	}
+	closeFd = syscall.Close
+)
+
+// gpioLine is a GPIO reached through its /dev/gpiochipN character device,
+// which unlike sysfs releases the line if the program dies.
+type gpioLine struct {
+	chip      file
+	offset    uint32
+	direction string
+	// handle is the descriptor of the requested line, or -1
+	handle int
+	// watcher waits for the events of a watched line, whose handle it
+	// owns.
+	watcher *edgeWatcher
+}
+
+// newGpioLine opens the chip at chipPath, to request its line offset as
+// it is used.
+func newGpioLine(fs filesystem, chipPath string, offset int) (*gpioLine, error) {
+	chip, err := fs.OpenFile(chipPath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &gpioLine{chip: chip, offset: uint32(offset), handle: -1}, nil
+}
+
+// setDirection requests the line again as an input, "in", or an output,
+// "out".
+func (l *gpioLine) setDirection(direction string) error {
+	if l.direction == direction {
+		return nil
+	}
+	l.unwatch()
+	l.release()
+	req := gpioHandleRequest{flags: gpioHandleRequestInput, lines: 1}
+	if direction == "out" {
+		req.flags = gpioHandleRequestOutput
+	}
+	req.lineOffsets[0] = l.offset
+	copy(req.consumerLabel[:], "gobot")
+	if err := gpioIoctl(l.chip.Fd(), gpioGetLineHandle, unsafe.Pointer(&req)); err != nil {
+		return err
+	}
+	l.handle = int(req.fd)
+	l.direction = direction
+	return nil
+}
+
+func (l *gpioLine) write(level byte) error {
+	if err := l.setDirection("out"); err != nil {
+		return err
+	}
+	data := gpioHandleData{}
+	if level != 0 {
+		data.values[0] = 1
+	}
+	return gpioIoctl(uintptr(l.handle), gpioHandleSetLineValues, unsafe.Pointer(&data))
+}
+
+func (l *gpioLine) read() (int, error) {
+	if err := l.setDirection("in"); err != nil {
+		return -1, err
+	}
+	data := gpioHandleData{}
+	if err := gpioIoctl(uintptr(l.handle), gpioHandleGetLineValues, unsafe.Pointer(&data)); err != nil {
+		return -1, err
+	}
+	return int(data.values[0]), nil
+}
+
+// watch calls handler with the level of the line, and again on each edge,
+// "rising", "falling" or "both", until unwatch is called. The line is
+// requested again for its events, which the kernel timestamps and queues
+// so that short pulses are not missed.
+func (l *gpioLine) watch(edge string, handler func(int)) error {
+	flags, ok := gpioEventFlags[edge]
+	if !ok {
+		return fmt.Errorf("linux: invalid edge %v", edge)
+	}
+	l.unwatch()
+	l.release()
+	req := gpioEventRequest{lineOffset: l.offset, handleFlags: gpioHandleRequestInput, eventFlags: flags}
+	copy(req.consumerLabel[:], "gobot")
+	if err := gpioIoctl(l.chip.Fd(), gpioGetLineEvent, unsafe.Pointer(&req)); err != nil {
+		return err
+	}
+	// the handle also reads the level of the line
+	data := gpioHandleData{}
+	levelErr := gpioIoctl(uintptr(req.fd), gpioHandleGetLineValues, unsafe.Pointer(&data))
+	w, err := newLineEventWatcher(os.NewFile(uintptr(req.fd), "gpio-line-event"))
+	if err != nil {
+		return err
+	}
+	l.handle = int(req.fd)
+	l.direction = "in"
+	l.watcher = w
+
+	go func() {
+		defer w.close()
+		if levelErr == nil {
+			handler(int(data.values[0]))
+		}
+		for {
+			ok, err := w.wait()
+			if !ok || err != nil {
+				logError(err)
+				return
+			}
+			level, err := readLineEvent(w.file)
+			if errors.Is(err, os.ErrClosed) {
+				// unwatch gave up the line
+				return
+			}
+			if err != nil {
+				logError(err)
+				return
+			}
+			handler(level)
+		}
+	}()
+	return nil
+}
+
+// unwatch stops the watch started by watch, if any, and gives up the line
+// by closing its events. It does not wait for the goroutine of the watch,
+// whose handler may be waiting for the adaptor, and a read of the events
+// in progress finishes before their descriptor is closed.
+func (l *gpioLine) unwatch() error {
+	if l.watcher == nil {
+		return nil
+	}
+	l.watcher.stop()
+	l.watcher.file.Close()
+	l.watcher = nil
+	l.handle = -1
+	l.direction = ""
+	return nil
+}
+
+// readLineEvent reads the next event of a watched line, returning the
+// level the line changed to.
+func readLineEvent(f file) (int, error) {
+	data := gpioEventData{}
+	buf := (*[unsafe.Sizeof(data)]byte)(unsafe.Pointer(&data))[:]
+	if _, err := io.ReadFull(f, buf); err != nil {
+		return -1, err
+	}
+	if data.id == gpioEventRisingEdge {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// release gives up the line, so that it can be requested again.
+func (l *gpioLine) release() {
+	if l.handle != -1 {
+		closeFd(l.handle)
+		l.handle = -1
+	}
+	l.direction = ""
+}
+
+// unexport stops any watch, gives up the line and closes the chip.
+func (l *gpioLine) unexport() error {
+	l.unwatch()
+	l.release()
+	return l.chip.Close()
+}
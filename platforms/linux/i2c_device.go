@@ -0,0 +1,85 @@
+package linux
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// i2cSlave is the ioctl that sets the address of the device that reads
+// and writes on an i2c-dev file talk to.
+const i2cSlave = 0x0703
+
+var errI2cClosed = errors.New("linux: I2C device is closed")
+
+// i2cDevice is a device on an I2C bus, through its own file of
+// /dev/i2c-N.
+type i2cDevice struct {
+	mutex sync.Mutex
+	file  file
+}
+
+// ioctl is a variable so that tests can fake it.
+var ioctl = func(fd uintptr, request uintptr, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func newI2cDevice(fs filesystem, path string, address byte) (*i2cDevice, error) {
+	f, err := fs.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioctl(f.Fd(), i2cSlave, uintptr(address)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &i2cDevice{file: f}, nil
+}
+
+func (i *i2cDevice) Write(data []byte) (int, error) {
+	f, err := i.open()
+	if err != nil {
+		return 0, err
+	}
+	return f.Write(data)
+}
+
+func (i *i2cDevice) Read(data []byte) (int, error) {
+	f, err := i.open()
+	if err != nil {
+		return 0, err
+	}
+	return f.Read(data)
+}
+
+func (i *i2cDevice) closed() bool {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return i.file == nil
+}
+
+// Close closes the file of the device, which may be closed by both its
+// driver and the adaptor.
+func (i *i2cDevice) Close() error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if i.file == nil {
+		return nil
+	}
+	err := i.file.Close()
+	i.file = nil
+	return err
+}
+
+func (i *i2cDevice) open() (file, error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if i.file == nil {
+		return nil, errI2cClosed
+	}
+	return i.file, nil
+}
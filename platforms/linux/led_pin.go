@@ -0,0 +1,60 @@
+package linux
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ledPin is an LED in /sys/class/leds, taken from the kernel trigger that
+// normally flashes it, such as the heartbeat of a Beaglebone, until
+// released.
+type ledPin struct {
+	fs      filesystem
+	path    string
+	trigger string
+}
+
+func newLedPin(fs filesystem, path string) (*ledPin, error) {
+	l := &ledPin{fs: fs, path: path}
+	triggers, err := readFile(fs, l.path+"/trigger")
+	if err != nil {
+		return nil, err
+	}
+	// the trigger in use is in brackets
+	for _, trigger := range strings.Fields(triggers) {
+		if strings.HasPrefix(trigger, "[") {
+			l.trigger = strings.Trim(trigger, "[]")
+		}
+	}
+	if err := writeFile(fs, l.path+"/trigger", "none"); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *ledPin) write(level byte) error {
+	return writeFile(l.fs, l.path+"/brightness", strconv.Itoa(int(level)))
+}
+
+func (l *ledPin) read() (int, error) {
+	value, err := readFile(l.fs, l.path+"/brightness")
+	if err != nil {
+		return -1, err
+	}
+	brightness, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return -1, err
+	}
+	if brightness > 0 {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// release gives the LED back to its trigger.
+func (l *ledPin) release() error {
+	if l.trigger == "" || l.trigger == "none" {
+		return nil
+	}
+	return writeFile(l.fs, l.path+"/trigger", l.trigger)
+}
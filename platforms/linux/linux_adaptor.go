@@ -0,0 +1,440 @@
+package linux
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/gpio"
+	"github.com/edmontongo/gobot/platforms/i2c"
+)
+
+// servoPeriod is the time between servo pulses.
+const servoPeriod = 20 * time.Millisecond
+
+// LinuxAdaptor drives the pins of a Linux board through the kernel's sysfs
+// GPIO, PWM, LED and IIO interfaces, or its GPIO character devices, and its
+// I2C bus through i2c-dev. Which pins there are comes from its Board.
+type LinuxAdaptor struct {
+	gobot.Adaptor
+	Board Board
+	// Root is prepended to every sysfs and device path, "/" by default, for
+	// boards with sysfs mounted elsewhere.
+	Root string
+	// AnalogMillivolts makes AnalogRead return millivolts, up to the
+	// AnalogFullScale of the board, rather than the raw reading.
+	AnalogMillivolts bool
+	// AnalogSamples is the number of readings AnalogRead averages, to
+	// smooth out noise. It reads once when less than 2.
+	AnalogSamples int
+	// fs reaches the files under Root, and is faked by tests.
+	fs          filesystem
+	cape        cape
+	mutex       sync.Mutex
+	digitalPins map[int]gpioPin
+	pwmPins     map[PwmPin]pwmOutput
+	ledPins     map[string]*ledPin
+	i2cDevices  []*i2cDevice
+}
+
+// NewLinuxAdaptor creates an adaptor for the pins of board.
+func NewLinuxAdaptor(name string, board Board) *LinuxAdaptor {
+	return NewBoardAdaptor(name, "LinuxAdaptor", board)
+}
+
+// NewBoardAdaptor creates an adaptor for the pins of board, whose Type is
+// adaptorType, for the packages of boards that wrap a LinuxAdaptor.
+func NewBoardAdaptor(name string, adaptorType string, board Board) *LinuxAdaptor {
+	l := &LinuxAdaptor{
+		Adaptor: *gobot.NewAdaptor(
+			name,
+			adaptorType,
+		),
+		Board:       board,
+		Root:        "/",
+		fs:          nativeFilesystem{},
+		digitalPins: make(map[int]gpioPin),
+		pwmPins:     make(map[PwmPin]pwmOutput),
+		ledPins:     make(map[string]*ledPin),
+	}
+	l.cape = plainCape{l}
+	if board.newCape != nil {
+		l.cape = board.newCape(l)
+	}
+
+	l.AddCommand("I2cScan", i2c.ScanCommand(l))
+
+	return l
+}
+
+// Connect sets up what the board needs, such as the capes or overlays of
+// the Beaglebone.
+func (l *LinuxAdaptor) Connect() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if err := l.cape.connect(); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// Finalize unexports the pins that were used, gives the LEDs back to their
+// triggers, and closes the I2C devices.
+func (l *LinuxAdaptor) Finalize() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	ok := true
+	for key, pin := range l.pwmPins {
+		if err := pin.unexport(); err != nil {
+			log.Println(err)
+			ok = false
+		}
+		delete(l.pwmPins, key)
+	}
+	for key, pin := range l.digitalPins {
+		if err := pin.unexport(); err != nil {
+			log.Println(err)
+			ok = false
+		}
+		delete(l.digitalPins, key)
+	}
+	for key, pin := range l.ledPins {
+		if err := pin.release(); err != nil {
+			log.Println(err)
+			ok = false
+		}
+		delete(l.ledPins, key)
+	}
+	for _, device := range l.i2cDevices {
+		if err := device.Close(); err != nil {
+			log.Println(err)
+			ok = false
+		}
+	}
+	l.i2cDevices = nil
+	return ok
+}
+
+func (l *LinuxAdaptor) Reconnect() bool  { return true }
+func (l *LinuxAdaptor) Disconnect() bool { return true }
+
+// DigitalRead returns the level of pin, or -1 if it cannot be read. The
+// LED pins of the board read whether the LED is lit.
+func (l *LinuxAdaptor) DigitalRead(pin string) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	var value int
+	var err error
+	if led, ok := l.Board.LedPins[pin]; ok {
+		var p *ledPin
+		if p, err = l.ledPin(pin, led); err == nil {
+			value, err = p.read()
+		}
+	} else {
+		var p gpioPin
+		if p, err = l.digitalPin(pin); err == nil {
+			value, err = p.read()
+		}
+	}
+	if err != nil {
+		log.Println(err)
+		return -1
+	}
+	return value
+}
+
+// DigitalWrite sets pin to val. The LED pins of the board are given back
+// to their usual triggers on Finalize.
+func (l *LinuxAdaptor) DigitalWrite(pin string, val byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if led, ok := l.Board.LedPins[pin]; ok {
+		p, err := l.ledPin(pin, led)
+		if err == nil {
+			err = p.write(val)
+		}
+		logError(err)
+		return
+	}
+	p, err := l.digitalPin(pin)
+	if err == nil {
+		err = p.write(val)
+	}
+	logError(err)
+}
+
+// WatchDigital calls handler with the level of pin, and again after each
+// change on edge, using the interrupts of the sysfs GPIO interface, or the
+// line events of the character device, rather than polling. The handler
+// runs on a goroutine of the watch.
+func (l *LinuxAdaptor) WatchDigital(pin string, edge string, handler func(int)) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	p, err := l.digitalPin(pin)
+	if err != nil {
+		return err
+	}
+	return p.watch(edge, handler)
+}
+
+// UnwatchDigital stops calling the handler given to WatchDigital for pin.
+func (l *LinuxAdaptor) UnwatchDigital(pin string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	number, ok := l.Board.DigitalPins[pin]
+	if !ok {
+		return fmt.Errorf("linux: %v has no digital pin %v", l.Board.Name, pin)
+	}
+	if p, ok := l.digitalPins[number]; ok {
+		return p.unwatch()
+	}
+	return nil
+}
+
+// PulseIn waits for pin to reach level and returns how long it stays
+// there. Reading sysfs limits the resolution to tens of microseconds.
+func (l *LinuxAdaptor) PulseIn(pin string, level byte, timeout time.Duration) (time.Duration, error) {
+	return pulseIn(func() (int, error) {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		p, err := l.digitalPin(pin)
+		if err != nil {
+			return -1, err
+		}
+		return p.read()
+	}, int(level), timeout)
+}
+
+// AnalogRead returns the raw reading of pin, from 0 to the AnalogMax of the
+// board, or in millivolts with AnalogMillivolts, averaged over
+// AnalogSamples readings. It returns -1 if the pin cannot be read.
+func (l *LinuxAdaptor) AnalogRead(pin string) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	channel, ok := l.Board.AnalogPins[pin]
+	if !ok {
+		log.Println(fmt.Errorf("linux: %v has no analog pin %v", l.Board.Name, pin))
+		return -1
+	}
+	if l.AnalogMillivolts && l.Board.AnalogFullScale <= 0 {
+		log.Println(fmt.Errorf("linux: %v has no analog full scale voltage", l.Board.Name))
+		return -1
+	}
+	samples := l.AnalogSamples
+	if samples < 1 {
+		samples = 1
+	}
+	total := 0.0
+	for n := 0; n < samples; n++ {
+		raw, millivolts, err := l.cape.analogRead(channel)
+		if err != nil {
+			log.Println(err)
+			return -1
+		}
+		if l.AnalogMillivolts {
+			total += millivolts
+		} else {
+			total += float64(raw)
+		}
+	}
+	return int(total/float64(samples) + 0.5)
+}
+
+func (l *LinuxAdaptor) AnalogWrite(pin string, val byte) {
+	l.PwmWrite(pin, val)
+}
+
+// PwmWrite sets the duty cycle of pin to val out of 255, keeping its
+// period.
+func (l *LinuxAdaptor) PwmWrite(pin string, val byte) {
+	logError(l.PwmSetDutyCycle(pin, gobot.FromScale(float64(val), 0, 255)))
+}
+
+// PwmSetPeriod sets the PWM period of pin, 500µs until it is set.
+func (l *LinuxAdaptor) PwmSetPeriod(pin string, period time.Duration) error {
+	return l.withPwmPin(pin, func(p pwmOutput) error {
+		return p.setPeriod(period.Nanoseconds())
+	})
+}
+
+// PwmSetDutyCycle sets the fraction of each period, from 0 to 1, that pin
+// is high, or low when its polarity is inverted.
+func (l *LinuxAdaptor) PwmSetDutyCycle(pin string, duty float64) error {
+	return l.withPwmPin(pin, func(p pwmOutput) error {
+		return p.setDuty(duty)
+	})
+}
+
+// PwmSetEnabled starts or stops PWM on pin. Pins are enabled when first
+// used.
+func (l *LinuxAdaptor) PwmSetEnabled(pin string, enabled bool) error {
+	return l.withPwmPin(pin, func(p pwmOutput) error {
+		return p.setEnabled(enabled)
+	})
+}
+
+// PwmSetPolarity inverts the output of pin when inverted is true.
+func (l *LinuxAdaptor) PwmSetPolarity(pin string, inverted bool) error {
+	return l.withPwmPin(pin, func(p pwmOutput) error {
+		return p.setPolarity(inverted)
+	})
+}
+
+func (l *LinuxAdaptor) InitServo() {}
+
+// ServoWrite moves the servo on pin to angle, from 0 to 180 degrees, using
+// the default pulse range of gpio.ServoDriver.
+func (l *LinuxAdaptor) ServoWrite(pin string, angle byte) {
+	scale := gobot.FromScale(math.Min(float64(angle), 180), 0, 180)
+	l.ServoPulseWrite(pin, gpio.DefaultServoMinPulse+
+		time.Duration(scale*float64(gpio.DefaultServoMaxPulse-gpio.DefaultServoMinPulse)))
+}
+
+// ServoPulseWrite sends pulses of the given width to the servo on pin, at
+// the usual 50Hz.
+func (l *LinuxAdaptor) ServoPulseWrite(pin string, pulse time.Duration) {
+	logError(l.withPwmPin(pin, func(p pwmOutput) error {
+		if err := p.setPeriod(servoPeriod.Nanoseconds()); err != nil {
+			return err
+		}
+		return p.setDuty(float64(pulse) / float64(servoPeriod))
+	}))
+}
+
+// ToneWrite plays a square wave of frequency hertz on the PWM pin, or
+// stops it when frequency is 0.
+func (l *LinuxAdaptor) ToneWrite(pin string, frequency float64) {
+	logError(l.withPwmPin(pin, func(p pwmOutput) error {
+		if frequency <= 0 {
+			return p.setDuty(0)
+		}
+		if err := p.setPeriod(gpio.PwmFrequency(frequency).Nanoseconds()); err != nil {
+			return err
+		}
+		return p.setDuty(0.5)
+	}))
+}
+
+// I2cOpen opens the device at address on the I2C bus of the board, with
+// a file of its own.
+func (l *LinuxAdaptor) I2cOpen(address byte) (i2c.I2cDevice, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	device, err := newI2cDevice(l.fs, l.path(fmt.Sprintf("dev/i2c-%v", l.cape.i2cBus())), address)
+	if err != nil {
+		return nil, err
+	}
+	// forget the devices closed by their drivers, or by scans
+	open := l.i2cDevices[:0]
+	for _, d := range l.i2cDevices {
+		if !d.closed() {
+			open = append(open, d)
+		}
+	}
+	l.i2cDevices = append(open, device)
+	return i2c.NewI2cDevice(device), nil
+}
+
+// path joins elements to the sysfs root.
+func (l *LinuxAdaptor) path(elements ...string) string {
+	return filepath.Join(append([]string{l.Root}, elements...)...)
+}
+
+// digitalPin sets up pin the first time it is used, through its character
+// device when the board has them and sysfs otherwise.
+func (l *LinuxAdaptor) digitalPin(pin string) (gpioPin, error) {
+	number, ok := l.Board.DigitalPins[pin]
+	if !ok {
+		return nil, fmt.Errorf("linux: %v has no digital pin %v", l.Board.Name, pin)
+	}
+	if p, ok := l.digitalPins[number]; ok {
+		return p, nil
+	}
+	if err := l.cape.configure(pin, "gpio"); err != nil {
+		return nil, err
+	}
+	var p gpioPin
+	var err error
+	if chip, ok := l.gpioChip(number); ok {
+		p, err = newGpioLine(l.fs, chip, number%l.Board.LinesPerChip)
+	} else {
+		p, err = newDigitalPin(l.fs, l.path("sys/class/gpio"), number)
+	}
+	if err != nil {
+		return nil, err
+	}
+	l.digitalPins[number] = p
+	return p, nil
+}
+
+// gpioChip returns the path of the character device of GPIO number, and
+// whether there is one.
+func (l *LinuxAdaptor) gpioChip(number int) (string, bool) {
+	if l.Board.LinesPerChip <= 0 {
+		return "", false
+	}
+	path := l.path("dev", "gpiochip"+strconv.Itoa(number/l.Board.LinesPerChip))
+	if _, err := l.fs.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// ledPin takes the LED of pin from its trigger the first time it is used.
+func (l *LinuxAdaptor) ledPin(pin string, led string) (*ledPin, error) {
+	if p, ok := l.ledPins[pin]; ok {
+		return p, nil
+	}
+	p, err := newLedPin(l.fs, l.path("sys/class/leds", led))
+	if err != nil {
+		return nil, err
+	}
+	l.ledPins[pin] = p
+	return p, nil
+}
+
+// withPwmPin calls f with the PWM output of pin, starting it the first
+// time it is used.
+func (l *LinuxAdaptor) withPwmPin(pin string, f func(pwmOutput) error) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	key, ok := l.Board.PwmPins[pin]
+	if !ok {
+		return fmt.Errorf("linux: %v has no PWM pin %v", l.Board.Name, pin)
+	}
+	p, ok := l.pwmPins[key]
+	if !ok {
+		var err error
+		if p, err = l.cape.pwmOutput(pin, key); err != nil {
+			return err
+		}
+		l.pwmPins[key] = p
+	}
+	return f(p)
+}
+
+// pwmChip returns the path of the chip of a PWM channel, finding it in
+// the directory of its device when it has one.
+func (l *LinuxAdaptor) pwmChip(pin PwmPin) (string, error) {
+	if pin.Device == "" {
+		return l.path("sys/class/pwm", "pwmchip"+strconv.Itoa(pin.Chip)), nil
+	}
+	chip, err := glob(l.fs, l.path("sys/bus/platform/devices", pin.Device, "pwm/pwmchip*"))
+	if err != nil {
+		return "", fmt.Errorf("linux: no PWM chip for device %v", pin.Device)
+	}
+	return l.path("sys/class/pwm", filepath.Base(chip)), nil
+}
+
+// logError logs errors from the methods that cannot return them.
+func logError(err error) {
+	if err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,249 @@
+package linux
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/edmontongo/gobot"
+	"github.com/edmontongo/gobot/platforms/i2c"
+)
+
+var testBoard = Board{
+	Name: "Test",
+	DigitalPins: map[string]int{
+		"led":    60,
+		"button": 61,
+		"new":    62,
+	},
+	PwmPins: map[string]PwmPin{
+		"motor": {Chip: 0, Channel: 0},
+		"servo": {Device: "48302200.pwm", Channel: 1},
+	},
+	AnalogPins: map[string]string{
+		"pot": "iio:device0/in_voltage0_raw",
+	},
+	AnalogMax: 4095,
+	I2cBus:    1,
+}
+
+// initTestLinuxAdaptor returns an adaptor for testBoard on a fake sysfs,
+// with GPIOs 60 and 61 and PWM channel 0 of pwmchip0 already exported.
+// Writing to an export file makes the files of the GPIO or PWM channel.
+func initTestLinuxAdaptor() (*LinuxAdaptor, *fakeFilesystem) {
+	fs := newFakeFilesystem(
+		"/sys/class/gpio/export",
+		"/sys/class/gpio/unexport",
+		"/sys/class/pwm/pwmchip0/export",
+		"/sys/class/pwm/pwmchip0/unexport",
+		"/sys/class/pwm/pwmchip3/export",
+		"/sys/class/pwm/pwmchip3/unexport",
+		"/sys/bus/platform/devices/48300200.pwm/pwm/pwmchip0/npwm",
+		"/sys/bus/platform/devices/48302200.pwm/pwm/pwmchip3/npwm",
+		"/dev/i2c-1",
+	)
+	fs.add("/sys/class/gpio/gpio60/direction", "in")
+	fs.add("/sys/class/gpio/gpio60/value", "0")
+	fs.add("/sys/class/gpio/gpio61/direction", "in")
+	fs.add("/sys/class/gpio/gpio61/value", "1")
+	for _, name := range []string{"period", "duty_cycle", "enable", "polarity"} {
+		fs.add("/sys/class/pwm/pwmchip0/pwm0/"+name, "")
+	}
+	fs.add("/sys/bus/iio/devices/iio:device0/in_voltage0_raw", "2048\n")
+	fs.onWrite = func(path string, data string) {
+		switch {
+		case path == "/sys/class/gpio/export":
+			for _, name := range []string{"direction", "value"} {
+				fs.add("/sys/class/gpio/gpio"+data+"/"+name, "")
+			}
+		case strings.HasPrefix(path, "/sys/class/pwm/") && strings.HasSuffix(path, "/export"):
+			dir := strings.TrimSuffix(path, "export") + "pwm" + data
+			for _, name := range []string{"period", "duty_cycle", "enable", "polarity"} {
+				fs.add(dir+"/"+name, "")
+			}
+		}
+	}
+	a := NewLinuxAdaptor("bot", testBoard)
+	a.fs = fs
+	return a, fs
+}
+
+func TestLinuxAdaptorConnect(t *testing.T) {
+	a := NewLinuxAdaptor("bot", Beaglebone)
+	gobot.Assert(t, a.Connect(), true)
+	gobot.Assert(t, a.Root, "/")
+	gobot.Assert(t, a.Board.Name, "Beaglebone")
+}
+
+func TestLinuxAdaptorDigitalWrite(t *testing.T) {
+	a, fs := initTestLinuxAdaptor()
+	a.DigitalWrite("led", 1)
+	gobot.Assert(t, fs.contents("/sys/class/gpio/gpio60/direction"), "out")
+	gobot.Assert(t, fs.contents("/sys/class/gpio/gpio60/value"), "1")
+	// already exported
+	gobot.Assert(t, fs.written("/sys/class/gpio/export"), "")
+
+	a.DigitalWrite("led", 0)
+	gobot.Assert(t, fs.written("/sys/class/gpio/gpio60/value"), "10")
+	// the direction is written once
+	gobot.Assert(t, fs.written("/sys/class/gpio/gpio60/direction"), "out")
+}
+
+func TestLinuxAdaptorDigitalRead(t *testing.T) {
+	a, fs := initTestLinuxAdaptor()
+	gobot.Assert(t, a.DigitalRead("button"), 1)
+	gobot.Assert(t, fs.contents("/sys/class/gpio/gpio61/direction"), "in")
+
+	a.DigitalWrite("led", 1)
+	gobot.Assert(t, a.DigitalRead("led"), 1)
+	gobot.Assert(t, fs.written("/sys/class/gpio/gpio60/direction"), "outin")
+}
+
+func TestLinuxAdaptorUnknownPins(t *testing.T) {
+	a, fs := initTestLinuxAdaptor()
+	gobot.Assert(t, a.DigitalRead("P9_99"), -1)
+	a.DigitalWrite("P9_99", 1)
+	gobot.Assert(t, fs.written("/sys/class/gpio/export"), "")
+	gobot.Assert(t, a.AnalogRead("led"), -1)
+	gobot.Refute(t, a.PwmSetDutyCycle("led", 0.5), nil)
+}
+
+func TestLinuxAdaptorExport(t *testing.T) {
+	a, fs := initTestLinuxAdaptor()
+	a.DigitalWrite("new", 1)
+	gobot.Assert(t, fs.written("/sys/class/gpio/export"), "62")
+	gobot.Assert(t, fs.contents("/sys/class/gpio/gpio62/direction"), "out")
+	gobot.Assert(t, fs.contents("/sys/class/gpio/gpio62/value"), "1")
+}
+
+func TestLinuxAdaptorExportTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { fileTimeout = timeout }(fileTimeout)
+	fileTimeout = 50 * time.Millisecond
+	a, fs := initTestLinuxAdaptor()
+	fs.onWrite = nil
+	gobot.Assert(t, a.DigitalRead("new"), -1)
+	gobot.Assert(t, fs.written("/sys/class/gpio/export"), "62")
+	gobot.Refute(t, a.PwmSetDutyCycle("servo", 0.5), nil)
+	gobot.Assert(t, fs.written("/sys/class/pwm/pwmchip3/export"), "1")
+}
+
+func TestLinuxAdaptorFinalize(t *testing.T) {
+	a, fs := initTestLinuxAdaptor()
+	gobot.Assert(t, a.Finalize(), true)
+	gobot.Assert(t, fs.written("/sys/class/gpio/unexport"), "")
+
+	a.DigitalWrite("led", 1)
+	a.PwmWrite("motor", 255)
+	gobot.Assert(t, a.Finalize(), true)
+	gobot.Assert(t, fs.written("/sys/class/gpio/unexport"), "60")
+	gobot.Assert(t, fs.written("/sys/class/pwm/pwmchip0/unexport"), "0")
+	gobot.Assert(t, fs.contents("/sys/class/pwm/pwmchip0/pwm0/enable"), "0")
+}
+
+func TestLinuxAdaptorAnalogRead(t *testing.T) {
+	a, fs := initTestLinuxAdaptor()
+	gobot.Assert(t, a.AnalogRead("pot"), 2048)
+	fs.add("/sys/bus/iio/devices/iio:device0/in_voltage0_raw", "")
+	gobot.Assert(t, a.AnalogRead("pot"), -1)
+}
+
+func TestLinuxAdaptorPwm(t *testing.T) {
+	a, fs := initTestLinuxAdaptor()
+	dir := "/sys/class/pwm/pwmchip0/pwm0"
+	a.PwmWrite("motor", 51)
+	// already exported
+	gobot.Assert(t, fs.written("/sys/class/pwm/pwmchip0/export"), "")
+	gobot.Assert(t, fs.contents(dir+"/period"), "500000")
+	gobot.Assert(t, fs.contents(dir+"/duty_cycle"), "100000")
+	gobot.Assert(t, fs.contents(dir+"/enable"), "1")
+
+	gobot.Assert(t, a.PwmSetPeriod("motor", 100*time.Microsecond), nil)
+	// the duty cycle goes first when the period gets shorter
+	gobot.Assert(t, fs.written(dir+"/duty_cycle"), "0010000020000")
+	gobot.Assert(t, fs.written(dir+"/period"), "500000100000")
+
+	gobot.Assert(t, a.PwmSetPolarity("motor", true), nil)
+	gobot.Assert(t, fs.contents(dir+"/polarity"), "inversed")
+	gobot.Assert(t, fs.written(dir+"/enable"), "101")
+
+	gobot.Assert(t, a.PwmSetEnabled("motor", false), nil)
+	gobot.Assert(t, fs.contents(dir+"/enable"), "0")
+	gobot.Refute(t, a.PwmSetPeriod("motor", 0), nil)
+}
+
+func TestLinuxAdaptorPwmDevice(t *testing.T) {
+	a, fs := initTestLinuxAdaptor()
+	dir := "/sys/class/pwm/pwmchip3/pwm1"
+	a.ServoPulseWrite("servo", 1500*time.Microsecond)
+	gobot.Assert(t, fs.written("/sys/class/pwm/pwmchip3/export"), "1")
+	gobot.Assert(t, fs.contents(dir+"/period"), "20000000")
+	gobot.Assert(t, fs.contents(dir+"/duty_cycle"), "1500000")
+
+	a.ServoWrite("servo", 180)
+	gobot.Assert(t, fs.contents(dir+"/duty_cycle"), "2400000")
+
+	a.Board.PwmPins["missing"] = PwmPin{Device: "48304200.pwm"}
+	defer delete(a.Board.PwmPins, "missing")
+	gobot.Assert(t, a.PwmSetDutyCycle("missing", 0.5).Error(), "linux: no PWM chip for device 48304200.pwm")
+}
+
+func TestLinuxAdaptorToneWrite(t *testing.T) {
+	a, fs := initTestLinuxAdaptor()
+	a.ToneWrite("motor", 1000)
+	gobot.Assert(t, fs.contents("/sys/class/pwm/pwmchip0/pwm0/period"), "1000000")
+	gobot.Assert(t, fs.contents("/sys/class/pwm/pwmchip0/pwm0/duty_cycle"), "500000")
+	a.ToneWrite("motor", 0)
+	gobot.Assert(t, fs.contents("/sys/class/pwm/pwmchip0/pwm0/duty_cycle"), "0")
+}
+
+func TestLinuxAdaptorI2c(t *testing.T) {
+	a, fs := initTestLinuxAdaptor()
+	defer func(f func(uintptr, uintptr, uintptr) error) { ioctl = f }(ioctl)
+	requests := map[uintptr]uintptr{}
+	ioctl = func(fd uintptr, request uintptr, arg uintptr) error {
+		requests[request] = arg
+		return nil
+	}
+
+	device, err := a.I2cOpen(0x1e)
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, requests[i2cSlave], uintptr(0x1e))
+	gobot.Assert(t, device.WriteByteData(0x01, 0x02), nil)
+	gobot.Assert(t, fs.written("/dev/i2c-1"), "\x01\x02")
+
+	other, _ := a.I2cOpen(0x1e)
+	value, err := other.ReadByte()
+	gobot.Assert(t, err, nil)
+	gobot.Assert(t, value, byte(0x01))
+
+	gobot.Assert(t, device.Close(), nil)
+	gobot.Assert(t, a.Finalize(), true)
+	_, err = other.ReadByte()
+	gobot.Assert(t, err, errI2cClosed)
+}
+
+func TestLinuxAdaptorI2cMissingBus(t *testing.T) {
+	a, _ := initTestLinuxAdaptor()
+	a.Board.I2cBus = 9
+	_, err := a.I2cOpen(0x1e)
+	gobot.Refute(t, err, nil)
+}
+
+func TestLinuxAdaptorI2cScan(t *testing.T) {
+	a, fs := initTestLinuxAdaptor()
+	defer func(f func(uintptr, uintptr, uintptr) error) { ioctl = f }(ioctl)
+	ioctl = func(fd uintptr, request uintptr, arg uintptr) error {
+		if arg == 0x09 {
+			return nil
+		}
+		return syscall.EBUSY
+	}
+	fs.add("/dev/i2c-1", "\x01")
+	gobot.Assert(t, a.Command("I2cScan")(map[string]interface{}{}), []i2c.ScanResult{
+		{Address: 0x09, Drivers: []string{"BlinkMDriver"}},
+	})
+
+	a.Board.I2cBus = 9
+	gobot.Refute(t, a.Command("I2cScan")(map[string]interface{}{}), nil)
+}
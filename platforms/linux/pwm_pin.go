@@ -0,0 +1,166 @@
+package linux
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// defaultPwmPeriod is the period of a PWM channel, in nanoseconds, until
+// one is set.
+const defaultPwmPeriod = 500000
+
+// pwmOutput is a PWM output, a channel of /sys/class/pwm or, on some
+// boards, a device of their own.
+type pwmOutput interface {
+	setPeriod(period int64) error
+	setDuty(duty float64) error
+	setPolarity(inverted bool) error
+	setEnabled(enabled bool) error
+	unexport() error
+}
+
+// pwmPin is a PWM channel exported through /sys/class/pwm.
+type pwmPin struct {
+	fs       filesystem
+	chipPath string
+	channel  int
+	// dir is the directory of the exported channel, pwmN, or pwm-C:N on
+	// some kernels
+	dir string
+	// period is in nanoseconds, and duty the fraction of it that the
+	// output is active
+	period   int64
+	duty     float64
+	inverted bool
+	enabled  bool
+}
+
+// newPwmPin exports channel of the chip, with the default period and a
+// duty cycle of 0, and enables it.
+func newPwmPin(fs filesystem, chipPath string, channel int) (*pwmPin, error) {
+	p := &pwmPin{fs: fs, chipPath: chipPath, channel: channel}
+	var err error
+	if p.dir, err = p.findChannel(); err != nil {
+		err = writeFile(fs, chipPath+"/export", strconv.Itoa(channel))
+		if err != nil && !isBusy(err) {
+			return nil, err
+		}
+		err = waitFor(func() error {
+			p.dir, err = p.findChannel()
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	// the duty cycle may be left over from a longer period
+	if err := writeFile(p.fs, p.path("duty_cycle"), "0"); err != nil {
+		return nil, err
+	}
+	if err := p.setPeriod(defaultPwmPeriod); err != nil {
+		return nil, err
+	}
+	if err := p.setEnabled(true); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// findChannel returns the directory of the channel, once exported.
+func (p *pwmPin) findChannel() (string, error) {
+	chip := strings.TrimPrefix(filepath.Base(p.chipPath), "pwmchip")
+	for _, name := range []string{
+		fmt.Sprintf("pwm%v", p.channel),
+		fmt.Sprintf("pwm-%v:%v", chip, p.channel),
+	} {
+		dir := p.chipPath + "/" + name
+		if _, err := p.fs.Stat(dir + "/period"); err == nil {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("linux: channel %v of %v is not exported", p.channel, p.chipPath)
+}
+
+func (p *pwmPin) path(file string) string {
+	return p.dir + "/" + file
+}
+
+// setPeriod sets the period in nanoseconds, keeping the duty cycle. The
+// kernel refuses a duty cycle longer than the period, so it is written
+// first when the period gets shorter.
+func (p *pwmPin) setPeriod(period int64) error {
+	if period <= 0 {
+		return fmt.Errorf("linux: invalid PWM period %vns", period)
+	}
+	shorter := period < p.period
+	p.period = period
+	if shorter {
+		if err := p.writeDuty(); err != nil {
+			return err
+		}
+	}
+	if err := writeFile(p.fs, p.path("period"), strconv.FormatInt(period, 10)); err != nil {
+		return err
+	}
+	if !shorter {
+		return p.writeDuty()
+	}
+	return nil
+}
+
+// setDuty sets the fraction of the period, from 0 to 1, that the output is
+// active.
+func (p *pwmPin) setDuty(duty float64) error {
+	p.duty = math.Max(0, math.Min(1, duty))
+	return p.writeDuty()
+}
+
+func (p *pwmPin) writeDuty() error {
+	duty := int64(p.duty*float64(p.period) + 0.5)
+	return writeFile(p.fs, p.path("duty_cycle"), strconv.FormatInt(duty, 10))
+}
+
+func (p *pwmPin) setEnabled(enabled bool) error {
+	value := "0"
+	if enabled {
+		value = "1"
+	}
+	if err := writeFile(p.fs, p.path("enable"), value); err != nil {
+		return err
+	}
+	p.enabled = enabled
+	return nil
+}
+
+// setPolarity inverts the output when inverted is true. The kernel only
+// changes the polarity of a disabled channel.
+func (p *pwmPin) setPolarity(inverted bool) error {
+	polarity := "normal"
+	if inverted {
+		polarity = "inversed"
+	}
+	enabled := p.enabled
+	if enabled {
+		if err := p.setEnabled(false); err != nil {
+			return err
+		}
+	}
+	if err := writeFile(p.fs, p.path("polarity"), polarity); err != nil {
+		return err
+	}
+	p.inverted = inverted
+	if enabled {
+		return p.setEnabled(true)
+	}
+	return nil
+}
+
+func (p *pwmPin) unexport() error {
+	if err := p.setEnabled(false); err != nil {
+		return err
+	}
+	return writeFile(p.fs, p.chipPath+"/unexport", strconv.Itoa(p.channel))
+}
@@ -0,0 +1,39 @@
+package linux
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// readInt reads an integer from a sysfs file.
+func readInt(fs filesystem, path string) (int, error) {
+	data, err := readFile(fs, path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(data))
+}
+
+// export writes n to the export file, unless it is already exported, and
+// waits up to fileTimeout for the file it creates to appear.
+func export(fs filesystem, exportPath string, n int, created string) error {
+	if _, err := fs.Stat(created); err == nil {
+		return nil
+	}
+	err := writeFile(fs, exportPath, strconv.Itoa(n))
+	if err != nil && !isBusy(err) {
+		return err
+	}
+	return waitForFile(fs, created)
+}
+
+// isBusy returns true for the error sysfs gives when exporting something
+// that is already exported.
+func isBusy(err error) bool {
+	if e, ok := err.(*os.PathError); ok {
+		return e.Err == syscall.EBUSY
+	}
+	return false
+}
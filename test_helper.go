@@ -98,13 +98,20 @@ func (t *testAdaptor) Finalize() bool { return true }
 func (t *testAdaptor) Connect() bool  { return true }
 
 func NewTestAdaptor(name string) *testAdaptor {
-	return &testAdaptor{
+	t := &testAdaptor{
 		Adaptor: *NewAdaptor(
 			name,
 			"TestAdaptor",
 			"/dev/null",
 		),
 	}
+
+	t.AddCommand("TestAdaptorCommand", func(params map[string]interface{}) interface{} {
+		name := params["name"].(string)
+		return fmt.Sprintf("hi %v", name)
+	})
+
+	return t
 }
 
 func NewTestRobot(name string) *Robot {
@@ -22,6 +22,34 @@ func Every(t time.Duration, f func()) {
 	}()
 }
 
+// Poll calls f every `t` time like Every, but one call at a time, until
+// the returned channel is closed. Only one tick is kept while f is still
+// running, so a call that runs late is followed straight away by one more,
+// and polling an adaptor slower than `t` does not pile up calls. A call in
+// progress when the channel is closed finishes, but f is not called again.
+func Poll(t time.Duration, f func()) chan bool {
+	halt := make(chan bool)
+	go func() {
+		ticker := time.NewTicker(t)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-halt:
+				return
+			case <-ticker.C:
+			}
+			// a kept tick may be ready along with the close
+			select {
+			case <-halt:
+				return
+			default:
+				f()
+			}
+		}
+	}()
+	return halt
+}
+
 // After triggers the passed function after `t` duration.
 func After(t time.Duration, f func()) {
 	time.AfterFunc(t, f)
@@ -32,11 +60,11 @@ func Publish(e *Event, val interface{}) {
 }
 
 func On(e *Event, f func(s interface{})) {
-	e.Callbacks = append(e.Callbacks, callback{f, false})
+	e.addCallback(callback{f, false})
 }
 
 func Once(e *Event, f func(s interface{})) {
-	e.Callbacks = append(e.Callbacks, callback{f, true})
+	e.addCallback(callback{f, true})
 }
 
 func Rand(max int) int {
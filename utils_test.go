@@ -15,6 +15,27 @@ func TestEvery(t *testing.T) {
 	Assert(t, i, 2)
 }
 
+func TestPoll(t *testing.T) {
+	calls := make(chan bool)
+	halt := Poll(time.Millisecond, func() {
+		calls <- true
+	})
+	// each call waits for the last to be counted
+	for i := 0; i < 3; i++ {
+		<-calls
+	}
+	close(halt)
+	// only a call already waiting to be counted may follow
+	for late := 0; late <= 1; late++ {
+		select {
+		case <-calls:
+		case <-time.After(20 * time.Millisecond):
+			return
+		}
+	}
+	t.Errorf("Poll called f after it was halted")
+}
+
 func TestAfter(t *testing.T) {
 	i := 0
 	After(1*time.Millisecond, func() {
@@ -77,3 +98,19 @@ func TestRand(t *testing.T) {
 		t.Error(fmt.Sprintf("%v should not equal %v", a, b))
 	}
 }
+
+func TestOnConcurrent(t *testing.T) {
+	e := NewEvent()
+	done := make(chan bool)
+	for i := 0; i < 10; i++ {
+		go func() {
+			On(e, func(data interface{}) {})
+			Once(e, func(data interface{}) {})
+			Publish(e, 1)
+			done <- true
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		<-done
+	}
+}